@@ -0,0 +1,143 @@
+package abrsim
+
+import (
+	"math"
+	"time"
+)
+
+// Constants for the default algorithm parameters
+const (
+	DEFAULT_SAFETY_FACTOR     = 0.9
+	DEFAULT_THROUGHPUT_WINDOW = 3
+	DEFAULT_RESERVOIR         = 5 * time.Second
+	DEFAULT_CUSHION           = 20 * time.Second
+	DEFAULT_BOLA_GAMMA_P      = 5
+)
+
+// State is what an Algorithm knows when choosing the Level of the next segment.
+type State struct {
+	Ladder      *Ladder
+	Segment     int           // Index of the next segment
+	Buffer      time.Duration // Media buffered ahead of the playhead
+	MaxBuffer   time.Duration
+	Last        int       // Level of the previous segment, -1 for the first segment
+	Throughputs []float64 // Measured throughput of the previous downloads in bits per second, oldest first
+}
+
+// Algorithm chooses the Level of each segment.
+type Algorithm interface {
+	Name() string
+	// Choose returns the index in Ladder.Levels of the Level to download the next segment from.
+	Choose(s *State) int
+}
+
+// highestBelow returns the highest Level with a bandwidth of at most bitrate, the lowest one when
+// there is none.
+func highestBelow(l *Ladder, bitrate float64) int {
+	level := 0
+	for i, lv := range l.Levels {
+		if float64(lv.Bandwidth) <= bitrate {
+			level = i
+		}
+	}
+	return level
+}
+
+// ThroughputRule picks the highest Level below the harmonic mean of the recent throughput, scaled
+// by a safety factor, as the rate based rule of dash.js.
+type ThroughputRule struct {
+	SafetyFactor float64 // Fraction of the estimated throughput to use (i.e. 0.9)
+	Window       int     // Number of downloads in the estimate
+}
+
+// Creates a new ThroughputRule with the default safety factor and window.
+func NewThroughputRule() *ThroughputRule {
+	return &ThroughputRule{SafetyFactor: DEFAULT_SAFETY_FACTOR, Window: DEFAULT_THROUGHPUT_WINDOW}
+}
+
+func (a *ThroughputRule) Name() string {
+	return "throughput"
+}
+
+func (a *ThroughputRule) Choose(s *State) int {
+	if len(s.Throughputs) == 0 {
+		return 0
+	}
+	samples := s.Throughputs[max(0, len(s.Throughputs)-max(1, a.Window)):]
+	var inverse float64
+	for _, t := range samples {
+		inverse += 1 / t
+	}
+	return highestBelow(s.Ladder, a.SafetyFactor*float64(len(samples))/inverse)
+}
+
+// BufferRule maps the buffer level to a bitrate, as the BBA-0 algorithm of Huang et al. (2014):
+// the lowest Level below the reservoir, the highest one above the reservoir and cushion, and a
+// linear map in between.
+type BufferRule struct {
+	Reservoir time.Duration
+	Cushion   time.Duration
+}
+
+// Creates a new BufferRule with the default reservoir and cushion.
+func NewBufferRule() *BufferRule {
+	return &BufferRule{Reservoir: DEFAULT_RESERVOIR, Cushion: DEFAULT_CUSHION}
+}
+
+func (a *BufferRule) Name() string {
+	return "buffer"
+}
+
+func (a *BufferRule) Choose(s *State) int {
+	top := len(s.Ladder.Levels) - 1
+	switch {
+	case s.Buffer <= a.Reservoir:
+		return 0
+	case s.Buffer >= a.Reservoir+a.Cushion:
+		return top
+	}
+	lowest := float64(s.Ladder.Levels[0].Bandwidth)
+	highest := float64(s.Ladder.Levels[top].Bandwidth)
+	rate := lowest + (highest-lowest)*float64(s.Buffer-a.Reservoir)/float64(a.Cushion)
+	return highestBelow(s.Ladder, rate)
+}
+
+// BOLA maximizes a utility of the buffer level and the logarithmic bitrate utility of each Level,
+// as BOLA-BASIC of Spiteri et al. (2016).
+type BOLA struct {
+	GammaP float64 // Weight of rebuffering avoidance against the bitrate utility (γp)
+}
+
+// Creates a new BOLA with the default gamma p.
+func NewBOLA() *BOLA {
+	return &BOLA{GammaP: DEFAULT_BOLA_GAMMA_P}
+}
+
+func (a *BOLA) Name() string {
+	return "bola"
+}
+
+func (a *BOLA) Choose(s *State) int {
+	levels := s.Ladder.Levels
+	p := levels[0].Segments[s.Segment].Duration.Seconds()
+	if p <= 0 {
+		return 0
+	}
+	// Buffer capacity in segments, at least 2 for V to be positive
+	qMax := math.Max(2, s.MaxBuffer.Seconds()/p)
+	lowest := float64(levels[0].Bandwidth)
+	utility := func(i int) float64 {
+		return math.Log(float64(levels[i].Bandwidth) / lowest)
+	}
+	v := (qMax - 1) / (utility(len(levels)-1) + a.GammaP)
+	q := s.Buffer.Seconds() / p
+
+	best, bestScore := 0, math.Inf(-1)
+	for i, lv := range levels {
+		score := (v*(utility(i)+a.GammaP) - q) / float64(lv.Segments[s.Segment].Size+1)
+		if score > bestScore {
+			best, bestScore = i, score
+		}
+	}
+	return best
+}
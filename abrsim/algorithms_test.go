@@ -0,0 +1,64 @@
+package abrsim
+
+import (
+	"testing"
+	"time"
+
+	"github.com/zencoder/go-dash/v3/helpers/require"
+)
+
+func TestThroughputRule(t *testing.T) {
+	a := NewThroughputRule()
+	s := &State{Ladder: newTestLadder(t), Last: -1}
+	require.EqualInt(t, 0, a.Choose(s))
+
+	// 0.9 * 1.2 Mbps
+	s.Throughputs = []float64{1200000}
+	require.EqualInt(t, 1, a.Choose(s))
+
+	// The harmonic mean of the last 3 is 3.6 Mbps, 0.9 * 3.6 Mbps is above the top level
+	s.Throughputs = []float64{100, 2400000, 3600000, 7200000}
+	require.EqualInt(t, 2, a.Choose(s))
+
+	// Below the lowest level
+	s.Throughputs = []float64{100000}
+	require.EqualInt(t, 0, a.Choose(s))
+}
+
+func TestBufferRule(t *testing.T) {
+	a := NewBufferRule()
+	s := &State{Ladder: newTestLadder(t), Last: -1}
+	for _, tc := range []struct {
+		buffer time.Duration
+		level  int
+	}{
+		{0, 0},
+		{5 * time.Second, 0},
+		// 500k + 2.5M * 5 / 20
+		{10 * time.Second, 1},
+		{24 * time.Second, 1},
+		{25 * time.Second, 2},
+		{30 * time.Second, 2},
+	} {
+		s.Buffer = tc.buffer
+		require.EqualInt(t, tc.level, a.Choose(s), tc.buffer.String())
+	}
+}
+
+func TestBOLA(t *testing.T) {
+	a := NewBOLA()
+	s := &State{Ladder: newTestLadder(t), MaxBuffer: 30 * time.Second, Last: -1}
+	previous := 0
+	for buffer := time.Duration(0); buffer <= 30*time.Second; buffer += time.Second {
+		s.Buffer = buffer
+		level := a.Choose(s)
+		if level < previous {
+			t.Errorf("Level %d at %s buffer is below level %d with less buffer", level, buffer, previous)
+		}
+		previous = level
+	}
+	s.Buffer = 0
+	require.EqualInt(t, 0, a.Choose(s))
+	s.Buffer = 30 * time.Second
+	require.EqualInt(t, 2, a.Choose(s))
+}
@@ -0,0 +1,209 @@
+// Package abrsim simulates adaptive bitrate playback of an MPEG-DASH encoding ladder over a
+// network bandwidth trace, to compare ladders and ABR algorithms offline.
+package abrsim
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zencoder/go-dash/v3/mpd"
+)
+
+// Known error variables
+var (
+	ErrNoRepresentations = errors.New("AdaptationSet has no Representations")
+	ErrNoBandwidth       = errors.New("Representation has no bandwidth")
+	ErrNoSegments        = errors.New("Representations have no media segments")
+	ErrNoVideo           = errors.New("MPD has no video AdaptationSet")
+	ErrSizeCount         = errors.New("Size table doesn't match the segment count")
+	ErrUnknownLevel      = errors.New("Size table Representation not in the ladder")
+	ErrTraceEmpty        = errors.New("Trace has no bandwidth")
+	ErrTraceInvalid      = errors.New("Invalid trace line")
+	ErrSizeTableInvalid  = errors.New("Invalid size table line")
+)
+
+// Ladder is the Representations of an AdaptationSet, from the lowest to the highest bandwidth.
+type Ladder struct {
+	Levels []*Level
+}
+
+// Level is a Representation of the ladder with its media segments.
+type Level struct {
+	Representation *mpd.Representation
+	ID             string
+	Bandwidth      int64 // Declared @bandwidth in bits per second
+	Segments       []Segment
+}
+
+// Segment is a media segment of a Level.
+type Segment struct {
+	Duration time.Duration
+	Size     int64 // In bytes
+}
+
+// Creates a new Ladder from the Representations of an AdaptationSet, with segment durations from
+// their segment addressing and sizes estimated from their @bandwidth. The Levels keep the segments
+// all the Representations have.
+// as - AdaptationSet of the ladder.
+// periodDuration - duration of the Period, needed for SegmentTemplate@duration.
+func NewLadder(as *mpd.AdaptationSet, periodDuration time.Duration) (*Ladder, error) {
+	if len(as.Representations) == 0 {
+		return nil, ErrNoRepresentations
+	}
+	l := &Ladder{}
+	count := -1
+	for _, r := range as.Representations {
+		id := ""
+		if r.ID != nil {
+			id = *r.ID
+		}
+		if r.Bandwidth == nil || *r.Bandwidth <= 0 {
+			return nil, fmt.Errorf("%w: %q", ErrNoBandwidth, id)
+		}
+		linked := *r
+		linked.AdaptationSet = as
+		rs, err := linked.Segments(periodDuration)
+		if err != nil {
+			return nil, fmt.Errorf("Representation %s: %w", id, err)
+		}
+		level := &Level{Representation: r, ID: id, Bandwidth: *r.Bandwidth}
+		for _, s := range rs.Media {
+			d := time.Duration(float64(s.Duration) / float64(rs.Timescale) * float64(time.Second))
+			level.Segments = append(level.Segments, Segment{
+				Duration: d,
+				Size:     int64(float64(level.Bandwidth) * d.Seconds() / 8),
+			})
+		}
+		if count < 0 || len(level.Segments) < count {
+			count = len(level.Segments)
+		}
+		l.Levels = append(l.Levels, level)
+	}
+	if count == 0 {
+		return nil, ErrNoSegments
+	}
+	for _, level := range l.Levels {
+		level.Segments = level.Segments[:count]
+	}
+	sort.SliceStable(l.Levels, func(i, j int) bool {
+		return l.Levels[i].Bandwidth < l.Levels[j].Bandwidth
+	})
+	return l, nil
+}
+
+// VideoLadder returns the Ladder of the first video AdaptationSet of the first Period.
+func VideoLadder(m *mpd.MPD) (*Ladder, error) {
+	if len(m.Periods) == 0 || m.Periods[0] == nil {
+		return nil, ErrNoVideo
+	}
+	p := m.Periods[0]
+	duration := time.Duration(p.Duration)
+	if duration == 0 && len(m.Periods) == 1 && m.MediaPresentationDuration != nil {
+		d, err := mpd.ParseDuration(*m.MediaPresentationDuration)
+		if err != nil {
+			return nil, err
+		}
+		duration = d
+		if p.Start != nil {
+			duration -= time.Duration(*p.Start)
+		}
+	}
+	for _, as := range p.AdaptationSets {
+		if isVideo(as) {
+			return NewLadder(as, duration)
+		}
+	}
+	return nil, ErrNoVideo
+}
+
+func isVideo(as *mpd.AdaptationSet) bool {
+	if as.ContentType != nil {
+		return *as.ContentType == "video"
+	}
+	if as.MimeType != nil {
+		return strings.HasPrefix(*as.MimeType, "video/")
+	}
+	for _, r := range as.Representations {
+		if r.MimeType != nil && strings.HasPrefix(*r.MimeType, "video/") || r.Width != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// SegmentCount returns the number of media segments of every Level.
+func (l *Ladder) SegmentCount() int {
+	return len(l.Levels[0].Segments)
+}
+
+// SetSizes replaces the estimated segment sizes with the actual ones.
+// sizes - segment sizes in bytes by Representation id, as read by ReadSizeTable.
+func (l *Ladder) SetSizes(sizes map[string][]int64) error {
+	for id, list := range sizes {
+		var level *Level
+		for _, lv := range l.Levels {
+			if lv.ID == id {
+				level = lv
+			}
+		}
+		if level == nil {
+			return fmt.Errorf("%w: %q", ErrUnknownLevel, id)
+		}
+		if len(list) < len(level.Segments) {
+			return fmt.Errorf("%w: %q has %d sizes for %d segments", ErrSizeCount, id, len(list), len(level.Segments))
+		}
+		for i := range level.Segments {
+			level.Segments[i].Size = list[i]
+		}
+	}
+	return nil
+}
+
+// ReadSizeTable reads segment sizes, one Representation per line: its id followed by the sizes of
+// its segments in bytes, separated by spaces or commas (i.e. 800,501234,498765,512000). Empty lines
+// and lines starting with # are ignored.
+func ReadSizeTable(r io.Reader) (map[string][]int64, error) {
+	sizes := map[string][]int64{}
+	err := readFields(r, func(line int, fields []string) error {
+		if len(fields) < 2 {
+			return fmt.Errorf("%w %d", ErrSizeTableInvalid, line)
+		}
+		for _, f := range fields[1:] {
+			size, err := strconv.ParseInt(f, 10, 64)
+			if err != nil || size < 0 {
+				return fmt.Errorf("%w %d: %q", ErrSizeTableInvalid, line, f)
+			}
+			sizes[fields[0]] = append(sizes[fields[0]], size)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sizes, nil
+}
+
+// readFields calls fn with the line number and the space or comma separated fields of every line
+// that isn't empty or a # comment.
+func readFields(r io.Reader, fn func(line int, fields []string) error) error {
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.FieldsFunc(text, func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\t'
+		})
+		if err := fn(line, fields); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
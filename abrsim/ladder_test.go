@@ -0,0 +1,100 @@
+package abrsim
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zencoder/go-dash/v3/helpers/require"
+	"github.com/zencoder/go-dash/v3/mpd"
+)
+
+// newTestMPD returns 20 seconds of 2 second segments in 3 Representations, out of order.
+func newTestMPD(t *testing.T) *mpd.MPD {
+	m := mpd.NewMPD(mpd.DASH_PROFILE_LIVE, "PT20S", "PT2S")
+	audio, err := m.AddNewAdaptationSetAudio("audio/mp4", true, 1, "en")
+	require.NoError(t, err)
+	_, err = audio.SetNewSegmentTemplate(2000, "$RepresentationID$/init.mp4", "$RepresentationID$/$Number$.m4s", 1, 1000)
+	require.NoError(t, err)
+	_, err = audio.AddNewRepresentationAudio(48000, 128000, "mp4a.40.2", "audio")
+	require.NoError(t, err)
+
+	video, err := m.AddNewAdaptationSetVideo("video/mp4", "progressive", true, 1)
+	require.NoError(t, err)
+	_, err = video.SetNewSegmentTemplate(2000, "$RepresentationID$/init.mp4", "$RepresentationID$/$Number$.m4s", 1, 1000)
+	require.NoError(t, err)
+	for _, r := range []struct {
+		id        string
+		bandwidth int64
+	}{{"1000k", 1000000}, {"500k", 500000}, {"3000k", 3000000}} {
+		_, err = video.AddNewRepresentationVideo(r.bandwidth, "avc1.4d401f", r.id, "30", 1280, 720)
+		require.NoError(t, err)
+	}
+	return m
+}
+
+func newTestLadder(t *testing.T) *Ladder {
+	l, err := VideoLadder(newTestMPD(t))
+	require.NoError(t, err)
+	return l
+}
+
+func TestVideoLadder(t *testing.T) {
+	l := newTestLadder(t)
+	require.EqualInt(t, 3, len(l.Levels))
+	require.EqualString(t, "500k", l.Levels[0].ID)
+	require.EqualString(t, "1000k", l.Levels[1].ID)
+	require.EqualString(t, "3000k", l.Levels[2].ID)
+	require.EqualInt(t, 10, l.SegmentCount())
+	require.EqualInt(t, int(2*time.Second), int(l.Levels[0].Segments[9].Duration))
+	require.EqualUInt64(t, 125000, uint64(l.Levels[0].Segments[0].Size))
+	require.EqualUInt64(t, 750000, uint64(l.Levels[2].Segments[0].Size))
+}
+
+func TestVideoLadderFixture(t *testing.T) {
+	m, err := mpd.ReadFromFile("../mpd/fixtures/live_profile.mpd")
+	require.NoError(t, err)
+	l, err := VideoLadder(m)
+	require.NoError(t, err)
+	require.EqualInt(t, 4, len(l.Levels))
+	require.EqualInt(t, 192, l.SegmentCount())
+	require.EqualUInt64(t, 2780732, uint64(l.Levels[3].Bandwidth))
+}
+
+func TestVideoLadderErrors(t *testing.T) {
+	_, err := VideoLadder(&mpd.MPD{})
+	require.EqualErr(t, ErrNoVideo, err)
+
+	m := newTestMPD(t)
+	m.Periods[0].AdaptationSets = m.Periods[0].AdaptationSets[:1]
+	_, err = VideoLadder(m)
+	require.EqualErr(t, ErrNoVideo, err)
+
+	m = newTestMPD(t)
+	m.Periods[0].AdaptationSets[1].Representations[0].Bandwidth = nil
+	_, err = VideoLadder(m)
+	require.EqualError(t, err, `Representation has no bandwidth: "1000k"`)
+
+	_, err = NewLadder(&mpd.AdaptationSet{}, time.Minute)
+	require.EqualErr(t, ErrNoRepresentations, err)
+}
+
+func TestSetSizes(t *testing.T) {
+	l := newTestLadder(t)
+	sizes, err := ReadSizeTable(strings.NewReader("# id, sizes\n500k,1,2,3,4,5,6,7,8,9,10\n\n3000k 10 20 30 40 50 60 70 80 90 100 110\n"))
+	require.NoError(t, err)
+	require.NoError(t, l.SetSizes(sizes))
+	require.EqualUInt64(t, 10, uint64(l.Levels[0].Segments[9].Size))
+	require.EqualUInt64(t, 250000, uint64(l.Levels[1].Segments[9].Size))
+	require.EqualUInt64(t, 100, uint64(l.Levels[2].Segments[9].Size))
+
+	require.EqualError(t, l.SetSizes(map[string][]int64{"500k": {1, 2}}), `Size table doesn't match the segment count: "500k" has 2 sizes for 10 segments`)
+	require.EqualError(t, l.SetSizes(map[string][]int64{"other": {1}}), `Size table Representation not in the ladder: "other"`)
+}
+
+func TestReadSizeTableErrors(t *testing.T) {
+	_, err := ReadSizeTable(strings.NewReader("500k\n"))
+	require.EqualError(t, err, "Invalid size table line 1")
+	_, err = ReadSizeTable(strings.NewReader("\n500k 1 -2\n"))
+	require.EqualError(t, err, `Invalid size table line 2: "-2"`)
+}
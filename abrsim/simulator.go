@@ -0,0 +1,146 @@
+package abrsim
+
+import (
+	"fmt"
+	"time"
+)
+
+// Constants for the default player parameters
+const (
+	DEFAULT_MAX_BUFFER     = 30 * time.Second
+	DEFAULT_STARTUP_BUFFER = 2 * time.Second
+)
+
+// Simulator plays a Ladder over a Trace, downloading the segments one after the other.
+type Simulator struct {
+	Ladder *Ladder
+	Trace  Trace
+	// Downloads wait while the next segment doesn't fit in the buffer
+	MaxBuffer time.Duration
+	// Playback starts once this much media is buffered, or all the segments are
+	StartupBuffer time.Duration
+}
+
+// Result is the outcome of a simulated playback.
+type Result struct {
+	Algorithm    string
+	Downloads    []Download
+	Rebuffers    []Rebuffer
+	StartupDelay time.Duration // Time from the first request to the start of playback
+	RebufferTime time.Duration // Total stall time after the start of playback
+	Switches     int           // Number of Level changes between consecutive segments
+	// Average quality, as the @bandwidth of the played segments weighted by their duration
+	AverageBitrate float64
+}
+
+// Download is a segment download of the playback.
+type Download struct {
+	Segment        int // Segment index
+	Level          int // Level index in the Ladder
+	Representation string
+	Bandwidth      int64         // Declared @bandwidth of the Level
+	Start          time.Duration // Time of the request from the start of the session
+	Duration       time.Duration
+	Throughput     float64       // Measured throughput in bits per second
+	Buffer         time.Duration // Media buffered once the segment is downloaded
+}
+
+// Rebuffer is a playback stall waiting for a segment download.
+type Rebuffer struct {
+	Segment  int           // Index of the segment being downloaded
+	Start    time.Duration // Time of the stall from the start of the session
+	Duration time.Duration
+}
+
+// Creates a new Simulator with the default player parameters.
+// ladder - Representations to play, i.e. from VideoLadder.
+// trace - network bandwidth over time, i.e. from ReadTrace.
+func NewSimulator(ladder *Ladder, trace Trace) *Simulator {
+	return &Simulator{
+		Ladder:        ladder,
+		Trace:         trace,
+		MaxBuffer:     DEFAULT_MAX_BUFFER,
+		StartupBuffer: DEFAULT_STARTUP_BUFFER,
+	}
+}
+
+// Run plays all the segments of the Ladder, the Algorithm choosing the Level of each.
+func (sim *Simulator) Run(algorithm Algorithm) (*Result, error) {
+	if err := sim.Trace.validate(); err != nil {
+		return nil, err
+	}
+	state := &State{Ladder: sim.Ladder, MaxBuffer: sim.MaxBuffer, Last: -1}
+	result := &Result{Algorithm: algorithm.Name()}
+
+	var now time.Duration
+	var played time.Duration
+	var weightedBitrate float64
+	playing := false
+	count := sim.Ladder.SegmentCount()
+	for i := 0; i < count; i++ {
+		state.Segment = i
+
+		// Wait for the next segment to fit in the buffer
+		if duration := sim.Ladder.Levels[0].Segments[i].Duration; playing && state.Buffer+duration > sim.MaxBuffer {
+			wait := min(state.Buffer, state.Buffer+duration-sim.MaxBuffer)
+			now += wait
+			state.Buffer -= wait
+		}
+
+		level := algorithm.Choose(state)
+		if level < 0 || level >= len(sim.Ladder.Levels) {
+			return nil, fmt.Errorf("Algorithm %s chose level %d of %d", algorithm.Name(), level, len(sim.Ladder.Levels))
+		}
+		lv := sim.Ladder.Levels[level]
+		segment := lv.Segments[i]
+		elapsed := sim.Trace.download(now, segment.Size)
+
+		if playing {
+			if elapsed > state.Buffer {
+				stall := elapsed - state.Buffer
+				result.Rebuffers = append(result.Rebuffers, Rebuffer{Segment: i, Start: now + state.Buffer, Duration: stall})
+				result.RebufferTime += stall
+				state.Buffer = 0
+			} else {
+				state.Buffer -= elapsed
+			}
+		}
+		download := Download{
+			Segment:        i,
+			Level:          level,
+			Representation: lv.ID,
+			Bandwidth:      lv.Bandwidth,
+			Start:          now,
+			Duration:       elapsed,
+		}
+		now += elapsed
+		state.Buffer += segment.Duration
+		download.Buffer = state.Buffer
+		if elapsed > 0 {
+			download.Throughput = float64(segment.Size*8) / elapsed.Seconds()
+			state.Throughputs = append(state.Throughputs, download.Throughput)
+		}
+		result.Downloads = append(result.Downloads, download)
+
+		if state.Last >= 0 && state.Last != level {
+			result.Switches++
+		}
+		state.Last = level
+		played += segment.Duration
+		weightedBitrate += float64(lv.Bandwidth) * segment.Duration.Seconds()
+
+		if !playing && (state.Buffer >= sim.StartupBuffer || i == count-1) {
+			playing = true
+			result.StartupDelay = now
+		}
+	}
+	if played > 0 {
+		result.AverageBitrate = weightedBitrate / played.Seconds()
+	}
+	return result, nil
+}
+
+func (r *Result) String() string {
+	return fmt.Sprintf("%s: %.0f bps average, %d switches, %d rebuffers (%s), startup %s",
+		r.Algorithm, r.AverageBitrate, r.Switches, len(r.Rebuffers), r.RebufferTime.Round(time.Millisecond), r.StartupDelay.Round(time.Millisecond))
+}
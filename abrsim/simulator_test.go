@@ -0,0 +1,101 @@
+package abrsim
+
+import (
+	"testing"
+	"time"
+
+	"github.com/zencoder/go-dash/v3/helpers/require"
+)
+
+// fixedLevel always chooses the same Level.
+type fixedLevel int
+
+func (a fixedLevel) Name() string {
+	return "fixed"
+}
+
+func (a fixedLevel) Choose(s *State) int {
+	return int(a)
+}
+
+func constantTrace(bandwidth float64) Trace {
+	return Trace{{Duration: time.Minute, Bandwidth: bandwidth}}
+}
+
+func TestRunThroughputRule(t *testing.T) {
+	sim := NewSimulator(newTestLadder(t), constantTrace(2000000))
+	result, err := sim.Run(NewThroughputRule())
+	require.NoError(t, err)
+
+	require.EqualString(t, "throughput", result.Algorithm)
+	require.EqualInt(t, 10, len(result.Downloads))
+	require.EqualString(t, "500k", result.Downloads[0].Representation)
+	require.EqualInt(t, int(500*time.Millisecond), int(result.Downloads[0].Duration))
+	require.EqualFloat64(t, 2000000, result.Downloads[0].Throughput)
+	for _, d := range result.Downloads[1:] {
+		require.EqualString(t, "1000k", d.Representation)
+		require.EqualInt(t, int(time.Second), int(d.Duration))
+	}
+	require.EqualInt(t, 1, result.Switches)
+	require.EqualInt(t, 0, len(result.Rebuffers))
+	require.EqualInt(t, int(500*time.Millisecond), int(result.StartupDelay))
+	require.EqualFloat64(t, 950000, result.AverageBitrate)
+	require.EqualString(t, "throughput: 950000 bps average, 1 switches, 0 rebuffers (0s), startup 500ms", result.String())
+}
+
+func TestRunRebuffers(t *testing.T) {
+	// 3 seconds to download each 2 second segment
+	sim := NewSimulator(newTestLadder(t), constantTrace(2000000))
+	result, err := sim.Run(fixedLevel(2))
+	require.NoError(t, err)
+
+	require.EqualInt(t, int(3*time.Second), int(result.StartupDelay))
+	require.EqualInt(t, 9, len(result.Rebuffers))
+	require.EqualInt(t, 1, result.Rebuffers[0].Segment)
+	require.EqualInt(t, int(5*time.Second), int(result.Rebuffers[0].Start))
+	require.EqualInt(t, int(time.Second), int(result.Rebuffers[0].Duration))
+	require.EqualInt(t, int(9*time.Second), int(result.RebufferTime))
+	require.EqualInt(t, 0, result.Switches)
+	require.EqualFloat64(t, 3000000, result.AverageBitrate)
+}
+
+func TestRunMaxBuffer(t *testing.T) {
+	sim := NewSimulator(newTestLadder(t), constantTrace(100000000))
+	sim.MaxBuffer = 6 * time.Second
+	result, err := sim.Run(fixedLevel(0))
+	require.NoError(t, err)
+
+	for _, d := range result.Downloads {
+		if d.Buffer > sim.MaxBuffer {
+			t.Errorf("Segment %d buffer %s is above the maximum", d.Segment, d.Buffer)
+		}
+	}
+	// Downloads follow the playback once the buffer is full
+	require.EqualInt(t, int(2*time.Second), int(result.Downloads[9].Start-result.Downloads[8].Start))
+	require.EqualInt(t, 0, len(result.Rebuffers))
+}
+
+func TestRunAlgorithms(t *testing.T) {
+	sim := NewSimulator(newTestLadder(t), Trace{
+		{Duration: 10 * time.Second, Bandwidth: 4000000},
+		{Duration: 10 * time.Second, Bandwidth: 600000},
+	})
+	for _, a := range []Algorithm{NewThroughputRule(), NewBufferRule(), NewBOLA()} {
+		result, err := sim.Run(a)
+		require.NoError(t, err)
+		require.EqualInt(t, 10, len(result.Downloads), a.Name())
+		if result.AverageBitrate < 500000 || result.AverageBitrate > 3000000 {
+			t.Errorf("%s average bitrate %f out of the ladder", a.Name(), result.AverageBitrate)
+		}
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	sim := NewSimulator(newTestLadder(t), constantTrace(0))
+	_, err := sim.Run(fixedLevel(0))
+	require.EqualErr(t, ErrTraceEmpty, err)
+
+	sim.Trace = constantTrace(1000000)
+	_, err = sim.Run(fixedLevel(3))
+	require.EqualError(t, err, "Algorithm fixed chose level 3 of 3")
+}
@@ -0,0 +1,98 @@
+package abrsim
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// Trace is the available network bandwidth over time, looped when the playback outlasts it.
+type Trace []TraceSample
+
+// TraceSample is a period of constant bandwidth.
+type TraceSample struct {
+	Duration  time.Duration
+	Bandwidth float64 // In bits per second
+}
+
+// ReadTrace reads a bandwidth trace, one sample per line: its duration in seconds and the
+// bandwidth in bits per second, separated by spaces or commas (i.e. 1.5 4000000). Empty lines and
+// lines starting with # are ignored.
+func ReadTrace(r io.Reader) (Trace, error) {
+	var trace Trace
+	err := readFields(r, func(line int, fields []string) error {
+		if len(fields) != 2 {
+			return fmt.Errorf("%w %d", ErrTraceInvalid, line)
+		}
+		seconds, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil || seconds <= 0 {
+			return fmt.Errorf("%w %d: duration %q", ErrTraceInvalid, line, fields[0])
+		}
+		bandwidth, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil || bandwidth < 0 {
+			return fmt.Errorf("%w %d: bandwidth %q", ErrTraceInvalid, line, fields[1])
+		}
+		trace = append(trace, TraceSample{
+			Duration:  time.Duration(seconds * float64(time.Second)),
+			Bandwidth: bandwidth,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := trace.validate(); err != nil {
+		return nil, err
+	}
+	return trace, nil
+}
+
+// validate checks that downloads can complete, the trace needs some bandwidth.
+func (tr Trace) validate() error {
+	for _, s := range tr {
+		if s.Duration > 0 && s.Bandwidth > 0 {
+			return nil
+		}
+	}
+	return ErrTraceEmpty
+}
+
+// Duration returns the length of the trace before it loops.
+func (tr Trace) Duration() time.Duration {
+	var d time.Duration
+	for _, s := range tr {
+		d += s.Duration
+	}
+	return d
+}
+
+// download returns how long downloading size bytes takes when starting at start.
+func (tr Trace) download(start time.Duration, size int64) time.Duration {
+	bits := float64(size) * 8
+	if bits <= 0 {
+		return 0
+	}
+
+	// Find the sample playing at start
+	pos := start % tr.Duration()
+	i := 0
+	for pos >= tr[i].Duration {
+		pos -= tr[i].Duration
+		i++
+	}
+
+	var elapsed time.Duration
+	for {
+		s := tr[i]
+		remaining := s.Duration - pos
+		capacity := s.Bandwidth * remaining.Seconds()
+		if capacity >= bits {
+			return elapsed + time.Duration(bits/s.Bandwidth*float64(time.Second))
+		}
+		bits -= capacity
+		elapsed += remaining
+		pos = 0
+		i = (i + 1) % len(tr)
+	}
+}
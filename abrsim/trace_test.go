@@ -0,0 +1,47 @@
+package abrsim
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zencoder/go-dash/v3/helpers/require"
+)
+
+func TestReadTrace(t *testing.T) {
+	trace, err := ReadTrace(strings.NewReader("# seconds bps\n1 8000000\n0.5,0\n\n2.5\t1000000\n"))
+	require.NoError(t, err)
+	require.EqualInt(t, 3, len(trace))
+	require.EqualInt(t, int(500*time.Millisecond), int(trace[1].Duration))
+	require.EqualFloat64(t, 1000000, trace[2].Bandwidth)
+	require.EqualInt(t, int(4*time.Second), int(trace.Duration()))
+}
+
+func TestReadTraceErrors(t *testing.T) {
+	_, err := ReadTrace(strings.NewReader("1 2 3\n"))
+	require.EqualError(t, err, "Invalid trace line 1")
+	_, err = ReadTrace(strings.NewReader("0 1000\n"))
+	require.EqualError(t, err, `Invalid trace line 1: duration "0"`)
+	_, err = ReadTrace(strings.NewReader("1 fast\n"))
+	require.EqualError(t, err, `Invalid trace line 1: bandwidth "fast"`)
+	_, err = ReadTrace(strings.NewReader("1 0\n"))
+	require.EqualErr(t, ErrTraceEmpty, err)
+	_, err = ReadTrace(strings.NewReader(""))
+	require.EqualErr(t, ErrTraceEmpty, err)
+}
+
+func TestTraceDownload(t *testing.T) {
+	// 1 MB/s for 1s, an outage of 1s, then 0.5 MB/s for 2s
+	trace := Trace{
+		{Duration: time.Second, Bandwidth: 8000000},
+		{Duration: time.Second, Bandwidth: 0},
+		{Duration: 2 * time.Second, Bandwidth: 4000000},
+	}
+	require.EqualInt(t, int(500*time.Millisecond), int(trace.download(0, 500000)))
+	// Across the outage
+	require.EqualInt(t, int(2500*time.Millisecond), int(trace.download(500*time.Millisecond, 1000000)))
+	// Looping to the start of the trace
+	require.EqualInt(t, int(1500*time.Millisecond), int(trace.download(3*time.Second, 1000000)))
+	require.EqualInt(t, int(1500*time.Millisecond), int(trace.download(7*time.Second, 1000000)))
+	require.EqualInt(t, 0, int(trace.download(0, 0)))
+}
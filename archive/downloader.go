@@ -0,0 +1,397 @@
+// Package archive mirrors MPEG-DASH presentations: it downloads the init and media segments of the
+// selected Representations of an MPD and writes an MPD addressing the local copies.
+package archive
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zencoder/go-dash/v3/mpd"
+)
+
+// Constants for the Downloader defaults
+const (
+	DEFAULT_CONCURRENCY   = 4
+	DEFAULT_RETRIES       = 3
+	DEFAULT_RETRY_DELAY   = time.Second
+	DEFAULT_MANIFEST_NAME = "manifest.mpd"
+	// Suffix of the files being downloaded, renamed once complete
+	PART_SUFFIX = ".part"
+)
+
+// Known error variables
+var (
+	ErrManifestURL  = errors.New("Manifest URL must be absolute")
+	ErrInvalidRange = errors.New("Invalid byte range")
+	ErrInvalidURL   = errors.New("Invalid segment URL")
+)
+
+// Downloader archives the segments of an MPD with bounded concurrency. Interrupted downloads
+// resume where they stopped: complete files are skipped and partial ones continued with range
+// requests.
+type Downloader struct {
+	Client       *http.Client // http.DefaultClient when nil
+	Concurrency  int          // Simultaneous downloads
+	Retries      int          // Attempts after the first for network failures and 5xx responses
+	RetryDelay   time.Duration
+	ManifestName string // Name of the rewritten MPD in the destination
+}
+
+// Creates a new Downloader with the default HTTP client, concurrency and retries.
+func NewDownloader() *Downloader {
+	return &Downloader{
+		Concurrency:  DEFAULT_CONCURRENCY,
+		Retries:      DEFAULT_RETRIES,
+		RetryDelay:   DEFAULT_RETRY_DELAY,
+		ManifestName: DEFAULT_MANIFEST_NAME,
+	}
+}
+
+// file is a local copy of a remote file, fetched in full or up to the last byte range of its
+// segments. Byte ranges keep their offsets, so the MPD addressing stays valid.
+type file struct {
+	url  string
+	name string
+	end  int64 // Last byte needed, -1 for the whole file
+}
+
+// Download archives the Representations of the MPD matching all the filters to dest, and writes
+// the MPD with only these Representations and BaseURLs pointing at the local copies, which it
+// returns. Files mirror the layout of the segment URLs relative to the manifest, and URLs outside
+// of its directory go under their host name. The MPD is written last, once every segment is.
+// m - manifest to archive, not modified.
+// manifestURL - URL m was loaded from, against which BaseURLs resolve (i.e. https://cdn.example.com/vod/manifest.mpd).
+// dest - destination file system (i.e. NewDirFS("archive")).
+func (d *Downloader) Download(ctx context.Context, m *mpd.MPD, manifestURL string, dest FS, filters ...mpd.RepresentationFilter) (*mpd.MPD, error) {
+	location, err := url.Parse(manifestURL)
+	if err != nil || !location.IsAbs() {
+		return nil, fmt.Errorf("%w: %q", ErrManifestURL, manifestURL)
+	}
+	archived := m.Filter(filters...)
+	files, err := plan(archived, location)
+	if err != nil {
+		return nil, err
+	}
+	if err := d.fetchAll(ctx, dest, files); err != nil {
+		return nil, err
+	}
+
+	manifest, err := archived.WriteToString()
+	if err != nil {
+		return nil, err
+	}
+	name := d.ManifestName
+	if name == "" {
+		name = DEFAULT_MANIFEST_NAME
+	}
+	if err := writeFile(dest, name, manifest); err != nil {
+		return nil, err
+	}
+	return archived, nil
+}
+
+// plan lists the files of the Representations of m, and rewrites its BaseURLs to their local
+// copies.
+func plan(m *mpd.MPD, location *url.URL) ([]*file, error) {
+	root := location.ResolveReference(&url.URL{Path: "."})
+	var files []*file
+	byName := map[string]*file{}
+	add := func(u *url.URL, byteRange string) error {
+		name := localName(root, u)
+		if name == "" {
+			return fmt.Errorf("%w: %s", ErrInvalidURL, u)
+		}
+		end := int64(-1)
+		if byteRange != "" {
+			var err error
+			if end, err = rangeEnd(byteRange); err != nil {
+				return fmt.Errorf("%s: %w", u, err)
+			}
+		}
+		// The query is not part of the local name, so URLs differing only in their query would
+		// overwrite each other
+		f, ok := byName[name]
+		switch {
+		case ok && f.url != u.String():
+			return fmt.Errorf("%w: %s and %s are both saved as %q", ErrInvalidURL, f.url, u, name)
+		case !ok:
+			f = &file{url: u.String(), name: name, end: end}
+			byName[name] = f
+			files = append(files, f)
+		case f.end >= 0 && (end < 0 || end > f.end):
+			f.end = end
+		}
+		return nil
+	}
+
+	baseURLs := map[*mpd.Representation][]string{}
+	durations, err := m.PeriodDurations()
+	if err != nil {
+		return nil, err
+	}
+	for i, p := range m.Periods {
+		duration := durations[i]
+		for _, as := range p.AdaptationSets {
+			for _, r := range as.Representations {
+				base, err := resolveBaseURL(location, m.BaseURL, p.BaseURL, as.BaseURL, r.BaseURL)
+				if err != nil {
+					return nil, err
+				}
+				rs, err := r.Segments(duration)
+				if err != nil {
+					return nil, fmt.Errorf("Representation %s: %w", strOrEmpty(r.ID), err)
+				}
+
+				wholeFile := false
+				if rs.Initialization != "" || rs.InitializationRange != "" {
+					u, err := base.Parse(rs.Initialization)
+					if err != nil {
+						return nil, fmt.Errorf("%w: %s", ErrInvalidURL, rs.Initialization)
+					}
+					if err := add(u, rs.InitializationRange); err != nil {
+						return nil, err
+					}
+				}
+				for _, s := range rs.Media {
+					u, err := base.Parse(s.URL)
+					if err != nil {
+						return nil, fmt.Errorf("%w: %s", ErrInvalidURL, s.URL)
+					}
+					if err := add(u, s.Range); err != nil {
+						return nil, err
+					}
+					wholeFile = wholeFile || s.URL == ""
+				}
+
+				// Segment URLs resolve against the directory of the BaseURL, unless they are the
+				// BaseURL itself
+				localBase := localName(root, base)
+				if !wholeFile {
+					localBase = localName(root, base.ResolveReference(&url.URL{Path: "."}))
+					if localBase != "" {
+						localBase += "/"
+					}
+				}
+				baseURLs[r] = nil
+				if localBase != "" {
+					baseURLs[r] = []string{localBase}
+				}
+			}
+		}
+	}
+
+	m.BaseURL = nil
+	for _, p := range m.Periods {
+		p.BaseURL = nil
+		for _, as := range p.AdaptationSets {
+			as.BaseURL = nil
+			for _, r := range as.Representations {
+				r.BaseURL = baseURLs[r]
+			}
+		}
+	}
+	return files, nil
+}
+
+// fetchAll downloads the files, stopping at the first failure.
+func (d *Downloader) fetchAll(ctx context.Context, dest FS, files []*file) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var mu sync.Mutex
+	var firstErr error
+	jobs := make(chan *file)
+	var wg sync.WaitGroup
+	for i := 0; i < max(d.Concurrency, 1); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for f := range jobs {
+				if err := d.fetch(ctx, dest, f); err != nil {
+					mu.Lock()
+					if firstErr == nil {
+						firstErr = err
+					}
+					mu.Unlock()
+					cancel()
+				}
+			}
+		}()
+	}
+
+feed:
+	for _, f := range files {
+		select {
+		case jobs <- f:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
+
+// fetch downloads a file to its part file, retrying failed attempts, and renames it once complete.
+func (d *Downloader) fetch(ctx context.Context, dest FS, f *file) error {
+	if _, err := fs.Stat(dest, f.name); err == nil {
+		return nil
+	}
+	part := f.name + PART_SUFFIX
+	for attempt := 0; ; attempt++ {
+		retry, err := d.fetchPart(ctx, dest, f, part)
+		if err == nil {
+			break
+		}
+		if !retry || attempt >= d.Retries || ctx.Err() != nil {
+			return fmt.Errorf("%s: %w", f.url, err)
+		}
+		timer := time.NewTimer(d.RetryDelay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+	return dest.Rename(part, f.name)
+}
+
+// fetchPart continues the part file of a download from its current size, and reports whether a
+// failure is worth retrying.
+func (d *Downloader) fetchPart(ctx context.Context, dest FS, f *file, part string) (bool, error) {
+	var size int64
+	if info, err := fs.Stat(dest, part); err == nil {
+		size = info.Size()
+	}
+	if f.end >= 0 && size > f.end {
+		return false, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, f.url, nil)
+	if err != nil {
+		return false, err
+	}
+	switch {
+	case f.end >= 0:
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", size, f.end))
+	case size > 0:
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", size))
+	}
+	client := d.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+
+	flag := os.O_APPEND
+	switch {
+	case resp.StatusCode == http.StatusPartialContent:
+		var start int64
+		if _, err := fmt.Sscanf(resp.Header.Get("Content-Range"), "bytes %d-", &start); err != nil || start != size {
+			return false, fmt.Errorf("%w: Content-Range %q", ErrInvalidRange, resp.Header.Get("Content-Range"))
+		}
+	case resp.StatusCode == http.StatusOK:
+		// The server ignored the range, start over
+		flag, size = os.O_TRUNC, 0
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && f.end < 0 && size > 0:
+		// The part file already has the whole file
+		return false, nil
+	default:
+		retry := resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests
+		return retry, errors.New(resp.Status)
+	}
+
+	var body io.Reader = resp.Body
+	if f.end >= 0 {
+		body = io.LimitReader(resp.Body, f.end+1-size)
+	}
+	w, err := dest.OpenFile(part, flag)
+	if err != nil {
+		return false, err
+	}
+	if _, err := io.Copy(w, body); err != nil {
+		w.Close()
+		return true, err
+	}
+	return false, w.Close()
+}
+
+// writeFile writes a complete file, through a part file so that it only appears once written.
+func writeFile(dest FS, name, content string) error {
+	w, err := dest.OpenFile(name+PART_SUFFIX, os.O_TRUNC)
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, content); err != nil {
+		w.Close()
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return dest.Rename(name+PART_SUFFIX, name)
+}
+
+// resolveBaseURL resolves the first BaseURL of every level against the manifest location.
+func resolveBaseURL(location *url.URL, levels ...[]string) (*url.URL, error) {
+	base := location
+	for _, baseURLs := range levels {
+		if len(baseURLs) == 0 {
+			continue
+		}
+		u, err := base.Parse(strings.TrimSpace(baseURLs[0]))
+		if err != nil {
+			return nil, fmt.Errorf("%w: BaseURL %q", ErrInvalidURL, baseURLs[0])
+		}
+		base = u
+	}
+	return base, nil
+}
+
+// localName returns the path of the local copy of a URL: relative to root when under it, under its
+// host name otherwise. The query is left out. Empty for root itself.
+func localName(root, u *url.URL) string {
+	name := path.Join(u.Host, u.Path)
+	if u.Scheme == root.Scheme && u.Host == root.Host && strings.HasPrefix(u.Path, root.Path) {
+		name = strings.TrimPrefix(u.Path, root.Path)
+	}
+	return strings.TrimPrefix(path.Clean("/"+name), "/")
+}
+
+// rangeEnd returns the last byte of a byte range (i.e. 757 for 0-757).
+func rangeEnd(byteRange string) (int64, error) {
+	start, end, ok := strings.Cut(byteRange, "-")
+	first, err1 := strconv.ParseInt(start, 10, 64)
+	last, err2 := strconv.ParseInt(end, 10, 64)
+	if !ok || err1 != nil || err2 != nil || last < first {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidRange, byteRange)
+	}
+	return last, nil
+}
+
+func strOrEmpty(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,294 @@
+package archive
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zencoder/go-dash/v3/helpers/ptrs"
+	"github.com/zencoder/go-dash/v3/helpers/require"
+	"github.com/zencoder/go-dash/v3/helpers/testfixtures"
+	"github.com/zencoder/go-dash/v3/mpd"
+)
+
+const TEST_MANIFEST_URL = "http://cdn.example.com/vod/manifest.mpd"
+
+// testOrigin serves files by host and path, failing the first requests of the ones in failures.
+type testOrigin struct {
+	mu       sync.Mutex
+	files    map[string]string
+	failures map[string]int
+	requests []string
+}
+
+func newTestOrigin() *testOrigin {
+	return &testOrigin{
+		files: map[string]string{
+			"cdn.example.com/vod/media/video/800/init.mp4": "init-800",
+			"cdn.example.com/vod/media/video/800/1.m4s":    "segment-800-1",
+			"cdn.example.com/vod/media/video/800/2.m4s":    "segment-800-2",
+			"cdn.example.com/vod/media/video/800/3.m4s":    "segment-800-3",
+			"cdn.example.com/vod/media/video/400/init.mp4": "init-400",
+			"audio.example.com/en/audio.mp4":               "audio-init|audio-sidx|audio-media-0123456789",
+			"cdn.example.com/vod/media/subtitles.mp4":      "subs-init.subs-seg1.gap--seg2.not-needed",
+		},
+		failures: map[string]int{},
+	}
+}
+
+func (o *testOrigin) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key := r.Host + r.URL.Path
+	o.mu.Lock()
+	o.requests = append(o.requests, strings.TrimSpace(key+" "+r.Header.Get("Range")))
+	content, ok := o.files[key]
+	failing := o.failures[key] > 0
+	if failing {
+		o.failures[key]--
+	}
+	o.mu.Unlock()
+
+	switch {
+	case failing:
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	case !ok:
+		http.NotFound(w, r)
+	default:
+		http.ServeContent(w, r, "", time.Time{}, strings.NewReader(content))
+	}
+}
+
+func (o *testOrigin) sortedRequests() []string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	requests := append([]string(nil), o.requests...)
+	sort.Strings(requests)
+	return requests
+}
+
+// newTestDownloader returns a Downloader whose requests to any host reach the origin.
+func newTestDownloader(t *testing.T, origin http.Handler) *Downloader {
+	server := httptest.NewServer(origin)
+	t.Cleanup(server.Close)
+	d := NewDownloader()
+	d.RetryDelay = 0
+	d.Client = &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, server.Listener.Addr().String())
+		},
+	}}
+	return d
+}
+
+func withID(id string) mpd.RepresentationFilter {
+	return func(as *mpd.AdaptationSet, r *mpd.Representation) bool {
+		return r.ID != nil && *r.ID == id
+	}
+}
+
+func readSource(t *testing.T) *mpd.MPD {
+	m, err := mpd.ReadFromFile("fixtures/source.mpd")
+	require.NoError(t, err)
+	return m
+}
+
+func readDir(t *testing.T, dir string) map[string]string {
+	files := map[string]string{}
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		b, err := os.ReadFile(path)
+		rel, _ := filepath.Rel(dir, path)
+		files[filepath.ToSlash(rel)] = string(b)
+		return err
+	})
+	require.NoError(t, err)
+	return files
+}
+
+func TestDownload(t *testing.T) {
+	origin := newTestOrigin()
+	d := newTestDownloader(t, origin)
+	d.Concurrency = 2
+	dir := t.TempDir()
+	source := readSource(t)
+
+	archived, err := d.Download(context.Background(), source, TEST_MANIFEST_URL, NewDirFS(dir), mpd.Not(withID("400")))
+	require.NoError(t, err)
+	require.EqualInt(t, 2, len(source.Periods[0].AdaptationSets[0].Representations), "The source MPD is not modified")
+	require.EqualInt(t, 1, len(archived.Periods[0].AdaptationSets[0].Representations))
+
+	files := readDir(t, dir)
+	got, err := archived.WriteToString()
+	require.NoError(t, err)
+	require.EqualString(t, got, files[DEFAULT_MANIFEST_NAME])
+	testfixtures.CompareFixture(t, "fixtures/archived.mpd", got)
+
+	delete(files, DEFAULT_MANIFEST_NAME)
+	require.EqualInt(t, 6, len(files))
+	for name, content := range map[string]string{
+		"media/video/800/init.mp4":       "init-800",
+		"media/video/800/1.m4s":          "segment-800-1",
+		"media/video/800/3.m4s":          "segment-800-3",
+		"audio.example.com/en/audio.mp4": "audio-init|audio-sidx|audio-media-0123456789",
+		"media/subtitles.mp4":            "subs-init.subs-seg1.gap--seg2.",
+	} {
+		require.EqualString(t, content, files[name], name)
+	}
+	require.EqualStringSlice(t, []string{
+		"audio.example.com/en/audio.mp4",
+		"cdn.example.com/vod/media/subtitles.mp4 bytes=0-29",
+		"cdn.example.com/vod/media/video/800/1.m4s",
+		"cdn.example.com/vod/media/video/800/2.m4s",
+		"cdn.example.com/vod/media/video/800/3.m4s",
+		"cdn.example.com/vod/media/video/800/init.mp4",
+	}, origin.sortedRequests())
+
+	// The archived MPD addresses the local copies
+	local, err := mpd.ReadFromFile(filepath.Join(dir, DEFAULT_MANIFEST_NAME))
+	require.NoError(t, err)
+	r := local.Periods[0].AdaptationSets[0].Representations[0]
+	base, err := resolveBaseURL(&url.URL{}, local.BaseURL, local.Periods[0].BaseURL, r.AdaptationSet.BaseURL, r.BaseURL)
+	require.NoError(t, err)
+	u, err := base.Parse("800/2.m4s")
+	require.NoError(t, err)
+	require.EqualString(t, "segment-800-2", files[strings.TrimPrefix(u.Path, "/")])
+}
+
+func TestDownloadResume(t *testing.T) {
+	origin := newTestOrigin()
+	d := newTestDownloader(t, origin)
+	dir := t.TempDir()
+	dest := NewDirFS(dir)
+	for name, content := range map[string]string{
+		"media/video/800/1.m4s":                        "complete",
+		"media/video/800/2.m4s" + PART_SUFFIX:          "segment-",
+		"audio.example.com/en/audio.mp4" + PART_SUFFIX: "audio-init|",
+		"media/subtitles.mp4" + PART_SUFFIX:            "subs-init.subs-seg1.gap--seg2.",
+	} {
+		require.NoError(t, writeFile(dest, name, content))
+	}
+
+	_, err := d.Download(context.Background(), readSource(t), TEST_MANIFEST_URL, dest, mpd.Not(withID("400")))
+	require.NoError(t, err)
+	files := readDir(t, dir)
+	require.EqualString(t, "complete", files["media/video/800/1.m4s"])
+	require.EqualString(t, "segment-800-2", files["media/video/800/2.m4s"])
+	require.EqualString(t, "audio-init|audio-sidx|audio-media-0123456789", files["audio.example.com/en/audio.mp4"])
+	require.EqualString(t, "subs-init.subs-seg1.gap--seg2.", files["media/subtitles.mp4"])
+	require.EqualStringSlice(t, []string{
+		"audio.example.com/en/audio.mp4 bytes=11-",
+		"cdn.example.com/vod/media/video/800/2.m4s bytes=8-",
+		"cdn.example.com/vod/media/video/800/3.m4s",
+		"cdn.example.com/vod/media/video/800/init.mp4",
+	}, origin.sortedRequests())
+}
+
+func TestDownloadRetries(t *testing.T) {
+	origin := newTestOrigin()
+	origin.failures["cdn.example.com/vod/media/video/800/2.m4s"] = 2
+	d := newTestDownloader(t, origin)
+	d.Retries = 2
+	d.Concurrency = 1
+	dir := t.TempDir()
+
+	_, err := d.Download(context.Background(), readSource(t), TEST_MANIFEST_URL, NewDirFS(dir), mpd.WithBandwidth(500000, 1000000))
+	require.NoError(t, err)
+	require.EqualString(t, "segment-800-2", readDir(t, dir)["media/video/800/2.m4s"])
+
+	origin.failures["cdn.example.com/vod/media/video/400/init.mp4"] = 3
+	_, err = d.Download(context.Background(), readSource(t), TEST_MANIFEST_URL, NewDirFS(t.TempDir()), mpd.WithBandwidth(0, 500000))
+	require.EqualError(t, err, "http://cdn.example.com/vod/media/video/400/init.mp4: 503 Service Unavailable")
+}
+
+func TestDownloadErrors(t *testing.T) {
+	origin := newTestOrigin()
+	d := newTestDownloader(t, origin)
+	d.Concurrency = 1
+	dir := t.TempDir()
+
+	// Segment 1 of Representation 400 is missing, which isn't retried
+	_, err := d.Download(context.Background(), readSource(t), TEST_MANIFEST_URL, NewDirFS(dir), mpd.WithBandwidth(0, 500000))
+	require.EqualError(t, err, "http://cdn.example.com/vod/media/video/400/1.m4s: 404 Not Found")
+	if _, err := os.Stat(filepath.Join(dir, DEFAULT_MANIFEST_NAME)); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected no manifest after a failed download, got %v", err)
+	}
+	count := 0
+	for _, request := range origin.sortedRequests() {
+		if strings.HasSuffix(request, "/400/1.m4s") {
+			count++
+		}
+	}
+	require.EqualInt(t, 1, count)
+
+	_, err = d.Download(context.Background(), readSource(t), "manifest.mpd", NewDirFS(dir))
+	require.EqualError(t, err, `Manifest URL must be absolute: "manifest.mpd"`)
+
+	m := readSource(t)
+	m.Periods[0].AdaptationSets[2].Representations[0].SegmentList.SegmentURLs[0].MediaRange = ptrs.Strptr("19-10")
+	_, err = d.Download(context.Background(), m, TEST_MANIFEST_URL, NewDirFS(dir))
+	require.EqualError(t, err, `http://cdn.example.com/vod/media/subtitles.mp4: Invalid byte range: "19-10"`)
+}
+
+func TestPlanPeriodStarts(t *testing.T) {
+	// The first Period lasts until the start of the second one
+	m := mpd.NewMPD(mpd.DASH_PROFILE_LIVE, "PT10S", "PT2S")
+	for i, start := range []time.Duration{0, 6 * time.Second} {
+		p := m.Periods[0]
+		if i > 0 {
+			p = m.AddNewPeriod()
+		}
+		p.Start = (*mpd.Duration)(&start)
+		as, err := p.AddNewAdaptationSetVideo(mpd.DASH_MIME_TYPE_VIDEO_MP4, "progressive", true, 1)
+		require.NoError(t, err)
+		_, err = as.SetNewSegmentTemplate(2000, "init.mp4", "p"+strconv.Itoa(i)+"/$Number$.m4s", 1, 1000)
+		require.NoError(t, err)
+		_, err = as.AddNewRepresentationVideo(1518664, "avc1.4d401f", "800", "30000/1001", 960, 540)
+		require.NoError(t, err)
+	}
+
+	location, _ := url.Parse(TEST_MANIFEST_URL)
+	files, err := plan(m, location)
+	require.NoError(t, err)
+	var names []string
+	for _, f := range files {
+		names = append(names, f.name)
+	}
+	require.EqualString(t, "init.mp4 p0/1.m4s p0/2.m4s p0/3.m4s p1/1.m4s p1/2.m4s", strings.Join(names, " "))
+}
+
+func TestPlanQueryCollision(t *testing.T) {
+	m := mpd.NewMPD(mpd.DASH_PROFILE_LIVE, "PT4S", "PT2S")
+	as, err := m.AddNewAdaptationSetVideo(mpd.DASH_MIME_TYPE_VIDEO_MP4, "progressive", true, 1)
+	require.NoError(t, err)
+	_, err = as.SetNewSegmentTemplate(2000, "init.mp4?token=a", "segment.m4s?n=$Number$", 1, 1000)
+	require.NoError(t, err)
+	_, err = as.AddNewRepresentationVideo(1518664, "avc1.4d401f", "800", "30000/1001", 960, 540)
+	require.NoError(t, err)
+
+	location, _ := url.Parse(TEST_MANIFEST_URL)
+	_, err = plan(m, location)
+	if !errors.Is(err, ErrInvalidURL) {
+		t.Fatalf("Expected %v, got %v", ErrInvalidURL, err)
+	}
+	require.EqualError(t, err, `Invalid segment URL: http://cdn.example.com/vod/segment.m4s?n=1 and http://cdn.example.com/vod/segment.m4s?n=2 are both saved as "segment.m4s"`)
+
+	// A query shared by all the segments, such as a token, is kept out of the local names
+	_, err = as.SetNewSegmentTemplate(2000, "init.mp4?token=a", "$Number$.m4s?token=a", 1, 1000)
+	require.NoError(t, err)
+	files, err := plan(m, location)
+	require.NoError(t, err)
+	require.EqualInt(t, 3, len(files))
+	require.EqualString(t, "1.m4s", files[1].name)
+}
@@ -0,0 +1,48 @@
+package archive
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// FS is a writable file system the Downloader archives to. Names are slash-separated paths, as
+// in io/fs.
+type FS interface {
+	fs.StatFS
+	// OpenFile opens a file for writing with the flags of os.OpenFile (i.e. os.O_APPEND), creating
+	// its parent directories.
+	OpenFile(name string, flag int) (io.WriteCloser, error)
+	Rename(oldName, newName string) error
+}
+
+// DirFS is an FS rooted at a local directory.
+type DirFS struct {
+	fs.StatFS
+	dir string
+}
+
+// Creates a new DirFS.
+// dir - directory the files are written to (i.e. /var/archive/event1).
+func NewDirFS(dir string) *DirFS {
+	return &DirFS{StatFS: os.DirFS(dir).(fs.StatFS), dir: dir}
+}
+
+func (d *DirFS) OpenFile(name string, flag int) (io.WriteCloser, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	path := filepath.Join(d.dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	return os.OpenFile(path, flag|os.O_WRONLY|os.O_CREATE, 0o644)
+}
+
+func (d *DirFS) Rename(oldName, newName string) error {
+	if !fs.ValidPath(oldName) || !fs.ValidPath(newName) {
+		return &fs.PathError{Op: "rename", Path: newName, Err: fs.ErrInvalid}
+	}
+	return os.Rename(filepath.Join(d.dir, filepath.FromSlash(oldName)), filepath.Join(d.dir, filepath.FromSlash(newName)))
+}
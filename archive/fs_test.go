@@ -0,0 +1,36 @@
+package archive
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"testing"
+
+	"github.com/zencoder/go-dash/v3/helpers/require"
+)
+
+func TestDirFS(t *testing.T) {
+	d := NewDirFS(t.TempDir())
+	for _, flag := range []int{os.O_TRUNC, os.O_APPEND} {
+		w, err := d.OpenFile("a/b/file.part", flag)
+		require.NoError(t, err)
+		_, err = io.WriteString(w, "data")
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+	}
+	require.NoError(t, d.Rename("a/b/file.part", "a/b/file"))
+
+	b, err := fs.ReadFile(d, "a/b/file")
+	require.NoError(t, err)
+	require.EqualString(t, "datadata", string(b))
+	_, err = fs.Stat(d, "a/b/file.part")
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Expected the part file to be renamed, got %v", err)
+	}
+
+	_, err = d.OpenFile("../outside", os.O_TRUNC)
+	require.EqualError(t, err, "open ../outside: invalid argument")
+	err = d.Rename("a/b/file", "/etc/file")
+	require.EqualError(t, err, "rename /etc/file: invalid argument")
+}
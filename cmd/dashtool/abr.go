@@ -0,0 +1,95 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/zencoder/go-dash/v3/abrsim"
+)
+
+// abrAlgorithms are the algorithms of the abr command, in output order.
+var abrAlgorithms = []func() abrsim.Algorithm{
+	func() abrsim.Algorithm { return abrsim.NewThroughputRule() },
+	func() abrsim.Algorithm { return abrsim.NewBufferRule() },
+	func() abrsim.Algorithm { return abrsim.NewBOLA() },
+}
+
+// runABR simulates the playback of the video ladder over a bandwidth trace. With a single
+// algorithm every download is listed, otherwise a summary of each algorithm is printed.
+func runABR(fs *flag.FlagSet, args []string, stdin io.Reader, stdout io.Writer) error {
+	tracePath := fs.String("trace", "", "bandwidth trace, lines of <seconds> <bits per second>")
+	sizesPath := fs.String("sizes", "", "segment sizes, lines of <representation id> <bytes>... (default: from @bandwidth)")
+	algorithm := fs.String("algorithm", "all", "throughput, buffer, bola or all")
+	maxBuffer := fs.Duration("max-buffer", abrsim.DEFAULT_MAX_BUFFER, "player buffer size")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 || *tracePath == "" {
+		return errUsage
+	}
+
+	var algorithms []abrsim.Algorithm
+	for _, newAlgorithm := range abrAlgorithms {
+		if a := newAlgorithm(); *algorithm == "all" || a.Name() == *algorithm {
+			algorithms = append(algorithms, a)
+		}
+	}
+	if len(algorithms) == 0 {
+		return fmt.Errorf("Unknown algorithm %q", *algorithm)
+	}
+
+	m, err := readMPD(fs.Arg(0), stdin)
+	if err != nil {
+		return err
+	}
+	ladder, err := abrsim.VideoLadder(m)
+	if err != nil {
+		return err
+	}
+	if *sizesPath != "" {
+		sizes, err := readFile(*sizesPath, abrsim.ReadSizeTable)
+		if err != nil {
+			return err
+		}
+		if err := ladder.SetSizes(sizes); err != nil {
+			return err
+		}
+	}
+	trace, err := readFile(*tracePath, abrsim.ReadTrace)
+	if err != nil {
+		return err
+	}
+
+	sim := abrsim.NewSimulator(ladder, trace)
+	sim.MaxBuffer = *maxBuffer
+	for _, a := range algorithms {
+		result, err := sim.Run(a)
+		if err != nil {
+			return err
+		}
+		if len(algorithms) == 1 {
+			for _, d := range result.Downloads {
+				fmt.Fprintf(stdout, "%d\t%s\t%d\t%.3f\t%.3f\t%.3f\n", d.Segment+1, d.Representation, d.Bandwidth, d.Start.Seconds(), d.Duration.Seconds(), d.Buffer.Seconds())
+			}
+		}
+		fmt.Fprintln(stdout, result)
+	}
+	return nil
+}
+
+// readFile parses a file with one of the abrsim readers.
+func readFile[T any](name string, read func(io.Reader) (T, error)) (T, error) {
+	var zero T
+	f, err := os.Open(name)
+	if err != nil {
+		return zero, err
+	}
+	defer f.Close()
+	v, err := read(f)
+	if err != nil {
+		return zero, fmt.Errorf("%s: %w", name, err)
+	}
+	return v, nil
+}
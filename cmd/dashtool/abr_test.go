@@ -0,0 +1,67 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zencoder/go-dash/v3/helpers/require"
+)
+
+// writeTestFile writes content to a file of a temporary directory and returns its path.
+func writeTestFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func TestABR(t *testing.T) {
+	trace := writeTestFile(t, "trace.txt", "60 2000000\n")
+	code, stdout, stderr := runCommand(nil, "abr", "-trace", trace, FIXTURE_LIVE)
+	require.EqualInt(t, EXIT_OK, code, stderr)
+	lines := strings.Split(strings.TrimSuffix(stdout, "\n"), "\n")
+	require.EqualInt(t, 3, len(lines))
+	require.EqualString(t, "throughput: ", lines[0][:12])
+	require.EqualString(t, "buffer: ", lines[1][:8])
+	require.EqualString(t, "bola: ", lines[2][:6])
+}
+
+func TestABRSingleAlgorithm(t *testing.T) {
+	trace := writeTestFile(t, "trace.txt", "60 2000000\n")
+	code, stdout, stderr := runCommand(nil, "abr", "-trace", trace, "-algorithm", "throughput", FIXTURE_LIVE)
+	require.EqualInt(t, EXIT_OK, code, stderr)
+	lines := strings.Split(strings.TrimSuffix(stdout, "\n"), "\n")
+	// 192 downloads and the summary
+	require.EqualInt(t, 193, len(lines))
+	require.EqualString(t, "1\t800\t1518664\t0.000\t1.494\t1.968", lines[0])
+	if !strings.HasPrefix(lines[192], "throughput: ") {
+		t.Errorf("Unexpected summary %q", lines[192])
+	}
+}
+
+func TestABRSizes(t *testing.T) {
+	trace := writeTestFile(t, "trace.txt", "60 2000000\n")
+	sizes := writeTestFile(t, "sizes.txt", "800 "+strings.Repeat("1000 ", 192)+"\n")
+	code, stdout, stderr := runCommand(nil, "abr", "-trace", trace, "-sizes", sizes, "-algorithm", "bola", FIXTURE_LIVE)
+	require.EqualInt(t, EXIT_OK, code, stderr)
+	require.EqualString(t, "1\t800\t1518664\t0.000\t0.004\t1.968", strings.SplitN(stdout, "\n", 2)[0])
+}
+
+func TestABRErrors(t *testing.T) {
+	code, _, stderr := runCommand(nil, "abr", FIXTURE_LIVE)
+	require.EqualInt(t, EXIT_USAGE, code)
+	if !strings.HasPrefix(stderr, "usage: dashtool abr") {
+		t.Errorf("Unexpected stderr %q", stderr)
+	}
+
+	trace := writeTestFile(t, "trace.txt", "60 2000000\n")
+	code, _, stderr = runCommand(nil, "abr", "-trace", trace, "-algorithm", "random", FIXTURE_LIVE)
+	require.EqualInt(t, EXIT_FAILURE, code)
+	require.EqualString(t, "dashtool abr: Unknown algorithm \"random\"\n", stderr)
+
+	invalid := writeTestFile(t, "trace.txt", "fast\n")
+	code, _, stderr = runCommand(nil, "abr", "-trace", invalid, FIXTURE_LIVE)
+	require.EqualInt(t, EXIT_FAILURE, code)
+	require.EqualString(t, "dashtool abr: "+invalid+": Invalid trace line 1\n", stderr)
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/zencoder/go-dash/v3/mpd"
+)
+
+// runDiff prints the changes between two manifests, one per line, with elements matched by id
+// rather than by position. Returns errFailure when they differ.
+func runDiff(fs *flag.FlagSet, args []string, stdin io.Reader, stdout io.Writer) error {
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		return errUsage
+	}
+	if fs.Arg(0) == "-" && fs.Arg(1) == "-" {
+		return fmt.Errorf("only one manifest can be read from stdin")
+	}
+
+	var manifests [2]*mpd.MPD
+	for i, name := range fs.Args() {
+		m, err := readMPD(name, stdin)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		manifests[i] = m
+	}
+
+	changes := mpd.Diff(manifests[0], manifests[1])
+	if len(changes) == 0 {
+		return nil
+	}
+	for _, c := range changes {
+		fmt.Fprintln(stdout, c)
+	}
+	return errFailure
+}
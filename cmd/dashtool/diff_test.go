@@ -0,0 +1,24 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/zencoder/go-dash/v3/helpers/require"
+)
+
+func TestDiff(t *testing.T) {
+	code, stdout, _ := runCommand(nil, "diff", FIXTURE_LIVE, FIXTURE_LIVE)
+	require.EqualInt(t, EXIT_OK, code)
+	require.EqualString(t, "", stdout)
+
+	code, stdout, _ = runCommand(nil, "diff", FIXTURE_LIVE, FIXTURE_LIVE_BASE_URL)
+	require.EqualInt(t, EXIT_FAILURE, code)
+	require.EqualString(t, `added BaseURL: ./
+added BaseURL: ../a/
+added BaseURL: ../b/
+`, stdout)
+
+	code, _, stderr := runCommand(nil, "diff", "-", "-")
+	require.EqualInt(t, EXIT_FAILURE, code)
+	require.EqualString(t, "dashtool diff: only one manifest can be read from stdin\n", stderr)
+}
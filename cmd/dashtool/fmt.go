@@ -0,0 +1,31 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+)
+
+// runFmt re-serializes a manifest with the canonical attribute order and indentation.
+func runFmt(fs *flag.FlagSet, args []string, stdin io.Reader, stdout io.Writer) error {
+	write := fs.Bool("w", false, "write the result back to the file instead of stdout")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errUsage
+	}
+	name := fs.Arg(0)
+	if *write && (name == "-" || isURL(name)) {
+		return fmt.Errorf("-w needs a file, not %s", name)
+	}
+
+	m, err := readMPD(name, stdin)
+	if err != nil {
+		return err
+	}
+	if *write {
+		return m.WriteToFile(name)
+	}
+	return m.Write(stdout)
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zencoder/go-dash/v3/helpers/require"
+)
+
+func TestFmt(t *testing.T) {
+	stdin := strings.NewReader(`<MPD  minBufferTime="PT2S" profiles="urn:mpeg:dash:profile:isoff-live:2011" xmlns="urn:mpeg:dash:schema:mpd:2011"><Period/></MPD>`)
+	code, stdout, _ := runCommand(stdin, "fmt", "-")
+	require.EqualInt(t, EXIT_OK, code)
+	require.EqualString(t, `<?xml version="1.0" encoding="UTF-8"?>
+<MPD xmlns="urn:mpeg:dash:schema:mpd:2011" profiles="urn:mpeg:dash:profile:isoff-live:2011" minBufferTime="PT2S">
+  <Period></Period>
+</MPD>
+`, stdout)
+
+	code, _, stderr := runCommand(nil, "fmt", "-w", "-")
+	require.EqualInt(t, EXIT_FAILURE, code)
+	require.EqualString(t, "dashtool fmt: -w needs a file, not -\n", stderr)
+}
+
+func TestFmtWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "manifest.mpd")
+	require.NoError(t, os.WriteFile(path, []byte(`<MPD profiles="urn:mpeg:dash:profile:isoff-live:2011" xmlns="urn:mpeg:dash:schema:mpd:2011"></MPD>`), 0644))
+
+	code, stdout, _ := runCommand(nil, "fmt", "-w", path)
+	require.EqualInt(t, EXIT_OK, code)
+	require.EqualString(t, "", stdout)
+
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.EqualString(t, `<?xml version="1.0" encoding="UTF-8"?>
+<MPD xmlns="urn:mpeg:dash:schema:mpd:2011" profiles="urn:mpeg:dash:profile:isoff-live:2011"></MPD>
+`, string(b))
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/zencoder/go-dash/v3/mpd"
+)
+
+// runInfo prints a summary of a manifest: durations, the ladder of every Period, languages and
+// DRM systems.
+func runInfo(fs *flag.FlagSet, args []string, stdin io.Reader, stdout io.Writer) error {
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errUsage
+	}
+	m, err := readMPD(fs.Arg(0), stdin)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(stdout, "Type: %s\n", valueOr(m.Type, "static"))
+	fmt.Fprintf(stdout, "Profiles: %s\n", valueOr(m.Profiles, "-"))
+	if m.MediaPresentationDuration != nil {
+		fmt.Fprintf(stdout, "Duration: %s\n", formatXSDuration(*m.MediaPresentationDuration))
+	}
+	if m.MinBufferTime != nil {
+		fmt.Fprintf(stdout, "Min buffer time: %s\n", formatXSDuration(*m.MinBufferTime))
+	}
+	if m.AvailabilityStartTime != nil {
+		fmt.Fprintf(stdout, "Availability start time: %s\n", *m.AvailabilityStartTime)
+	}
+
+	languages := map[string]bool{}
+	drm := map[string]bool{}
+	for i, p := range m.Periods {
+		fmt.Fprintf(stdout, "\nPeriod %d", i)
+		if p.ID != "" {
+			fmt.Fprintf(stdout, " id=%s", p.ID)
+		}
+		if p.Start != nil {
+			fmt.Fprintf(stdout, " start=%s", time.Duration(*p.Start))
+		}
+		if p.Duration != 0 {
+			fmt.Fprintf(stdout, " duration=%s", time.Duration(p.Duration))
+		}
+		fmt.Fprintln(stdout)
+
+		for _, as := range p.AdaptationSets {
+			fmt.Fprintf(stdout, "  AdaptationSet %s %s", valueOr(as.ID, "-"), valueOr(as.MimeType, valueOr(as.ContentType, "-")))
+			if as.Lang != nil {
+				fmt.Fprintf(stdout, " lang=%s", *as.Lang)
+				languages[*as.Lang] = true
+			}
+			for _, role := range as.Roles {
+				fmt.Fprintf(stdout, " role=%s", valueOr(role.Value, "-"))
+			}
+			systems := drmSystems(as.ContentProtection)
+			if len(systems) > 0 {
+				fmt.Fprintf(stdout, " drm=%s", strings.Join(systems, ","))
+			}
+			fmt.Fprintln(stdout)
+			for _, s := range systems {
+				drm[s] = true
+			}
+
+			for _, r := range as.Representations {
+				fmt.Fprintf(stdout, "    Representation %s: %s\n", valueOr(r.ID, "-"), describeRepresentation(as, r))
+				for _, s := range drmSystems(r.ContentProtection) {
+					drm[s] = true
+				}
+			}
+		}
+	}
+
+	fmt.Fprintf(stdout, "\nLanguages: %s\n", joinKeys(languages))
+	fmt.Fprintf(stdout, "DRM systems: %s\n", joinKeys(drm))
+	return nil
+}
+
+// describeRepresentation formats bandwidth, codecs and the video or audio properties of a
+// Representation (i.e. 1518664 bps avc1.4d401f 960x540 30000/1001fps).
+func describeRepresentation(as *mpd.AdaptationSet, r *mpd.Representation) string {
+	var parts []string
+	if r.Bandwidth != nil {
+		parts = append(parts, fmt.Sprintf("%d bps", *r.Bandwidth))
+	}
+	if codecs := valueOr(r.Codecs, valueOr(as.Codecs, "")); codecs != "" {
+		parts = append(parts, codecs)
+	}
+	if r.Width != nil && r.Height != nil {
+		parts = append(parts, fmt.Sprintf("%dx%d", *r.Width, *r.Height))
+	}
+	if frameRate := valueOr(r.FrameRate, valueOr(as.FrameRate, "")); frameRate != "" {
+		parts = append(parts, frameRate+"fps")
+	}
+	if r.AudioSamplingRate != nil {
+		parts = append(parts, fmt.Sprintf("%dHz", *r.AudioSamplingRate))
+	}
+	return strings.Join(parts, " ")
+}
+
+// drmSystems names the DRM systems signalled by ContentProtection elements (i.e. Widevine).
+func drmSystems(cps []mpd.ContentProtectioner) []string {
+	var systems []string
+	for _, cp := range cps {
+		scheme := strings.ToLower(schemeIDURI(cp))
+		switch scheme {
+		case mpd.CONTENT_PROTECTION_ROOT_SCHEME_ID_URI:
+			value := mpd.CONTENT_PROTECTION_ROOT_VALUE
+			if cenc, ok := cp.(*mpd.CENCContentProtection); ok && cenc.Value != nil {
+				value = *cenc.Value
+			}
+			systems = append(systems, "CENC("+value+")")
+		case mpd.CONTENT_PROTECTION_WIDEVINE_SCHEME_ID:
+			systems = append(systems, "Widevine")
+		case mpd.CONTENT_PROTECTION_PLAYREADY_SCHEME_ID, mpd.CONTENT_PROTECTION_PLAYREADY_SCHEME_V10_ID:
+			systems = append(systems, "PlayReady")
+		case "":
+		default:
+			systems = append(systems, scheme)
+		}
+	}
+	return systems
+}
+
+// schemeIDURI returns the @schemeIdUri of any ContentProtectioner, including custom registered
+// schemes, by marshalling it.
+func schemeIDURI(cp mpd.ContentProtectioner) string {
+	b, err := xml.Marshal(cp)
+	if err != nil {
+		return ""
+	}
+	tok, err := xml.NewDecoder(bytes.NewReader(b)).Token()
+	if err != nil {
+		return ""
+	}
+	if start, ok := tok.(xml.StartElement); ok {
+		for _, a := range start.Attr {
+			if a.Name.Local == "schemeIdUri" {
+				return a.Value
+			}
+		}
+	}
+	return ""
+}
+
+// formatXSDuration adds the Go notation to an xs:duration (i.e. PT6M16S (6m16s)).
+func formatXSDuration(s string) string {
+	d, err := mpd.ParseDuration(s)
+	if err != nil {
+		return s
+	}
+	return fmt.Sprintf("%s (%s)", s, d)
+}
+
+func valueOr(s *string, fallback string) string {
+	if s == nil || *s == "" {
+		return fallback
+	}
+	return *s
+}
+
+func joinKeys(m map[string]bool) string {
+	if len(m) == 0 {
+		return "-"
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, ", ")
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/zencoder/go-dash/v3/helpers/require"
+)
+
+func TestInfo(t *testing.T) {
+	code, stdout, _ := runCommand(nil, "info", FIXTURE_LIVE)
+	require.EqualInt(t, EXIT_OK, code)
+	require.EqualString(t, `Type: static
+Profiles: urn:mpeg:dash:profile:isoff-live:2011
+Duration: PT6M16S (6m16s)
+Min buffer time: PT1.97S (1.97s)
+Availability start time: 1970-01-01T00:00:00Z
+
+Period 0
+  AdaptationSet 7357 audio/mp4 lang=en role=main drm=CENC(cenc),Widevine,PlayReady
+    Representation 800: 67095 bps mp4a.40.2 44100Hz
+  AdaptationSet 7357 video/mp4 role=main drm=CENC(cenc),Widevine,PlayReady
+    Representation 800: 1518664 bps avc1.4d401f 960x540 30000/1001fps
+    Representation 1000: 1911775 bps avc1.4d401f 1024x576 30000/1001fps
+    Representation 1200: 2295158 bps avc1.4d401f 1024x576 30000/1001fps
+    Representation 1500: 2780732 bps avc1.4d401f 1280x720 30000/1001fps
+  AdaptationSet 7357 text/vtt lang=en
+    Representation subtitle_en: 256 bps
+
+Languages: en
+DRM systems: CENC(cenc), PlayReady, Widevine
+`, stdout)
+}
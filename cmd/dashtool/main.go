@@ -0,0 +1,132 @@
+// Command dashtool inspects, validates and rewrites MPEG-DASH manifests.
+//
+// Usage:
+//
+//	dashtool validate <mpd>...
+//	dashtool info <mpd>
+//	dashtool fmt [-w] <mpd>
+//	dashtool diff <old mpd> <new mpd>
+//	dashtool segments [-period id] -representation id <mpd>
+//	dashtool abr -trace file [-sizes file] [-algorithm name] [-max-buffer duration] <mpd>
+//
+// A manifest can be a file path, an http(s) URL or - for stdin.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/zencoder/go-dash/v3/mpd"
+)
+
+// Exit codes
+const (
+	EXIT_OK      = 0
+	EXIT_FAILURE = 1 // Invalid manifest or manifests differ
+	EXIT_USAGE   = 2
+)
+
+// Time limit of the requests of manifests from http(s) URLs
+const HTTP_TIMEOUT = 30 * time.Second
+
+// httpClient fetches the manifests from http(s) URLs.
+var httpClient = &http.Client{Timeout: HTTP_TIMEOUT}
+
+var (
+	// errFailure is returned by commands that already reported why they failed.
+	errFailure = errors.New("failure")
+	// errUsage is returned by commands called with the wrong arguments.
+	errUsage = errors.New("usage")
+)
+
+type command struct {
+	usage string
+	run   func(fs *flag.FlagSet, args []string, stdin io.Reader, stdout io.Writer) error
+}
+
+var commands = map[string]command{
+	"validate": {"validate <mpd>...", runValidate},
+	"info":     {"info <mpd>", runInfo},
+	"fmt":      {"fmt [-w] <mpd>", runFmt},
+	"diff":     {"diff <old mpd> <new mpd>", runDiff},
+	"segments": {"segments [-period id] -representation id <mpd>", runSegments},
+	"abr":      {"abr -trace file [-sizes file] [-algorithm name] [-max-buffer duration] <mpd>", runABR},
+}
+
+var commandOrder = []string{"validate", "info", "fmt", "diff", "segments", "abr"}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	if len(args) == 0 {
+		usage(stderr)
+		return EXIT_USAGE
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "dashtool: unknown command %q\n", args[0])
+		usage(stderr)
+		return EXIT_USAGE
+	}
+
+	fs := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "usage: dashtool %s\n", cmd.usage)
+		fs.PrintDefaults()
+	}
+	err := cmd.run(fs, args[1:], stdin, stdout)
+	switch {
+	case err == nil:
+		return EXIT_OK
+	case errors.Is(err, flag.ErrHelp):
+		return EXIT_USAGE
+	case errors.Is(err, errUsage):
+		fs.Usage()
+		return EXIT_USAGE
+	case errors.Is(err, errFailure):
+		return EXIT_FAILURE
+	}
+	fmt.Fprintf(stderr, "dashtool %s: %s\n", args[0], err)
+	return EXIT_FAILURE
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "usage: dashtool <command> [arguments]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "commands:")
+	for _, name := range commandOrder {
+		fmt.Fprintf(w, "  dashtool %s\n", commands[name].usage)
+	}
+}
+
+// readMPD reads a manifest from a file path, an http(s) URL or stdin when name is -.
+func readMPD(name string, stdin io.Reader) (*mpd.MPD, error) {
+	if name == "-" {
+		return mpd.Read(stdin)
+	}
+	if isURL(name) {
+		resp, err := httpClient.Get(name)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("GET %s: %s", name, resp.Status)
+		}
+		return mpd.Read(resp.Body)
+	}
+	return mpd.ReadFromFile(name)
+}
+
+func isURL(name string) bool {
+	return strings.HasPrefix(name, "http://") || strings.HasPrefix(name, "https://")
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zencoder/go-dash/v3/helpers/require"
+)
+
+const (
+	FIXTURE_LIVE          = "../../mpd/fixtures/live_profile.mpd"
+	FIXTURE_LIVE_BASE_URL = "../../mpd/fixtures/live_profile_multi_base_url.mpd"
+	FIXTURE_ONDEMAND      = "../../mpd/fixtures/ondemand_profile.mpd"
+	FIXTURE_TIMELINE      = "../../mpd/fixtures/segment_timeline.mpd"
+	FIXTURE_INVALID       = "../../mpd/fixtures/invalid.mpd"
+)
+
+// runCommand runs dashtool with args and returns the exit code, stdout and stderr.
+func runCommand(stdin io.Reader, args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	if stdin == nil {
+		stdin = strings.NewReader("")
+	}
+	code := run(args, stdin, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestRunUsage(t *testing.T) {
+	code, _, stderr := runCommand(nil)
+	require.EqualInt(t, EXIT_USAGE, code)
+	require.EqualString(t, `usage: dashtool <command> [arguments]
+
+commands:
+  dashtool validate <mpd>...
+  dashtool info <mpd>
+  dashtool fmt [-w] <mpd>
+  dashtool diff <old mpd> <new mpd>
+  dashtool segments [-period id] -representation id <mpd>
+  dashtool abr -trace file [-sizes file] [-algorithm name] [-max-buffer duration] <mpd>
+`, stderr)
+
+	code, _, stderr = runCommand(nil, "lint")
+	require.EqualInt(t, EXIT_USAGE, code)
+	if !strings.HasPrefix(stderr, `dashtool: unknown command "lint"`) {
+		t.Errorf("Unexpected stderr %q", stderr)
+	}
+
+	code, _, stderr = runCommand(nil, "info")
+	require.EqualInt(t, EXIT_USAGE, code)
+	require.EqualString(t, "usage: dashtool info <mpd>\n", stderr)
+}
+
+func TestRunReadError(t *testing.T) {
+	code, _, stderr := runCommand(nil, "info", "does-not-exist.mpd")
+	require.EqualInt(t, EXIT_FAILURE, code)
+	require.EqualString(t, "dashtool info: open does-not-exist.mpd: no such file or directory\n", stderr)
+}
+
+func TestReadMPDTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+	defer func(client *http.Client) { httpClient = client }(httpClient)
+	httpClient = &http.Client{Timeout: 50 * time.Millisecond}
+
+	code, _, stderr := runCommand(nil, "info", server.URL+"/manifest.mpd")
+	require.EqualInt(t, EXIT_FAILURE, code)
+	if !strings.Contains(stderr, "Client.Timeout exceeded") {
+		t.Errorf("Expected a timeout, got %q", stderr)
+	}
+}
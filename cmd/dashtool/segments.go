@@ -0,0 +1,118 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/zencoder/go-dash/v3/mpd"
+)
+
+// runSegments lists the init and media segment URLs of a Representation, resolved against the
+// manifest location and every BaseURL level.
+func runSegments(fs *flag.FlagSet, args []string, stdin io.Reader, stdout io.Writer) error {
+	periodID := fs.String("period", "", "Period id, or index when the Periods have no id (default: the first Period with the Representation)")
+	repID := fs.String("representation", "", "Representation id")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 || *repID == "" {
+		return errUsage
+	}
+	name := fs.Arg(0)
+	m, err := readMPD(name, stdin)
+	if err != nil {
+		return err
+	}
+
+	p, as, r := findRepresentation(m, *periodID, *repID)
+	if r == nil {
+		return fmt.Errorf("Representation %q not found", *repID)
+	}
+
+	durations, err := m.PeriodDurations()
+	if err != nil {
+		return err
+	}
+	rs, err := r.Segments(durations[slices.Index(m.Periods, p)])
+	if err != nil {
+		return err
+	}
+
+	base := baseURL(name, m, p, as, r)
+	fmt.Fprintf(stdout, "init\t%s\t%s\n", resolve(base, rs.Initialization), rs.InitializationRange)
+	for _, s := range rs.Media {
+		start := float64(s.Time-rs.PresentationTimeOffset) / float64(rs.Timescale)
+		fmt.Fprintf(stdout, "%d\t%.3f\t%.3f\t%s\t%s\n", s.Number, start, float64(s.Duration)/float64(rs.Timescale), resolve(base, s.URL), s.Range)
+	}
+	return nil
+}
+
+// findRepresentation returns the Representation with the given id, in the Period with the given
+// id or index, or in the first Period that has it when periodID is empty.
+func findRepresentation(m *mpd.MPD, periodID, repID string) (*mpd.Period, *mpd.AdaptationSet, *mpd.Representation) {
+	for i, p := range m.Periods {
+		matches := periodID == "" || p.ID == periodID || p.ID == "" && strconv.Itoa(i) == periodID
+		if !matches {
+			continue
+		}
+		for _, as := range p.AdaptationSets {
+			for _, r := range as.Representations {
+				if r.ID != nil && *r.ID == repID {
+					return p, as, r
+				}
+			}
+		}
+	}
+	return nil, nil, nil
+}
+
+// baseURL resolves the first BaseURL of every level against the manifest location.
+func baseURL(location string, m *mpd.MPD, p *mpd.Period, as *mpd.AdaptationSet, r *mpd.Representation) string {
+	base := location
+	if base == "-" {
+		base = ""
+	}
+	for _, urls := range [][]string{m.BaseURL, p.BaseURL, as.BaseURL, r.BaseURL} {
+		if len(urls) > 0 {
+			base = resolve(base, urls[0])
+		}
+	}
+	return base
+}
+
+// resolve resolves ref against base as in RFC 3986. A base without a scheme is a file path, which
+// may be relative to the working directory.
+func resolve(base, ref string) string {
+	if base == "" {
+		return ref
+	}
+	u, err := url.Parse(ref)
+	if err != nil || u.IsAbs() {
+		return ref
+	}
+	b, err := url.Parse(base)
+	if err != nil {
+		return ref
+	}
+	if b.IsAbs() {
+		return b.ResolveReference(u).String()
+	}
+
+	if ref == "" {
+		return base
+	}
+	if strings.HasPrefix(ref, "/") {
+		return ref
+	}
+	resolved := path.Join(base[:strings.LastIndex(base, "/")+1], ref)
+	if strings.HasSuffix(ref, "/") {
+		resolved += "/"
+	}
+	return resolved
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/zencoder/go-dash/v3/helpers/require"
+)
+
+func TestSegmentsTemplate(t *testing.T) {
+	code, stdout, _ := runCommand(nil, "segments", "-representation", "1000", FIXTURE_LIVE)
+	require.EqualInt(t, EXIT_OK, code)
+	lines := strings.Split(strings.TrimSuffix(stdout, "\n"), "\n")
+	// 376s of 1.968s segments
+	require.EqualInt(t, 1+192, len(lines))
+	require.EqualString(t, "init\t../../mpd/fixtures/1000/video/1/init.mp4\t", lines[0])
+	require.EqualString(t, "1\t1.968\t1.968\t../../mpd/fixtures/1000/video/1/seg-1.m4f\t", lines[2])
+}
+
+func TestSegmentsTimeline(t *testing.T) {
+	code, stdout, _ := runCommand(nil, "segments", "-period", "0", "-representation", "video_1", FIXTURE_TIMELINE)
+	require.EqualInt(t, EXIT_OK, code)
+	lines := strings.Split(stdout, "\n")
+	require.EqualString(t, "init\thttp://localhost:8002/public/video/init.m4f\t", lines[0])
+	require.EqualString(t, "2\t4.838\t9.009\thttp://localhost:8002/public/video/segment2.m4f\t", lines[2])
+}
+
+func TestSegmentsBase(t *testing.T) {
+	code, stdout, _ := runCommand(nil, "segments", "-representation", "800k/audio-und", FIXTURE_ONDEMAND)
+	require.EqualInt(t, EXIT_OK, code)
+	require.EqualString(t, "init\t../../mpd/fixtures/800k/output-audio-und.mp4\t0-628\n1\t0.000\t30.000\t../../mpd/fixtures/800k/output-audio-und.mp4\t\n", stdout)
+}
+
+func TestSegmentsErrors(t *testing.T) {
+	code, _, stderr := runCommand(nil, "segments", FIXTURE_LIVE)
+	require.EqualInt(t, EXIT_USAGE, code)
+	if !strings.HasPrefix(stderr, "usage: dashtool segments") {
+		t.Errorf("Unexpected stderr %q", stderr)
+	}
+
+	code, _, stderr = runCommand(nil, "segments", "-period", "1", "-representation", "800", FIXTURE_LIVE)
+	require.EqualInt(t, EXIT_FAILURE, code)
+	require.EqualString(t, "dashtool segments: Representation \"800\" not found\n", stderr)
+}
+
+func TestResolve(t *testing.T) {
+	require.EqualString(t, "dir/800/seg.m4s", resolve("dir/manifest.mpd", "800/seg.m4s"))
+	require.EqualString(t, "/abs/seg.m4s", resolve("/abs/manifest.mpd", "seg.m4s"))
+	require.EqualString(t, "https://cdn.example.com/b/seg.m4s", resolve("https://example.com/a/manifest.mpd", "https://cdn.example.com/b/seg.m4s"))
+	require.EqualString(t, "https://example.com/a/seg.m4s", resolve("https://example.com/a/manifest.mpd", "seg.m4s"))
+	require.EqualString(t, "seg.m4s", resolve("", "seg.m4s"))
+	require.EqualString(t, "../../a/", resolve("../../x/manifest.mpd", "../a/"))
+	require.EqualString(t, "dir/manifest.mpd", resolve("dir/manifest.mpd", ""))
+}
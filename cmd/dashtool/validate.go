@@ -0,0 +1,35 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+)
+
+// runValidate reads and validates every manifest, reporting ok or the first problem of each.
+func runValidate(fs *flag.FlagSet, args []string, stdin io.Reader, stdout io.Writer) error {
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return errUsage
+	}
+
+	failed := false
+	for _, name := range fs.Args() {
+		m, err := readMPD(name, stdin)
+		if err == nil {
+			err = m.Validate()
+		}
+		if err != nil {
+			failed = true
+			fmt.Fprintf(stdout, "%s: %s\n", name, err)
+			continue
+		}
+		fmt.Fprintf(stdout, "%s: ok\n", name)
+	}
+	if failed {
+		return errFailure
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/zencoder/go-dash/v3/helpers/require"
+)
+
+func TestValidate(t *testing.T) {
+	code, stdout, _ := runCommand(nil, "validate", FIXTURE_LIVE, FIXTURE_ONDEMAND)
+	require.EqualInt(t, EXIT_OK, code)
+	require.EqualString(t, FIXTURE_LIVE+": ok\n"+FIXTURE_ONDEMAND+": ok\n", stdout)
+}
+
+func TestValidateErrors(t *testing.T) {
+	code, stdout, _ := runCommand(nil, "validate", FIXTURE_LIVE, FIXTURE_INVALID)
+	require.EqualInt(t, EXIT_FAILURE, code)
+	require.EqualString(t, FIXTURE_LIVE+": ok\n"+FIXTURE_INVALID+": XML syntax error on line 3: unexpected EOF\n", stdout)
+
+	stdin := strings.NewReader(`<MPD xmlns="urn:mpeg:dash:schema:mpd:2011" profiles="urn:mpeg:dash:profile:isoff-live:2011">
+  <Period><AdaptationSet id="1"><Representation id="v1" codecs="avc1.4d40"></Representation></AdaptationSet></Period>
+</MPD>`)
+	code, stdout, _ = runCommand(stdin, "validate", "-")
+	require.EqualInt(t, EXIT_FAILURE, code)
+	require.EqualString(t, `-: Representation v1: Malformed codec string "avc1.4d40": profile, constraints and level should be 6 hex digits`+"\n", stdout)
+}
@@ -0,0 +1,93 @@
+// Command livesim serves on-demand content as a simulated live stream, looping a static MPD using
+// SegmentTemplate and its segments on disk.
+//
+// Usage:
+//
+//	livesim [-addr host:port] [-ast time] [-tsbd duration] [-mup duration] [-spd duration] <mpd>
+//
+// The live manifest is served at /manifest.mpd.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/zencoder/go-dash/v3/livesim"
+	"github.com/zencoder/go-dash/v3/mpd"
+)
+
+// Exit codes
+const (
+	EXIT_OK      = 0
+	EXIT_FAILURE = 1
+	EXIT_USAGE   = 2
+)
+
+const DEFAULT_ADDR = "localhost:8080"
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr, (*http.Server).ListenAndServe))
+}
+
+// run configures the server from the arguments and calls serve with it.
+func run(args []string, stdout, stderr io.Writer, serve func(*http.Server) error) int {
+	server, err := newServer(args, stderr)
+	switch {
+	case errors.Is(err, flag.ErrHelp):
+		return EXIT_USAGE
+	case err != nil:
+		fmt.Fprintf(stderr, "livesim: %s\n", err)
+		return EXIT_USAGE
+	}
+	fmt.Fprintf(stdout, "Serving http://%s%s\n", server.Addr, livesim.MANIFEST_PATH)
+	if err := serve(server); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fmt.Fprintf(stderr, "livesim: %s\n", err)
+		return EXIT_FAILURE
+	}
+	return EXIT_OK
+}
+
+func newServer(args []string, stderr io.Writer) (*http.Server, error) {
+	fs := flag.NewFlagSet("livesim", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: livesim [flags] <mpd>")
+		fs.PrintDefaults()
+	}
+	addr := fs.String("addr", DEFAULT_ADDR, "address to listen on")
+	ast := fs.String("ast", "", "availabilityStartTime in RFC 3339 format (default the Unix epoch)")
+	tsbd := fs.Duration("tsbd", livesim.DEFAULT_TIME_SHIFT_BUFFER_DEPTH, "timeShiftBufferDepth")
+	mup := fs.Duration("mup", livesim.DEFAULT_MINIMUM_UPDATE_PERIOD, "minimumUpdatePeriod")
+	spd := fs.Duration("spd", livesim.DEFAULT_SUGGESTED_PRESENTATION_DELAY, "suggestedPresentationDelay")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return nil, flag.ErrHelp
+	}
+
+	vod, err := mpd.ReadFromFile(fs.Arg(0))
+	if err != nil {
+		return nil, err
+	}
+	sim, err := livesim.New(vod, os.DirFS(filepath.Dir(fs.Arg(0))))
+	if err != nil {
+		return nil, err
+	}
+	if *ast != "" {
+		if sim.AvailabilityStartTime, err = time.Parse(time.RFC3339, *ast); err != nil {
+			return nil, err
+		}
+	}
+	sim.TimeShiftBufferDepth = *tsbd
+	sim.MinimumUpdatePeriod = *mup
+	sim.SuggestedPresentationDelay = *spd
+	return &http.Server{Addr: *addr, Handler: sim}, nil
+}
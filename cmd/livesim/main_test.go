@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/zencoder/go-dash/v3/helpers/ptrs"
+	"github.com/zencoder/go-dash/v3/helpers/require"
+	"github.com/zencoder/go-dash/v3/mpd"
+)
+
+// writeVOD writes a static MPD with a single Representation and its init segment, and returns the
+// MPD path.
+func writeVOD(t *testing.T) string {
+	dir := t.TempDir()
+	m := mpd.NewMPD(mpd.DASH_PROFILE_LIVE, "PT6S", "PT2S")
+	as, err := m.AddNewAdaptationSetVideo("video/mp4", "progressive", true, 1)
+	require.NoError(t, err)
+	_, err = as.SetNewSegmentTemplate(2000, "$RepresentationID$/init.mp4", "$RepresentationID$/$Number$.m4s", 1, 1000)
+	require.NoError(t, err)
+	_, err = as.AddNewRepresentationVideo(1518664, "avc1.4d401f", "800", "30", 1280, 720)
+	require.NoError(t, err)
+	name := filepath.Join(dir, "vod.mpd")
+	require.NoError(t, m.WriteToFile(name))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "800"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "800", "init.mp4"), []byte("init"), 0o644))
+	return name
+}
+
+func TestRun(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	var served *http.Server
+	code := run([]string{"-addr", "localhost:9000", "-ast", "2024-01-01T00:00:00Z", "-tsbd", "30s", writeVOD(t)}, &stdout, &stderr, func(s *http.Server) error {
+		served = s
+		return http.ErrServerClosed
+	})
+	require.EqualInt(t, EXIT_OK, code, stderr.String())
+	require.EqualString(t, "Serving http://localhost:9000/manifest.mpd\n", stdout.String())
+	require.EqualString(t, "localhost:9000", served.Addr)
+
+	w := httptest.NewRecorder()
+	served.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/manifest.mpd", nil))
+	require.EqualInt(t, http.StatusOK, w.Code)
+	m, err := mpd.ReadFromString(w.Body.String())
+	require.NoError(t, err)
+	require.EqualStringPtr(t, m.AvailabilityStartTime, Strptr("2024-01-01T00:00:00Z"))
+	require.EqualStringPtr(t, m.TimeShiftBufferDepth, Strptr("PT30S"))
+
+	w = httptest.NewRecorder()
+	served.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/800/init.mp4", nil))
+	require.EqualString(t, "init", w.Body.String())
+}
+
+func TestRunErrors(t *testing.T) {
+	serve := func(s *http.Server) error {
+		return nil
+	}
+	var stdout, stderr bytes.Buffer
+	require.EqualInt(t, EXIT_USAGE, run(nil, &stdout, &stderr, serve))
+	if !strings.HasPrefix(stderr.String(), "usage: livesim [flags] <mpd>") {
+		t.Errorf("Expected usage but got %s", stderr.String())
+	}
+
+	stderr.Reset()
+	require.EqualInt(t, EXIT_USAGE, run([]string{"-ast", "yesterday", writeVOD(t)}, &stdout, &stderr, serve))
+	if !strings.HasPrefix(stderr.String(), "livesim: parsing time") {
+		t.Errorf("Expected time error but got %s", stderr.String())
+	}
+
+	stderr.Reset()
+	require.EqualInt(t, EXIT_FAILURE, run([]string{writeVOD(t)}, &stdout, &stderr, func(s *http.Server) error {
+		return errors.New("address in use")
+	}))
+	require.EqualString(t, "livesim: address in use\n", stderr.String())
+}
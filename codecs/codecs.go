@@ -0,0 +1,568 @@
+// Package codecs parses and formats RFC 6381 codec strings, as used in the @codecs attribute of
+// AdaptationSets and Representations (i.e. avc1.4d401f, hvc1.2.4.L120.90, mp4a.40.2).
+package codecs
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Family groups the sample entries that share a codec string syntax.
+type Family string
+
+const (
+	FAMILY_UNKNOWN      Family = ""
+	FAMILY_AVC          Family = "avc"
+	FAMILY_HEVC         Family = "hevc"
+	FAMILY_DOLBY_VISION Family = "dolby-vision"
+	FAMILY_AV1          Family = "av1"
+	FAMILY_VP8          Family = "vp8"
+	FAMILY_VP9          Family = "vp9"
+	FAMILY_MPEG4_AUDIO  Family = "mp4a"
+	FAMILY_AC3          Family = "ac-3"
+	FAMILY_EC3          Family = "ec-3"
+	FAMILY_AC4          Family = "ac-4"
+	FAMILY_OPUS         Family = "opus"
+	FAMILY_VORBIS       Family = "vorbis"
+	FAMILY_FLAC         Family = "flac"
+	FAMILY_TTML         Family = "stpp"
+	FAMILY_WEBVTT       Family = "wvtt"
+)
+
+var families = map[string]Family{
+	"avc1": FAMILY_AVC,
+	"avc2": FAMILY_AVC,
+	"avc3": FAMILY_AVC,
+	"avc4": FAMILY_AVC,
+	"hvc1": FAMILY_HEVC,
+	"hev1": FAMILY_HEVC,
+	"dvh1": FAMILY_DOLBY_VISION,
+	"dvhe": FAMILY_DOLBY_VISION,
+	"dva1": FAMILY_DOLBY_VISION,
+	"dvav": FAMILY_DOLBY_VISION,
+	"dav1": FAMILY_DOLBY_VISION,
+	"av01": FAMILY_AV1,
+	"vp08": FAMILY_VP8,
+	"vp09": FAMILY_VP9,
+	"mp4a": FAMILY_MPEG4_AUDIO,
+	"ac-3": FAMILY_AC3,
+	"ec-3": FAMILY_EC3,
+	"ac-4": FAMILY_AC4,
+	"opus": FAMILY_OPUS,
+	"flac": FAMILY_FLAC,
+	"stpp": FAMILY_TTML,
+	"wvtt": FAMILY_WEBVTT,
+}
+
+// Registered codec identifiers that aren't ISO BMFF sample entries, as used for WebM. They take
+// no parameters.
+var identifiers = map[string]Family{
+	"vp8":    FAMILY_VP8,
+	"vp9":    FAMILY_VP9,
+	"vorbis": FAMILY_VORBIS,
+}
+
+// Known error variables
+var (
+	ErrMalformed = errors.New("Malformed codec string")
+)
+
+// Codec is a single parsed codec string. Only the fields of its Family are set.
+type Codec struct {
+	SampleEntry string // Four character code (i.e. avc1), or a registered identifier (i.e. vp9)
+	Family      Family
+
+	// Profile is the AVC profile_idc, HEVC general_profile_idc, AV1 seq_profile, VP profile,
+	// Dolby Vision profile or MPEG-4 Audio Object Type.
+	Profile int
+	// Level is the AVC level_idc, HEVC general_level_idc, AV1 seq_level_idx, VP level, Dolby
+	// Vision level or AC-4 mdcompat, as written in the codec string. See LevelName.
+	Level    int
+	Tier     string // HEVC L or H, AV1 M or H
+	BitDepth int    // AV1 and VP only
+
+	ConstraintSetFlags uint8 // AVC constraint_set flags byte
+
+	ProfileSpace       int    // HEVC general_profile_space, written as A, B or C
+	CompatibilityFlags uint32 // HEVC general_profile_compatibility_flags, in codec string bit order
+	ConstraintFlags    []byte // HEVC constraint indicator bytes
+
+	Color *ColorInfo // Optional AV1 and VP fields, nil when omitted
+
+	ObjectTypeIndication uint8 // MPEG-4 Audio (i.e. 0x40)
+
+	BitstreamVersion    int // AC-4
+	PresentationVersion int // AC-4
+
+	TTMLProfiles []string // TTML profiles after stpp.ttml (i.e. im1t)
+
+	Parameters []string // Dot separated elements of an unknown Family
+
+	// Case of the hexadecimal fields of a parsed codec string, 'x' or 'X', kept by String. Codecs
+	// built in code use the case of their specification.
+	hexCase byte
+}
+
+// ColorInfo holds the optional colour fields of the AV1 and VP codec strings.
+type ColorInfo struct {
+	Monochrome              bool // AV1 only
+	ChromaSubsampling       int  // AV1 as 3 digits (i.e. 110), VP as 2 digits (i.e. 1)
+	ColorPrimaries          int
+	TransferCharacteristics int
+	MatrixCoefficients      int
+	VideoFullRange          bool
+}
+
+// Parses a single codec string (i.e. hvc1.2.4.L120.90). Strings of an unknown sample entry or
+// identifier are only checked for RFC 6381 syntax. Hexadecimal fields are case insensitive, and
+// String keeps their case.
+func Parse(s string) (*Codec, error) {
+	parts := strings.Split(s, ".")
+	for _, p := range parts {
+		if !validElement(p) {
+			return nil, malformed(s, "elements should be non-empty and alphanumeric")
+		}
+	}
+
+	params := parts[1:]
+	if family, ok := identifiers[parts[0]]; ok {
+		if len(params) > 0 {
+			return nil, malformed(s, "no parameters expected")
+		}
+		return &Codec{SampleEntry: parts[0], Family: family}, nil
+	}
+	c := &Codec{SampleEntry: parts[0], Family: families[parts[0]]}
+	var err error
+	switch c.Family {
+	case FAMILY_AVC:
+		err = c.parseAVC(params)
+		c.hexCase = hexCase(params...)
+	case FAMILY_HEVC:
+		err = c.parseHEVC(params)
+		if err == nil {
+			c.hexCase = hexCase(append([]string{params[1]}, params[3:]...)...)
+		}
+	case FAMILY_DOLBY_VISION:
+		err = c.parseDolbyVision(params)
+	case FAMILY_AV1:
+		err = c.parseAV1(params)
+	case FAMILY_VP8, FAMILY_VP9:
+		err = c.parseVP(params)
+	case FAMILY_MPEG4_AUDIO:
+		err = c.parseMPEG4Audio(params)
+		if err == nil {
+			c.hexCase = hexCase(params[0])
+		}
+	case FAMILY_AC4:
+		err = c.parseAC4(params)
+	case FAMILY_TTML:
+		err = c.parseTTML(params)
+	case FAMILY_AC3, FAMILY_EC3, FAMILY_OPUS, FAMILY_FLAC, FAMILY_WEBVTT:
+		if len(params) > 0 {
+			err = errors.New("no parameters expected")
+		}
+	default:
+		c.Parameters = params
+	}
+	if err != nil {
+		return nil, malformed(s, err.Error())
+	}
+	return c, nil
+}
+
+// Parses a comma separated list of codec strings, as found in a @codecs attribute
+// (i.e. avc1.4d401f,mp4a.40.2).
+func ParseList(s string) ([]*Codec, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, malformed(s, "empty")
+	}
+	var list []*Codec
+	for _, part := range strings.Split(s, ",") {
+		c, err := Parse(strings.TrimSpace(part))
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, c)
+	}
+	return list, nil
+}
+
+// Validate checks that every codec string of a @codecs attribute is well formed.
+func Validate(s string) error {
+	_, err := ParseList(s)
+	return err
+}
+
+func malformed(s string, reason string) error {
+	return fmt.Errorf("%w %q: %s", ErrMalformed, s, reason)
+}
+
+func validElement(p string) bool {
+	if p == "" {
+		return false
+	}
+	for _, r := range p {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '+') {
+			return false
+		}
+	}
+	return true
+}
+
+// hexCase returns the case of the first letter of hexadecimal fields, or 0 when they are only digits.
+func hexCase(fields ...string) byte {
+	for _, f := range fields {
+		for _, r := range f {
+			switch {
+			case r >= 'a' && r <= 'f':
+				return 'x'
+			case r >= 'A' && r <= 'F':
+				return 'X'
+			}
+		}
+	}
+	return 0
+}
+
+// hexVerb returns the fmt verb of the hexadecimal fields, in their parsed case or else in
+// conventional, the case of the specification of the Family.
+func (c *Codec) hexVerb(conventional byte) string {
+	if c.hexCase != 0 {
+		return string(c.hexCase)
+	}
+	return string(conventional)
+}
+
+// decimal parses a base 10 field of exactly digits digits, or of any length when digits is 0.
+func decimal(s string, digits int) (int, bool) {
+	if s == "" || digits > 0 && len(s) != digits {
+		return 0, false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return 0, false
+		}
+	}
+	n, err := strconv.Atoi(s)
+	return n, err == nil
+}
+
+// hex parses a base 16 field of at most maxDigits digits.
+func hex(s string, maxDigits int) (uint64, bool) {
+	if s == "" || len(s) > maxDigits {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(s, 16, 64)
+	return n, err == nil
+}
+
+// avc1.PPCCLL
+func (c *Codec) parseAVC(params []string) error {
+	if len(params) != 1 || len(params[0]) != 6 {
+		return errors.New("profile, constraints and level should be 6 hex digits")
+	}
+	n, ok := hex(params[0], 6)
+	if !ok {
+		return errors.New("profile, constraints and level should be 6 hex digits")
+	}
+	c.Profile = int(n >> 16)
+	c.ConstraintSetFlags = uint8(n >> 8)
+	c.Level = int(n & 0xff)
+	return nil
+}
+
+// hvc1.[A-C]P.CCCCCCCC.[LH]L[.CC[.CC...]], ISO/IEC 14496-15 Annex E
+func (c *Codec) parseHEVC(params []string) error {
+	if len(params) < 3 || len(params) > 9 {
+		return errors.New("expected profile, compatibility flags, tier and level and up to 6 constraint bytes")
+	}
+
+	profile := params[0]
+	if profile[0] >= 'A' && profile[0] <= 'C' {
+		c.ProfileSpace = int(profile[0]-'A') + 1
+		profile = profile[1:]
+	}
+	var ok bool
+	if c.Profile, ok = decimal(profile, 0); !ok {
+		return errors.New("invalid profile")
+	}
+
+	flags, ok := hex(params[1], 8)
+	if !ok {
+		return errors.New("invalid compatibility flags")
+	}
+	c.CompatibilityFlags = uint32(flags)
+
+	c.Tier = params[2][:1]
+	if c.Tier != "L" && c.Tier != "H" {
+		return errors.New("tier should be L or H")
+	}
+	if c.Level, ok = decimal(params[2][1:], 0); !ok {
+		return errors.New("invalid level")
+	}
+
+	for _, p := range params[3:] {
+		b, ok := hex(p, 2)
+		if !ok {
+			return errors.New("invalid constraint byte")
+		}
+		c.ConstraintFlags = append(c.ConstraintFlags, byte(b))
+	}
+	return nil
+}
+
+// dvh1.PP.LL
+func (c *Codec) parseDolbyVision(params []string) error {
+	var ok1, ok2 bool
+	if len(params) == 2 {
+		c.Profile, ok1 = decimal(params[0], 2)
+		c.Level, ok2 = decimal(params[1], 2)
+	}
+	if !ok1 || !ok2 {
+		return errors.New("profile and level should be 2 digits each")
+	}
+	return nil
+}
+
+// av01.P.LLT.DD[.M.CCC.cp.tc.mc.F]
+func (c *Codec) parseAV1(params []string) error {
+	if len(params) != 3 && len(params) != 9 {
+		return errors.New("expected profile, level and tier, bit depth and optionally all six colour fields")
+	}
+	var ok bool
+	if c.Profile, ok = decimal(params[0], 1); !ok {
+		return errors.New("profile should be 1 digit")
+	}
+	if len(params[1]) != 3 {
+		return errors.New("level and tier should be 2 digits and M or H")
+	}
+	if c.Level, ok = decimal(params[1][:2], 2); !ok {
+		return errors.New("level and tier should be 2 digits and M or H")
+	}
+	c.Tier = params[1][2:]
+	if c.Tier != "M" && c.Tier != "H" {
+		return errors.New("level and tier should be 2 digits and M or H")
+	}
+	if c.BitDepth, ok = decimal(params[2], 2); !ok {
+		return errors.New("bit depth should be 2 digits")
+	}
+	if len(params) == 3 {
+		return nil
+	}
+
+	monochrome, ok := decimal(params[3], 1)
+	if !ok || monochrome > 1 {
+		return errors.New("monochrome should be 0 or 1")
+	}
+	chroma, ok := decimal(params[4], 3)
+	if !ok {
+		return errors.New("chroma subsampling should be 3 digits")
+	}
+	c.Color = &ColorInfo{Monochrome: monochrome == 1, ChromaSubsampling: chroma}
+	return c.Color.parseColorFields(params[5:], 1)
+}
+
+// vp09.PP.LL.DD[.CC.cp.tc.mc.FF]
+func (c *Codec) parseVP(params []string) error {
+	if len(params) != 3 && len(params) != 8 {
+		return errors.New("expected profile, level, bit depth and optionally all five colour fields")
+	}
+	var ok bool
+	if c.Profile, ok = decimal(params[0], 2); !ok {
+		return errors.New("profile should be 2 digits")
+	}
+	if c.Level, ok = decimal(params[1], 2); !ok {
+		return errors.New("level should be 2 digits")
+	}
+	if c.BitDepth, ok = decimal(params[2], 2); !ok {
+		return errors.New("bit depth should be 2 digits")
+	}
+	if len(params) == 3 {
+		return nil
+	}
+
+	chroma, ok := decimal(params[3], 2)
+	if !ok {
+		return errors.New("chroma subsampling should be 2 digits")
+	}
+	c.Color = &ColorInfo{ChromaSubsampling: chroma}
+	return c.Color.parseColorFields(params[4:], 2)
+}
+
+// parseColorFields parses the colour primaries, transfer characteristics, matrix coefficients
+// and full range flag shared by AV1 and VP. The full range flag is 1 digit for AV1 and 2 for VP.
+func (ci *ColorInfo) parseColorFields(params []string, fullRangeDigits int) error {
+	var ok1, ok2, ok3 bool
+	ci.ColorPrimaries, ok1 = decimal(params[0], 2)
+	ci.TransferCharacteristics, ok2 = decimal(params[1], 2)
+	ci.MatrixCoefficients, ok3 = decimal(params[2], 2)
+	fullRange, ok4 := decimal(params[3], fullRangeDigits)
+	ok4 = ok4 && fullRange <= 1
+	ci.VideoFullRange = fullRange == 1
+	if !ok1 || !ok2 || !ok3 {
+		return errors.New("colour primaries, transfer characteristics and matrix coefficients should be 2 digits")
+	}
+	if !ok4 {
+		return errors.New("video full range flag should be 0 or 1")
+	}
+	return nil
+}
+
+// mp4a.OO[.A]
+func (c *Codec) parseMPEG4Audio(params []string) error {
+	if len(params) < 1 || len(params) > 2 {
+		return errors.New("expected object type indication and optionally audio object type")
+	}
+	oti, ok := hex(params[0], 2)
+	if !ok || len(params[0]) != 2 {
+		return errors.New("object type indication should be 2 hex digits")
+	}
+	c.ObjectTypeIndication = uint8(oti)
+	if len(params) == 2 {
+		if c.ObjectTypeIndication != 0x40 {
+			return errors.New("audio object type is only allowed for object type indication 40")
+		}
+		if c.Profile, ok = decimal(params[1], 0); !ok || c.Profile == 0 {
+			return errors.New("invalid audio object type")
+		}
+	}
+	return nil
+}
+
+// ac-4.BB.PP.LL, ETSI TS 103 190-2 Annex E
+func (c *Codec) parseAC4(params []string) error {
+	var ok1, ok2, ok3 bool
+	if len(params) == 3 {
+		c.BitstreamVersion, ok1 = decimal(params[0], 2)
+		c.PresentationVersion, ok2 = decimal(params[1], 2)
+		c.Level, ok3 = decimal(params[2], 2)
+	}
+	if !ok1 || !ok2 || !ok3 {
+		return errors.New("bitstream version, presentation version and level should be 2 digits each")
+	}
+	return nil
+}
+
+// stpp[.ttml.PROFILE[+PROFILE...]], ISO/IEC 14496-30
+func (c *Codec) parseTTML(params []string) error {
+	if len(params) == 0 {
+		return nil
+	}
+	if len(params) != 2 || params[0] != "ttml" {
+		return errors.New("expected stpp.ttml followed by profiles")
+	}
+	for _, p := range strings.Split(params[1], "+") {
+		if p == "" {
+			return errors.New("empty TTML profile")
+		}
+		c.TTMLProfiles = append(c.TTMLProfiles, p)
+	}
+	return nil
+}
+
+// String formats the codec string (i.e. avc1.4d401f).
+func (c *Codec) String() string {
+	var sb strings.Builder
+	sb.WriteString(c.SampleEntry)
+	if _, ok := identifiers[c.SampleEntry]; ok {
+		return sb.String()
+	}
+	switch c.Family {
+	case FAMILY_AVC:
+		x := c.hexVerb('x')
+		fmt.Fprintf(&sb, ".%02"+x+"%02"+x+"%02"+x, c.Profile, c.ConstraintSetFlags, c.Level)
+	case FAMILY_HEVC:
+		sb.WriteString(".")
+		if c.ProfileSpace > 0 {
+			sb.WriteByte(byte('A' + c.ProfileSpace - 1))
+		}
+		x := c.hexVerb('X')
+		fmt.Fprintf(&sb, "%d.%"+x+".%s%d", c.Profile, c.CompatibilityFlags, c.Tier, c.Level)
+		// Trailing zero constraint bytes are omitted
+		last := len(c.ConstraintFlags)
+		for last > 0 && c.ConstraintFlags[last-1] == 0 {
+			last--
+		}
+		for _, b := range c.ConstraintFlags[:last] {
+			fmt.Fprintf(&sb, ".%"+x, b)
+		}
+	case FAMILY_DOLBY_VISION:
+		fmt.Fprintf(&sb, ".%02d.%02d", c.Profile, c.Level)
+	case FAMILY_AV1:
+		fmt.Fprintf(&sb, ".%d.%02d%s.%02d", c.Profile, c.Level, c.Tier, c.BitDepth)
+		if ci := c.Color; ci != nil {
+			fmt.Fprintf(&sb, ".%d.%03d.%02d.%02d.%02d.%d", boolDigit(ci.Monochrome), ci.ChromaSubsampling,
+				ci.ColorPrimaries, ci.TransferCharacteristics, ci.MatrixCoefficients, boolDigit(ci.VideoFullRange))
+		}
+	case FAMILY_VP8, FAMILY_VP9:
+		fmt.Fprintf(&sb, ".%02d.%02d.%02d", c.Profile, c.Level, c.BitDepth)
+		if ci := c.Color; ci != nil {
+			fmt.Fprintf(&sb, ".%02d.%02d.%02d.%02d.%02d", ci.ChromaSubsampling,
+				ci.ColorPrimaries, ci.TransferCharacteristics, ci.MatrixCoefficients, boolDigit(ci.VideoFullRange))
+		}
+	case FAMILY_MPEG4_AUDIO:
+		fmt.Fprintf(&sb, ".%02"+c.hexVerb('X'), c.ObjectTypeIndication)
+		if c.Profile > 0 {
+			fmt.Fprintf(&sb, ".%d", c.Profile)
+		}
+	case FAMILY_AC4:
+		fmt.Fprintf(&sb, ".%02d.%02d.%02d", c.BitstreamVersion, c.PresentationVersion, c.Level)
+	case FAMILY_TTML:
+		if len(c.TTMLProfiles) > 0 {
+			sb.WriteString(".ttml.")
+			sb.WriteString(strings.Join(c.TTMLProfiles, "+"))
+		}
+	case FAMILY_UNKNOWN:
+		for _, p := range c.Parameters {
+			sb.WriteString(".")
+			sb.WriteString(p)
+		}
+	}
+	return sb.String()
+}
+
+func boolDigit(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// MediaType returns video, audio or text for a known Family, or an empty string.
+func (c *Codec) MediaType() string {
+	switch c.Family {
+	case FAMILY_AVC, FAMILY_HEVC, FAMILY_DOLBY_VISION, FAMILY_AV1, FAMILY_VP8, FAMILY_VP9:
+		return "video"
+	case FAMILY_MPEG4_AUDIO, FAMILY_AC3, FAMILY_EC3, FAMILY_AC4, FAMILY_OPUS, FAMILY_FLAC, FAMILY_VORBIS:
+		return "audio"
+	case FAMILY_TTML, FAMILY_WEBVTT:
+		return "text"
+	}
+	return ""
+}
+
+// LevelName returns the level in its usual notation (i.e. 3.1 for avc1.4d401f, hvc1.1.6.L93.B0
+// or av01.0.05M.08), or an empty string when the Family has no level.
+func (c *Codec) LevelName() string {
+	if _, ok := identifiers[c.SampleEntry]; ok {
+		return ""
+	}
+	switch c.Family {
+	case FAMILY_AVC:
+		if c.Level == 9 {
+			return "1b"
+		}
+		return fmt.Sprintf("%d.%d", c.Level/10, c.Level%10)
+	case FAMILY_HEVC:
+		return fmt.Sprintf("%d.%d", c.Level/30, c.Level%30/3)
+	case FAMILY_AV1:
+		return fmt.Sprintf("%d.%d", 2+(c.Level>>2), c.Level&3)
+	case FAMILY_VP8, FAMILY_VP9:
+		return fmt.Sprintf("%d.%d", c.Level/10, c.Level%10)
+	case FAMILY_DOLBY_VISION, FAMILY_AC4:
+		return strconv.Itoa(c.Level)
+	}
+	return ""
+}
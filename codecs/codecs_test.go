@@ -0,0 +1,162 @@
+package codecs
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/zencoder/go-dash/v3/helpers/require"
+)
+
+func TestParseRoundTrip(t *testing.T) {
+	testCases := []struct {
+		codecs    string
+		family    Family
+		mediaType string
+		profile   int
+		level     int
+		levelName string
+	}{
+		{"avc1.4d401f", FAMILY_AVC, "video", 77, 31, "3.1"},
+		{"avc3.640028", FAMILY_AVC, "video", 100, 40, "4.0"},
+		{"hvc1.1.6.L93.B0", FAMILY_HEVC, "video", 1, 93, "3.1"},
+		{"hev1.2.4.H150.90", FAMILY_HEVC, "video", 2, 150, "5.0"},
+		{"hev1.A2.4.H120.90", FAMILY_HEVC, "video", 2, 120, "4.0"},
+		{"dvh1.05.06", FAMILY_DOLBY_VISION, "video", 5, 6, "6"},
+		{"dvhe.08.09", FAMILY_DOLBY_VISION, "video", 8, 9, "9"},
+		{"av01.0.04M.08", FAMILY_AV1, "video", 0, 4, "3.0"},
+		{"av01.0.13H.10.0.110.09.16.09.0", FAMILY_AV1, "video", 0, 13, "5.1"},
+		{"vp09.00.10.08", FAMILY_VP9, "video", 0, 10, "1.0"},
+		{"vp09.02.31.10.01.09.16.09.01", FAMILY_VP9, "video", 2, 31, "3.1"},
+		{"mp4a.40.2", FAMILY_MPEG4_AUDIO, "audio", 2, 0, ""},
+		{"mp4a.6B", FAMILY_MPEG4_AUDIO, "audio", 0, 0, ""},
+		{"ec-3", FAMILY_EC3, "audio", 0, 0, ""},
+		{"ac-3", FAMILY_AC3, "audio", 0, 0, ""},
+		{"ac-4.02.01.03", FAMILY_AC4, "audio", 0, 3, "3"},
+		{"opus", FAMILY_OPUS, "audio", 0, 0, ""},
+		{"flac", FAMILY_FLAC, "audio", 0, 0, ""},
+		{"stpp.ttml.im1t", FAMILY_TTML, "text", 0, 0, ""},
+		{"stpp", FAMILY_TTML, "text", 0, 0, ""},
+		{"wvtt", FAMILY_WEBVTT, "text", 0, 0, ""},
+		{"mp4v.20.9", FAMILY_UNKNOWN, "", 0, 0, ""},
+		{"vp8", FAMILY_VP8, "video", 0, 0, ""},
+		{"vp9", FAMILY_VP9, "video", 0, 0, ""},
+		{"vorbis", FAMILY_VORBIS, "audio", 0, 0, ""},
+		{"theora", FAMILY_UNKNOWN, "", 0, 0, ""},
+		// Hexadecimal fields keep their case
+		{"avc1.4D401F", FAMILY_AVC, "video", 77, 31, "3.1"},
+		{"hvc1.2.4.L120.b0", FAMILY_HEVC, "video", 2, 120, "4.0"},
+		{"hvc1.2.c.L120.90", FAMILY_HEVC, "video", 2, 120, "4.0"},
+		{"mp4a.6b", FAMILY_MPEG4_AUDIO, "audio", 0, 0, ""},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.codecs, func(t *testing.T) {
+			c, err := Parse(tc.codecs)
+			require.NoError(t, err)
+			require.EqualString(t, string(tc.family), string(c.Family))
+			require.EqualString(t, tc.mediaType, c.MediaType())
+			require.EqualInt(t, tc.profile, c.Profile)
+			require.EqualInt(t, tc.level, c.Level)
+			require.EqualString(t, tc.levelName, c.LevelName())
+			require.EqualString(t, tc.codecs, c.String())
+		})
+	}
+}
+
+func TestParseFields(t *testing.T) {
+	c, err := Parse("avc1.42c01e")
+	require.NoError(t, err)
+	require.EqualInt(t, 0xc0, int(c.ConstraintSetFlags))
+
+	c, err = Parse("hev1.B2.4.H120.90.0.0")
+	require.NoError(t, err)
+	require.EqualInt(t, 2, c.ProfileSpace)
+	require.EqualInt(t, 4, int(c.CompatibilityFlags))
+	require.EqualString(t, "H", c.Tier)
+	require.EqualInt(t, 3, len(c.ConstraintFlags))
+	require.EqualString(t, "hev1.B2.4.H120.90", c.String())
+
+	c, err = Parse("av01.2.19H.12.0.110.09.16.09.1")
+	require.NoError(t, err)
+	require.EqualInt(t, 12, c.BitDepth)
+	require.NotNil(t, c.Color)
+	require.EqualInt(t, 110, c.Color.ChromaSubsampling)
+	require.EqualInt(t, 16, c.Color.TransferCharacteristics)
+	if !c.Color.VideoFullRange || c.Color.Monochrome {
+		t.Errorf("Expected full range, not monochrome")
+	}
+
+	c, err = Parse("mp4a.40.5")
+	require.NoError(t, err)
+	require.EqualInt(t, 0x40, int(c.ObjectTypeIndication))
+
+	c, err = Parse("stpp.ttml.im1t+etd1")
+	require.NoError(t, err)
+	require.EqualStringSlice(t, []string{"im1t", "etd1"}, c.TTMLProfiles)
+
+	c, err = Parse("ac-4.02.01.03")
+	require.NoError(t, err)
+	require.EqualInt(t, 2, c.BitstreamVersion)
+	require.EqualInt(t, 1, c.PresentationVersion)
+}
+
+func TestParseMalformed(t *testing.T) {
+	testCases := []struct {
+		codecs string
+		err    string
+	}{
+		{"", `Malformed codec string "": elements should be non-empty and alphanumeric`},
+		{"avc1..4d401f", `Malformed codec string "avc1..4d401f": elements should be non-empty and alphanumeric`},
+		{"avc1.4d40", `Malformed codec string "avc1.4d40": profile, constraints and level should be 6 hex digits`},
+		{"avc1.4d40zz", `Malformed codec string "avc1.4d40zz": profile, constraints and level should be 6 hex digits`},
+		{"hvc1.1.6", `Malformed codec string "hvc1.1.6": expected profile, compatibility flags, tier and level and up to 6 constraint bytes`},
+		{"hvc1.1.6.X93", `Malformed codec string "hvc1.1.6.X93": tier should be L or H`},
+		{"hvc1.1.6.L93.B00", `Malformed codec string "hvc1.1.6.L93.B00": invalid constraint byte`},
+		{"dvh1.5.6", `Malformed codec string "dvh1.5.6": profile and level should be 2 digits each`},
+		{"av01.0.04.08", `Malformed codec string "av01.0.04.08": level and tier should be 2 digits and M or H`},
+		{"av01.0.04M.08.0.110", `Malformed codec string "av01.0.04M.08.0.110": expected profile, level and tier, bit depth and optionally all six colour fields`},
+		{"av01.0.04M.08.0.110.09.16.09.2", `Malformed codec string "av01.0.04M.08.0.110.09.16.09.2": video full range flag should be 0 or 1`},
+		{"vp09.0.10.08", `Malformed codec string "vp09.0.10.08": profile should be 2 digits`},
+		{"mp4a.40.0", `Malformed codec string "mp4a.40.0": invalid audio object type`},
+		{"mp4a.6B.2", `Malformed codec string "mp4a.6B.2": audio object type is only allowed for object type indication 40`},
+		{"ec-3.1", `Malformed codec string "ec-3.1": no parameters expected`},
+		{"ac-4.2.1.3", `Malformed codec string "ac-4.2.1.3": bitstream version, presentation version and level should be 2 digits each`},
+		{"stpp.im1t", `Malformed codec string "stpp.im1t": expected stpp.ttml followed by profiles`},
+		{"mp4 a.40.2", `Malformed codec string "mp4 a.40.2": elements should be non-empty and alphanumeric`},
+		{"vp9.1", `Malformed codec string "vp9.1": no parameters expected`},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.codecs, func(t *testing.T) {
+			_, err := Parse(tc.codecs)
+			require.EqualError(t, err, tc.err)
+			if !errors.Is(err, ErrMalformed) {
+				t.Errorf("Expected error to wrap ErrMalformed")
+			}
+		})
+	}
+}
+
+func TestParseList(t *testing.T) {
+	list, err := ParseList("avc1.4d401f, mp4a.40.2")
+	require.NoError(t, err)
+	require.EqualInt(t, 2, len(list))
+	require.EqualString(t, "video", list[0].MediaType())
+	require.EqualString(t, "audio", list[1].MediaType())
+
+	require.NoError(t, Validate("hvc1.1.6.L93.B0,ec-3"))
+	require.EqualError(t, Validate("avc1.4d401f,"), `Malformed codec string "": elements should be non-empty and alphanumeric`)
+	require.EqualError(t, Validate(" "), `Malformed codec string " ": empty`)
+}
+
+func TestStringConventionalCase(t *testing.T) {
+	// Codecs built in code use the case of their specification
+	for _, tc := range []struct {
+		codec    *Codec
+		expected string
+	}{
+		{&Codec{SampleEntry: "avc1", Family: FAMILY_AVC, Profile: 0x64, Level: 0x1f}, "avc1.64001f"},
+		{&Codec{SampleEntry: "hvc1", Family: FAMILY_HEVC, Profile: 1, CompatibilityFlags: 6, Tier: "L", Level: 93, ConstraintFlags: []byte{0xb0}}, "hvc1.1.6.L93.B0"},
+		{&Codec{SampleEntry: "mp4a", Family: FAMILY_MPEG4_AUDIO, ObjectTypeIndication: 0x6b}, "mp4a.6B"},
+	} {
+		require.EqualString(t, tc.expected, tc.codec.String())
+	}
+}
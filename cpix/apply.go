@@ -0,0 +1,454 @@
+package cpix
+
+import (
+	"encoding/base64"
+	"encoding/xml"
+	"strconv"
+	"strings"
+
+	. "github.com/zencoder/go-dash/v3/helpers/ptrs"
+	"github.com/zencoder/go-dash/v3/mpd"
+)
+
+// intendedContentTypes maps the usual intendedTrackType values to the content type of the tracks
+// they apply to. Other values don't restrict the tracks of a rule.
+var intendedContentTypes = map[string]string{
+	"AUDIO": "audio",
+	"VIDEO": "video",
+	"SD":    "video",
+	"HD":    "video",
+	"UHD":   "video",
+	"UHD1":  "video",
+	"UHD2":  "video",
+}
+
+// contentProtectionTarget is implemented by both mpd.AdaptationSet and mpd.Representation.
+type contentProtectionTarget interface {
+	AddNewContentProtectionRoot(defaultKIDHex string) (*mpd.CENCContentProtection, error)
+	AddContentProtection(cp mpd.ContentProtectioner) error
+	RemoveContentProtection(schemeIDURI string)
+}
+
+// Apply adds ContentProtection elements to every AdaptationSet and Representation of the MPD
+// that matches one of the document's content key usage rules. When every Representation of an
+// AdaptationSet uses the same key, the signalling is added to the AdaptationSet, otherwise it
+// is added to each Representation. Tracks that match no rule, and text and image tracks, are left
+// unencrypted. Rules with an intendedTrackType of AUDIO only match audio tracks, and ones of VIDEO,
+// SD, HD, UHD, UHD1 or UHD2 only match video tracks.
+//
+// For each key a root ContentProtection carrying the default_KID is added, followed by one
+// ContentProtection per DRM system in document order. Widevine and PlayReady systems are
+// signalled with their PSSH and PRO data; other systems only get their schemeIdUri. They replace
+// the ContentProtections with the same schemeIdUri of the AdaptationSet and its Representations,
+// so applying a document again doesn't duplicate them.
+//
+// KeyPeriodFilters are ignored, key rotation is not supported.
+func (c *CPIX) Apply(m *mpd.MPD) error {
+	for _, rule := range c.ContentKeyUsageRules {
+		if c.ContentKey(rule.KID) == nil {
+			return ErrUnknownKID
+		}
+	}
+
+	for _, period := range m.Periods {
+		for _, as := range period.AdaptationSets {
+			if err := c.applyAdaptationSet(as); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (c *CPIX) applyAdaptationSet(as *mpd.AdaptationSet) error {
+	if len(as.Representations) == 0 {
+		return nil
+	}
+
+	kids := make([]string, len(as.Representations))
+	shared := true
+	for i, r := range as.Representations {
+		kid, err := c.kidForTrack(newTrack(as, r))
+		if err != nil {
+			return err
+		}
+		kids[i] = kid
+		if kid != kids[0] {
+			shared = false
+		}
+	}
+
+	for _, scheme := range c.schemeIDURIs(kids) {
+		as.RemoveContentProtection(scheme)
+		for _, r := range as.Representations {
+			r.RemoveContentProtection(scheme)
+		}
+	}
+	if shared {
+		if kids[0] == "" {
+			return nil
+		}
+		return c.addContentProtection(as, kids[0])
+	}
+	for i, r := range as.Representations {
+		if kids[i] == "" {
+			continue
+		}
+		if err := c.addContentProtection(r, kids[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// kidForTrack returns the KID of the usage rules matching the track, or an empty string if no
+// rule matches.
+func (c *CPIX) kidForTrack(t *track) (string, error) {
+	var kid string
+	for _, rule := range c.ContentKeyUsageRules {
+		if !rule.matches(t) {
+			continue
+		}
+		if kid != "" && normalizeKID(kid) != normalizeKID(rule.KID) {
+			return "", ErrAmbiguousUsageRule
+		}
+		kid = rule.KID
+	}
+	return kid, nil
+}
+
+// schemeIDURIs returns the schemeIdUri of the ContentProtections added for the keys, none when no
+// key is used.
+func (c *CPIX) schemeIDURIs(kids []string) []string {
+	var schemes []string
+	for _, kid := range kids {
+		if kid == "" {
+			continue
+		}
+		if len(schemes) == 0 {
+			schemes = append(schemes, mpd.CONTENT_PROTECTION_ROOT_SCHEME_ID_URI)
+		}
+		for _, system := range c.DRMSystemsForKID(kid) {
+			schemes = append(schemes, "urn:uuid:"+strings.ToLower(system.SystemID))
+		}
+	}
+	return schemes
+}
+
+func (c *CPIX) addContentProtection(target contentProtectionTarget, kid string) error {
+	kidHex := normalizeKID(kid)
+	if len(kidHex) != 32 {
+		return ErrInvalidKID
+	}
+	root, err := target.AddNewContentProtectionRoot(kidHex)
+	if err != nil {
+		return err
+	}
+	if key := c.ContentKey(kid); key.CommonEncryptionScheme != nil {
+		root.Value = Strptr(*key.CommonEncryptionScheme)
+	}
+
+	for _, system := range c.DRMSystemsForKID(kid) {
+		cp, err := system.contentProtection()
+		if err != nil {
+			return err
+		}
+		if err := target.AddContentProtection(cp); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// contentProtectionData holds the children of a ContentProtection element, as carried by
+// the ContentProtectionData element of a DRMSystem.
+type contentProtectionData struct {
+	PSSH *string `xml:"pssh"`
+	PRO  *string `xml:"pro"`
+}
+
+func (s *DRMSystem) contentProtectionData() (*contentProtectionData, error) {
+	var data contentProtectionData
+	if s.ContentProtectionData == nil {
+		return &data, nil
+	}
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(*s.ContentProtectionData))
+	if err != nil {
+		return nil, err
+	}
+	// The data is a fragment that relies on the MPD's namespace declarations, wrap it so it can be decoded.
+	doc := `<ContentProtection xmlns:cenc="` + mpd.CENC_XMLNS + `" xmlns:mspr="` + mpd.CONTENT_PROTECTION_PLAYREADY_XMLNS + `">` +
+		string(raw) + `</ContentProtection>`
+	if err := xml.Unmarshal([]byte(doc), &data); err != nil {
+		return nil, err
+	}
+	return &data, nil
+}
+
+func (s *DRMSystem) pssh(data *contentProtectionData) *string {
+	if s.PSSH != nil {
+		return Strptr(strings.TrimSpace(*s.PSSH))
+	}
+	if data.PSSH != nil {
+		return Strptr(strings.TrimSpace(*data.PSSH))
+	}
+	return nil
+}
+
+func (s *DRMSystem) contentProtection() (mpd.ContentProtectioner, error) {
+	data, err := s.contentProtectionData()
+	if err != nil {
+		return nil, err
+	}
+	pssh := s.pssh(data)
+	schemeIDURI := "urn:uuid:" + strings.ToLower(s.SystemID)
+
+	switch schemeIDURI {
+	case mpd.CONTENT_PROTECTION_WIDEVINE_SCHEME_ID:
+		cp := &mpd.WidevineContentProtection{}
+		cp.SchemeIDURI = Strptr(schemeIDURI)
+		if pssh != nil {
+			cp.XMLNS = Strptr(mpd.CENC_XMLNS)
+			cp.PSSH = pssh
+		}
+		return cp, nil
+	case mpd.CONTENT_PROTECTION_PLAYREADY_SCHEME_ID, mpd.CONTENT_PROTECTION_PLAYREADY_SCHEME_V10_ID:
+		pro, err := playreadyPRO(data, pssh)
+		if err != nil {
+			return nil, err
+		}
+		cp := &mpd.PlayreadyContentProtection{
+			PlayreadyXMLNS: Strptr(mpd.CONTENT_PROTECTION_PLAYREADY_XMLNS),
+			PRO:            Strptr(pro),
+		}
+		cp.SchemeIDURI = Strptr(schemeIDURI)
+		if pssh != nil {
+			cp.XMLNS = Strptr(mpd.CENC_XMLNS)
+			cp.PSSH = pssh
+		}
+		return cp, nil
+	default:
+		cp := &mpd.ContentProtection{}
+		cp.SchemeIDURI = Strptr(schemeIDURI)
+		return cp, nil
+	}
+}
+
+// playreadyPRO returns the Base64 PlayReady Object, either as signalled in the ContentProtectionData
+// or extracted from the PlayReady pssh box.
+func playreadyPRO(data *contentProtectionData, pssh *string) (string, error) {
+	if data.PRO != nil {
+		return strings.TrimSpace(*data.PRO), nil
+	}
+	if pssh == nil {
+		return "", ErrPRONotFound
+	}
+	box, err := base64.StdEncoding.DecodeString(*pssh)
+	if err != nil {
+		return "", err
+	}
+	p, err := mpd.ParsePSSHBox(box)
+	if err != nil {
+		return "", err
+	}
+	if len(p.Data) == 0 {
+		return "", ErrPRONotFound
+	}
+	return base64.StdEncoding.EncodeToString(p.Data), nil
+}
+
+func normalizeKID(kid string) string {
+	return strings.ToLower(strings.ReplaceAll(kid, "-", ""))
+}
+
+// track holds the properties of a Representation that usage rules filter on.
+type track struct {
+	contentType string
+	pixels      int64
+	fps         float64
+	hdr         bool
+	wcg         bool
+	channels    int64
+	bandwidth   int64
+	labels      []string
+}
+
+func newTrack(as *mpd.AdaptationSet, r *mpd.Representation) *track {
+	t := &track{
+		labels: as.Labels,
+	}
+
+	switch {
+	case r.MimeType != nil:
+		t.contentType = *r.MimeType
+	case as.MimeType != nil:
+		t.contentType = *as.MimeType
+	case as.ContentType != nil:
+		t.contentType = *as.ContentType
+	}
+	if i := strings.Index(t.contentType, "/"); i >= 0 {
+		t.contentType = t.contentType[:i]
+	}
+	if isText(as, r) {
+		t.contentType = "text"
+	}
+
+	if r.Width != nil && r.Height != nil {
+		t.pixels = *r.Width * *r.Height
+	}
+	if r.FrameRate != nil {
+		t.fps, _ = mpd.ParseFrameRate(*r.FrameRate)
+	} else if as.FrameRate != nil {
+		t.fps, _ = mpd.ParseFrameRate(*as.FrameRate)
+	}
+	if r.Bandwidth != nil {
+		t.bandwidth = *r.Bandwidth
+	}
+
+	var descriptors []mpd.DescriptorType
+	for _, d := range [][]mpd.DescriptorType{as.EssentialProperty, as.SupplementalProperty, r.EssentialProperty, r.SupplementalProperty} {
+		descriptors = append(descriptors, d...)
+	}
+	for _, d := range descriptors {
+		if d.SchemeIDURI == nil || d.Value == nil {
+			continue
+		}
+		if *d.SchemeIDURI == mpd.CICP_COLOUR_PRIMARIES_SCHEME_ID {
+			// 9 is ITU-R BT.2020
+			t.wcg = *d.Value == "9"
+		}
+	}
+	t.hdr = mpd.IsHDR(as, r)
+
+	if r.AudioChannelConfiguration != nil {
+		t.channels = parseChannels(r.AudioChannelConfiguration.SchemeIDURI, r.AudioChannelConfiguration.Value)
+	} else if len(as.AudioChannelConfiguration) > 0 {
+		t.channels = parseChannels(as.AudioChannelConfiguration[0].SchemeIDURI, as.AudioChannelConfiguration[0].Value)
+	}
+	return t
+}
+
+// isText reports whether a Representation is subtitles or captions, which can be carried in
+// application/ MIME types.
+func isText(as *mpd.AdaptationSet, r *mpd.Representation) bool {
+	if as.ContentType != nil && *as.ContentType == "text" {
+		return true
+	}
+	for _, mimeType := range []*string{r.MimeType, as.MimeType} {
+		if mimeType != nil && *mimeType == "application/ttml+xml" {
+			return true
+		}
+	}
+	for _, codecs := range []*string{r.Codecs, as.Codecs} {
+		if codecs != nil && (strings.HasPrefix(*codecs, "stpp") || strings.HasPrefix(*codecs, "wvtt")) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseChannels returns the channel count for the MPEG-DASH channel configuration scheme. The
+// Dolby scheme uses a channel mask, which is not interpreted, so 0 is returned.
+func parseChannels(scheme, value *string) int64 {
+	if scheme == nil || value == nil || *scheme != string(mpd.AUDIO_CHANNEL_CONFIGURATION_MPEG_DASH) {
+		return 0
+	}
+	channels, err := strconv.ParseInt(*value, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return channels
+}
+
+// matches reports whether the rule applies to the track. Filters of different types must all
+// match, while any one of several filters of the same type is enough.
+func (rule *ContentKeyUsageRule) matches(t *track) bool {
+	if t.contentType == "image" || t.contentType == "text" {
+		return false
+	}
+	if rule.IntendedTrackType != nil {
+		contentType, ok := intendedContentTypes[strings.ToUpper(strings.TrimSpace(*rule.IntendedTrackType))]
+		if ok && contentType != t.contentType {
+			return false
+		}
+	}
+	if len(rule.LabelFilters) > 0 && !anyMatch(len(rule.LabelFilters), func(i int) bool { return rule.LabelFilters[i].matches(t) }) {
+		return false
+	}
+	if len(rule.VideoFilters) > 0 && !anyMatch(len(rule.VideoFilters), func(i int) bool { return rule.VideoFilters[i].matches(t) }) {
+		return false
+	}
+	if len(rule.AudioFilters) > 0 && !anyMatch(len(rule.AudioFilters), func(i int) bool { return rule.AudioFilters[i].matches(t) }) {
+		return false
+	}
+	if len(rule.BitrateFilters) > 0 && !anyMatch(len(rule.BitrateFilters), func(i int) bool { return rule.BitrateFilters[i].matches(t) }) {
+		return false
+	}
+	return true
+}
+
+func anyMatch(n int, match func(i int) bool) bool {
+	for i := 0; i < n; i++ {
+		if match(i) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *LabelFilter) matches(t *track) bool {
+	for _, l := range t.labels {
+		if l == f.Label {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *VideoFilter) matches(t *track) bool {
+	if t.contentType != "video" {
+		return false
+	}
+	if f.MinPixels != nil && t.pixels < *f.MinPixels {
+		return false
+	}
+	if f.MaxPixels != nil && t.pixels > *f.MaxPixels {
+		return false
+	}
+	if f.HDR != nil && t.hdr != *f.HDR {
+		return false
+	}
+	if f.WCG != nil && t.wcg != *f.WCG {
+		return false
+	}
+	if f.MinFPS != nil && t.fps <= float64(*f.MinFPS) {
+		return false
+	}
+	if f.MaxFPS != nil && t.fps > float64(*f.MaxFPS) {
+		return false
+	}
+	return true
+}
+
+func (f *AudioFilter) matches(t *track) bool {
+	if t.contentType != "audio" {
+		return false
+	}
+	if f.MinChannels != nil && t.channels < *f.MinChannels {
+		return false
+	}
+	if f.MaxChannels != nil && t.channels > *f.MaxChannels {
+		return false
+	}
+	return true
+}
+
+func (f *BitrateFilter) matches(t *track) bool {
+	if f.MinBitrate != nil && t.bandwidth < *f.MinBitrate {
+		return false
+	}
+	if f.MaxBitrate != nil && t.bandwidth > *f.MaxBitrate {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,147 @@
+// Package cpix reads DASH-IF Content Protection Information Exchange (CPIX) documents
+// and applies the keys and DRM signalling they contain to an MPD.
+package cpix
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"os"
+)
+
+// Constants for the CPIX document namespaces
+const (
+	CPIX_XMLNS = "urn:dashif:org:cpix"
+	PSKC_XMLNS = "urn:ietf:params:xml:ns:keyprov:pskc"
+)
+
+// Known error variables
+var (
+	ErrNoContentKeys      = errors.New("CPIX document has no content keys")
+	ErrAmbiguousUsageRule = errors.New("Content key usage rules match more than one key for a track")
+	ErrUnknownKID         = errors.New("Usage rule references a KID that is not in the content key list")
+	ErrInvalidKID         = errors.New("Invalid KID, should be a UUID")
+	ErrPRONotFound        = errors.New("PlayReady DRM system has no PRO")
+)
+
+// CPIX is a DASH-IF CPIX 2.3 document.
+type CPIX struct {
+	XMLName              xml.Name               `xml:"urn:dashif:org:cpix CPIX"`
+	ID                   *string                `xml:"id,attr,omitempty"`
+	ContentID            *string                `xml:"contentId,attr,omitempty"`
+	Version              *string                `xml:"version,attr,omitempty"`
+	ContentKeys          []*ContentKey          `xml:"ContentKeyList>ContentKey"`
+	DRMSystems           []*DRMSystem           `xml:"DRMSystemList>DRMSystem"`
+	ContentKeyUsageRules []*ContentKeyUsageRule `xml:"ContentKeyUsageRuleList>ContentKeyUsageRule"`
+}
+
+type ContentKey struct {
+	KID                    string  `xml:"kid,attr"`
+	ExplicitIV             *string `xml:"explicitIV,attr,omitempty"`
+	CommonEncryptionScheme *string `xml:"commonEncryptionScheme,attr,omitempty"` // i.e. cenc, cbcs
+	PlainValue             *string `xml:"Data>Secret>PlainValue,omitempty"`      // Base64 encoded key, if not encrypted
+}
+
+type DRMSystem struct {
+	KID                                 string  `xml:"kid,attr"`
+	SystemID                            string  `xml:"systemId,attr"`
+	PSSH                                *string `xml:"PSSH,omitempty"`                  // Base64 encoded pssh box
+	ContentProtectionData               *string `xml:"ContentProtectionData,omitempty"` // Base64 encoded ContentProtection children
+	SmoothStreamingProtectionHeaderData *string `xml:"SmoothStreamingProtectionHeaderData,omitempty"`
+	URIExtXKey                          *string `xml:"URIExtXKey,omitempty"`
+}
+
+type ContentKeyUsageRule struct {
+	KID               string             `xml:"kid,attr"`
+	IntendedTrackType *string            `xml:"intendedTrackType,attr,omitempty"`
+	KeyPeriodFilters  []*KeyPeriodFilter `xml:"KeyPeriodFilter,omitempty"`
+	LabelFilters      []*LabelFilter     `xml:"LabelFilter,omitempty"`
+	VideoFilters      []*VideoFilter     `xml:"VideoFilter,omitempty"`
+	AudioFilters      []*AudioFilter     `xml:"AudioFilter,omitempty"`
+	BitrateFilters    []*BitrateFilter   `xml:"BitrateFilter,omitempty"`
+}
+
+// KeyPeriodFilter restricts a rule to a key period. Key rotation is not supported when applying
+// to an MPD, so these filters are decoded but ignored.
+type KeyPeriodFilter struct {
+	PeriodID string `xml:"periodId,attr"`
+}
+
+type LabelFilter struct {
+	Label string `xml:"label,attr"`
+}
+
+type VideoFilter struct {
+	MinPixels *int64 `xml:"minPixels,attr,omitempty"` // Inclusive
+	MaxPixels *int64 `xml:"maxPixels,attr,omitempty"` // Inclusive
+	HDR       *bool  `xml:"hdr,attr,omitempty"`
+	WCG       *bool  `xml:"wcg,attr,omitempty"`
+	MinFPS    *int64 `xml:"minFps,attr,omitempty"` // Exclusive
+	MaxFPS    *int64 `xml:"maxFps,attr,omitempty"` // Inclusive
+}
+
+type AudioFilter struct {
+	MinChannels *int64 `xml:"minChannels,attr,omitempty"` // Inclusive
+	MaxChannels *int64 `xml:"maxChannels,attr,omitempty"` // Inclusive
+}
+
+type BitrateFilter struct {
+	MinBitrate *int64 `xml:"minBitrate,attr,omitempty"` // Inclusive
+	MaxBitrate *int64 `xml:"maxBitrate,attr,omitempty"` // Inclusive
+}
+
+// Reads a CPIX XML file from disk into a CPIX object.
+// path - File path to a CPIX document on disk
+func ReadFromFile(path string) (*CPIX, error) {
+	f, err := os.OpenFile(path, os.O_RDONLY, 0666)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return Read(f)
+}
+
+// Reads a string into a CPIX object.
+// xmlStr - CPIX document as a string.
+func ReadFromString(xmlStr string) (*CPIX, error) {
+	b := bytes.NewBufferString(xmlStr)
+	return Read(b)
+}
+
+// Reads from an io.Reader interface into a CPIX object.
+// r - Must implement the io.Reader interface.
+func Read(r io.Reader) (*CPIX, error) {
+	var c CPIX
+	d := xml.NewDecoder(r)
+	err := d.Decode(&c)
+	if err != nil {
+		return nil, err
+	}
+	if len(c.ContentKeys) == 0 {
+		return nil, ErrNoContentKeys
+	}
+	return &c, nil
+}
+
+// ContentKey returns the content key with the given KID, or nil if there is none.
+func (c *CPIX) ContentKey(kid string) *ContentKey {
+	for _, k := range c.ContentKeys {
+		if normalizeKID(k.KID) == normalizeKID(kid) {
+			return k
+		}
+	}
+	return nil
+}
+
+// DRMSystemsForKID returns the DRM system signalling for the given KID, in document order.
+func (c *CPIX) DRMSystemsForKID(kid string) []*DRMSystem {
+	var systems []*DRMSystem
+	for _, s := range c.DRMSystems {
+		if normalizeKID(s.KID) == normalizeKID(kid) {
+			systems = append(systems, s)
+		}
+	}
+	return systems
+}
@@ -0,0 +1,196 @@
+package cpix
+
+import (
+	"testing"
+
+	"github.com/zencoder/go-dash/v3/helpers/ptrs"
+	"github.com/zencoder/go-dash/v3/helpers/require"
+	"github.com/zencoder/go-dash/v3/helpers/testfixtures"
+	"github.com/zencoder/go-dash/v3/mpd"
+)
+
+func TestReadFromFile(t *testing.T) {
+	c, err := ReadFromFile("fixtures/cpix.xml")
+	require.NoError(t, err)
+
+	require.EqualStringPtr(t, ptrs.Strptr("example-content"), c.ContentID)
+	require.EqualInt(t, 3, len(c.ContentKeys))
+	require.EqualInt(t, 5, len(c.DRMSystems))
+	require.EqualInt(t, 3, len(c.ContentKeyUsageRules))
+
+	require.EqualStringPtr(t, ptrs.Strptr("cbcs"), c.ContentKeys[0].CommonEncryptionScheme)
+	require.EqualStringPtr(t, ptrs.Strptr("jw8jEj5PRzKhvE8T4CtO1w=="), c.ContentKeys[0].PlainValue)
+	require.EqualInt(t, 2, len(c.DRMSystemsForKID("5ABDD52F554A4F2AB8D061F761425155")))
+	require.EqualStringPtr(t, ptrs.Strptr("HD"), c.ContentKeyUsageRules[1].IntendedTrackType)
+	require.EqualInt(t, 1, len(c.ContentKeyUsageRules[1].VideoFilters))
+}
+
+func TestReadErrors(t *testing.T) {
+	_, err := ReadFromString(`<CPIX xmlns="urn:dashif:org:cpix"></CPIX>`)
+	require.EqualErr(t, ErrNoContentKeys, err)
+
+	_, err = ReadFromString(`<MPD xmlns="urn:mpeg:dash:schema:mpd:2011"></MPD>`)
+	require.EqualError(t, err, "expected element type <CPIX> but have <MPD>")
+}
+
+func testMPD() *mpd.MPD {
+	m := mpd.NewMPD(mpd.DASH_PROFILE_LIVE, "PT6M16S", "PT1.97S")
+
+	audioAS, _ := m.AddNewAdaptationSetAudioWithID("1", mpd.DASH_MIME_TYPE_AUDIO_MP4, true, 1, "en")
+	_, _ = audioAS.SetNewSegmentTemplate(1968, "$RepresentationID$/audio/en/init.mp4", "$RepresentationID$/audio/en/seg-$Number$.m4f", 0, 1000)
+	_, _ = audioAS.AddNewRepresentationAudio(44100, 67095, "mp4a.40.2", "audio-64k")
+	_, _ = audioAS.AddNewRepresentationAudio(44100, 128000, "mp4a.40.2", "audio-128k")
+
+	videoAS, _ := m.AddNewAdaptationSetVideoWithID("2", mpd.DASH_MIME_TYPE_VIDEO_MP4, "progressive", true, 1)
+	_, _ = videoAS.SetNewSegmentTemplate(1968, "$RepresentationID$/video/1/init.mp4", "$RepresentationID$/video/1/seg-$Number$.m4f", 0, 1000)
+	_, _ = videoAS.AddNewRepresentationVideo(1518664, "avc1.4d401f", "800", "30000/1001", 960, 540)
+	_, _ = videoAS.AddNewRepresentationVideo(1911775, "avc1.4d401f", "1000", "30000/1001", 1024, 576)
+	_, _ = videoAS.AddNewRepresentationVideo(2780732, "avc1.4d401f", "1500", "30000/1001", 1280, 720)
+
+	subtitleAS, _ := m.AddNewAdaptationSetSubtitleWithID("3", mpd.DASH_MIME_TYPE_SUBTITLE_VTT, "en", "Subtitle (En)")
+	subtitleRep, _ := subtitleAS.AddNewRepresentationSubtitle(256, "subtitle_en")
+	_ = subtitleRep.SetNewBaseURL("http://example.com/content/sintel/subtitles/subtitles_en.vtt")
+
+	return m
+}
+
+func TestApply(t *testing.T) {
+	c, err := ReadFromFile("fixtures/cpix.xml")
+	require.NoError(t, err)
+
+	m := testMPD()
+	require.NoError(t, c.Apply(m))
+
+	xmlStr, err := m.WriteToString()
+	require.NoError(t, err)
+	testfixtures.CompareFixture(t, "fixtures/apply.mpd", xmlStr)
+
+	// Applying again replaces the ContentProtections
+	require.NoError(t, c.Apply(m))
+	xmlStr, err = m.WriteToString()
+	require.NoError(t, err)
+	testfixtures.CompareFixture(t, "fixtures/apply.mpd", xmlStr)
+}
+
+func TestApplyTrackTypes(t *testing.T) {
+	c, err := ReadFromFile("fixtures/cpix.xml")
+	require.NoError(t, err)
+
+	// A rule without filters matches the audio and video tracks, but not the text ones
+	c.ContentKeyUsageRules = []*ContentKeyUsageRule{{KID: "08e36702-8f33-436c-a5dd-60ffe5571e60"}}
+	m := testMPD()
+	m.Periods[0].AdaptationSets[2].MimeType = ptrs.Strptr("application/mp4")
+	m.Periods[0].AdaptationSets[2].Codecs = ptrs.Strptr("stpp")
+	require.NoError(t, c.Apply(m))
+	require.EqualInt(t, 3, len(m.Periods[0].AdaptationSets[0].ContentProtection))
+	require.EqualInt(t, 3, len(m.Periods[0].AdaptationSets[1].ContentProtection))
+	require.EqualInt(t, 0, len(m.Periods[0].AdaptationSets[2].ContentProtection))
+
+	c.ContentKeyUsageRules[0].IntendedTrackType = ptrs.Strptr("AUDIO")
+	m = testMPD()
+	require.NoError(t, c.Apply(m))
+	require.EqualInt(t, 3, len(m.Periods[0].AdaptationSets[0].ContentProtection))
+	require.EqualInt(t, 0, len(m.Periods[0].AdaptationSets[1].ContentProtection))
+
+	c.ContentKeyUsageRules[0].IntendedTrackType = ptrs.Strptr("HD")
+	m = testMPD()
+	require.NoError(t, c.Apply(m))
+	require.EqualInt(t, 0, len(m.Periods[0].AdaptationSets[0].ContentProtection))
+	require.EqualInt(t, 3, len(m.Periods[0].AdaptationSets[1].ContentProtection))
+}
+
+func TestApplyHDR(t *testing.T) {
+	c, err := ReadFromFile("fixtures/cpix.xml")
+	require.NoError(t, err)
+	c.ContentKeyUsageRules = []*ContentKeyUsageRule{
+		{KID: "08e36702-8f33-436c-a5dd-60ffe5571e60", IntendedTrackType: ptrs.Strptr("AUDIO")},
+		{KID: "08e36702-8f33-436c-a5dd-60ffe5571e60", VideoFilters: []*VideoFilter{{HDR: ptrs.Boolptr(false)}}},
+		{KID: "5abdd52f-554a-4f2a-b8d0-61f761425155", VideoFilters: []*VideoFilter{{HDR: ptrs.Boolptr(true)}}},
+	}
+
+	// Dolby Vision is HDR without any CICP property, as for mpd.WithHDR
+	m := testMPD()
+	videoAS := m.Periods[0].AdaptationSets[1]
+	videoAS.Representations[2].Codecs = ptrs.Strptr("dvh1.05.06")
+	require.NoError(t, c.Apply(m))
+	expectedKIDs := []string{"08e36702-8f33-436c-a5dd-60ffe5571e60", "08e36702-8f33-436c-a5dd-60ffe5571e60", "5abdd52f-554a-4f2a-b8d0-61f761425155"}
+	for i, r := range videoAS.Representations {
+		if hdr := mpd.IsHDR(videoAS, r); hdr != (i == 2) {
+			t.Errorf("Representation %s HDR %v", *r.ID, hdr)
+		}
+		root := r.ContentProtection[0].(*mpd.CENCContentProtection)
+		require.EqualStringPtr(t, ptrs.Strptr(expectedKIDs[i]), root.DefaultKID)
+	}
+}
+
+func TestApplySharedKeyAtAdaptationSet(t *testing.T) {
+	c, err := ReadFromFile("fixtures/cpix.xml")
+	require.NoError(t, err)
+
+	m := testMPD()
+	require.NoError(t, c.Apply(m))
+
+	audioAS := m.Periods[0].AdaptationSets[0]
+	require.EqualInt(t, 2, len(audioAS.ContentProtection))
+	for _, r := range audioAS.Representations {
+		require.EqualInt(t, 0, len(r.ContentProtection))
+	}
+
+	videoAS := m.Periods[0].AdaptationSets[1]
+	require.EqualInt(t, 0, len(videoAS.ContentProtection))
+	expectedKIDs := []string{"08e36702-8f33-436c-a5dd-60ffe5571e60", "08e36702-8f33-436c-a5dd-60ffe5571e60", "5abdd52f-554a-4f2a-b8d0-61f761425155"}
+	for i, r := range videoAS.Representations {
+		require.EqualInt(t, 3, len(r.ContentProtection))
+		root := r.ContentProtection[0].(*mpd.CENCContentProtection)
+		require.EqualStringPtr(t, ptrs.Strptr(expectedKIDs[i]), root.DefaultKID)
+		require.EqualStringPtr(t, ptrs.Strptr("cbcs"), root.Value)
+	}
+
+	subtitleAS := m.Periods[0].AdaptationSets[2]
+	require.EqualInt(t, 0, len(subtitleAS.ContentProtection))
+	require.EqualInt(t, 0, len(subtitleAS.Representations[0].ContentProtection))
+}
+
+func TestApplyAmbiguousUsageRule(t *testing.T) {
+	c, err := ReadFromFile("fixtures/cpix.xml")
+	require.NoError(t, err)
+	c.ContentKeyUsageRules = append(c.ContentKeyUsageRules, &ContentKeyUsageRule{
+		KID:            "5abdd52f-554a-4f2a-b8d0-61f761425155",
+		BitrateFilters: []*BitrateFilter{{MaxBitrate: ptrs.Int64ptr(100000)}},
+	})
+
+	err = c.Apply(testMPD())
+	require.EqualErr(t, ErrAmbiguousUsageRule, err)
+}
+
+func TestApplyUnknownKID(t *testing.T) {
+	c, err := ReadFromFile("fixtures/cpix.xml")
+	require.NoError(t, err)
+	c.ContentKeyUsageRules[0].KID = "00000000-0000-0000-0000-000000000000"
+
+	err = c.Apply(testMPD())
+	require.EqualErr(t, ErrUnknownKID, err)
+}
+
+func TestVideoFilterMatches(t *testing.T) {
+	testCases := []struct {
+		name     string
+		filter   VideoFilter
+		track    track
+		expected bool
+	}{
+		{name: "audio track", filter: VideoFilter{}, track: track{contentType: "audio"}, expected: false},
+		{name: "no bounds", filter: VideoFilter{}, track: track{contentType: "video"}, expected: true},
+		{name: "max pixels inclusive", filter: VideoFilter{MaxPixels: ptrs.Int64ptr(921600)}, track: track{contentType: "video", pixels: 921600}, expected: true},
+		{name: "min fps exclusive", filter: VideoFilter{MinFPS: ptrs.Int64ptr(30)}, track: track{contentType: "video", fps: 30}, expected: false},
+		{name: "max fps inclusive", filter: VideoFilter{MaxFPS: ptrs.Int64ptr(30)}, track: track{contentType: "video", fps: 30}, expected: true},
+		{name: "hdr", filter: VideoFilter{HDR: ptrs.Boolptr(true)}, track: track{contentType: "video"}, expected: false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.filter.matches(&tc.track); got != tc.expected {
+				t.Errorf("Expected %v but got %v", tc.expected, got)
+			}
+		})
+	}
+}
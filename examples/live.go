@@ -29,12 +29,7 @@ func main() {
 	subtitleAS, _ := m.AddNewAdaptationSetSubtitle(mpd.DASH_MIME_TYPE_SUBTITLE_VTT, "en", "Subtitle (En)")
 	subtitleRep, _ := subtitleAS.AddNewRepresentationSubtitle(256, "subtitle_en")
 	_ = subtitleRep.SetNewBaseURL("http://example.com/content/sintel/subtitles/subtitles_en.vtt")
-	schemeIDURI := "urn:mpeg:dash:utc:direct:2014"
-	value := "2019-10-23T15:56:29Z"
-	m.UTCTiming = &mpd.DescriptorType{
-		SchemeIDURI: &schemeIDURI,
-		Value:       &value,
-	}
+	m.AddNewUTCTiming(mpd.UTC_TIMING_DIRECT_SCHEME_ID, "2019-10-23T15:56:29Z")
 
 	mpdStr, _ := m.WriteToString()
 	fmt.Println(mpdStr)
@@ -0,0 +1,95 @@
+package livesim
+
+import (
+	"errors"
+	"io/fs"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// Constants for the served paths
+const (
+	MANIFEST_PATH     = "/manifest.mpd"
+	UTC_TIMING_PATH   = "/utctiming"
+	CONTENT_TYPE_DASH = "application/dash+xml"
+)
+
+// ServeHTTP serves the dynamic MPD at MANIFEST_PATH, the time of the clock at UTC_TIMING_PATH and
+// the segments at the paths of INITIALIZATION_TEMPLATE and MEDIA_TEMPLATE relative to the MPD.
+func (s *Simulator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	now := s.Clock.Now()
+
+	switch r.URL.Path {
+	case MANIFEST_PATH:
+		utcTimingURL := s.UTCTimingURL
+		if utcTimingURL == "" {
+			utcTimingURL = requestURL(r, UTC_TIMING_PATH)
+		}
+		m := s.liveMPD(now, utcTimingURL)
+		body, err := m.WriteToString()
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		serve(w, r, CONTENT_TYPE_DASH, []byte(body))
+		return
+	case UTC_TIMING_PATH:
+		serve(w, r, "text/plain", []byte(now.UTC().Format(utcTimingLayout)))
+		return
+	}
+
+	dir, name := path.Split(strings.TrimPrefix(r.URL.Path, "/"))
+	id := strings.TrimSuffix(dir, "/")
+	t, ok := s.tracks[id]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	var b []byte
+	var err error
+	if name == path.Base(INITIALIZATION_TEMPLATE) {
+		b, err = s.Init(id)
+	} else if number, parseErr := strconv.ParseInt(strings.TrimSuffix(name, path.Ext(MEDIA_TEMPLATE)), 10, 64); parseErr == nil && strings.HasSuffix(name, path.Ext(MEDIA_TEMPLATE)) {
+		b, err = s.Segment(id, number, now)
+	} else {
+		http.NotFound(w, r)
+		return
+	}
+	switch {
+	case errors.Is(err, ErrSegmentNotAvailable), errors.Is(err, fs.ErrNotExist):
+		http.NotFound(w, r)
+	case err != nil:
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	default:
+		serve(w, r, t.mimeType, b)
+	}
+}
+
+// serve writes an uncacheable response, the manifest and the segment availability change with the
+// clock.
+func serve(w http.ResponseWriter, r *http.Request, contentType string, body []byte) {
+	header := w.Header()
+	header.Set("Content-Type", contentType)
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(http.StatusOK)
+	if r.Method != http.MethodHead {
+		_, _ = w.Write(body)
+	}
+}
+
+// requestURL returns the absolute URL of a path on the host of the request.
+func requestURL(r *http.Request, p string) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + p
+}
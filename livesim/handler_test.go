@@ -0,0 +1,67 @@
+package livesim
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/zencoder/go-dash/v3/helpers/ptrs"
+	"github.com/zencoder/go-dash/v3/helpers/require"
+	"github.com/zencoder/go-dash/v3/mpd"
+)
+
+func serveTest(h http.Handler, method, target string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(method, target, nil))
+	return w
+}
+
+func TestServeManifest(t *testing.T) {
+	s := newTestSimulator(t)
+
+	w := serveTest(s, http.MethodGet, "http://localhost:8080"+MANIFEST_PATH)
+	require.EqualInt(t, http.StatusOK, w.Code)
+	require.EqualString(t, CONTENT_TYPE_DASH, w.Header().Get("Content-Type"))
+	require.EqualString(t, "no-cache", w.Header().Get("Cache-Control"))
+	m, err := mpd.ReadFromString(w.Body.String())
+	require.NoError(t, err)
+	require.EqualStringPtr(t, Strptr("dynamic"), m.Type)
+	require.EqualStringPtr(t, Strptr("2024-01-01T00:01:40Z"), m.PublishTime)
+	require.EqualStringPtr(t, Strptr(mpd.UTC_TIMING_HTTP_ISO_SCHEME_ID), m.UTCTiming[0].SchemeIDURI)
+	require.EqualStringPtr(t, Strptr("http://localhost:8080"+UTC_TIMING_PATH), m.UTCTiming[0].Value)
+
+	w = serveTest(s, http.MethodHead, MANIFEST_PATH)
+	require.EqualInt(t, http.StatusOK, w.Code)
+	require.EqualString(t, "", w.Body.String())
+
+	w = serveTest(s, http.MethodPost, MANIFEST_PATH)
+	require.EqualInt(t, http.StatusMethodNotAllowed, w.Code)
+	require.EqualString(t, "GET, HEAD", w.Header().Get("Allow"))
+}
+
+func TestServeUTCTiming(t *testing.T) {
+	s := newTestSimulator(t)
+	w := serveTest(s, http.MethodGet, UTC_TIMING_PATH)
+	require.EqualInt(t, http.StatusOK, w.Code)
+	require.EqualString(t, "2024-01-01T00:01:40.000Z", w.Body.String())
+}
+
+func TestServeSegments(t *testing.T) {
+	s := newTestSimulator(t)
+
+	w := serveTest(s, http.MethodGet, "/800/init.mp4")
+	require.EqualInt(t, http.StatusOK, w.Code)
+	require.EqualString(t, "video/mp4", w.Header().Get("Content-Type"))
+
+	w = serveTest(s, http.MethodGet, "/audio/50.m4s")
+	require.EqualInt(t, http.StatusOK, w.Code)
+	require.EqualString(t, "audio/mp4", w.Header().Get("Content-Type"))
+	sequenceNumber, decodeTime := parseTestFragment(t, w.Body.Bytes())
+	require.EqualUInt32(t, 50, sequenceNumber)
+	require.EqualUInt64(t, 49*96000, decodeTime)
+
+	for _, target := range []string{"/800/51.m4s", "/800/1.m4s", "/800/x.m4s", "/800/50.mp4", "/missing/1.m4s", "/other"} {
+		w = serveTest(s, http.MethodGet, target)
+		require.EqualInt(t, http.StatusNotFound, w.Code, target)
+	}
+}
@@ -0,0 +1,383 @@
+// Package livesim simulates a live MPEG-DASH stream from on-demand content: a static MPD using
+// SegmentTemplate and its segments are looped forever as a wall-clock driven dynamic MPD, with
+// segments renumbered and their decode times moved to the live timeline.
+package livesim
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+	"time"
+
+	. "github.com/zencoder/go-dash/v3/helpers/ptrs"
+	"github.com/zencoder/go-dash/v3/mpd"
+	"github.com/zencoder/go-dash/v3/probe"
+)
+
+// Constants for the simulated streams
+const (
+	DEFAULT_TIME_SHIFT_BUFFER_DEPTH      = time.Minute
+	DEFAULT_MINIMUM_UPDATE_PERIOD        = 2 * time.Second
+	DEFAULT_SUGGESTED_PRESENTATION_DELAY = 6 * time.Second
+	INITIALIZATION_TEMPLATE              = "$RepresentationID$/init.mp4"
+	MEDIA_TEMPLATE                       = "$RepresentationID$/$Number$.m4s"
+	LIVE_PERIOD_ID                       = "p0"
+	dateTimeLayout                       = "2006-01-02T15:04:05Z"
+	utcTimingLayout                      = "2006-01-02T15:04:05.000Z"
+)
+
+// Known error variables
+var (
+	ErrSinglePeriod           = errors.New("On-demand MPD should have a single Period")
+	ErrDurationUnknown        = errors.New("On-demand MPD has no mediaPresentationDuration or Period duration")
+	ErrRepresentationID       = errors.New("Representation ID should be a unique, non empty path element")
+	ErrNoInitialization       = errors.New("Representation has no initialization segment")
+	ErrNoMediaSegments        = errors.New("Representation has no media segments")
+	ErrRepresentationNotFound = errors.New("Representation not found")
+	ErrSegmentNotAvailable    = errors.New("Segment is not available")
+)
+
+// Clock returns the wall-clock time of the simulated stream.
+type Clock interface {
+	Now() time.Time
+}
+
+// ClockFunc adapts a function to the Clock interface.
+type ClockFunc func() time.Time
+
+func (f ClockFunc) Now() time.Time {
+	return f()
+}
+
+// SystemClock is the system wall-clock.
+var SystemClock Clock = ClockFunc(time.Now)
+
+// Simulator loops on-demand content as a live stream. The content of every Representation loops
+// on its own duration, so tracks with slightly different durations drift apart by that
+// difference on each loop.
+type Simulator struct {
+	Clock                      Clock
+	AvailabilityStartTime      time.Time // Start of the live timeline, the first loop starts there
+	TimeShiftBufferDepth       time.Duration
+	MinimumUpdatePeriod        time.Duration
+	SuggestedPresentationDelay time.Duration
+	// URL of the http-iso UTCTiming source. When empty, ServeHTTP signals UTC_TIMING_PATH on the
+	// host of the request, and MPD signals the time directly.
+	UTCTimingURL string
+
+	vod    *mpd.MPD
+	fsys   fs.FS
+	tracks map[string]*track
+}
+
+// track is the on-demand content of a Representation.
+type track struct {
+	mimeType  string
+	timescale uint64
+	init      string // Init segment path in the file system
+	segments  []vodSegment
+	loop      uint64 // Duration of the content in timescale units
+}
+
+type vodSegment struct {
+	path       string // Path in the file system
+	decodeTime uint64 // Media time of the segment, as in its tfdt
+	start      uint64 // Presentation time from the start of the content
+	duration   uint64
+}
+
+// Creates a new Simulator starting the live timeline at the Unix epoch, with the default
+// durations and the system clock.
+// vod - static MPD with a single Period addressing its segments with SegmentTemplate.
+// fsys - file system holding the segments, with paths relative to the MPD.
+func New(vod *mpd.MPD, fsys fs.FS) (*Simulator, error) {
+	if len(vod.Periods) != 1 || vod.Periods[0] == nil {
+		return nil, ErrSinglePeriod
+	}
+	period := vod.Periods[0]
+	periodDuration := time.Duration(period.Duration)
+	if vod.MediaPresentationDuration != nil && *vod.MediaPresentationDuration != "" {
+		d, err := mpd.ParseDuration(*vod.MediaPresentationDuration)
+		if err != nil {
+			return nil, err
+		}
+		periodDuration = d
+	}
+	if periodDuration <= 0 {
+		return nil, ErrDurationUnknown
+	}
+
+	s := &Simulator{
+		Clock:                      SystemClock,
+		AvailabilityStartTime:      time.Unix(0, 0).UTC(),
+		TimeShiftBufferDepth:       DEFAULT_TIME_SHIFT_BUFFER_DEPTH,
+		MinimumUpdatePeriod:        DEFAULT_MINIMUM_UPDATE_PERIOD,
+		SuggestedPresentationDelay: DEFAULT_SUGGESTED_PRESENTATION_DELAY,
+		vod:                        vod,
+		fsys:                       fsys,
+		tracks:                     map[string]*track{},
+	}
+	for _, as := range period.AdaptationSets {
+		if as == nil {
+			continue
+		}
+		for _, r := range as.Representations {
+			if r == nil {
+				continue
+			}
+			id := strOrEmpty(r.ID)
+			if id == "" || strings.Contains(id, "/") || id == "." || id == ".." || s.tracks[id] != nil {
+				return nil, fmt.Errorf("%w: %q", ErrRepresentationID, id)
+			}
+			t, err := newTrack(vod, period, as, r, periodDuration)
+			if err != nil {
+				return nil, fmt.Errorf("Representation %s: %w", id, err)
+			}
+			s.tracks[id] = t
+		}
+	}
+	return s, nil
+}
+
+func newTrack(vod *mpd.MPD, period *mpd.Period, as *mpd.AdaptationSet, r *mpd.Representation, periodDuration time.Duration) (*track, error) {
+	linked := *r
+	linked.AdaptationSet = as
+	rs, err := linked.Segments(periodDuration)
+	if err != nil {
+		return nil, err
+	}
+	if rs.Initialization == "" {
+		return nil, ErrNoInitialization
+	}
+	if len(rs.Media) == 0 {
+		return nil, ErrNoMediaSegments
+	}
+
+	dir := baseDir(vod.BaseURL, period.BaseURL, as.BaseURL, r.BaseURL)
+	t := &track{
+		mimeType:  "video/mp4",
+		timescale: rs.Timescale,
+		init:      path.Join(dir, rs.Initialization),
+	}
+	if r.MimeType != nil {
+		t.mimeType = *r.MimeType
+	} else if as.MimeType != nil {
+		t.mimeType = *as.MimeType
+	}
+	first := rs.Media[0].Time
+	for _, seg := range rs.Media {
+		t.segments = append(t.segments, vodSegment{
+			path:       path.Join(dir, seg.URL),
+			decodeTime: seg.Time,
+			start:      seg.Time - first,
+			duration:   seg.Duration,
+		})
+	}
+	last := t.segments[len(t.segments)-1]
+	t.loop = last.start + last.duration
+	if t.loop == 0 {
+		return nil, ErrNoMediaSegments
+	}
+	return t, nil
+}
+
+// baseDir joins the first relative BaseURL of each level, absolute ones are ignored.
+func baseDir(levels ...[]string) string {
+	dir := "."
+	for _, baseURLs := range levels {
+		if len(baseURLs) == 0 || strings.Contains(baseURLs[0], "://") || strings.HasPrefix(baseURLs[0], "/") {
+			continue
+		}
+		dir = path.Join(dir, baseURLs[0])
+	}
+	return dir
+}
+
+// segment returns the start and duration of live segment n, counting from 0 at the
+// AvailabilityStartTime, in timescale units.
+func (t *track) segment(n uint64) (vodSegment, uint64) {
+	count := uint64(len(t.segments))
+	seg := t.segments[n%count]
+	return seg, n/count*t.loop + seg.start
+}
+
+// firstEndingAfter returns the first live segment ending after ticks.
+func (t *track) firstEndingAfter(ticks uint64) uint64 {
+	n := ticks / t.loop * uint64(len(t.segments))
+	for {
+		seg, start := t.segment(n)
+		if start+seg.duration > ticks {
+			return n
+		}
+		n++
+	}
+}
+
+// window returns the live segments available at now, from first to last inclusive. ok is false
+// when no segment is available yet.
+func (s *Simulator) window(t *track, now time.Time) (first, last uint64, ok bool) {
+	elapsed := now.Sub(s.AvailabilityStartTime)
+	if elapsed <= 0 {
+		return 0, 0, false
+	}
+	edge := ticks(elapsed, t.timescale)
+	end := t.firstEndingAfter(edge)
+	if end == 0 {
+		return 0, 0, false
+	}
+	if s.TimeShiftBufferDepth > 0 && elapsed > s.TimeShiftBufferDepth {
+		first = t.firstEndingAfter(ticks(elapsed-s.TimeShiftBufferDepth, t.timescale))
+	}
+	if first >= end {
+		first = end - 1
+	}
+	return first, end - 1, true
+}
+
+// ticks converts a duration to timescale units without overflowing for durations since the
+// Unix epoch.
+func ticks(d time.Duration, timescale uint64) uint64 {
+	return uint64(d/time.Second)*timescale + uint64(d%time.Second)*timescale/uint64(time.Second)
+}
+
+// MPD returns the dynamic MPD at now. The on-demand MPD is copied with its BaseURLs removed and
+// the segment addressing of every Representation replaced by INITIALIZATION_TEMPLATE and
+// MEDIA_TEMPLATE with a SegmentTimeline of the segments in the time shift buffer.
+func (s *Simulator) MPD(now time.Time) *mpd.MPD {
+	return s.liveMPD(now, s.UTCTimingURL)
+}
+
+// liveMPD returns the dynamic MPD at now, with an http-iso UTCTiming on utcTimingURL, or a direct
+// one when it is empty.
+func (s *Simulator) liveMPD(now time.Time, utcTimingURL string) *mpd.MPD {
+	live := s.vod.Clone()
+	live.Type = Strptr("dynamic")
+	if live.Profiles != nil && *live.Profiles == string(mpd.DASH_PROFILE_ONDEMAND) {
+		live.Profiles = Strptr(string(mpd.DASH_PROFILE_LIVE))
+	}
+	live.MediaPresentationDuration = nil
+	live.AvailabilityStartTime = Strptr(s.AvailabilityStartTime.UTC().Format(dateTimeLayout))
+	live.PublishTime = Strptr(now.UTC().Format(dateTimeLayout))
+	live.MinimumUpdatePeriod = Strptr(durationString(s.MinimumUpdatePeriod))
+	live.TimeShiftBufferDepth = nil
+	if s.TimeShiftBufferDepth > 0 {
+		live.TimeShiftBufferDepth = Strptr(durationString(s.TimeShiftBufferDepth))
+	}
+	live.SuggestedPresentationDelay = nil
+	if s.SuggestedPresentationDelay > 0 {
+		spd := mpd.Duration(s.SuggestedPresentationDelay)
+		live.SuggestedPresentationDelay = &spd
+	}
+	live.BaseURL = nil
+	live.UTCTiming = nil
+	if utcTimingURL != "" {
+		live.AddNewUTCTiming(mpd.UTC_TIMING_HTTP_ISO_SCHEME_ID, utcTimingURL)
+	} else {
+		live.AddNewUTCTiming(mpd.UTC_TIMING_DIRECT_SCHEME_ID, now.UTC().Format(utcTimingLayout))
+	}
+
+	period := live.Periods[0]
+	if period.ID == "" {
+		period.ID = LIVE_PERIOD_ID
+	}
+	start := mpd.Duration(0)
+	period.Start = &start
+	period.Duration = 0
+	period.BaseURL = nil
+	period.SegmentBase, period.SegmentList, period.SegmentTemplate = nil, nil, nil
+	for _, as := range period.AdaptationSets {
+		if as == nil {
+			continue
+		}
+		as.BaseURL = nil
+		as.SegmentBase, as.SegmentList, as.SegmentTemplate = nil, nil, nil
+		for _, r := range as.Representations {
+			if r == nil {
+				continue
+			}
+			r.BaseURL = nil
+			r.SegmentBase, r.SegmentList = nil, nil
+			r.SegmentTemplate = s.segmentTemplate(s.tracks[strOrEmpty(r.ID)], now)
+		}
+	}
+	return live
+}
+
+func (s *Simulator) segmentTemplate(t *track, now time.Time) *mpd.SegmentTemplate {
+	st := &mpd.SegmentTemplate{
+		Initialization:  Strptr(INITIALIZATION_TEMPLATE),
+		Media:           Strptr(MEDIA_TEMPLATE),
+		Timescale:       Int64ptr(int64(t.timescale)),
+		StartNumber:     Int64ptr(1),
+		SegmentTimeline: &mpd.SegmentTimeline{},
+	}
+	first, last, ok := s.window(t, now)
+	if !ok {
+		return st
+	}
+	st.StartNumber = Int64ptr(int64(first) + 1)
+	var current *mpd.SegmentTimelineSegment
+	for n := first; n <= last; n++ {
+		seg, start := t.segment(n)
+		if current != nil && current.Duration == seg.duration {
+			current.RepeatCount = Intptr(*current.RepeatCount + 1)
+			continue
+		}
+		current = &mpd.SegmentTimelineSegment{Duration: seg.duration, RepeatCount: Intptr(0)}
+		if n == first {
+			current.StartTime = Uint64ptr(start)
+		}
+		st.SegmentTimeline.Segments = append(st.SegmentTimeline.Segments, current)
+	}
+	for _, seg := range st.SegmentTimeline.Segments {
+		if *seg.RepeatCount == 0 {
+			seg.RepeatCount = nil
+		}
+	}
+	return st
+}
+
+// Init returns the initialization segment of a Representation.
+func (s *Simulator) Init(representationID string) ([]byte, error) {
+	t, ok := s.tracks[representationID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrRepresentationNotFound, representationID)
+	}
+	return fs.ReadFile(s.fsys, t.init)
+}
+
+// Segment returns live media segment number of a Representation, as addressed by the MPD at now,
+// with its mfhd sequence number set to the segment number and its tfdt decode time moved to the
+// live timeline. Segments outside the time shift buffer at now are not available.
+// number - segment number, starting at 1 at the AvailabilityStartTime.
+func (s *Simulator) Segment(representationID string, number int64, now time.Time) ([]byte, error) {
+	t, ok := s.tracks[representationID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrRepresentationNotFound, representationID)
+	}
+	first, last, ok := s.window(t, now)
+	n := uint64(number - 1)
+	if !ok || number < 1 || n < first || n > last {
+		return nil, fmt.Errorf("%w: %s/%d", ErrSegmentNotAvailable, representationID, number)
+	}
+	seg, start := t.segment(n)
+	b, err := fs.ReadFile(s.fsys, seg.path)
+	if err != nil {
+		return nil, err
+	}
+	return probe.RetimeFragment(b, uint32(number), int64(start)-int64(seg.decodeTime), uint32(t.timescale))
+}
+
+func durationString(d time.Duration) string {
+	md := mpd.Duration(d)
+	return md.String()
+}
+
+func strOrEmpty(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
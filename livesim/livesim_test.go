@@ -0,0 +1,246 @@
+package livesim
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	. "github.com/zencoder/go-dash/v3/helpers/ptrs"
+	"github.com/zencoder/go-dash/v3/helpers/require"
+	"github.com/zencoder/go-dash/v3/helpers/testfixtures"
+	"github.com/zencoder/go-dash/v3/mpd"
+	"github.com/zencoder/go-dash/v3/probe"
+)
+
+var testAvailabilityStartTime = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// newTestSimulator loops 6 seconds of 2 second video and audio segments.
+func newTestSimulator(t *testing.T) *Simulator {
+	vod := mpd.NewMPD(mpd.DASH_PROFILE_LIVE, "PT6S", "PT2S")
+	video, err := vod.AddNewAdaptationSetVideo("video/mp4", "progressive", true, 1)
+	require.NoError(t, err)
+	_, err = video.SetNewSegmentTemplate(180000, "$RepresentationID$/init.mp4", "$RepresentationID$/$Number$.m4s", 1, 90000)
+	require.NoError(t, err)
+	_, err = video.AddNewRepresentationVideo(1518664, "avc1.4d401f", "800", "30", 1280, 720)
+	require.NoError(t, err)
+	audio, err := vod.AddNewAdaptationSetAudio("audio/mp4", true, 1, "en")
+	require.NoError(t, err)
+	_, err = audio.SetNewSegmentTemplate(96000, "$RepresentationID$/init.mp4", "$RepresentationID$/$Number$.m4s", 1, 48000)
+	require.NoError(t, err)
+	_, err = audio.AddNewRepresentationAudio(48000, 128000, "mp4a.40.2", "audio")
+	require.NoError(t, err)
+
+	fsys := fstest.MapFS{
+		"800/init.mp4":   {Data: mkbox("ftyp", []byte("iso6"))},
+		"audio/init.mp4": {Data: mkbox("ftyp", []byte("iso6"))},
+	}
+	for i := 0; i < 3; i++ {
+		fsys[fmt.Sprintf("800/%d.m4s", i+1)] = &fstest.MapFile{Data: testFragment(uint32(i+1), uint64(i)*180000)}
+		fsys[fmt.Sprintf("audio/%d.m4s", i+1)] = &fstest.MapFile{Data: testFragment(uint32(i+1), uint64(i)*96000)}
+	}
+
+	s, err := New(vod, fsys)
+	require.NoError(t, err)
+	s.AvailabilityStartTime = testAvailabilityStartTime
+	s.Clock = ClockFunc(func() time.Time { return testAvailabilityStartTime.Add(100 * time.Second) })
+	return s
+}
+
+func TestMPD(t *testing.T) {
+	s := newTestSimulator(t)
+	s.TimeShiftBufferDepth = 10 * time.Second
+	m := s.MPD(testAvailabilityStartTime.Add(100*time.Second + 500*time.Millisecond))
+	xml, err := m.WriteToString()
+	require.NoError(t, err)
+	testfixtures.CompareFixture(t, "fixtures/live.mpd", xml)
+}
+
+func TestMPDWindow(t *testing.T) {
+	s := newTestSimulator(t)
+
+	// Nothing is available before the end of the first segment
+	st := s.MPD(testAvailabilityStartTime.Add(time.Second)).Periods[0].AdaptationSets[0].Representations[0].SegmentTemplate
+	require.EqualInt(t, 0, len(st.SegmentTimeline.Segments))
+
+	// The first loop
+	st = s.MPD(testAvailabilityStartTime.Add(7 * time.Second)).Periods[0].AdaptationSets[0].Representations[0].SegmentTemplate
+	require.EqualInt(t, 1, int(*st.StartNumber))
+	require.EqualInt(t, 1, len(st.SegmentTimeline.Segments))
+	require.EqualUInt64Ptr(t, Uint64ptr(0), st.SegmentTimeline.Segments[0].StartTime)
+	require.EqualUInt64(t, 180000, st.SegmentTimeline.Segments[0].Duration)
+	require.EqualIntPtr(t, Intptr(2), st.SegmentTimeline.Segments[0].RepeatCount)
+
+	// The time shift buffer slides over the loops
+	st = s.MPD(testAvailabilityStartTime.Add(time.Hour)).Periods[0].AdaptationSets[0].Representations[0].SegmentTemplate
+	require.EqualInt(t, 1771, int(*st.StartNumber))
+	require.EqualUInt64Ptr(t, Uint64ptr(1770*180000), st.SegmentTimeline.Segments[0].StartTime)
+	require.EqualIntPtr(t, Intptr(29), st.SegmentTimeline.Segments[0].RepeatCount)
+}
+
+func TestMPDDirectUTCTiming(t *testing.T) {
+	s := newTestSimulator(t)
+	m := s.MPD(testAvailabilityStartTime.Add(1500 * time.Millisecond))
+	require.EqualStringPtr(t, Strptr(mpd.UTC_TIMING_DIRECT_SCHEME_ID), m.UTCTiming[0].SchemeIDURI)
+	require.EqualStringPtr(t, Strptr("2024-01-01T00:00:01.500Z"), m.UTCTiming[0].Value)
+
+	s.UTCTimingURL = "https://time.example.com/iso"
+	m = s.MPD(testAvailabilityStartTime)
+	require.EqualStringPtr(t, Strptr(mpd.UTC_TIMING_HTTP_ISO_SCHEME_ID), m.UTCTiming[0].SchemeIDURI)
+	require.EqualStringPtr(t, Strptr("https://time.example.com/iso"), m.UTCTiming[0].Value)
+}
+
+func TestMPDDoesNotModifyVOD(t *testing.T) {
+	s := newTestSimulator(t)
+	s.MPD(testAvailabilityStartTime.Add(time.Minute))
+	require.EqualStringPtr(t, Strptr("static"), s.vod.Type)
+	require.NotNil(t, s.vod.Periods[0].AdaptationSets[0].SegmentTemplate)
+}
+
+func TestSegment(t *testing.T) {
+	s := newTestSimulator(t)
+	now := testAvailabilityStartTime.Add(100 * time.Second)
+
+	// Segment 50 is the second segment of the 17th loop
+	b, err := s.Segment("800", 50, now)
+	require.NoError(t, err)
+	sequenceNumber, decodeTime := parseTestFragment(t, b)
+	require.EqualUInt32(t, 50, sequenceNumber)
+	require.EqualUInt64(t, 49*180000, decodeTime)
+
+	b, err = s.Segment("audio", 49, now)
+	require.NoError(t, err)
+	sequenceNumber, decodeTime = parseTestFragment(t, b)
+	require.EqualUInt32(t, 49, sequenceNumber)
+	require.EqualUInt64(t, 48*96000, decodeTime)
+
+	b, err = s.Init("800")
+	require.NoError(t, err)
+	require.EqualString(t, string(mkbox("ftyp", []byte("iso6"))), string(b))
+}
+
+func TestSegmentSidx(t *testing.T) {
+	vod := mpd.NewMPD(mpd.DASH_PROFILE_LIVE, "PT6S", "PT2S")
+	video, err := vod.AddNewAdaptationSetVideo("video/mp4", "progressive", true, 1)
+	require.NoError(t, err)
+	_, err = video.SetNewSegmentTemplate(180000, "$RepresentationID$/init.mp4", "$RepresentationID$/$Number$.m4s", 1, 90000)
+	require.NoError(t, err)
+	_, err = video.AddNewRepresentationVideo(1518664, "avc1.4d401f", "800", "30", 1280, 720)
+	require.NoError(t, err)
+
+	fsys := fstest.MapFS{"800/init.mp4": {Data: mkbox("ftyp", []byte("iso6"))}}
+	for i := 0; i < 3; i++ {
+		fragment := testFragment(uint32(i+1), uint64(i)*180000)
+		sidx := fullbox(0, "sidx", concat(u32(1), u32(90000), u32(uint32(i)*180000), u32(0),
+			u32(1), u32(uint32(len(fragment))), u32(180000), u32(0x90000000)))
+		fsys[fmt.Sprintf("800/%d.m4s", i+1)] = &fstest.MapFile{Data: concat(sidx, fragment)}
+	}
+	s, err := New(vod, fsys)
+	require.NoError(t, err)
+	s.AvailabilityStartTime = testAvailabilityStartTime
+	now := testAvailabilityStartTime.Add(100 * time.Second)
+
+	b, err := s.Segment("800", 50, now)
+	require.NoError(t, err)
+	idx, err := probe.ReadSegmentIndex(bytes.NewReader(concat(mkbox("moov"), b)))
+	require.NoError(t, err)
+	require.EqualUInt64(t, 49*180000, idx.EarliestPresentationTime)
+	require.EqualInt(t, 1, len(idx.References))
+	// The referenced size covers the moof grown by the version 1 tfdt
+	require.EqualInt(t, 8+len(b)-1, int(idx.References[0].End))
+
+	sequenceNumber, decodeTime := parseTestFragment(t, b[idx.IndexEnd-8+1:])
+	require.EqualUInt32(t, 50, sequenceNumber)
+	require.EqualUInt64(t, 49*180000, decodeTime)
+}
+
+func TestSegmentNotAvailable(t *testing.T) {
+	s := newTestSimulator(t)
+	now := testAvailabilityStartTime.Add(100 * time.Second)
+
+	_, err := s.Segment("800", 51, now)
+	require.EqualError(t, err, "Segment is not available: 800/51")
+	_, err = s.Segment("800", 20, now)
+	require.EqualError(t, err, "Segment is not available: 800/20")
+	_, err = s.Segment("800", 0, now)
+	require.EqualError(t, err, "Segment is not available: 800/0")
+	_, err = s.Segment("800", 1, testAvailabilityStartTime.Add(-time.Second))
+	require.EqualError(t, err, "Segment is not available: 800/1")
+	_, err = s.Segment("missing", 50, now)
+	require.EqualError(t, err, `Representation not found: "missing"`)
+	_, err = s.Init("missing")
+	require.EqualError(t, err, `Representation not found: "missing"`)
+}
+
+func TestNewErrors(t *testing.T) {
+	m := mpd.NewMPD(mpd.DASH_PROFILE_LIVE, "PT6S", "PT2S")
+	m.Periods = append(m.Periods, &mpd.Period{})
+	_, err := New(m, fstest.MapFS{})
+	require.EqualErr(t, ErrSinglePeriod, err)
+
+	m = mpd.NewMPD(mpd.DASH_PROFILE_LIVE, "", "PT2S")
+	_, err = New(m, fstest.MapFS{})
+	require.EqualErr(t, ErrDurationUnknown, err)
+
+	m = mpd.NewMPD(mpd.DASH_PROFILE_LIVE, "PT6S", "PT2S")
+	as, err := m.AddNewAdaptationSetVideo("video/mp4", "progressive", true, 1)
+	require.NoError(t, err)
+	_, err = as.SetNewSegmentTemplate(180000, "$RepresentationID$/init.mp4", "$RepresentationID$/$Number$.m4s", 1, 90000)
+	require.NoError(t, err)
+	_, err = as.AddNewRepresentationVideo(1518664, "avc1.4d401f", "a/b", "30", 1280, 720)
+	require.NoError(t, err)
+	_, err = New(m, fstest.MapFS{})
+	require.EqualError(t, err, `Representation ID should be a unique, non empty path element: "a/b"`)
+}
+
+// testFragment builds a moof + mdat with a version 0 tfdt, as written by many packagers.
+func testFragment(sequenceNumber uint32, decodeTime uint64) []byte {
+	return append(
+		mkbox("moof",
+			fullbox(0, "mfhd", u32(sequenceNumber)),
+			mkbox("traf",
+				fullbox(0x020000, "tfhd", u32(1)),
+				fullbox(0, "tfdt", u32(uint32(decodeTime))),
+			),
+		),
+		mkbox("mdat", make([]byte, 8))...,
+	)
+}
+
+// parseTestFragment returns the mfhd sequence number and the version 1 tfdt decode time of a
+// fragment built by testFragment.
+func parseTestFragment(t *testing.T, b []byte) (uint32, uint64) {
+	// moof header, mfhd header and version
+	sequenceNumber := binary.BigEndian.Uint32(b[8+12 : 8+16])
+	// mfhd, traf header, tfhd
+	tfdt := b[8+16+8+16:]
+	require.EqualString(t, "tfdt", string(tfdt[4:8]))
+	require.EqualInt(t, 1, int(tfdt[8]))
+	return sequenceNumber, binary.BigEndian.Uint64(tfdt[12:20])
+}
+
+func mkbox(typ string, payloads ...[]byte) []byte {
+	var payload []byte
+	for _, p := range payloads {
+		payload = append(payload, p...)
+	}
+	return append(append(u32(uint32(8+len(payload))), typ...), payload...)
+}
+
+func concat(parts ...[]byte) []byte {
+	var b []byte
+	for _, p := range parts {
+		b = append(b, p...)
+	}
+	return b
+}
+
+func fullbox(flags uint32, typ string, payload []byte) []byte {
+	return mkbox(typ, u32(flags), payload)
+}
+
+func u32(v uint32) []byte {
+	return binary.BigEndian.AppendUint32(nil, v)
+}
@@ -0,0 +1,51 @@
+package liveupdate
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/zencoder/go-dash/v3/mpd"
+)
+
+// Fetcher loads the manifest at a URL.
+type Fetcher interface {
+	Fetch(ctx context.Context, url string) (*mpd.MPD, error)
+}
+
+// FetcherFunc adapts a function to the Fetcher interface.
+type FetcherFunc func(ctx context.Context, url string) (*mpd.MPD, error)
+
+func (f FetcherFunc) Fetch(ctx context.Context, url string) (*mpd.MPD, error) {
+	return f(ctx, url)
+}
+
+// HTTPFetcher loads manifests over HTTP.
+type HTTPFetcher struct {
+	Client *http.Client // http.DefaultClient when nil
+}
+
+// Creates a new HTTPFetcher with the default HTTP client.
+func NewHTTPFetcher() *HTTPFetcher {
+	return &HTTPFetcher{}
+}
+
+func (f *HTTPFetcher) Fetch(ctx context.Context, url string) (*mpd.MPD, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	client := f.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", url, resp.Status)
+	}
+	return mpd.Read(resp.Body)
+}
@@ -0,0 +1,25 @@
+package liveupdate
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/zencoder/go-dash/v3/helpers/require"
+)
+
+func TestHTTPFetcher(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.Handle("/live/", http.StripPrefix("/live/", http.FileServer(http.Dir("../mpd/fixtures"))))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	f := NewHTTPFetcher()
+	m, err := f.Fetch(context.Background(), server.URL+"/live/live_profile_dynamic.mpd")
+	require.NoError(t, err)
+	require.EqualString(t, "dynamic", *m.Type)
+
+	_, err = f.Fetch(context.Background(), server.URL+"/missing.mpd")
+	require.EqualError(t, err, server.URL+"/missing.mpd: 404 Not Found")
+}
@@ -0,0 +1,197 @@
+// Package liveupdate keeps a live MPD up to date, polling it at its minimumUpdatePeriod and
+// reporting the Periods, segments and events that appear as typed updates.
+package liveupdate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zencoder/go-dash/v3/mpd"
+)
+
+// Constants for the polling intervals
+const (
+	DEFAULT_UPDATE_PERIOD    = 2 * time.Second
+	DEFAULT_MINIMUM_INTERVAL = 500 * time.Millisecond
+)
+
+// Known error variables
+var (
+	ErrStaleManifest = errors.New("Manifest publishTime is older than the last one")
+	ErrInvalidMPD    = errors.New("Invalid MPD")
+)
+
+// Refresher polls a live manifest. Its methods can be called from any goroutine, one refresh
+// running at a time.
+type Refresher struct {
+	Fetcher Fetcher
+	// Polling interval when the manifest has no minimumUpdatePeriod, and after failed refreshes
+	// before any manifest was fetched
+	DefaultUpdatePeriod time.Duration
+	// Floor of the polling interval, guarding against a minimumUpdatePeriod of zero
+	MinimumInterval time.Duration
+
+	refresh     sync.Mutex // Serializes refreshes
+	mu          sync.Mutex // Guards the fields below
+	url         string
+	current     *mpd.MPD
+	publishTime time.Time
+	known       *state
+	ended       bool
+}
+
+// Creates a new Refresher with the default polling intervals.
+// manifestURL - URL of the live manifest (i.e. https://cdn.example.com/live/manifest.mpd).
+// fetcher - loader of the manifest (i.e. NewHTTPFetcher()).
+func NewRefresher(manifestURL string, fetcher Fetcher) *Refresher {
+	return &Refresher{
+		Fetcher:             fetcher,
+		DefaultUpdatePeriod: DEFAULT_UPDATE_PERIOD,
+		MinimumInterval:     DEFAULT_MINIMUM_INTERVAL,
+		url:                 manifestURL,
+		known:               newState(),
+	}
+}
+
+// URL returns the manifest URL, as moved by Location elements.
+func (r *Refresher) URL() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.url
+}
+
+// MPD returns the last manifest fetched, nil before the first refresh succeeds.
+func (r *Refresher) MPD() *mpd.MPD {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.current
+}
+
+// Ended returns whether the manifest became static.
+func (r *Refresher) Ended() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.ended
+}
+
+// UpdatePeriod returns the interval until the next refresh: the minimumUpdatePeriod of the last
+// manifest, or DefaultUpdatePeriod without one, no shorter than MinimumInterval.
+func (r *Refresher) UpdatePeriod() time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	period := r.DefaultUpdatePeriod
+	if r.current != nil && r.current.MinimumUpdatePeriod != nil {
+		if d, err := mpd.ParseDuration(*r.current.MinimumUpdatePeriod); err == nil {
+			period = d
+		}
+	}
+	return max(period, r.MinimumInterval)
+}
+
+// Refresh fetches the manifest once and returns its changes from the last one. The first refresh
+// reports every Period, segment and event. A manifest published before the last one, as served by a
+// stale cache, is ignored with ErrStaleManifest, and one published at the same time has no changes.
+func (r *Refresher) Refresh(ctx context.Context) ([]Update, error) {
+	r.refresh.Lock()
+	defer r.refresh.Unlock()
+
+	manifestURL := r.URL()
+	m, err := r.Fetcher.Fetch(ctx, manifestURL)
+	if err != nil {
+		return nil, err
+	}
+	if m == nil {
+		return nil, fmt.Errorf("%w: no manifest from %q", ErrInvalidMPD, manifestURL)
+	}
+	var publishTime time.Time
+	if m.PublishTime != nil {
+		publishTime, err = time.Parse(time.RFC3339Nano, *m.PublishTime)
+		if err != nil {
+			return nil, fmt.Errorf("%w: publishTime %q", ErrInvalidMPD, *m.PublishTime)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !publishTime.IsZero() && !r.publishTime.IsZero() {
+		switch {
+		case publishTime.Before(r.publishTime):
+			return nil, fmt.Errorf("%w: %s before %s", ErrStaleManifest, *m.PublishTime,
+				r.publishTime.Format(time.RFC3339Nano))
+		case publishTime.Equal(r.publishTime):
+			return nil, nil
+		}
+	}
+
+	updates := []Update{{Type: UPDATE_MANIFEST, MPD: m}}
+	changes, known := r.known.update(m)
+	updates = append(updates, changes...)
+	if location := strings.TrimSpace(m.Location); location != "" {
+		next, err := resolveURL(manifestURL, location)
+		if err != nil {
+			return nil, fmt.Errorf("%w: Location %q", ErrInvalidMPD, location)
+		}
+		if next != r.url {
+			r.url = next
+			updates = append(updates, Update{Type: UPDATE_LOCATION, MPD: m, Location: next})
+		}
+	}
+	if m.Type == nil || *m.Type != "dynamic" {
+		r.ended = true
+		updates = append(updates, Update{Type: UPDATE_STATIC, MPD: m})
+	}
+	r.current, r.publishTime, r.known = m, publishTime, known
+	return updates, nil
+}
+
+// Run refreshes the manifest at its update period and sends the updates, failures included as
+// UPDATE_ERROR ones, until the manifest becomes static or the context is done. It returns nil when
+// the stream ended and the context error otherwise. Run is meant to be started as a goroutine; it
+// doesn't close the channel.
+func (r *Refresher) Run(ctx context.Context, updates chan<- Update) error {
+	for {
+		changes, err := r.Refresh(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			changes = []Update{{Type: UPDATE_ERROR, MPD: r.MPD(), Err: err}}
+		}
+		for _, u := range changes {
+			select {
+			case updates <- u:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		if r.Ended() {
+			return nil
+		}
+
+		timer := time.NewTimer(r.UpdatePeriod())
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// resolveURL resolves a Location against the URL of the manifest holding it.
+func resolveURL(base, location string) (string, error) {
+	ref, err := url.Parse(location)
+	if err != nil {
+		return "", err
+	}
+	u, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+	return u.ResolveReference(ref).String(), nil
+}
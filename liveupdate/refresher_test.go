@@ -0,0 +1,203 @@
+package liveupdate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/zencoder/go-dash/v3/helpers/ptrs"
+	"github.com/zencoder/go-dash/v3/helpers/require"
+	"github.com/zencoder/go-dash/v3/mpd"
+)
+
+const TEST_URL = "https://cdn.example.com/live/channel1/manifest.mpd"
+
+// newTestMPD returns a live manifest published at second publishTime, whose video Representation
+// lists the 2 second segments first to last.
+func newTestMPD(t *testing.T, publishTime, first, last int) *mpd.MPD {
+	m := mpd.NewDynamicMPD(mpd.DASH_PROFILE_LIVE, "2024-01-01T00:00:00Z", "PT2S",
+		mpd.AttrMinimumUpdatePeriod("PT2S"),
+		mpd.AttrPublishTime(time.Date(2024, 1, 1, 0, 0, publishTime, 0, time.UTC).Format(time.RFC3339)))
+	p := m.GetCurrentPeriod()
+	p.ID = "p0"
+	as, err := m.AddNewAdaptationSetVideo("video/mp4", "progressive", true, 1)
+	require.NoError(t, err)
+	as.ID = Strptr("1")
+	st, err := as.SetNewSegmentTemplate(2, "$RepresentationID$/init.mp4", "$RepresentationID$/$Number$.m4s", int64(first), 1)
+	require.NoError(t, err)
+	st.Duration = nil
+	st.SegmentTimeline = &mpd.SegmentTimeline{Segments: []*mpd.SegmentTimelineSegment{
+		{StartTime: Uint64ptr(uint64(first) * 2), Duration: 2, RepeatCount: Intptr(last - first)},
+	}}
+	_, err = as.AddNewRepresentationVideo(1518664, "avc1.4d401f", "800", "30", 1280, 720)
+	require.NoError(t, err)
+	return m
+}
+
+// describe summarizes updates (i.e. [manifest period:p0 segments:800:0-2]).
+func describe(updates []Update) string {
+	var s []string
+	for _, u := range updates {
+		switch u.Type {
+		case UPDATE_PERIOD:
+			s = append(s, "period:"+u.Period.ID)
+		case UPDATE_SEGMENTS:
+			s = append(s, fmt.Sprintf("segments:%s:%d-%d", *u.Representation.ID,
+				u.Segments[0].Number, u.Segments[len(u.Segments)-1].Number))
+		case UPDATE_EVENT:
+			s = append(s, "event:"+*u.Event.ID)
+		case UPDATE_LOCATION:
+			s = append(s, "location:"+u.Location)
+		case UPDATE_ERROR:
+			s = append(s, "error:"+u.Err.Error())
+		default:
+			s = append(s, string(u.Type))
+		}
+	}
+	return "[" + strings.Join(s, " ") + "]"
+}
+
+// scriptedFetcher returns the manifests in order, recording the URLs fetched.
+func scriptedFetcher(urls *[]string, manifests ...*mpd.MPD) Fetcher {
+	return FetcherFunc(func(ctx context.Context, url string) (*mpd.MPD, error) {
+		*urls = append(*urls, url)
+		if len(manifests) == 0 {
+			return nil, errors.New("no more manifests")
+		}
+		m := manifests[0]
+		manifests = manifests[1:]
+		if m == nil {
+			return nil, errors.New("unavailable")
+		}
+		return m, nil
+	})
+}
+
+func TestRefresh(t *testing.T) {
+	m4 := newTestMPD(t, 8, 1, 4)
+	m4.Periods[0].EventStreams = []mpd.EventStream{{
+		SchemeIDURI: Strptr("urn:example:ad"),
+		Events:      []mpd.Event{{ID: Strptr("ad1"), PresentationTime: Uint64ptr(6)}},
+	}}
+	m4.Periods = append(m4.Periods, &mpd.Period{ID: "p1", Start: durationPtr(10 * time.Second)})
+	m5 := newTestMPD(t, 10, 2, 4)
+	m5.Location = "../moved/manifest.mpd"
+	m6 := newTestMPD(t, 12, 2, 4)
+	m6.Type = Strptr("static")
+
+	var urls []string
+	r := NewRefresher(TEST_URL, scriptedFetcher(&urls,
+		newTestMPD(t, 4, 0, 2), newTestMPD(t, 2, 0, 1), newTestMPD(t, 4, 0, 2), m4, m5, m6))
+
+	for i, expected := range []string{
+		"[manifest period:p0 segments:800:0-2]",
+		"",
+		"[]",
+		"[manifest segments:800:3-4 event:ad1 period:p1]",
+		"[manifest location:https://cdn.example.com/live/moved/manifest.mpd]",
+		"[manifest static]",
+	} {
+		updates, err := r.Refresh(context.Background())
+		if expected == "" {
+			if !errors.Is(err, ErrStaleManifest) {
+				t.Fatalf("Refresh %d: expected ErrStaleManifest, got %v", i, err)
+			}
+			continue
+		}
+		require.NoError(t, err)
+		require.EqualString(t, expected, describe(updates), fmt.Sprintf("Refresh %d", i))
+	}
+	require.EqualStringSlice(t, []string{TEST_URL, TEST_URL, TEST_URL, TEST_URL, TEST_URL,
+		"https://cdn.example.com/live/moved/manifest.mpd"}, urls)
+	require.EqualString(t, "https://cdn.example.com/live/moved/manifest.mpd", r.URL())
+	if r.MPD() != m6 || !r.Ended() {
+		t.Errorf("Expected the static manifest to end the stream")
+	}
+}
+
+func TestRefreshErrors(t *testing.T) {
+	invalid := newTestMPD(t, 0, 0, 1)
+	invalid.PublishTime = Strptr("yesterday")
+	var urls []string
+	r := NewRefresher(TEST_URL, scriptedFetcher(&urls, nil, invalid))
+
+	_, err := r.Refresh(context.Background())
+	require.EqualError(t, err, "unavailable")
+	_, err = r.Refresh(context.Background())
+	require.EqualError(t, err, `Invalid MPD: publishTime "yesterday"`)
+
+	r.Fetcher = FetcherFunc(func(ctx context.Context, url string) (*mpd.MPD, error) {
+		return nil, nil
+	})
+	_, err = r.Refresh(context.Background())
+	require.EqualError(t, err, `Invalid MPD: no manifest from "`+TEST_URL+`"`)
+	if r.MPD() != nil {
+		t.Errorf("Expected no manifest after failed refreshes")
+	}
+}
+
+func TestUpdatePeriod(t *testing.T) {
+	var urls []string
+	noUpdatePeriod := newTestMPD(t, 2, 0, 1)
+	noUpdatePeriod.MinimumUpdatePeriod = nil
+	zero := newTestMPD(t, 4, 0, 1)
+	zero.MinimumUpdatePeriod = Strptr("PT0S")
+	r := NewRefresher(TEST_URL, scriptedFetcher(&urls, newTestMPD(t, 0, 0, 1), noUpdatePeriod, zero))
+	require.EqualInt(t, int(DEFAULT_UPDATE_PERIOD), int(r.UpdatePeriod()))
+
+	for _, expected := range []time.Duration{2 * time.Second, DEFAULT_UPDATE_PERIOD, DEFAULT_MINIMUM_INTERVAL} {
+		_, err := r.Refresh(context.Background())
+		require.NoError(t, err)
+		require.EqualInt(t, int(expected), int(r.UpdatePeriod()))
+	}
+}
+
+func TestRun(t *testing.T) {
+	m1 := newTestMPD(t, 0, 0, 1)
+	m2 := newTestMPD(t, 2, 0, 2)
+	m2.Type = Strptr("static")
+	var urls []string
+	r := NewRefresher(TEST_URL, scriptedFetcher(&urls, nil, m1, m2))
+	r.DefaultUpdatePeriod = time.Millisecond
+	r.MinimumInterval = 0
+	m1.MinimumUpdatePeriod = Strptr("PT0.001S")
+
+	updates := make(chan Update)
+	done := make(chan error)
+	go func() { done <- r.Run(context.Background(), updates) }()
+	var received []Update
+	for {
+		select {
+		case u := <-updates:
+			received = append(received, u)
+			continue
+		case err := <-done:
+			require.NoError(t, err)
+		}
+		break
+	}
+	require.EqualString(t, "[error:unavailable manifest period:p0 segments:800:0-1 manifest segments:800:2-2 static]",
+		describe(received))
+}
+
+func TestRunCanceled(t *testing.T) {
+	var urls []string
+	r := NewRefresher(TEST_URL, scriptedFetcher(&urls, newTestMPD(t, 0, 0, 1)))
+	ctx, cancel := context.WithCancel(context.Background())
+	updates := make(chan Update, 10)
+	done := make(chan error)
+	go func() { done <- r.Run(ctx, updates) }()
+
+	u := <-updates
+	require.EqualString(t, string(UPDATE_MANIFEST), string(u.Type))
+	cancel()
+	require.EqualErr(t, context.Canceled, <-done)
+}
+
+func durationPtr(d time.Duration) *mpd.Duration {
+	v := mpd.Duration(d)
+	return &v
+}
@@ -0,0 +1,156 @@
+package liveupdate
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/zencoder/go-dash/v3/mpd"
+)
+
+// Type definition for the kind of an Update
+type UpdateType string
+
+// Constants for the kinds of Update sent by a Refresher
+const (
+	UPDATE_MANIFEST UpdateType = "manifest" // A newer manifest was fetched
+	UPDATE_PERIOD   UpdateType = "period"   // A Period appeared
+	// Segments were appended to the SegmentTimeline or SegmentList of a Representation. Without a
+	// Period@duration, the segments of SegmentTemplate@duration Representations and of a last
+	// S@r="-1" depend on the clock rather than on the manifest, and these Representations are not
+	// reported.
+	UPDATE_SEGMENTS UpdateType = "segments"
+	UPDATE_EVENT    UpdateType = "event"    // An Event appeared in an EventStream
+	UPDATE_LOCATION UpdateType = "location" // The Location element moved the manifest
+	UPDATE_STATIC   UpdateType = "static"   // The stream ended, the manifest became static
+	UPDATE_ERROR    UpdateType = "error"    // A refresh failed, the Refresher keeps polling
+)
+
+// Update is a single change to a live manifest. The elements point into MPD, which must not be
+// modified.
+type Update struct {
+	Type           UpdateType
+	MPD            *mpd.MPD
+	Period         *mpd.Period         // Period of the Period, segments and event updates
+	Representation *mpd.Representation // Representation of the segments update
+	Segments       []mpd.MediaSegment  // Appended segments, in order
+	EventStream    *mpd.EventStream    // EventStream of the event update
+	Event          *mpd.Event
+	Location       string // New manifest URL of the location update
+	Err            error  // Failure of the error update
+}
+
+// state is what a Refresher knows of the last manifest, to tell the changes of the next one.
+type state struct {
+	periods  map[string]bool
+	segments map[string]uint64 // Time of the last segment, by Representation key
+	events   map[string]bool
+}
+
+func newState() *state {
+	return &state{
+		periods:  map[string]bool{},
+		segments: map[string]uint64{},
+		events:   map[string]bool{},
+	}
+}
+
+// update lists the changes of m from s, and returns the state of m.
+func (s *state) update(m *mpd.MPD) ([]Update, *state) {
+	var updates []Update
+	next := newState()
+	for i, p := range m.Periods {
+		if p == nil {
+			continue
+		}
+		periodKey := p.ID
+		if periodKey == "" {
+			periodKey = "#" + strconv.Itoa(i)
+		}
+		next.periods[periodKey] = true
+		if !s.periods[periodKey] {
+			updates = append(updates, Update{Type: UPDATE_PERIOD, MPD: m, Period: p})
+		}
+
+		for j, as := range p.AdaptationSets {
+			if as == nil {
+				continue
+			}
+			asKey := "#" + strconv.Itoa(j)
+			if as.ID != nil {
+				asKey = *as.ID
+			}
+			for k, r := range as.Representations {
+				if r == nil {
+					continue
+				}
+				repKey := "#" + strconv.Itoa(k)
+				if r.ID != nil {
+					repKey = *r.ID
+				}
+				key := periodKey + "/" + asKey + "/" + repKey
+				segments := newSegments(r, time.Duration(p.Duration), key, s, next)
+				if len(segments) > 0 {
+					updates = append(updates, Update{
+						Type:           UPDATE_SEGMENTS,
+						MPD:            m,
+						Period:         p,
+						Representation: r,
+						Segments:       segments,
+					})
+				}
+			}
+		}
+
+		for j := range p.EventStreams {
+			es := &p.EventStreams[j]
+			for k := range es.Events {
+				e := &es.Events[k]
+				key := periodKey + "/" + strOrEmpty(es.SchemeIDURI) + "/" + strOrEmpty(es.Value) + "/" + eventKey(e, k)
+				next.events[key] = true
+				if !s.events[key] {
+					updates = append(updates, Update{Type: UPDATE_EVENT, MPD: m, Period: p, EventStream: es, Event: e})
+				}
+			}
+		}
+	}
+	return updates, next
+}
+
+// newSegments returns the segments of a Representation after the last one in s, and records the
+// last one in next. Representations whose segments aren't listed in the manifest, such as
+// SegmentTemplate@duration ones, have none.
+func newSegments(r *mpd.Representation, periodDuration time.Duration, key string, s, next *state) []mpd.MediaSegment {
+	rs, err := r.Segments(periodDuration)
+	if err != nil || len(rs.Media) == 0 {
+		return nil
+	}
+	next.segments[key] = rs.Media[len(rs.Media)-1].Time
+	last, known := s.segments[key]
+	if !known {
+		return rs.Media
+	}
+	for i, segment := range rs.Media {
+		if segment.Time > last {
+			return rs.Media[i:]
+		}
+	}
+	return nil
+}
+
+// eventKey identifies an Event in its EventStream by id, presentation time or position.
+func eventKey(e *mpd.Event, i int) string {
+	switch {
+	case e.ID != nil:
+		return "id=" + *e.ID
+	case e.PresentationTime != nil:
+		return "t=" + strconv.FormatUint(*e.PresentationTime, 10)
+	}
+	return "#" + strconv.Itoa(i)
+}
+
+func strOrEmpty(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,54 @@
+package liveupdate
+
+import (
+	"testing"
+
+	. "github.com/zencoder/go-dash/v3/helpers/ptrs"
+	"github.com/zencoder/go-dash/v3/helpers/require"
+	"github.com/zencoder/go-dash/v3/mpd"
+)
+
+func TestStateUpdate(t *testing.T) {
+	m := newTestMPD(t, 0, 0, 2)
+	m.Periods[0].ID = ""
+	updates, s := newState().update(m)
+	require.EqualString(t, "[period: segments:800:0-2]", describe(updates))
+
+	// A sliding window drops old segments and appends new ones
+	m = newTestMPD(t, 2, 2, 5)
+	m.Periods[0].ID = ""
+	updates, s = s.update(m)
+	require.EqualString(t, "[segments:800:3-5]", describe(updates))
+
+	// Events without an id are told apart by presentation time
+	m.Periods[0].EventStreams = []mpd.EventStream{{
+		SchemeIDURI: Strptr("urn:example:ad"),
+		Events: []mpd.Event{
+			{PresentationTime: Uint64ptr(4)},
+			{PresentationTime: Uint64ptr(8)},
+		},
+	}}
+	updates, s = s.update(m)
+	require.EqualInt(t, 2, len(updates))
+	require.EqualUInt64Ptr(t, Uint64ptr(8), updates[1].Event.PresentationTime)
+	require.EqualStringPtr(t, Strptr("urn:example:ad"), updates[1].EventStream.SchemeIDURI)
+
+	m.Periods[0].EventStreams[0].Events = append(m.Periods[0].EventStreams[0].Events, mpd.Event{PresentationTime: Uint64ptr(12)})
+	updates, _ = s.update(m)
+	require.EqualInt(t, 1, len(updates))
+	require.EqualUInt64Ptr(t, Uint64ptr(12), updates[0].Event.PresentationTime)
+}
+
+func TestStateUpdateDurationTemplate(t *testing.T) {
+	m := mpd.NewDynamicMPD(mpd.DASH_PROFILE_LIVE, "2024-01-01T00:00:00Z", "PT2S")
+	as, err := m.AddNewAdaptationSetAudio("audio/mp4", true, 1, "en")
+	require.NoError(t, err)
+	_, err = as.SetNewSegmentTemplate(96000, "$RepresentationID$/init.mp4", "$RepresentationID$/$Number$.m4s", 1, 48000)
+	require.NoError(t, err)
+	_, err = as.AddNewRepresentationAudio(48000, 128000, "mp4a.40.2", "audio")
+	require.NoError(t, err)
+
+	// Segment availability of @duration templates follows the clock, not the manifest
+	updates, _ := newState().update(m)
+	require.EqualString(t, "[period:]", describe(updates))
+}
@@ -0,0 +1,208 @@
+package mpd
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Known error variables
+var (
+	ErrInvalidCaptionServices   = errors.New("Invalid caption services")
+	ErrNotCaptionScheme         = errors.New("Not a CEA-608 or CEA-708 Accessibility scheme")
+	ErrCaptionServicesAmbiguous = errors.New("Caption services need a channel for every language or for none")
+)
+
+// CaptionService is one closed caption service embedded in the video, as signalled by a CEA-608 or
+// CEA-708 Accessibility element.
+type CaptionService struct {
+	// CC1 to CC4 for CEA-608, 1 to 63 for CEA-708. Empty when the value only lists languages, in
+	// channel order.
+	Channel  string
+	Language string // ISO 639-2 code (i.e. eng)
+	// CEA-708 only
+	EasyReader      bool
+	WideAspectRatio bool
+}
+
+// ParseCaptionServices parses the value of a CEA-608 or CEA-708 Accessibility element into its
+// caption services (i.e. CC1=eng;CC3=spa, or 1=lang:eng;2=lang:spa,war:1,er:1).
+// scheme - ACCESSIBILITY_ELEMENT_SCHEME_CEA_608 or ACCESSIBILITY_ELEMENT_SCHEME_CEA_708.
+func ParseCaptionServices(scheme AccessibilityElementScheme, value string) ([]CaptionService, error) {
+	if scheme != ACCESSIBILITY_ELEMENT_SCHEME_CEA_608 && scheme != ACCESSIBILITY_ELEMENT_SCHEME_CEA_708 {
+		return nil, fmt.Errorf("%w: %q", ErrNotCaptionScheme, scheme)
+	}
+	var services []CaptionService
+	for _, entry := range strings.Split(strings.TrimSpace(value), ";") {
+		var s CaptionService
+		description := entry
+		if channel, rest, ok := strings.Cut(entry, "="); ok {
+			s.Channel, description = strings.TrimSpace(channel), rest
+		}
+		if scheme == ACCESSIBILITY_ELEMENT_SCHEME_CEA_708 {
+			if err := s.parse708(description); err != nil {
+				return nil, fmt.Errorf("%w: %q: %w", ErrInvalidCaptionServices, value, err)
+			}
+		} else {
+			s.Language = strings.TrimSpace(description)
+		}
+		services = append(services, s)
+	}
+	if err := validateCaptionServices(scheme, services); err != nil {
+		return nil, fmt.Errorf("%w: %q: %w", ErrInvalidCaptionServices, value, err)
+	}
+	return services, nil
+}
+
+// parse708 parses a CEA-708 service description, either a language or comma separated lang, war
+// and er parameters.
+func (s *CaptionService) parse708(description string) error {
+	if !strings.Contains(description, ":") {
+		s.Language = strings.TrimSpace(description)
+		return nil
+	}
+	for _, parameter := range strings.Split(description, ",") {
+		key, value, _ := strings.Cut(parameter, ":")
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		switch key {
+		case "lang":
+			s.Language = value
+		case "war", "er":
+			if value != "0" && value != "1" {
+				return fmt.Errorf("%s should be 0 or 1", key)
+			}
+			if key == "war" {
+				s.WideAspectRatio = value == "1"
+			} else {
+				s.EasyReader = value == "1"
+			}
+		default:
+			return fmt.Errorf("unknown parameter %q", key)
+		}
+	}
+	return nil
+}
+
+// FormatCaptionServices returns the value of a CEA-608 or CEA-708 Accessibility element for the
+// caption services.
+// scheme - ACCESSIBILITY_ELEMENT_SCHEME_CEA_608 or ACCESSIBILITY_ELEMENT_SCHEME_CEA_708.
+func FormatCaptionServices(scheme AccessibilityElementScheme, services ...CaptionService) (string, error) {
+	if scheme != ACCESSIBILITY_ELEMENT_SCHEME_CEA_608 && scheme != ACCESSIBILITY_ELEMENT_SCHEME_CEA_708 {
+		return "", fmt.Errorf("%w: %q", ErrNotCaptionScheme, scheme)
+	}
+	if err := validateCaptionServices(scheme, services); err != nil {
+		return "", fmt.Errorf("%w: %w", ErrInvalidCaptionServices, err)
+	}
+	entries := make([]string, len(services))
+	for i, s := range services {
+		description := s.Language
+		if scheme == ACCESSIBILITY_ELEMENT_SCHEME_CEA_708 && s.Channel != "" {
+			description = "lang:" + s.Language
+			if s.WideAspectRatio {
+				description += ",war:1"
+			}
+			if s.EasyReader {
+				description += ",er:1"
+			}
+		}
+		if s.Channel != "" {
+			description = s.Channel + "=" + description
+		}
+		entries[i] = description
+	}
+	return strings.Join(entries, ";"), nil
+}
+
+// validateCaptionServices checks the channels and languages of caption services.
+func validateCaptionServices(scheme AccessibilityElementScheme, services []CaptionService) error {
+	if len(services) == 0 {
+		return errors.New("no service")
+	}
+	channels := map[string]bool{}
+	for _, s := range services {
+		if (s.Channel == "") != (services[0].Channel == "") {
+			return ErrCaptionServicesAmbiguous
+		}
+		if !validCaptionLanguage(s.Language) {
+			return fmt.Errorf("invalid language %q", s.Language)
+		}
+		if s.Channel == "" {
+			if s.EasyReader || s.WideAspectRatio {
+				return errors.New("war and er need a service number")
+			}
+			continue
+		}
+		if channels[s.Channel] {
+			return fmt.Errorf("duplicate channel %q", s.Channel)
+		}
+		channels[s.Channel] = true
+		if scheme == ACCESSIBILITY_ELEMENT_SCHEME_CEA_608 {
+			if s.EasyReader || s.WideAspectRatio {
+				return errors.New("war and er are CEA-708 only")
+			}
+			if len(s.Channel) != 3 || !strings.HasPrefix(s.Channel, "CC") || s.Channel[2] < '1' || s.Channel[2] > '4' {
+				return fmt.Errorf("channel %q should be CC1 to CC4", s.Channel)
+			}
+			continue
+		}
+		if n, err := strconv.Atoi(s.Channel); err != nil || n < 1 || n > 63 || strconv.Itoa(n) != s.Channel {
+			return fmt.Errorf("service number %q should be 1 to 63", s.Channel)
+		}
+	}
+	return nil
+}
+
+// validCaptionLanguage accepts ISO 639 two or three letter codes.
+func validCaptionLanguage(language string) bool {
+	if len(language) < 2 || len(language) > 3 {
+		return false
+	}
+	for _, c := range language {
+		if (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') {
+			return false
+		}
+	}
+	return true
+}
+
+// Adds a new CEA-608 or CEA-708 Accessibility element signalling the closed caption services
+// embedded in the video of an AdaptationSet.
+// scheme - ACCESSIBILITY_ELEMENT_SCHEME_CEA_608 or ACCESSIBILITY_ELEMENT_SCHEME_CEA_708.
+// services - caption channels and their language (i.e. CaptionService{Channel: "CC1", Language: "eng"}).
+func (as *AdaptationSet) AddNewCaptionServices(scheme AccessibilityElementScheme, services ...CaptionService) (*Accessibility, error) {
+	value, err := FormatCaptionServices(scheme, services...)
+	if err != nil {
+		return nil, err
+	}
+	return as.AddNewAccessibilityElement(scheme, value)
+}
+
+// CaptionServices returns the closed caption services of the CEA-608 or CEA-708 Accessibility
+// elements of an AdaptationSet, in the order of the elements.
+// scheme - ACCESSIBILITY_ELEMENT_SCHEME_CEA_608 or ACCESSIBILITY_ELEMENT_SCHEME_CEA_708.
+func (as *AdaptationSet) CaptionServices(scheme AccessibilityElementScheme) ([]CaptionService, error) {
+	var services []CaptionService
+	for _, a := range as.AccessibilityElems {
+		if a == nil || strOrEmpty(a.SchemeIdUri) != string(scheme) {
+			continue
+		}
+		s, err := ParseCaptionServices(scheme, strOrEmpty(a.Value))
+		if err != nil {
+			return nil, err
+		}
+		services = append(services, s...)
+	}
+	return services, nil
+}
+
+// validateCaptions checks the value of the CEA-608 and CEA-708 Accessibility elements of an
+// AdaptationSet.
+func validateCaptions(as *AdaptationSet) error {
+	for _, scheme := range []AccessibilityElementScheme{ACCESSIBILITY_ELEMENT_SCHEME_CEA_608, ACCESSIBILITY_ELEMENT_SCHEME_CEA_708} {
+		if _, err := as.CaptionServices(scheme); err != nil {
+			return err
+		}
+	}
+	return nil
+}
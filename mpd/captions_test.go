@@ -0,0 +1,145 @@
+package mpd
+
+import (
+	"errors"
+	"testing"
+
+	. "github.com/zencoder/go-dash/v3/helpers/ptrs"
+	"github.com/zencoder/go-dash/v3/helpers/require"
+	"github.com/zencoder/go-dash/v3/helpers/testfixtures"
+)
+
+func TestParseCaptionServices(t *testing.T) {
+	services, err := ParseCaptionServices(ACCESSIBILITY_ELEMENT_SCHEME_CEA_608, "CC1=eng;CC3=spa")
+	require.NoError(t, err)
+	require.EqualInt(t, 2, len(services))
+	require.EqualString(t, "CC1", services[0].Channel)
+	require.EqualString(t, "eng", services[0].Language)
+	require.EqualString(t, "CC3", services[1].Channel)
+	require.EqualString(t, "spa", services[1].Language)
+
+	// Languages only, in channel order
+	services, err = ParseCaptionServices(ACCESSIBILITY_ELEMENT_SCHEME_CEA_608, "eng;fra")
+	require.NoError(t, err)
+	require.EqualInt(t, 2, len(services))
+	require.EqualString(t, "", services[1].Channel)
+	require.EqualString(t, "fra", services[1].Language)
+
+	services, err = ParseCaptionServices(ACCESSIBILITY_ELEMENT_SCHEME_CEA_708, "1=lang:eng;2=lang:spa,war:1,er:1;3=deu")
+	require.NoError(t, err)
+	require.EqualInt(t, 3, len(services))
+	require.EqualString(t, "1", services[0].Channel)
+	require.EqualString(t, "eng", services[0].Language)
+	if services[0].EasyReader || services[0].WideAspectRatio {
+		t.Errorf("Expected service 1 without er and war, got %+v", services[0])
+	}
+	require.EqualString(t, "spa", services[1].Language)
+	if !services[1].EasyReader || !services[1].WideAspectRatio {
+		t.Errorf("Expected service 2 with er and war, got %+v", services[1])
+	}
+	require.EqualString(t, "3", services[2].Channel)
+	require.EqualString(t, "deu", services[2].Language)
+}
+
+func TestParseCaptionServicesErrors(t *testing.T) {
+	for _, tc := range []struct {
+		scheme AccessibilityElementScheme
+		value  string
+		err    string
+	}{
+		{ACCESSIBILITY_ELEMENT_SCHEME_CEA_608, "", `Invalid caption services: "": invalid language ""`},
+		{ACCESSIBILITY_ELEMENT_SCHEME_CEA_608, "CC1=eng;", `Invalid caption services: "CC1=eng;": Caption services need a channel for every language or for none`},
+		{ACCESSIBILITY_ELEMENT_SCHEME_CEA_608, "CC5=eng", `Invalid caption services: "CC5=eng": channel "CC5" should be CC1 to CC4`},
+		{ACCESSIBILITY_ELEMENT_SCHEME_CEA_608, "CC1=eng;CC1=spa", `Invalid caption services: "CC1=eng;CC1=spa": duplicate channel "CC1"`},
+		{ACCESSIBILITY_ELEMENT_SCHEME_CEA_608, "CC1=english", `Invalid caption services: "CC1=english": invalid language "english"`},
+		{ACCESSIBILITY_ELEMENT_SCHEME_CEA_608, "CC1=eng;spa", `Invalid caption services: "CC1=eng;spa": Caption services need a channel for every language or for none`},
+		{ACCESSIBILITY_ELEMENT_SCHEME_CEA_708, "64=lang:eng", `Invalid caption services: "64=lang:eng": service number "64" should be 1 to 63`},
+		{ACCESSIBILITY_ELEMENT_SCHEME_CEA_708, "CC1=eng", `Invalid caption services: "CC1=eng": service number "CC1" should be 1 to 63`},
+		{ACCESSIBILITY_ELEMENT_SCHEME_CEA_708, "1=lang:eng,er:yes", `Invalid caption services: "1=lang:eng,er:yes": er should be 0 or 1`},
+		{ACCESSIBILITY_ELEMENT_SCHEME_CEA_708, "1=lang:eng,font:2", `Invalid caption services: "1=lang:eng,font:2": unknown parameter "font"`},
+		{ACCESSIBILITY_ELEMENT_SCHEME_CEA_708, "lang:eng,er:1", `Invalid caption services: "lang:eng,er:1": war and er need a service number`},
+		{ACCESSIBILITY_ELEMENT_SCHEME_DESCRIPTIVE_AUDIO, "1", `Not a CEA-608 or CEA-708 Accessibility scheme: "urn:tva:metadata:cs:AudioPurposeCS:2007"`},
+	} {
+		_, err := ParseCaptionServices(tc.scheme, tc.value)
+		require.EqualError(t, err, tc.err)
+	}
+
+	_, err := ParseCaptionServices(ACCESSIBILITY_ELEMENT_SCHEME_CEA_608, "CC1=eng;spa")
+	if !errors.Is(err, ErrInvalidCaptionServices) || !errors.Is(err, ErrCaptionServicesAmbiguous) {
+		t.Errorf("Expected ErrInvalidCaptionServices and ErrCaptionServicesAmbiguous, got %v", err)
+	}
+}
+
+func TestFormatCaptionServices(t *testing.T) {
+	value, err := FormatCaptionServices(ACCESSIBILITY_ELEMENT_SCHEME_CEA_608,
+		CaptionService{Channel: "CC1", Language: "eng"}, CaptionService{Channel: "CC3", Language: "spa"})
+	require.NoError(t, err)
+	require.EqualString(t, "CC1=eng;CC3=spa", value)
+
+	value, err = FormatCaptionServices(ACCESSIBILITY_ELEMENT_SCHEME_CEA_708,
+		CaptionService{Channel: "1", Language: "eng"}, CaptionService{Channel: "2", Language: "spa", EasyReader: true, WideAspectRatio: true})
+	require.NoError(t, err)
+	require.EqualString(t, "1=lang:eng;2=lang:spa,war:1,er:1", value)
+
+	value, err = FormatCaptionServices(ACCESSIBILITY_ELEMENT_SCHEME_CEA_708, CaptionService{Language: "eng"})
+	require.NoError(t, err)
+	require.EqualString(t, "eng", value)
+
+	_, err = FormatCaptionServices(ACCESSIBILITY_ELEMENT_SCHEME_CEA_608, CaptionService{Channel: "CC1", Language: "eng", EasyReader: true})
+	require.EqualError(t, err, "Invalid caption services: war and er are CEA-708 only")
+	_, err = FormatCaptionServices(ACCESSIBILITY_ELEMENT_SCHEME_CEA_608)
+	require.EqualError(t, err, "Invalid caption services: no service")
+}
+
+func TestAddNewCaptionServices(t *testing.T) {
+	m := NewMPD(DASH_PROFILE_LIVE, VALID_MEDIA_PRESENTATION_DURATION, VALID_MIN_BUFFER_TIME)
+	videoAS, err := m.AddNewAdaptationSetVideoWithID("1", DASH_MIME_TYPE_VIDEO_MP4, VALID_SCAN_TYPE, VALID_SEGMENT_ALIGNMENT, VALID_START_WITH_SAP)
+	require.NoError(t, err)
+	_, err = videoAS.AddNewCaptionServices(ACCESSIBILITY_ELEMENT_SCHEME_CEA_608,
+		CaptionService{Channel: "CC1", Language: "eng"}, CaptionService{Channel: "CC3", Language: "spa"})
+	require.NoError(t, err)
+	_, err = videoAS.AddNewCaptionServices(ACCESSIBILITY_ELEMENT_SCHEME_CEA_708,
+		CaptionService{Channel: "1", Language: "eng"}, CaptionService{Channel: "2", Language: "spa", EasyReader: true})
+	require.NoError(t, err)
+	_, err = videoAS.AddNewRepresentationVideo(VALID_VIDEO_BITRATE, VALID_VIDEO_CODEC, VALID_VIDEO_ID, VALID_VIDEO_FRAMERATE, VALID_VIDEO_WIDTH, VALID_VIDEO_HEIGHT)
+	require.NoError(t, err)
+
+	a, err := videoAS.AddNewCaptionServices(ACCESSIBILITY_ELEMENT_SCHEME_CEA_608, CaptionService{Channel: "CC9", Language: "eng"})
+	require.Nil(t, a)
+	require.EqualError(t, err, `Invalid caption services: channel "CC9" should be CC1 to CC4`)
+	require.EqualInt(t, 2, len(videoAS.AccessibilityElems))
+
+	require.NoError(t, m.Validate())
+	got, err := m.WriteToString()
+	require.NoError(t, err)
+	testfixtures.CompareFixture(t, "fixtures/captions.mpd", got)
+}
+
+func TestReadCaptionServices(t *testing.T) {
+	m, err := ReadFromFile("fixtures/captions.mpd")
+	require.NoError(t, err)
+	videoAS := m.Periods[0].AdaptationSets[0]
+
+	services, err := videoAS.CaptionServices(ACCESSIBILITY_ELEMENT_SCHEME_CEA_608)
+	require.NoError(t, err)
+	require.EqualInt(t, 2, len(services))
+	require.EqualString(t, "CC3", services[1].Channel)
+	require.EqualString(t, "spa", services[1].Language)
+
+	services, err = videoAS.CaptionServices(ACCESSIBILITY_ELEMENT_SCHEME_CEA_708)
+	require.NoError(t, err)
+	require.EqualInt(t, 2, len(services))
+	require.EqualString(t, "2", services[1].Channel)
+	if !services[1].EasyReader {
+		t.Errorf("Expected service 2 with er, got %+v", services[1])
+	}
+
+	services, err = videoAS.CaptionServices(ACCESSIBILITY_ELEMENT_SCHEME_DESCRIPTIVE_AUDIO)
+	require.NoError(t, err)
+	require.EqualInt(t, 0, len(services))
+
+	videoAS.AccessibilityElems[0].Value = Strptr("CC1=eng;CC1=spa")
+	_, err = videoAS.CaptionServices(ACCESSIBILITY_ELEMENT_SCHEME_CEA_608)
+	require.EqualError(t, err, `Invalid caption services: "CC1=eng;CC1=spa": duplicate channel "CC1"`)
+	require.EqualError(t, m.Validate(), `AdaptationSet 1: Invalid caption services: "CC1=eng;CC1=spa": duplicate channel "CC1"`)
+}
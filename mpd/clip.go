@@ -0,0 +1,297 @@
+package mpd
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	. "github.com/zencoder/go-dash/v3/helpers/ptrs"
+)
+
+// Known error variables
+var (
+	ErrInvalidClipRange    = errors.New("Invalid clip range")
+	ErrClipOutOfRange      = errors.New("Clip range is outside of the presentation")
+	ErrSegmentTimesUnknown = errors.New("Segment times need a SegmentTimeline or @duration")
+)
+
+// periodBounds is where a Period is in the presentation.
+type periodBounds struct {
+	start    time.Duration
+	duration time.Duration
+}
+
+// Clip returns a copy of the MPD presenting only the range [from, to) of its presentation time, for
+// start-over and highlights. Periods outside of the range are dropped and the others trimmed: their
+// SegmentTemplates and SegmentLists keep the segments that cover the range, addressed with a
+// SegmentTimeline so that presentationTimeOffset can start the Period exactly at from, and their
+// events are trimmed to the range. Period starts and mediaPresentationDuration are relative to from.
+// The MPD itself is not modified.
+// from - start of the clip in the presentation (i.e. 10*time.Minute).
+// to - end of the clip, clamped to the end of the presentation.
+func (m *MPD) Clip(from, to time.Duration) (*MPD, error) {
+	if from < 0 || to <= from {
+		return nil, fmt.Errorf("%w: %s to %s", ErrInvalidClipRange, from, to)
+	}
+	bounds, err := m.periodBounds()
+	if err != nil {
+		return nil, err
+	}
+
+	clip := m.Clone()
+	var periods []*Period
+	var end time.Duration
+	for i, p := range clip.Periods {
+		start, stop := bounds[i].start, bounds[i].start+bounds[i].duration
+		if stop <= from || start >= to {
+			continue
+		}
+		a, b := max(from, start)-start, min(to, stop)-start
+		if err := p.clip(a, b, bounds[i].duration); err != nil {
+			return nil, fmt.Errorf("Period %q: %w", p.ID, err)
+		}
+		p.Start = durationPtr(max(from, start) - from)
+		p.Duration = Duration(b - a)
+		periods = append(periods, p)
+		end = min(to, stop) - from
+	}
+	if len(periods) == 0 {
+		return nil, fmt.Errorf("%w: %s to %s", ErrClipOutOfRange, from, to)
+	}
+	clip.Periods = periods
+	clip.period = periods[len(periods)-1]
+	clip.MediaPresentationDuration = Strptr(durationPtr(end).String())
+	return clip, nil
+}
+
+// PeriodDurations returns the duration of every Period of the MPD, to expand their segments:
+// Period@duration, or the time until the start of the next Period, or for the last one until the
+// end of the presentation or of its last segment. Zero for a Period when none is known.
+func (m *MPD) PeriodDurations() ([]time.Duration, error) {
+	bounds, err := m.periodSpans()
+	if err != nil {
+		return nil, err
+	}
+	durations := make([]time.Duration, len(bounds))
+	for i, pb := range bounds {
+		durations[i] = max(pb.duration, 0)
+	}
+	return durations, nil
+}
+
+// periodBounds returns the start and duration of every Period. A Period without a duration lasts
+// until the start of the next one, or for the last one until the end of the presentation or of its
+// last segment.
+func (m *MPD) periodBounds() ([]periodBounds, error) {
+	if len(m.Periods) == 0 {
+		return nil, ErrNoPeriods
+	}
+	bounds, err := m.periodSpans()
+	if err != nil {
+		return nil, err
+	}
+	for i, pb := range bounds {
+		if pb.duration <= 0 {
+			return nil, fmt.Errorf("Period %q: %w", m.Periods[i].ID, ErrPeriodDurationUnknown)
+		}
+	}
+	return bounds, nil
+}
+
+// periodSpans is periodBounds with a zero duration for the Periods whose duration is unknown.
+func (m *MPD) periodSpans() ([]periodBounds, error) {
+	var presentationDuration time.Duration
+	if m.MediaPresentationDuration != nil {
+		d, err := ParseDuration(*m.MediaPresentationDuration)
+		if err != nil {
+			return nil, fmt.Errorf("mediaPresentationDuration: %w", err)
+		}
+		presentationDuration = d
+	}
+
+	bounds := make([]periodBounds, len(m.Periods))
+	var end time.Duration
+	for i, p := range m.Periods {
+		pb := periodBounds{start: end}
+		if p.Start != nil {
+			pb.start = time.Duration(*p.Start)
+		}
+		last := i+1 == len(m.Periods)
+		_, mediaEnd, hasMedia := p.mediaExtent()
+		switch {
+		case p.Duration != 0:
+			pb.duration = time.Duration(p.Duration)
+		case !last && m.Periods[i+1].Start != nil:
+			pb.duration = time.Duration(*m.Periods[i+1].Start) - pb.start
+		case last && presentationDuration > 0:
+			pb.duration = presentationDuration - pb.start
+		case hasMedia:
+			pb.duration = mediaEnd
+		}
+		bounds[i] = pb
+		end = pb.start + max(pb.duration, 0)
+	}
+	return bounds, nil
+}
+
+// clip trims the Period to [a, b) of its own time.
+func (p *Period) clip(a, b, duration time.Duration) error {
+	for _, as := range p.AdaptationSets {
+		// Representations first, as they may inherit the addressing of the AdaptationSet
+		for _, r := range as.Representations {
+			if r.SegmentTemplate != nil && r.SegmentTemplate != as.SegmentTemplate {
+				st := r.segmentTemplate()
+				if err := r.SegmentTemplate.clip(st, a, b, duration); err != nil {
+					return fmt.Errorf("Representation %s: %w", strOrEmpty(r.ID), err)
+				}
+			}
+			if r.SegmentList != nil && r.SegmentList != as.SegmentList {
+				if err := r.SegmentList.clip(a, b, duration); err != nil {
+					return fmt.Errorf("Representation %s: %w", strOrEmpty(r.ID), err)
+				}
+			}
+			if r.SegmentBase != nil && r.SegmentBase != as.SegmentBase {
+				r.SegmentBase.shiftPTO(a)
+			}
+		}
+		if st := as.SegmentTemplate; st != nil {
+			if err := st.clip(st, a, b, duration); err != nil {
+				return err
+			}
+		}
+		if as.SegmentList != nil {
+			if err := as.SegmentList.clip(a, b, duration); err != nil {
+				return err
+			}
+		}
+		if as.SegmentBase != nil {
+			as.SegmentBase.shiftPTO(a)
+		}
+	}
+	for i := range p.EventStreams {
+		p.EventStreams[i].clip(a, b)
+	}
+	return nil
+}
+
+// clip sets the addressing of the SegmentTemplate to the segments of merged, its merge with the
+// AdaptationSet SegmentTemplate, that cover [a, b) of the Period.
+func (st *SegmentTemplate) clip(merged *SegmentTemplate, a, b, periodDuration time.Duration) error {
+	if merged.SegmentTimeline == nil && (merged.Duration == nil || *merged.Duration <= 0) {
+		// Initialization only, the Representations have the media addressing
+		return nil
+	}
+	rs, err := (&Representation{}).templateSegments(merged, periodDuration)
+	if err != nil {
+		return err
+	}
+	kept, _ := coveringSegments(rs, a, b)
+	st.PresentationTimeOffset = Uint64ptr(rs.PresentationTimeOffset + scaledDuration(a, rs.Timescale))
+	st.SegmentTimeline = newSegmentTimeline(kept)
+	st.Duration = nil
+	if len(kept) > 0 {
+		st.StartNumber = Int64ptr(kept[0].Number)
+	}
+	return nil
+}
+
+// clip keeps the SegmentURLs of the segments that cover [a, b) of the Period.
+func (sl *SegmentList) clip(a, b, periodDuration time.Duration) error {
+	if sl.SegmentTimeline == nil && (sl.Duration == nil || *sl.Duration == 0) {
+		return ErrSegmentTimesUnknown
+	}
+	rs, err := listSegments(sl, periodDuration)
+	if err != nil {
+		return err
+	}
+	kept, first := coveringSegments(rs, a, b)
+	sl.PresentationTimeOffset = Uint64ptr(rs.PresentationTimeOffset + scaledDuration(a, rs.Timescale))
+	sl.SegmentURLs = sl.SegmentURLs[first : first+len(kept)]
+	sl.SegmentTimeline = newSegmentTimeline(kept)
+	sl.Duration = nil
+	if len(kept) > 0 {
+		sl.StartNumber = Uint32ptr(uint32(kept[0].Number))
+	}
+	return nil
+}
+
+// coveringSegments returns the segments overlapping [a, b) of the Period, and the index of the
+// first one.
+func coveringSegments(rs *RepresentationSegments, a, b time.Duration) ([]MediaSegment, int) {
+	from := rs.PresentationTimeOffset + scaledDuration(a, rs.Timescale)
+	to := rs.PresentationTimeOffset + scaledDuration(b, rs.Timescale)
+	first, last := len(rs.Media), len(rs.Media)
+	for i, s := range rs.Media {
+		if s.Time+s.Duration > from && first == len(rs.Media) {
+			first = i
+		}
+		if s.Time >= to {
+			last = i
+			break
+		}
+	}
+	if first > last {
+		first = last
+	}
+	return rs.Media[first:last], first
+}
+
+// newSegmentTimeline returns a SegmentTimeline of the segments, repeating runs of equal durations.
+func newSegmentTimeline(segments []MediaSegment) *SegmentTimeline {
+	stl := &SegmentTimeline{}
+	var next uint64
+	for i, s := range segments {
+		if n := len(stl.Segments); n > 0 && s.Time == next && stl.Segments[n-1].Duration == s.Duration {
+			repeat := stl.Segments[n-1].RepeatCount
+			if repeat == nil {
+				repeat = Intptr(0)
+			}
+			*repeat++
+			stl.Segments[n-1].RepeatCount = repeat
+		} else {
+			entry := &SegmentTimelineSegment{Duration: s.Duration}
+			if i == 0 || s.Time != next {
+				entry.StartTime = Uint64ptr(s.Time)
+			}
+			stl.Segments = append(stl.Segments, entry)
+		}
+		next = s.Time + s.Duration
+	}
+	return stl
+}
+
+// clip keeps the events overlapping [a, b) of the Period, or starting in it for events without a
+// duration, trimmed to the range and relative to a. b is math.MaxInt64 for no end.
+func (es *EventStream) clip(a, b time.Duration) {
+	timescale := uint64(1)
+	if es.Timescale != nil && *es.Timescale > 0 {
+		timescale = uint64(*es.Timescale)
+	}
+	from := scaledDuration(a, timescale)
+	to := uint64(math.MaxUint64)
+	if b != math.MaxInt64 {
+		to = scaledDuration(b, timescale)
+	}
+
+	var events []Event
+	for _, e := range es.Events {
+		var t, d uint64
+		if e.PresentationTime != nil {
+			t = *e.PresentationTime
+		}
+		if e.Duration != nil {
+			d = *e.Duration
+		}
+		if t >= to || t < from && t+d <= from {
+			continue
+		}
+		start, end := max(t, from), t+d
+		if e.Duration != nil {
+			e.Duration = Uint64ptr(min(end, to) - start)
+		}
+		e.PresentationTime = Uint64ptr(start - from)
+		events = append(events, e)
+	}
+	es.Events = events
+}
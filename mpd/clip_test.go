@@ -0,0 +1,102 @@
+package mpd
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/zencoder/go-dash/v3/helpers/ptrs"
+	"github.com/zencoder/go-dash/v3/helpers/require"
+	"github.com/zencoder/go-dash/v3/helpers/testfixtures"
+)
+
+func TestClip(t *testing.T) {
+	m, err := ReadFromFile("fixtures/clip_source.mpd")
+	require.NoError(t, err)
+	source, err := m.WriteToString()
+	require.NoError(t, err)
+
+	clip, err := m.Clip(7*time.Second, 13*time.Second)
+	require.NoError(t, err)
+	got, err := clip.WriteToString()
+	require.NoError(t, err)
+	testfixtures.CompareFixture(t, "fixtures/clip.mpd", got)
+
+	after, err := m.WriteToString()
+	require.NoError(t, err)
+	require.EqualString(t, source, after, "The source MPD is not modified")
+
+	// The clipped addressing lists the segments covering the range, with their original URLs
+	rs, err := clip.Periods[0].AdaptationSets[0].Representations[0].Segments(time.Duration(clip.Periods[0].Duration))
+	require.NoError(t, err)
+	require.EqualInt(t, 2, len(rs.Media))
+	require.EqualString(t, "800/4.m4s", rs.Media[0].URL)
+	require.EqualString(t, "800/5.m4s", rs.Media[1].URL)
+	rs, err = clip.Periods[0].AdaptationSets[1].Representations[0].Segments(0)
+	require.NoError(t, err)
+	require.EqualString(t, "audio/276480.m4s", rs.Media[0].URL)
+	rs, err = clip.Periods[0].AdaptationSets[2].Representations[0].Segments(0)
+	require.NoError(t, err)
+	require.EqualString(t, "subtitles/2.m4s", rs.Media[0].URL)
+	require.EqualInt(t, 2, len(rs.Media))
+}
+
+func TestClipSinglePeriod(t *testing.T) {
+	m, err := ReadFromFile("fixtures/clip_source.mpd")
+	require.NoError(t, err)
+
+	clip, err := m.Clip(12*time.Second, time.Hour)
+	require.NoError(t, err)
+	require.EqualInt(t, 1, len(clip.Periods))
+	require.EqualString(t, "p1", clip.Periods[0].ID)
+	require.EqualString(t, "PT0S", clip.Periods[0].Start.String())
+	require.EqualStringPtr(t, ptrs.Strptr("PT8S"), clip.MediaPresentationDuration)
+	st := clip.Periods[0].AdaptationSets[0].SegmentTemplate
+	require.EqualUInt64Ptr(t, ptrs.Uint64ptr(180000), st.PresentationTimeOffset)
+	require.EqualInt(t, 2, int(*st.StartNumber))
+	require.EqualUInt64Ptr(t, ptrs.Uint64ptr(180000), st.SegmentTimeline.Segments[0].StartTime)
+	require.EqualIntPtr(t, ptrs.Intptr(3), st.SegmentTimeline.Segments[0].RepeatCount)
+}
+
+func TestClipErrors(t *testing.T) {
+	m, err := ReadFromFile("fixtures/clip_source.mpd")
+	require.NoError(t, err)
+
+	for _, r := range [][2]time.Duration{{-time.Second, time.Second}, {2 * time.Second, 2 * time.Second}} {
+		_, err = m.Clip(r[0], r[1])
+		if !errors.Is(err, ErrInvalidClipRange) {
+			t.Errorf("Expected ErrInvalidClipRange for %v, got %v", r, err)
+		}
+	}
+	_, err = m.Clip(20*time.Second, 30*time.Second)
+	require.EqualError(t, err, "Clip range is outside of the presentation: 20s to 30s")
+
+	m.Periods[0].AdaptationSets[2].Representations[0].SegmentList.Duration = nil
+	_, err = m.Clip(time.Second, 2*time.Second)
+	require.EqualError(t, err, `Period "p0": Representation subtitles: Segment times need a SegmentTimeline or @duration`)
+}
+
+func TestPeriodDurations(t *testing.T) {
+	m, err := ReadFromFile("fixtures/clip_source.mpd")
+	require.NoError(t, err)
+	durations, err := m.PeriodDurations()
+	require.NoError(t, err)
+	require.EqualInt(t, 2, len(durations))
+	require.EqualInt(t, int(10*time.Second), int(durations[0]))
+	require.EqualInt(t, int(10*time.Second), int(durations[1]))
+
+	// A Period without @duration lasts until the start of the next one
+	start := Duration(4 * time.Second)
+	m.Periods[0].Duration = 0
+	m.Periods[1].Start = &start
+	durations, err = m.PeriodDurations()
+	require.NoError(t, err)
+	require.EqualInt(t, int(4*time.Second), int(durations[0]))
+	require.EqualInt(t, int(16*time.Second), int(durations[1]))
+
+	m.MediaPresentationDuration = ptrs.Strptr("20 seconds")
+	_, err = m.PeriodDurations()
+	if err == nil {
+		t.Errorf("Expected an invalid mediaPresentationDuration error")
+	}
+}
@@ -0,0 +1,97 @@
+package mpd
+
+import "reflect"
+
+// Clone returns a deep copy of the MPD, which can be modified without affecting the original.
+// ContentProtection elements keep their concrete type, and pointers shared within the MPD, such as
+// the AdaptationSet back-references of Roles, Representations, SegmentTemplates and Accessibility
+// elements, the Period of AdaptationSets or the current Period, point into the copy.
+func (m *MPD) Clone() *MPD {
+	if m == nil {
+		return nil
+	}
+	c := &cloner{copies: map[clonedPointer]reflect.Value{}}
+	clone := c.copy(reflect.ValueOf(m)).Interface().(*MPD)
+	if m.period != nil {
+		clone.period = c.copy(reflect.ValueOf(m.period)).Interface().(*Period)
+	}
+	clone.setParents()
+	return clone
+}
+
+// cloner deep-copies values, copying every pointer once so that shared pointers stay shared.
+// Unexported fields are left zero.
+type cloner struct {
+	copies map[clonedPointer]reflect.Value
+}
+
+type clonedPointer struct {
+	t reflect.Type
+	p uintptr
+}
+
+func (c *cloner) copy(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return reflect.Zero(v.Type())
+		}
+		key := clonedPointer{v.Type(), v.Pointer()}
+		if p, ok := c.copies[key]; ok {
+			return p
+		}
+		p := reflect.New(v.Type().Elem())
+		c.copies[key] = p
+		c.copyInto(p.Elem(), v.Elem())
+		return p
+	case reflect.Interface:
+		if v.IsNil() {
+			return reflect.Zero(v.Type())
+		}
+		i := reflect.New(v.Type()).Elem()
+		i.Set(c.copy(v.Elem()))
+		return i
+	case reflect.Slice:
+		if v.IsNil() {
+			return reflect.Zero(v.Type())
+		}
+		s := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			s.Index(i).Set(c.copy(v.Index(i)))
+		}
+		return s
+	case reflect.Map:
+		if v.IsNil() {
+			return reflect.Zero(v.Type())
+		}
+		m := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			m.SetMapIndex(c.copy(iter.Key()), c.copy(iter.Value()))
+		}
+		return m
+	case reflect.Struct, reflect.Array:
+		s := reflect.New(v.Type()).Elem()
+		c.copyInto(s, v)
+		return s
+	}
+	return v
+}
+
+func (c *cloner) copyInto(dst, src reflect.Value) {
+	switch src.Kind() {
+	case reflect.Struct:
+		t := src.Type()
+		for i := 0; i < t.NumField(); i++ {
+			if t.Field(i).IsExported() {
+				dst.Field(i).Set(c.copy(src.Field(i)))
+			}
+		}
+	case reflect.Array:
+		for i := 0; i < src.Len(); i++ {
+			dst.Index(i).Set(c.copy(src.Index(i)))
+		}
+	default:
+		dst.Set(c.copy(src))
+	}
+}
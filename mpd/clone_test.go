@@ -0,0 +1,122 @@
+package mpd
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	. "github.com/zencoder/go-dash/v3/helpers/ptrs"
+	"github.com/zencoder/go-dash/v3/helpers/require"
+)
+
+func newCloneTestMPD(t *testing.T) *MPD {
+	m := NewMPD(DASH_PROFILE_LIVE, VALID_MEDIA_PRESENTATION_DURATION, VALID_MIN_BUFFER_TIME)
+	as, err := m.AddNewAdaptationSetVideoWithID("1", DASH_MIME_TYPE_VIDEO_MP4, VALID_SCAN_TYPE, VALID_SEGMENT_ALIGNMENT, VALID_START_WITH_SAP)
+	require.NoError(t, err)
+	cenc, err := as.AddNewContentProtectionRoot("08e367028f33436ca5dd60ffe5571e60")
+	require.NoError(t, err)
+	cenc.AdaptationSet = as
+	_, err = as.AddNewContentProtectionSchemeWidevineWithPSSH(getValidWVHeaderBytes())
+	require.NoError(t, err)
+	_, err = as.AddNewRole("urn:mpeg:dash:role:2011", "main")
+	require.NoError(t, err)
+	_, err = as.AddNewAccessibilityElement(ACCESSIBILITY_ELEMENT_SCHEME_DESCRIPTIVE_AUDIO, "1")
+	require.NoError(t, err)
+	_, err = as.SetNewSegmentTemplate(VALID_DURATION, VALID_INIT_PATH_AUDIO, VALID_MEDIA_PATH_AUDIO, VALID_START_NUMBER, VALID_TIMESCALE)
+	require.NoError(t, err)
+	_, err = as.AddNewRepresentationVideo(VALID_VIDEO_BITRATE, VALID_VIDEO_CODEC, VALID_VIDEO_ID, VALID_VIDEO_FRAMERATE, VALID_VIDEO_WIDTH, VALID_VIDEO_HEIGHT)
+	require.NoError(t, err)
+	return m
+}
+
+func TestClone(t *testing.T) {
+	m := newCloneTestMPD(t)
+	clone := m.Clone()
+
+	expected, err := m.WriteToString()
+	require.NoError(t, err)
+	actual, err := clone.WriteToString()
+	require.NoError(t, err)
+	require.EqualString(t, expected, actual)
+	require.EqualInt(t, 0, len(Diff(m, clone)))
+
+	// Back-references point into the copy
+	as := clone.Periods[0].AdaptationSets[0]
+	if as == m.Periods[0].AdaptationSets[0] {
+		t.Errorf("Expected the AdaptationSet to be copied")
+	}
+	if as.Representations[0].AdaptationSet != as || as.Roles[0].AdaptationSet != as ||
+		as.SegmentTemplate.AdaptationSet != as || as.AccessibilityElems[0].AdaptationSet != as {
+		t.Errorf("Expected back-references to the copied AdaptationSet")
+	}
+	if clone.GetCurrentPeriod() != clone.Periods[0] {
+		t.Errorf("Expected the current Period to be the copied one")
+	}
+
+	// ContentProtection keeps its concrete type
+	cenc, ok := as.ContentProtection[0].(*CENCContentProtection)
+	if !ok {
+		t.Fatalf("Expected a *CENCContentProtection, got %T", as.ContentProtection[0])
+	}
+	require.Implements(t, (*ContentProtectioner)(nil), as.ContentProtection[1])
+	if cenc.AdaptationSet != as {
+		t.Errorf("Expected ContentProtection back-reference to the copied AdaptationSet")
+	}
+}
+
+func TestCloneIsIndependent(t *testing.T) {
+	m := newCloneTestMPD(t)
+	expected, err := m.WriteToString()
+	require.NoError(t, err)
+
+	clone := m.Clone()
+	as := clone.Periods[0].AdaptationSets[0]
+	*as.Representations[0].Bandwidth = 1
+	*as.ContentProtection[0].(*CENCContentProtection).DefaultKID = "00000000-0000-0000-0000-000000000000"
+	*as.Roles[0].Value = "alternate"
+	as.SegmentTemplate.Timescale = Int64ptr(90000)
+	_, err = clone.AddNewAdaptationSetAudioWithID("2", DASH_MIME_TYPE_AUDIO_MP4, VALID_SEGMENT_ALIGNMENT, VALID_START_WITH_SAP, VALID_LANG)
+	require.NoError(t, err)
+	clone.AddNewPeriod()
+
+	actual, err := m.WriteToString()
+	require.NoError(t, err)
+	require.EqualString(t, expected, actual)
+	require.EqualInt(t, 1, len(m.Periods[0].AdaptationSets))
+	require.EqualInt(t, 2, len(clone.Periods[0].AdaptationSets))
+}
+
+func TestCloneRead(t *testing.T) {
+	m, err := ReadFromFile("fixtures/live_profile.mpd")
+	require.NoError(t, err)
+	clone := m.Clone()
+	require.EqualInt(t, 0, len(Diff(m, clone)))
+
+	var nilMPD *MPD
+	require.Nil(t, nilMPD.Clone())
+}
+
+// TestCloneConcurrent mutates per-variant copies of a shared manifest, run with -race to detect
+// writes reaching the base manifest.
+func TestCloneConcurrent(t *testing.T) {
+	base := newCloneTestMPD(t)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			variant := base.Clone()
+			as := variant.Periods[0].AdaptationSets[0]
+			*as.Representations[0].Bandwidth = int64(i)
+			*as.ContentProtection[0].(*CENCContentProtection).Value = fmt.Sprintf("variant-%d", i)
+			as.Representations = append(as.Representations, &Representation{ID: Strptr(fmt.Sprintf("%d", i))})
+			variant.Periods[0].BaseURL = []string{fmt.Sprintf("https://cdn%d.example.com/", i)}
+			if _, err := variant.WriteToString(); err != nil {
+				t.Errorf("Writing variant %d: %s", i, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	require.EqualInt(t, 1, len(base.Periods[0].AdaptationSets[0].Representations))
+	require.EqualInt(t, int(VALID_VIDEO_BITRATE), int(*base.Periods[0].AdaptationSets[0].Representations[0].Bandwidth))
+}
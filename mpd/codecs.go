@@ -0,0 +1,26 @@
+package mpd
+
+import (
+	"github.com/zencoder/go-dash/v3/codecs"
+)
+
+// ParseCodecs parses the @codecs of the Representation, or of its AdaptationSet when the
+// Representation has none. Returns nil when neither sets @codecs.
+func (r *Representation) ParseCodecs() ([]*codecs.Codec, error) {
+	s := r.Codecs
+	if (s == nil || *s == "") && r.AdaptationSet != nil {
+		s = r.AdaptationSet.Codecs
+	}
+	if s == nil || *s == "" {
+		return nil, nil
+	}
+	return codecs.ParseList(*s)
+}
+
+// ParseCodecs parses the @codecs of the AdaptationSet. Returns nil when it is not set.
+func (as *AdaptationSet) ParseCodecs() ([]*codecs.Codec, error) {
+	if as.Codecs == nil || *as.Codecs == "" {
+		return nil, nil
+	}
+	return codecs.ParseList(*as.Codecs)
+}
@@ -0,0 +1,86 @@
+package mpd
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/zencoder/go-dash/v3/codecs"
+	. "github.com/zencoder/go-dash/v3/helpers/ptrs"
+	"github.com/zencoder/go-dash/v3/helpers/require"
+)
+
+func TestAddNewRepresentationMalformedCodecs(t *testing.T) {
+	m := NewMPD(DASH_PROFILE_LIVE, VALID_MEDIA_PRESENTATION_DURATION, VALID_MIN_BUFFER_TIME)
+	videoAS, _ := m.AddNewAdaptationSetVideo(DASH_MIME_TYPE_VIDEO_MP4, VALID_SCAN_TYPE, VALID_SEGMENT_ALIGNMENT, VALID_START_WITH_SAP)
+	audioAS, _ := m.AddNewAdaptationSetAudio(DASH_MIME_TYPE_AUDIO_MP4, VALID_SEGMENT_ALIGNMENT, VALID_START_WITH_SAP, VALID_LANG)
+
+	r, err := videoAS.AddNewRepresentationVideo(VALID_VIDEO_BITRATE, "avc1.4d40", VALID_VIDEO_ID, VALID_VIDEO_FRAMERATE, VALID_VIDEO_WIDTH, VALID_VIDEO_HEIGHT)
+	require.Nil(t, r)
+	require.EqualError(t, err, `Malformed codec string "avc1.4d40": profile, constraints and level should be 6 hex digits`)
+
+	r, err = audioAS.AddNewRepresentationAudio(VALID_AUDIO_SAMPLE_RATE, VALID_AUDIO_BITRATE, "mp4a.40.", VALID_AUDIO_ID)
+	require.Nil(t, r)
+	if !errors.Is(err, codecs.ErrMalformed) {
+		t.Errorf("Expected codecs.ErrMalformed, got %v", err)
+	}
+	require.EqualInt(t, 0, len(audioAS.Representations))
+}
+
+func TestAddNewRepresentationWebMCodecs(t *testing.T) {
+	m := NewMPD(DASH_PROFILE_LIVE, VALID_MEDIA_PRESENTATION_DURATION, VALID_MIN_BUFFER_TIME)
+	videoAS, _ := m.AddNewAdaptationSetVideoWithID("1", "video/webm", VALID_SCAN_TYPE, VALID_SEGMENT_ALIGNMENT, VALID_START_WITH_SAP)
+	audioAS, _ := m.AddNewAdaptationSetAudio("audio/webm", VALID_SEGMENT_ALIGNMENT, VALID_START_WITH_SAP, VALID_LANG)
+
+	for _, c := range []string{"vp8", "vp9", "vp09.00.10.08"} {
+		r, err := videoAS.AddNewRepresentationVideo(VALID_VIDEO_BITRATE, c, c, VALID_VIDEO_FRAMERATE, VALID_VIDEO_WIDTH, VALID_VIDEO_HEIGHT)
+		require.NoError(t, err)
+		require.EqualStringPtr(t, Strptr(c), r.Codecs)
+	}
+	for _, c := range []string{"vorbis", "opus", "flac"} {
+		r, err := audioAS.AddNewRepresentationAudio(VALID_AUDIO_SAMPLE_RATE, VALID_AUDIO_BITRATE, c, c)
+		require.NoError(t, err)
+		require.EqualStringPtr(t, Strptr(c), r.Codecs)
+	}
+	trickAS, err := m.AddNewAdaptationSetTrickMode("2", "1", "video/webm", VALID_SCAN_TYPE, VALID_SEGMENT_ALIGNMENT, VALID_START_WITH_SAP)
+	require.NoError(t, err)
+	_, err = trickAS.AddNewRepresentationTrickMode(250000, "vp9", "trick", "1/2", 640, 360, 32, false)
+	require.NoError(t, err)
+	require.NoError(t, m.Validate())
+}
+
+func TestValidateCodecs(t *testing.T) {
+	m := NewMPD(DASH_PROFILE_LIVE, VALID_MEDIA_PRESENTATION_DURATION, VALID_MIN_BUFFER_TIME)
+	videoAS, _ := m.AddNewAdaptationSetVideoWithID("7357", DASH_MIME_TYPE_VIDEO_MP4, VALID_SCAN_TYPE, VALID_SEGMENT_ALIGNMENT, VALID_START_WITH_SAP)
+	r, _ := videoAS.AddNewRepresentationVideo(VALID_VIDEO_BITRATE, VALID_VIDEO_CODEC, VALID_VIDEO_ID, VALID_VIDEO_FRAMERATE, VALID_VIDEO_WIDTH, VALID_VIDEO_HEIGHT)
+	require.NoError(t, m.Validate())
+
+	r.Codecs = Strptr("hvc1.1.6.93")
+	require.EqualError(t, m.Validate(), `Representation 800: Malformed codec string "hvc1.1.6.93": tier should be L or H`)
+
+	r.Codecs = nil
+	videoAS.Codecs = Strptr("avc1.4d401f,")
+	require.EqualError(t, m.Validate(), `AdaptationSet 7357: Malformed codec string "": elements should be non-empty and alphanumeric`)
+}
+
+func TestParseCodecs(t *testing.T) {
+	m := NewMPD(DASH_PROFILE_LIVE, VALID_MEDIA_PRESENTATION_DURATION, VALID_MIN_BUFFER_TIME)
+	audioAS, _ := m.AddNewAdaptationSetAudio(DASH_MIME_TYPE_AUDIO_MP4, VALID_SEGMENT_ALIGNMENT, VALID_START_WITH_SAP, VALID_LANG)
+	r, _ := audioAS.AddNewRepresentationAudio(VALID_AUDIO_SAMPLE_RATE, VALID_AUDIO_BITRATE, VALID_AUDIO_CODEC, VALID_AUDIO_ID)
+
+	list, err := r.ParseCodecs()
+	require.NoError(t, err)
+	require.EqualInt(t, 1, len(list))
+	require.EqualString(t, string(codecs.FAMILY_MPEG4_AUDIO), string(list[0].Family))
+	require.EqualInt(t, 2, list[0].Profile)
+
+	list, err = audioAS.ParseCodecs()
+	require.NoError(t, err)
+	require.EqualInt(t, 0, len(list))
+
+	// Inherited from the AdaptationSet
+	audioAS.Codecs = Strptr("ec-3")
+	r.Codecs = nil
+	list, err = r.ParseCodecs()
+	require.NoError(t, err)
+	require.EqualString(t, "ec-3", list[0].String())
+}
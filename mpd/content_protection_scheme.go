@@ -0,0 +1,81 @@
+package mpd
+
+import (
+	"encoding/xml"
+	"strings"
+	"sync"
+)
+
+var (
+	contentProtectionSchemesMu sync.RWMutex
+	contentProtectionSchemes   = map[string]func() ContentProtectioner{}
+)
+
+func init() {
+	RegisterContentProtectionScheme(CONTENT_PROTECTION_ROOT_SCHEME_ID_URI, func() ContentProtectioner {
+		return &CENCContentProtection{}
+	})
+	RegisterContentProtectionScheme(CONTENT_PROTECTION_WIDEVINE_SCHEME_ID, func() ContentProtectioner {
+		return &WidevineContentProtection{}
+	})
+	RegisterContentProtectionScheme(CONTENT_PROTECTION_PLAYREADY_SCHEME_ID, func() ContentProtectioner {
+		return &PlayreadyContentProtection{}
+	})
+	RegisterContentProtectionScheme(CONTENT_PROTECTION_PLAYREADY_SCHEME_V10_ID, func() ContentProtectioner {
+		return &PlayreadyContentProtection{}
+	})
+}
+
+// RegisterContentProtectionScheme makes a ContentProtection type available for decoding.
+// Any ContentProtection element whose schemeIdUri matches schemeIDURI (case-insensitively)
+// will be decoded into the value returned by factory, at both AdaptationSet and Representation
+// level. Registering a scheme that already exists replaces the previous factory, which allows
+// the built-in Widevine, PlayReady and CENC types to be overridden.
+// schemeIDURI - Scheme ID URI of the DRM system (i.e. urn:uuid:edef8ba9-79d6-4ace-a3c8-27dcd51d21ed).
+// factory - returns a new, empty value to decode into. The value must be a pointer.
+func RegisterContentProtectionScheme(schemeIDURI string, factory func() ContentProtectioner) {
+	if factory == nil {
+		panic("mpd: RegisterContentProtectionScheme factory is nil")
+	}
+	contentProtectionSchemesMu.Lock()
+	defer contentProtectionSchemesMu.Unlock()
+	contentProtectionSchemes[strings.ToLower(schemeIDURI)] = factory
+}
+
+// unregisterContentProtectionScheme removes a scheme, so that its ContentProtection elements are
+// decoded into the generic ContentProtection type again.
+func unregisterContentProtectionScheme(schemeIDURI string) {
+	contentProtectionSchemesMu.Lock()
+	defer contentProtectionSchemesMu.Unlock()
+	delete(contentProtectionSchemes, strings.ToLower(schemeIDURI))
+}
+
+// newContentProtection returns an empty ContentProtectioner for the given scheme, falling back
+// to the generic ContentProtection type when no scheme has been registered.
+func newContentProtection(schemeIDURI string) ContentProtectioner {
+	contentProtectionSchemesMu.RLock()
+	factory, ok := contentProtectionSchemes[strings.ToLower(schemeIDURI)]
+	contentProtectionSchemesMu.RUnlock()
+	if !ok {
+		return &ContentProtection{}
+	}
+	return factory()
+}
+
+type contentProtections []ContentProtectioner
+
+func (as *contentProtections) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var scheme string
+	for _, a := range start.Attr {
+		if a.Name.Local == "schemeIdUri" {
+			scheme = a.Value
+			break
+		}
+	}
+	target := newContentProtection(scheme)
+	if err := d.DecodeElement(target, &start); err != nil {
+		return err
+	}
+	*as = append(*as, target)
+	return nil
+}
@@ -0,0 +1,98 @@
+package mpd
+
+import (
+	"encoding/xml"
+	"fmt"
+	"testing"
+
+	. "github.com/zencoder/go-dash/v3/helpers/ptrs"
+	"github.com/zencoder/go-dash/v3/helpers/require"
+)
+
+const testVendorSchemeIDURI = "urn:uuid:00000000-1111-2222-3333-444444444444"
+
+type testVendorContentProtection struct {
+	XMLName     xml.Name `xml:"ContentProtection"`
+	SchemeIDURI *string  `xml:"schemeIdUri,attr"`
+	Value       *string  `xml:"value,attr,omitempty"`
+	LicenseURL  *string  `xml:"LicenseURL,omitempty"`
+}
+
+func (s *testVendorContentProtection) ContentProtected() {}
+
+func TestRegisterContentProtectionScheme(t *testing.T) {
+	RegisterContentProtectionScheme(testVendorSchemeIDURI, func() ContentProtectioner {
+		return &testVendorContentProtection{}
+	})
+	t.Cleanup(func() { unregisterContentProtectionScheme(testVendorSchemeIDURI) })
+
+	in := `<?xml version="1.0" encoding="UTF-8"?>
+<MPD xmlns="urn:mpeg:dash:schema:mpd:2011" profiles="urn:mpeg:dash:profile:isoff-live:2011" type="static" mediaPresentationDuration="PT6M16S" minBufferTime="PT1.97S">
+  <Period>
+    <AdaptationSet mimeType="video/mp4" id="1">
+      <ContentProtection schemeIdUri="URN:UUID:00000000-1111-2222-3333-444444444444" value="vendor">
+        <LicenseURL>https://license.example.com/as</LicenseURL>
+      </ContentProtection>
+      <Representation bandwidth="1518664" codecs="avc1.4d401f" height="540" id="800" width="960">
+        <ContentProtection schemeIdUri="urn:uuid:00000000-1111-2222-3333-444444444444" value="vendor">
+          <LicenseURL>https://license.example.com/800</LicenseURL>
+        </ContentProtection>
+      </Representation>
+    </AdaptationSet>
+  </Period>
+</MPD>
+`
+	m, err := ReadFromString(in)
+	require.NoError(t, err)
+
+	as := m.Periods[0].AdaptationSets[0]
+	require.EqualInt(t, 1, len(as.ContentProtection))
+	asCP, ok := as.ContentProtection[0].(*testVendorContentProtection)
+	if !ok {
+		t.Fatalf("Expected *testVendorContentProtection, got %T", as.ContentProtection[0])
+	}
+	require.EqualStringPtr(t, Strptr("https://license.example.com/as"), asCP.LicenseURL)
+
+	r := as.Representations[0]
+	require.EqualInt(t, 1, len(r.ContentProtection))
+	rCP, ok := r.ContentProtection[0].(*testVendorContentProtection)
+	if !ok {
+		t.Fatalf("Expected *testVendorContentProtection, got %T", r.ContentProtection[0])
+	}
+	require.EqualStringPtr(t, Strptr("https://license.example.com/800"), rCP.LicenseURL)
+
+	out, err := m.WriteToString()
+	require.NoError(t, err)
+	require.EqualString(t, in, out)
+}
+
+func TestBuiltinContentProtectionSchemes(t *testing.T) {
+	testCases := []struct {
+		scheme   string
+		expected string
+	}{
+		{scheme: CONTENT_PROTECTION_ROOT_SCHEME_ID_URI, expected: "*mpd.CENCContentProtection"},
+		{scheme: CONTENT_PROTECTION_WIDEVINE_SCHEME_ID, expected: "*mpd.WidevineContentProtection"},
+		{scheme: CONTENT_PROTECTION_PLAYREADY_SCHEME_ID, expected: "*mpd.PlayreadyContentProtection"},
+		{scheme: CONTENT_PROTECTION_PLAYREADY_SCHEME_V10_ID, expected: "*mpd.PlayreadyContentProtection"},
+		{scheme: "urn:uuid:unknown", expected: "*mpd.ContentProtection"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.scheme, func(t *testing.T) {
+			require.EqualString(t, tc.expected, typeName(newContentProtection(tc.scheme)))
+		})
+	}
+}
+
+func TestRegisterContentProtectionSchemeNilFactory(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Expected RegisterContentProtectionScheme to panic on a nil factory")
+		}
+	}()
+	RegisterContentProtectionScheme(testVendorSchemeIDURI, nil)
+}
+
+func typeName(v interface{}) string {
+	return fmt.Sprintf("%T", v)
+}
@@ -0,0 +1,473 @@
+package mpd
+
+import (
+	"encoding/xml"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Type definition for the kind of a Change between two MPDs
+type ChangeType string
+
+// Constants for the kinds of Change reported by Diff
+const (
+	CHANGE_ADDED             ChangeType = "added"
+	CHANGE_REMOVED           ChangeType = "removed"
+	CHANGE_MODIFIED          ChangeType = "modified"
+	CHANGE_SEGMENTS_APPENDED ChangeType = "segments appended"
+	CHANGE_SEGMENTS_REMOVED  ChangeType = "segments removed"
+)
+
+// Change is a single difference between two MPDs.
+type Change struct {
+	// Path of the element or attribute (i.e. Period[id=p0]/AdaptationSet[id=1]/Representation[id=800]@bandwidth)
+	Path string
+	Type ChangeType
+	Old  string // Previous value, empty for added elements and attributes
+	New  string // New value, empty for removed elements and attributes
+}
+
+func (c Change) String() string {
+	s := string(c.Type) + " " + c.Path
+	switch {
+	case c.Type == CHANGE_MODIFIED:
+		return s + ": " + c.Old + " -> " + c.New
+	case c.New != "":
+		return s + ": " + c.New
+	case c.Old != "":
+		return s + ": " + c.Old
+	}
+	return s
+}
+
+// Diff returns the changes from a to b. Elements are matched by their id, or by another natural key
+// (schemeIdUri for descriptors, contentType and lang for AdaptationSets, presentationTime for
+// Events) where they have none, and by position otherwise. SegmentTimeline changes are summarized
+// as segments appended or removed.
+// a - previous MPD, nil for an empty one.
+// b - new MPD, nil for an empty one.
+func Diff(a, b *MPD) []Change {
+	if a == nil {
+		a = &MPD{}
+	}
+	if b == nil {
+		b = &MPD{}
+	}
+	d := &differ{}
+	d.element("", reflect.ValueOf(a).Elem(), reflect.ValueOf(b).Elem())
+	return d.changes
+}
+
+type differ struct {
+	changes []Change
+}
+
+func (d *differ) add(path string, t ChangeType, oldValue, newValue string) {
+	d.changes = append(d.changes, Change{Path: path, Type: t, Old: oldValue, New: newValue})
+}
+
+// element compares two elements, which may be of different types (i.e. ContentProtection variants).
+func (d *differ) element(path string, a, b reflect.Value) {
+	if a.Type() == segmentTimelineType && b.Type() == segmentTimelineType && d.segmentTimeline(path, a, b) {
+		return
+	}
+
+	na, nb := newNode(a), newNode(b)
+	for _, name := range union(na.attrNames, nb.attrNames) {
+		oldValue, inA := na.attrs[name]
+		newValue, inB := nb.attrs[name]
+		switch {
+		case !inB:
+			d.add(path+"@"+name, CHANGE_REMOVED, oldValue, "")
+		case !inA:
+			d.add(path+"@"+name, CHANGE_ADDED, "", newValue)
+		case oldValue != newValue:
+			d.add(path+"@"+name, CHANGE_MODIFIED, oldValue, newValue)
+		}
+	}
+	for _, name := range union(na.childNames, nb.childNames) {
+		d.children(childPath(path, name), name, na.children[name], nb.children[name], na.multiple[name] || nb.multiple[name])
+	}
+}
+
+// children compares the child elements of the same name, matching them by key.
+func (d *differ) children(path, name string, as, bs []reflect.Value, multiple bool) {
+	if isLeaf(as) || isLeaf(bs) {
+		d.leaves(path, as, bs)
+		return
+	}
+
+	ka, kb := itemKeys(name, as, multiple), itemKeys(name, bs, multiple)
+	inB := make(map[string]int, len(kb))
+	for j, k := range kb {
+		inB[k] = j
+	}
+	inA := make(map[string]bool, len(ka))
+	for i, k := range ka {
+		inA[k] = true
+		if j, ok := inB[k]; ok {
+			d.element(path+k, as[i], bs[j])
+		} else {
+			d.add(path+k, CHANGE_REMOVED, "", "")
+		}
+	}
+	for _, k := range kb {
+		if !inA[k] {
+			d.add(path+k, CHANGE_ADDED, "", "")
+		}
+	}
+}
+
+// leaves compares text elements (i.e. BaseURL) by value.
+func (d *differ) leaves(path string, as, bs []reflect.Value) {
+	if len(as) == 1 && len(bs) == 1 {
+		if oldValue, newValue := fmt.Sprint(as[0].Interface()), fmt.Sprint(bs[0].Interface()); oldValue != newValue {
+			d.add(path, CHANGE_MODIFIED, oldValue, newValue)
+		}
+		return
+	}
+
+	remaining := map[string]int{}
+	for _, v := range bs {
+		remaining[fmt.Sprint(v.Interface())]++
+	}
+	for _, v := range as {
+		s := fmt.Sprint(v.Interface())
+		if remaining[s] > 0 {
+			remaining[s]--
+			continue
+		}
+		d.add(path, CHANGE_REMOVED, s, "")
+	}
+	for _, v := range bs {
+		s := fmt.Sprint(v.Interface())
+		if remaining[s] > 0 {
+			remaining[s]--
+			d.add(path, CHANGE_ADDED, "", s)
+		}
+	}
+}
+
+var segmentTimelineType = reflect.TypeOf(SegmentTimeline{})
+
+// segmentTimeline reports the segments removed from the start, removed from the end and appended
+// to a SegmentTimeline, or a single modification when the segments they share differ. Returns false
+// when a timeline can't be expanded, to compare its S elements one by one instead.
+func (d *differ) segmentTimeline(path string, a, b reflect.Value) bool {
+	stlA, stlB := a.Interface().(SegmentTimeline), b.Interface().(SegmentTimeline)
+	sa, err := expandTimeline(&stlA, 0, 0, 0)
+	if err != nil {
+		return false
+	}
+	sb, err := expandTimeline(&stlB, 0, 0, 0)
+	if err != nil {
+		return false
+	}
+
+	// Position in a of the first segment of b
+	start := -1
+	switch {
+	case len(sa) == 0:
+		start = 0
+	case len(sb) == 0:
+		start = len(sa)
+	default:
+		for i := range sa {
+			if sa[i].Time == sb[0].Time {
+				start = i
+				break
+			}
+		}
+	}
+	if start < 0 {
+		d.add(path, CHANGE_MODIFIED, describeSegments(sa), describeSegments(sb))
+		return true
+	}
+
+	overlap := min(len(sa)-start, len(sb))
+	for i := 0; i < overlap; i++ {
+		if sa[start+i].Time != sb[i].Time || sa[start+i].Duration != sb[i].Duration {
+			d.add(path, CHANGE_MODIFIED, describeSegments(sa), describeSegments(sb))
+			return true
+		}
+	}
+	if start > 0 {
+		d.add(path, CHANGE_SEGMENTS_REMOVED, describeSegments(sa[:start]), "")
+	}
+	if start+overlap < len(sa) {
+		d.add(path, CHANGE_SEGMENTS_REMOVED, describeSegments(sa[start+overlap:]), "")
+	}
+	if overlap < len(sb) {
+		d.add(path, CHANGE_SEGMENTS_APPENDED, "", describeSegments(sb[overlap:]))
+	}
+	return true
+}
+
+// describeSegments summarizes a run of segments (i.e. 2 segments, t=360000 to 720000).
+func describeSegments(segments []MediaSegment) string {
+	if len(segments) == 0 {
+		return "0 segments"
+	}
+	noun := "segments"
+	if len(segments) == 1 {
+		noun = "segment"
+	}
+	last := segments[len(segments)-1]
+	return fmt.Sprintf("%d %s, t=%d to %d", len(segments), noun, segments[0].Time, last.Time+last.Duration)
+}
+
+// itemKeys returns the path suffix identifying each element within its siblings of the same name.
+// Duplicate keys get their occurrence appended (i.e. [id=1][2]).
+func itemKeys(name string, items []reflect.Value, multiple bool) []string {
+	keys := make([]string, len(items))
+	counts := map[string]int{}
+	for i, v := range items {
+		k := ""
+		if multiple {
+			k = itemKey(name, v, i)
+		}
+		counts[k]++
+		if counts[k] > 1 {
+			k += fmt.Sprintf("[%d]", counts[k])
+		}
+		keys[i] = k
+	}
+	return keys
+}
+
+func itemKey(name string, v reflect.Value, i int) string {
+	position := fmt.Sprintf("[%d]", i+1)
+	if name == "S" || name == "SegmentURL" {
+		return position
+	}
+
+	attrs := newNode(v).attrs
+	if id := attrs["id"]; id != "" {
+		return "[id=" + id + "]"
+	}
+	var keyAttrs []string
+	switch name {
+	case "AdaptationSet":
+		if attrs["contentType"] != "" {
+			keyAttrs = []string{"contentType", "lang"}
+		} else {
+			keyAttrs = []string{"mimeType", "lang"}
+		}
+	case "Event":
+		keyAttrs = []string{"presentationTime"}
+	default:
+		keyAttrs = []string{"schemeIdUri"}
+	}
+	var parts []string
+	for _, a := range keyAttrs {
+		if value, ok := attrs[a]; ok {
+			parts = append(parts, a+"="+value)
+		}
+	}
+	if len(parts) == 0 {
+		return position
+	}
+	return "[" + strings.Join(parts, ",") + "]"
+}
+
+// node holds the attributes and child elements of an element, as they would be marshalled.
+type node struct {
+	attrNames  []string
+	attrs      map[string]string
+	childNames []string
+	children   map[string][]reflect.Value // Structs, or values of text elements
+	multiple   map[string]bool            // Child elements from a slice
+}
+
+func newNode(v reflect.Value) *node {
+	n := &node{attrs: map[string]string{}, children: map[string][]reflect.Value{}, multiple: map[string]bool{}}
+	for _, f := range xmlFields(v.Type()) {
+		fv := v.FieldByIndex(f.index)
+		switch f.kind {
+		case fieldAttr:
+			if s, ok := attrString(fv, f.omitEmpty); ok {
+				n.addAttr(f.name, s)
+			}
+		case fieldAnyAttr:
+			for _, a := range fv.Interface().([]*xml.Attr) {
+				if a != nil {
+					name := a.Name.Local
+					if a.Name.Space != "" {
+						name = a.Name.Space + ":" + name
+					}
+					n.addAttr(name, a.Value)
+				}
+			}
+		case fieldText, fieldElement:
+			name := f.name
+			if f.kind == fieldText {
+				name = "text()"
+			}
+			if fv.Kind() == reflect.Slice {
+				n.multiple[name] = true
+				for i := 0; i < fv.Len(); i++ {
+					n.addChild(name, fv.Index(i), false)
+				}
+			} else {
+				n.addChild(name, fv, f.omitEmpty)
+			}
+		}
+	}
+	return n
+}
+
+func (n *node) addAttr(name, value string) {
+	if _, ok := n.attrs[name]; !ok {
+		n.attrNames = append(n.attrNames, name)
+	}
+	n.attrs[name] = value
+}
+
+func (n *node) addChild(name string, v reflect.Value, omitEmpty bool) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+	if omitEmpty && v.IsZero() {
+		return
+	}
+	if _, ok := n.children[name]; !ok {
+		n.childNames = append(n.childNames, name)
+	}
+	n.children[name] = append(n.children[name], v)
+}
+
+// attrString formats an attribute value, using its MarshalXMLAttr where it has one.
+func attrString(v reflect.Value, omitEmpty bool) (string, bool) {
+	if (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && v.IsNil() {
+		return "", false
+	}
+	if omitEmpty && v.IsZero() {
+		return "", false
+	}
+	if v.Kind() != reflect.Ptr {
+		p := reflect.New(v.Type())
+		p.Elem().Set(v)
+		v = p
+	}
+	if m, ok := v.Interface().(xml.MarshalerAttr); ok {
+		if a, err := m.MarshalXMLAttr(xml.Name{}); err == nil {
+			return a.Value, true
+		}
+	}
+	return fmt.Sprint(v.Elem().Interface()), true
+}
+
+type fieldKind int
+
+const (
+	fieldAttr fieldKind = iota
+	fieldAnyAttr
+	fieldText
+	fieldElement
+)
+
+type xmlField struct {
+	name      string
+	kind      fieldKind
+	index     []int
+	omitEmpty bool
+}
+
+// xmlFields lists the marshalled fields of a struct, including the ones of embedded structs unless
+// a shallower field has the same name, as encoding/xml does.
+func xmlFields(t reflect.Type) []xmlField {
+	var fields []xmlField
+	seen := map[string]bool{}
+	level := [][]int{nil}
+	for len(level) > 0 {
+		var next [][]int
+		for _, index := range level {
+			st := t
+			if len(index) > 0 {
+				st = t.FieldByIndex(index).Type
+			}
+			for i := 0; i < st.NumField(); i++ {
+				sf := st.Field(i)
+				tag := sf.Tag.Get("xml")
+				if !sf.IsExported() || tag == "-" || sf.Name == "XMLName" {
+					continue
+				}
+				fieldIndex := append(append([]int(nil), index...), i)
+				if sf.Anonymous && tag == "" && sf.Type.Kind() == reflect.Struct {
+					next = append(next, fieldIndex)
+					continue
+				}
+
+				f := xmlField{name: sf.Name, kind: fieldElement, index: fieldIndex}
+				parts := strings.Split(tag, ",")
+				if parts[0] != "" {
+					f.name = parts[0][strings.LastIndex(parts[0], " ")+1:]
+				}
+				for _, flag := range parts[1:] {
+					switch flag {
+					case "attr":
+						if f.kind == fieldElement {
+							f.kind = fieldAttr
+						}
+					case "any":
+						f.kind = fieldAnyAttr
+					case "chardata", "innerxml":
+						f.kind = fieldText
+					case "omitempty":
+						f.omitEmpty = true
+					}
+				}
+				if f.kind == fieldAnyAttr && !strings.Contains(tag, "attr") {
+					continue
+				}
+				key := fmt.Sprintf("%d %s", f.kind, f.name)
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+				fields = append(fields, f)
+			}
+		}
+		level = next
+	}
+	return fields
+}
+
+func isLeaf(values []reflect.Value) bool {
+	return len(values) > 0 && values[0].Kind() != reflect.Struct
+}
+
+func childPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "/" + name
+}
+
+// union returns the names of a, with the ones only in b inserted after the name preceding them in b.
+func union(a, b []string) []string {
+	names := append([]string(nil), a...)
+	at := 0
+	for _, name := range b {
+		i := indexOf(names, name)
+		if i < 0 {
+			names = append(names[:at], append([]string{name}, names[at:]...)...)
+			i = at
+		}
+		at = i + 1
+	}
+	return names
+}
+
+func indexOf(names []string, name string) int {
+	for i, n := range names {
+		if n == name {
+			return i
+		}
+	}
+	return -1
+}
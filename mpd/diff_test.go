@@ -0,0 +1,109 @@
+package mpd
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/zencoder/go-dash/v3/helpers/ptrs"
+	"github.com/zencoder/go-dash/v3/helpers/require"
+)
+
+func TestDiffIdentical(t *testing.T) {
+	for _, fixture := range []string{
+		"fixtures/live_profile.mpd",
+		"fixtures/ondemand_profile.mpd",
+		"fixtures/segment_list.mpd",
+		"fixtures/segment_timeline_multi_period.mpd",
+		"fixtures/events.mpd",
+		"fixtures/scte35.mpd",
+	} {
+		a, err := ReadFromFile(fixture)
+		require.NoError(t, err)
+		b, err := ReadFromFile(fixture)
+		require.NoError(t, err)
+		require.EqualInt(t, 0, len(Diff(a, b)))
+	}
+}
+
+func TestDiffAttributes(t *testing.T) {
+	a, err := ReadFromFile("fixtures/live_profile.mpd")
+	require.NoError(t, err)
+	b, err := ReadFromFile("fixtures/live_profile.mpd")
+	require.NoError(t, err)
+
+	b.Type = Strptr("dynamic")
+	b.Periods[0].AdaptationSets[1].Representations[0].Bandwidth = Int64ptr(1600000)
+	b.Periods[0].AdaptationSets[0].Lang = nil
+	b.BaseURL = []string{"https://cdn.example.com/"}
+
+	require.EqualString(t, `modified @type: static -> dynamic
+added BaseURL: https://cdn.example.com/
+removed Period[1]/AdaptationSet[id=7357]@lang: en
+modified Period[1]/AdaptationSet[id=7357][2]/Representation[id=800]@bandwidth: 1518664 -> 1600000`, diffString(Diff(a, b)))
+}
+
+func TestDiffMatchesByKey(t *testing.T) {
+	a, err := ReadFromFile("fixtures/segment_timeline.mpd")
+	require.NoError(t, err)
+	b, err := ReadFromFile("fixtures/segment_timeline.mpd")
+	require.NoError(t, err)
+
+	// Reordered AdaptationSets are matched by id
+	as := b.Periods[0].AdaptationSets
+	as[0], as[1] = as[1], as[0]
+	require.EqualInt(t, 0, len(Diff(a, b)))
+
+	as[1].Representations = nil
+	as[0].Representations = append(as[0].Representations, &Representation{ID: Strptr("video_2"), Bandwidth: Int64ptr(2000000)})
+	require.EqualString(t, `removed Period[1]/AdaptationSet[id=1]/Representation[id=audio_1]
+added Period[1]/AdaptationSet[id=2]/Representation[id=video_2]`, diffString(Diff(a, b)))
+}
+
+func TestDiffSegmentTimeline(t *testing.T) {
+	a := NewDynamicMPD(DASH_PROFILE_LIVE, "1970-01-01T00:00:00Z", "PT2S")
+	as, _ := a.AddNewAdaptationSetVideo(DASH_MIME_TYPE_VIDEO_MP4, "progressive", true, 1)
+	_, _ = as.SetNewSegmentTemplate(2, "$RepresentationID$/init.mp4", "$RepresentationID$/$Time$.m4s", 0, 1)
+	as.SegmentTemplate.Duration = nil
+	as.SegmentTemplate.SegmentTimeline = &SegmentTimeline{Segments: []*SegmentTimelineSegment{
+		{StartTime: Uint64ptr(0), Duration: 2, RepeatCount: Intptr(3)},
+	}}
+
+	// Sliding window, 2 segments out and 2 in
+	b, err := ReadFromString(mustWrite(t, a))
+	require.NoError(t, err)
+	b.Periods[0].AdaptationSets[0].SegmentTemplate.SegmentTimeline.Segments = []*SegmentTimelineSegment{
+		{StartTime: Uint64ptr(4), Duration: 2, RepeatCount: Intptr(1)},
+		{Duration: 2, RepeatCount: Intptr(1)},
+	}
+	require.EqualString(t, `segments removed Period[1]/AdaptationSet[mimeType=video/mp4]/SegmentTemplate/SegmentTimeline: 2 segments, t=0 to 4
+segments appended Period[1]/AdaptationSet[mimeType=video/mp4]/SegmentTemplate/SegmentTimeline: 2 segments, t=8 to 12`, diffString(Diff(a, b)))
+
+	// Segments that don't line up
+	b.Periods[0].AdaptationSets[0].SegmentTemplate.SegmentTimeline.Segments = []*SegmentTimelineSegment{
+		{StartTime: Uint64ptr(0), Duration: 3},
+	}
+	require.EqualString(t, `modified Period[1]/AdaptationSet[mimeType=video/mp4]/SegmentTemplate/SegmentTimeline: 4 segments, t=0 to 8 -> 1 segment, t=0 to 3`, diffString(Diff(a, b)))
+}
+
+func TestDiffNil(t *testing.T) {
+	m := NewMPD(DASH_PROFILE_ONDEMAND, "PT30S", "PT2S")
+	changes := Diff(nil, m)
+	require.EqualInt(t, 6, len(changes))
+	require.EqualString(t, "added @xmlns: urn:mpeg:dash:schema:mpd:2011", changes[0].String())
+	require.EqualString(t, "added Period[1]", changes[5].String())
+	require.EqualInt(t, 0, len(Diff(nil, nil)))
+}
+
+func diffString(changes []Change) string {
+	lines := make([]string, len(changes))
+	for i, c := range changes {
+		lines[i] = c.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+func mustWrite(t *testing.T, m *MPD) string {
+	s, err := m.WriteToString()
+	require.NoError(t, err)
+	return s
+}
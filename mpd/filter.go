@@ -0,0 +1,409 @@
+package mpd
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/zencoder/go-dash/v3/codecs"
+)
+
+// Constants for the CICP descriptors signalling the colour properties of video
+const (
+	CICP_TRANSFER_CHARACTERISTICS_SCHEME_ID = "urn:mpeg:mpegB:cicp:TransferCharacteristics"
+	CICP_COLOUR_PRIMARIES_SCHEME_ID         = "urn:mpeg:mpegB:cicp:ColourPrimaries"
+)
+
+// RepresentationFilter reports whether a Representation of an AdaptationSet should be kept.
+type RepresentationFilter func(as *AdaptationSet, r *Representation) bool
+
+// Filter returns a copy of the MPD with only the Representations matching all the filters.
+// AdaptationSets left without Representations are removed, and the @minBandwidth, @maxBandwidth,
+// @minWidth, @maxWidth, @minHeight and @maxHeight they have are updated to the remaining
+// Representations. The MPD itself is not modified.
+func (m *MPD) Filter(filters ...RepresentationFilter) *MPD {
+	filtered := m.Clone()
+	if filtered == nil {
+		return nil
+	}
+	for _, period := range filtered.Periods {
+		if period == nil {
+			continue
+		}
+		var adaptationSets []*AdaptationSet
+		for _, as := range period.AdaptationSets {
+			if as == nil {
+				continue
+			}
+			if len(as.Representations) == 0 {
+				adaptationSets = append(adaptationSets, as)
+				continue
+			}
+			var representations []*Representation
+			for _, r := range as.Representations {
+				if r != nil && matchAll(filters, as, r) {
+					representations = append(representations, r)
+				}
+			}
+			if len(representations) == 0 {
+				continue
+			}
+			as.Representations = representations
+			as.updateRanges()
+			adaptationSets = append(adaptationSets, as)
+		}
+		period.AdaptationSets = adaptationSets
+	}
+	return filtered
+}
+
+// SelectRepresentations returns the Representations of the MPD matching all the filters, in
+// document order.
+func (m *MPD) SelectRepresentations(filters ...RepresentationFilter) []*Representation {
+	var selected []*Representation
+	for _, period := range m.Periods {
+		if period == nil {
+			continue
+		}
+		for _, as := range period.AdaptationSets {
+			if as == nil {
+				continue
+			}
+			for _, r := range as.Representations {
+				if r != nil && matchAll(filters, as, r) {
+					selected = append(selected, r)
+				}
+			}
+		}
+	}
+	return selected
+}
+
+// Not keeps the Representations the filter removes, to select the ones to drop.
+func Not(f RepresentationFilter) RepresentationFilter {
+	return func(as *AdaptationSet, r *Representation) bool {
+		return !f(as, r)
+	}
+}
+
+func matchAll(filters []RepresentationFilter, as *AdaptationSet, r *Representation) bool {
+	for _, f := range filters {
+		if !f(as, r) {
+			return false
+		}
+	}
+	return true
+}
+
+// updateRanges recomputes the bandwidth and resolution ranges the AdaptationSet signals.
+func (as *AdaptationSet) updateRanges() {
+	var bandwidths, widths, heights []int64
+	for _, r := range as.Representations {
+		if r.Bandwidth != nil {
+			bandwidths = append(bandwidths, *r.Bandwidth)
+		}
+		if r.Width != nil {
+			widths = append(widths, *r.Width)
+		}
+		if r.Height != nil {
+			heights = append(heights, *r.Height)
+		}
+	}
+	updateRange(as.MinBandwidth, as.MaxBandwidth, bandwidths)
+	updateRange(as.MinWidth, as.MaxWidth, widths)
+	updateRange(as.MinHeight, as.MaxHeight, heights)
+}
+
+// updateRange sets the min and max attributes that are present to the range of values.
+func updateRange(minAttr, maxAttr *string, values []int64) {
+	if len(values) == 0 {
+		return
+	}
+	lo, hi := values[0], values[0]
+	for _, v := range values[1:] {
+		lo = min(lo, v)
+		hi = max(hi, v)
+	}
+	if minAttr != nil {
+		*minAttr = strconv.FormatInt(lo, 10)
+	}
+	if maxAttr != nil {
+		*maxAttr = strconv.FormatInt(hi, 10)
+	}
+}
+
+// WithCodecFamilies keeps the Representations whose codecs all belong to one of the families.
+// Representations without @codecs are kept, the ones with an invalid @codecs are removed.
+// families - supported codec families (i.e. codecs.FAMILY_AVC, codecs.FAMILY_MPEG4_AUDIO).
+func WithCodecFamilies(families ...codecs.Family) RepresentationFilter {
+	return func(as *AdaptationSet, r *Representation) bool {
+		list, err := r.withParent(as).ParseCodecs()
+		if err != nil {
+			return false
+		}
+		for _, c := range list {
+			if !containsFamily(families, c.Family) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// WithCodecProfiles keeps the Representations whose codecs of the family have one of the
+// profiles. Codecs of other families, and Representations without @codecs, are kept. The ones
+// with an invalid @codecs are removed.
+// family - codec family the profiles apply to (i.e. codecs.FAMILY_AVC).
+// profiles - profiles as written in the codec string, see codecs.Codec (i.e. 100 for AVC High).
+func WithCodecProfiles(family codecs.Family, profiles ...int) RepresentationFilter {
+	return withCodecs(family, func(c *codecs.Codec) bool {
+		for _, p := range profiles {
+			if c.Profile == p {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// WithMaxCodecLevel keeps the Representations whose codecs of the family have a level up to level
+// inclusive. Codecs of other families, and Representations without @codecs, are kept. The ones
+// with an invalid @codecs are removed.
+// family - codec family the level applies to (i.e. codecs.FAMILY_HEVC).
+// level - level as written in the codec string, see codecs.Codec (i.e. 41 for AVC 4.1, 123 for HEVC 4.1).
+func WithMaxCodecLevel(family codecs.Family, level int) RepresentationFilter {
+	return withCodecs(family, func(c *codecs.Codec) bool {
+		return c.Level <= level
+	})
+}
+
+// withCodecs keeps the Representations whose codecs of the family all match.
+func withCodecs(family codecs.Family, match func(c *codecs.Codec) bool) RepresentationFilter {
+	return func(as *AdaptationSet, r *Representation) bool {
+		list, err := r.withParent(as).ParseCodecs()
+		if err != nil {
+			return false
+		}
+		for _, c := range list {
+			if c.Family == family && !match(c) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+func containsFamily(families []codecs.Family, family codecs.Family) bool {
+	for _, f := range families {
+		if f == family {
+			return true
+		}
+	}
+	return false
+}
+
+// WithBandwidth keeps the Representations with a @bandwidth between minBandwidth and maxBandwidth
+// inclusive.
+// minBandwidth - minimum bandwidth in bits per second, 0 for no minimum.
+// maxBandwidth - maximum bandwidth in bits per second, 0 for no maximum.
+func WithBandwidth(minBandwidth, maxBandwidth int64) RepresentationFilter {
+	return func(as *AdaptationSet, r *Representation) bool {
+		if r.Bandwidth == nil {
+			return true
+		}
+		return *r.Bandwidth >= minBandwidth && (maxBandwidth <= 0 || *r.Bandwidth <= maxBandwidth)
+	}
+}
+
+// WithMaxResolution keeps the Representations no larger than width x height. Representations
+// without a resolution, such as audio, are kept.
+// width - maximum width in pixels, 0 for no maximum.
+// height - maximum height in pixels, 0 for no maximum.
+func WithMaxResolution(width, height int64) RepresentationFilter {
+	return func(as *AdaptationSet, r *Representation) bool {
+		if width > 0 && r.Width != nil && *r.Width > width {
+			return false
+		}
+		if height > 0 && r.Height != nil && *r.Height > height {
+			return false
+		}
+		return true
+	}
+}
+
+// WithMaxFrameRate keeps the Representations with a @frameRate, or one inherited from their
+// AdaptationSet, of at most fps. Representations without a frame rate are kept.
+// fps - maximum frames per second (i.e. 30).
+func WithMaxFrameRate(fps float64) RepresentationFilter {
+	return func(as *AdaptationSet, r *Representation) bool {
+		frameRate := r.FrameRate
+		if frameRate == nil {
+			frameRate = as.FrameRate
+		}
+		if frameRate == nil {
+			return true
+		}
+		f, ok := ParseFrameRate(*frameRate)
+		return !ok || f <= fps
+	}
+}
+
+// ParseFrameRate parses a @frameRate (i.e. 30000/1001 or 25) in frames per second.
+func ParseFrameRate(frameRate string) (float64, bool) {
+	parts := strings.SplitN(frameRate, "/", 2)
+	num, err := strconv.ParseFloat(parts[0], 64)
+	if err != nil {
+		return 0, false
+	}
+	if len(parts) == 1 {
+		return num, true
+	}
+	den, err := strconv.ParseFloat(parts[1], 64)
+	if err != nil || den == 0 {
+		return 0, false
+	}
+	return num / den, true
+}
+
+// WithLanguages keeps the Representations of AdaptationSets in one of the languages, matching
+// the primary language subtag too (i.e. en matches en-US). AdaptationSets without @lang, such as
+// video, are kept.
+// langs - RFC 5646 language tags (i.e. en, pt-BR).
+func WithLanguages(langs ...string) RepresentationFilter {
+	return func(as *AdaptationSet, r *Representation) bool {
+		if as.Lang == nil || *as.Lang == "" {
+			return true
+		}
+		lang := strings.ToLower(*as.Lang)
+		for _, l := range langs {
+			l = strings.ToLower(l)
+			if lang == l || strings.HasPrefix(lang, l+"-") {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// WithRoles keeps the Representations of AdaptationSets with a Role of one of the values.
+// AdaptationSets without a Role have the main role.
+// values - Role values (i.e. main, alternate, commentary).
+func WithRoles(values ...string) RepresentationFilter {
+	return func(as *AdaptationSet, r *Representation) bool {
+		roles := []string{"main"}
+		if len(as.Roles) > 0 {
+			roles = roles[:0]
+			for _, role := range as.Roles {
+				if role != nil && role.Value != nil {
+					roles = append(roles, *role.Value)
+				}
+			}
+		}
+		for _, role := range roles {
+			for _, v := range values {
+				if role == v {
+					return true
+				}
+			}
+		}
+		return false
+	}
+}
+
+// WithDRMSystems keeps the clear Representations and the encrypted ones signalling one of the
+// DRM systems, at AdaptationSet or Representation level.
+// schemeIDURIs - DRM system scheme ids (i.e. CONTENT_PROTECTION_WIDEVINE_SCHEME_ID).
+func WithDRMSystems(schemeIDURIs ...string) RepresentationFilter {
+	return func(as *AdaptationSet, r *Representation) bool {
+		encrypted := false
+		for _, cp := range append(append([]ContentProtectioner(nil), as.ContentProtection...), r.ContentProtection...) {
+			scheme := contentProtectionSchemeIDURI(cp)
+			if scheme == "" {
+				continue
+			}
+			encrypted = true
+			if strings.EqualFold(scheme, CONTENT_PROTECTION_ROOT_SCHEME_ID_URI) {
+				continue
+			}
+			for _, s := range schemeIDURIs {
+				if strings.EqualFold(scheme, s) {
+					return true
+				}
+			}
+		}
+		return !encrypted
+	}
+}
+
+// contentProtectionSchemeIDURI returns the @schemeIdUri of the ContentProtection types, including
+// the registered ones embedding ContentProtection.
+func contentProtectionSchemeIDURI(cp ContentProtectioner) string {
+	if c, ok := cp.(contentProtectionScheme); ok {
+		return strOrEmpty(c.schemeIDURI())
+	}
+	return ""
+}
+
+// removeContentProtection returns cps without the ContentProtections with the schemeIdUri.
+func removeContentProtection(cps []ContentProtectioner, schemeIDURI string) []ContentProtectioner {
+	kept := cps[:0]
+	for _, cp := range cps {
+		if !strings.EqualFold(contentProtectionSchemeIDURI(cp), schemeIDURI) {
+			kept = append(kept, cp)
+		}
+	}
+	return kept
+}
+
+// contentProtectionScheme is implemented by ContentProtection, and so by the types embedding it.
+type contentProtectionScheme interface {
+	schemeIDURI() *string
+}
+
+func (s *ContentProtection) schemeIDURI() *string {
+	return s.SchemeIDURI
+}
+
+// WithHDR keeps the HDR Representations when hdr is true, and the SDR ones otherwise. A
+// Representation is HDR when it is Dolby Vision, when its codecs signal the PQ or HLG transfer
+// characteristics (AV1, VP9), or when a CICP TransferCharacteristics property does.
+func WithHDR(hdr bool) RepresentationFilter {
+	return func(as *AdaptationSet, r *Representation) bool {
+		return IsHDR(as, r) == hdr
+	}
+}
+
+// IsHDR reports whether a Representation of an AdaptationSet is HDR, as used by WithHDR.
+func IsHDR(as *AdaptationSet, r *Representation) bool {
+	for _, properties := range [][]DescriptorType{as.EssentialProperty, as.SupplementalProperty, r.EssentialProperty, r.SupplementalProperty} {
+		for _, d := range properties {
+			if d.SchemeIDURI != nil && *d.SchemeIDURI == CICP_TRANSFER_CHARACTERISTICS_SCHEME_ID && d.Value != nil {
+				// 16 is SMPTE ST 2084 (PQ), 18 is ARIB STD-B67 (HLG)
+				if *d.Value == "16" || *d.Value == "18" {
+					return true
+				}
+			}
+		}
+	}
+
+	list, _ := r.withParent(as).ParseCodecs()
+	for _, c := range list {
+		if c.Family == codecs.FAMILY_DOLBY_VISION {
+			return true
+		}
+		if c.Color != nil && (c.Color.TransferCharacteristics == 16 || c.Color.TransferCharacteristics == 18) {
+			return true
+		}
+	}
+	return false
+}
+
+// withParent returns the Representation with its AdaptationSet set, for the inherited attributes
+// of Representations that were not added with the builder functions.
+func (r *Representation) withParent(as *AdaptationSet) *Representation {
+	if r.AdaptationSet == as {
+		return r
+	}
+	linked := *r
+	linked.AdaptationSet = as
+	return &linked
+}
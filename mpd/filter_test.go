@@ -0,0 +1,121 @@
+package mpd
+
+import (
+	"testing"
+
+	"github.com/zencoder/go-dash/v3/codecs"
+	. "github.com/zencoder/go-dash/v3/helpers/ptrs"
+	"github.com/zencoder/go-dash/v3/helpers/require"
+)
+
+func newFilterTestMPD(t *testing.T) *MPD {
+	m := NewMPD(DASH_PROFILE_LIVE, VALID_MEDIA_PRESENTATION_DURATION, VALID_MIN_BUFFER_TIME)
+
+	avc, err := m.AddNewAdaptationSetVideoWithID("1", DASH_MIME_TYPE_VIDEO_MP4, VALID_SCAN_TYPE, VALID_SEGMENT_ALIGNMENT, VALID_START_WITH_SAP)
+	require.NoError(t, err)
+	avc.MinBandwidth, avc.MaxBandwidth = Strptr("800000"), Strptr("3000000")
+	avc.MaxWidth, avc.MaxHeight = Strptr("1920"), Strptr("1080")
+	_, err = avc.AddNewContentProtectionRoot("08e367028f33436ca5dd60ffe5571e60")
+	require.NoError(t, err)
+	_, err = avc.AddNewContentProtectionSchemeWidevine()
+	require.NoError(t, err)
+	_, _ = avc.AddNewRepresentationVideo(800000, "avc1.4d401f", "540p", "30", 960, 540)
+	_, _ = avc.AddNewRepresentationVideo(1500000, "avc1.4d401f", "720p", "30", 1280, 720)
+	_, _ = avc.AddNewRepresentationVideo(3000000, "avc1.640028", "1080p60", "60000/1001", 1920, 1080)
+
+	hevc, err := m.AddNewAdaptationSetVideoWithID("2", DASH_MIME_TYPE_VIDEO_MP4, VALID_SCAN_TYPE, VALID_SEGMENT_ALIGNMENT, VALID_START_WITH_SAP)
+	require.NoError(t, err)
+	hevc.SupplementalProperty = []DescriptorType{{SchemeIDURI: Strptr(CICP_TRANSFER_CHARACTERISTICS_SCHEME_ID), Value: Strptr("16")}}
+	_, _ = hevc.AddNewRepresentationVideo(6000000, "hvc1.2.4.L150.90", "2160p-hdr", "30", 3840, 2160)
+
+	en, err := m.AddNewAdaptationSetAudioWithID("3", DASH_MIME_TYPE_AUDIO_MP4, VALID_SEGMENT_ALIGNMENT, VALID_START_WITH_SAP, "en-US")
+	require.NoError(t, err)
+	_, _ = en.AddNewRole("urn:mpeg:dash:role:2011", "main")
+	_, _ = en.AddNewRepresentationAudio(48000, 128000, "mp4a.40.2", "en-aac")
+	_, _ = en.AddNewRepresentationAudio(48000, 384000, "ec-3", "en-ec3")
+
+	fr, err := m.AddNewAdaptationSetAudioWithID("4", DASH_MIME_TYPE_AUDIO_MP4, VALID_SEGMENT_ALIGNMENT, VALID_START_WITH_SAP, "fr")
+	require.NoError(t, err)
+	_, _ = fr.AddNewRole("urn:mpeg:dash:role:2011", "commentary")
+	_, _ = fr.AddNewRepresentationAudio(48000, 128000, "mp4a.40.2", "fr-aac")
+	return m
+}
+
+func TestFilterPredicates(t *testing.T) {
+	m := newFilterTestMPD(t)
+	testCases := []struct {
+		name     string
+		filters  []RepresentationFilter
+		expected []string
+	}{
+		{"none", nil, []string{"540p", "720p", "1080p60", "2160p-hdr", "en-aac", "en-ec3", "fr-aac"}},
+		{"codec families", []RepresentationFilter{WithCodecFamilies(codecs.FAMILY_AVC, codecs.FAMILY_MPEG4_AUDIO)}, []string{"540p", "720p", "1080p60", "en-aac", "fr-aac"}},
+		{"codec profiles", []RepresentationFilter{WithCodecProfiles(codecs.FAMILY_AVC, 66, 77)}, []string{"540p", "720p", "2160p-hdr", "en-aac", "en-ec3", "fr-aac"}},
+		{"audio object types", []RepresentationFilter{WithCodecProfiles(codecs.FAMILY_MPEG4_AUDIO, 5)}, []string{"540p", "720p", "1080p60", "2160p-hdr", "en-ec3"}},
+		{"avc level", []RepresentationFilter{WithMaxCodecLevel(codecs.FAMILY_AVC, 31)}, []string{"540p", "720p", "2160p-hdr", "en-aac", "en-ec3", "fr-aac"}},
+		{"hevc level", []RepresentationFilter{WithMaxCodecLevel(codecs.FAMILY_HEVC, 123)}, []string{"540p", "720p", "1080p60", "en-aac", "en-ec3", "fr-aac"}},
+		{"bandwidth", []RepresentationFilter{WithBandwidth(200000, 2000000)}, []string{"540p", "720p", "en-ec3"}},
+		{"no maximum bandwidth", []RepresentationFilter{WithBandwidth(3000000, 0)}, []string{"1080p60", "2160p-hdr"}},
+		{"resolution", []RepresentationFilter{WithMaxResolution(1280, 720)}, []string{"540p", "720p", "en-aac", "en-ec3", "fr-aac"}},
+		{"frame rate", []RepresentationFilter{WithMaxFrameRate(30)}, []string{"540p", "720p", "2160p-hdr", "en-aac", "en-ec3", "fr-aac"}},
+		{"language", []RepresentationFilter{WithLanguages("EN")}, []string{"540p", "720p", "1080p60", "2160p-hdr", "en-aac", "en-ec3"}},
+		{"role", []RepresentationFilter{WithRoles("commentary")}, []string{"fr-aac"}},
+		{"implied main role", []RepresentationFilter{WithRoles("main")}, []string{"540p", "720p", "1080p60", "2160p-hdr", "en-aac", "en-ec3"}},
+		{"drm", []RepresentationFilter{WithDRMSystems(CONTENT_PROTECTION_PLAYREADY_SCHEME_ID)}, []string{"2160p-hdr", "en-aac", "en-ec3", "fr-aac"}},
+		{"hdr", []RepresentationFilter{WithHDR(true)}, []string{"2160p-hdr"}},
+		{"not", []RepresentationFilter{Not(WithHDR(true)), WithCodecFamilies(codecs.FAMILY_AVC), WithMaxResolution(0, 720)}, []string{"540p", "720p"}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var ids []string
+			for _, r := range m.SelectRepresentations(tc.filters...) {
+				ids = append(ids, *r.ID)
+			}
+			require.EqualStringSlice(t, tc.expected, ids)
+		})
+	}
+}
+
+func TestFilter(t *testing.T) {
+	m := newFilterTestMPD(t)
+	expected, err := m.WriteToString()
+	require.NoError(t, err)
+
+	filtered := m.Filter(WithMaxResolution(1280, 720), WithHDR(false), WithLanguages("en"))
+	as := filtered.Periods[0].AdaptationSets
+	require.EqualInt(t, 2, len(as))
+	require.EqualString(t, "1", *as[0].ID)
+	require.EqualString(t, "3", *as[1].ID)
+	require.EqualInt(t, 2, len(as[0].Representations))
+	require.EqualStringPtr(t, Strptr("800000"), as[0].MinBandwidth)
+	require.EqualStringPtr(t, Strptr("1500000"), as[0].MaxBandwidth)
+	require.EqualStringPtr(t, Strptr("1280"), as[0].MaxWidth)
+	require.EqualStringPtr(t, Strptr("720"), as[0].MaxHeight)
+	require.Nil(t, as[0].MinWidth)
+	if as[0].Representations[0].Parent() != as[0] || as[0].Period() != filtered.Periods[0] {
+		t.Errorf("Expected the filtered copy to be linked")
+	}
+
+	// The original is untouched
+	actual, err := m.WriteToString()
+	require.NoError(t, err)
+	require.EqualString(t, expected, actual)
+	require.EqualStringPtr(t, Strptr("3000000"), m.Periods[0].AdaptationSets[0].MaxBandwidth)
+}
+
+func TestFilterDecoded(t *testing.T) {
+	m, err := ReadFromFile("fixtures/live_profile.mpd")
+	require.NoError(t, err)
+
+	// The subtitles have no @codecs and are kept
+	filtered := m.Filter(WithCodecFamilies(codecs.FAMILY_AVC), WithMaxResolution(0, 576))
+	as := filtered.Periods[0].AdaptationSets
+	require.EqualInt(t, 2, len(as))
+	require.EqualString(t, "video/mp4", *as[0].MimeType)
+	require.EqualInt(t, 3, len(as[0].Representations))
+	require.EqualString(t, "text/vtt", *as[1].MimeType)
+	// Only the clear subtitles are left
+	as = m.Filter(WithDRMSystems("urn:uuid:unknown")).Periods[0].AdaptationSets
+	require.EqualInt(t, 1, len(as))
+	require.EqualString(t, "text/vtt", *as[0].MimeType)
+}
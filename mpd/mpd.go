@@ -39,6 +39,13 @@ type AccessibilityElementScheme string
 // Accessibility descriptor values for Audio Description
 const ACCESSIBILITY_ELEMENT_SCHEME_DESCRIPTIVE_AUDIO AccessibilityElementScheme = "urn:tva:metadata:cs:AudioPurposeCS:2007"
 
+// Accessibility schemes for closed captions embedded in the video (SCTE 214-1), with a caption
+// service map value (i.e. CC1=eng;CC3=spa)
+const (
+	ACCESSIBILITY_ELEMENT_SCHEME_CEA_608 AccessibilityElementScheme = "urn:scte:dash:cc:cea-608:2015"
+	ACCESSIBILITY_ELEMENT_SCHEME_CEA_708 AccessibilityElementScheme = "urn:scte:dash:cc:cea-708:2015"
+)
+
 // Constants for some known MIME types, this is a limited list and others can be used.
 const (
 	DASH_MIME_TYPE_VIDEO_MP4     string = "video/mp4"
@@ -84,8 +91,8 @@ type MPD struct {
 	BaseURL                    []string  `xml:"BaseURL,omitempty"`
 	Location                   string    `xml:"Location,omitempty"`
 	period                     *Period
-	Periods                    []*Period       `xml:"Period,omitempty"`
-	UTCTiming                  *DescriptorType `xml:"UTCTiming,omitempty"`
+	Periods                    []*Period         `xml:"Period,omitempty"`
+	UTCTiming                  []*DescriptorType `xml:"UTCTiming,omitempty"`
 }
 
 type Period struct {
@@ -98,6 +105,7 @@ type Period struct {
 	SegmentTemplate *SegmentTemplate `xml:"SegmentTemplate,omitempty"`
 	AdaptationSets  []*AdaptationSet `xml:"AdaptationSet,omitempty"`
 	EventStreams    []EventStream    `xml:"EventStream,omitempty"`
+	mpd             *MPD
 }
 
 type DescriptorType struct {
@@ -120,6 +128,7 @@ type CommonAttributesAndElements struct {
 	MaximumSAPPeriod          *string               `xml:"maximumSAPPeriod,attr"`
 	StartWithSAP              *int64                `xml:"startWithSAP,attr"`
 	MaxPlayoutRate            *string               `xml:"maxPlayoutRate,attr"`
+	CodingDependency          *bool                 `xml:"codingDependency,attr"`
 	ScanType                  *string               `xml:"scanType,attr"`
 	FramePacking              []DescriptorType      `xml:"FramePacking,omitempty"`
 	AudioChannelConfiguration []DescriptorType      `xml:"AudioChannelConfiguration,omitempty"`
@@ -129,34 +138,6 @@ type CommonAttributesAndElements struct {
 	InbandEventStream         []DescriptorType      `xml:"InbandEventStream,omitempty"`
 }
 
-type contentProtections []ContentProtectioner
-
-func (as *contentProtections) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
-	var scheme string
-	for _, a := range start.Attr {
-		if a.Name.Local == "schemeIdUri" {
-			scheme = a.Value
-			break
-		}
-	}
-	var target ContentProtectioner
-	switch scheme {
-	case CONTENT_PROTECTION_ROOT_SCHEME_ID_URI:
-		target = &CENCContentProtection{}
-	case CONTENT_PROTECTION_PLAYREADY_SCHEME_ID:
-		target = &PlayreadyContentProtection{}
-	case CONTENT_PROTECTION_WIDEVINE_SCHEME_ID:
-		target = &WidevineContentProtection{}
-	default:
-		target = &ContentProtection{}
-	}
-	if err := d.DecodeElement(target, &start); err != nil {
-		return err
-	}
-	*as = append(*as, target)
-	return nil
-}
-
 // wrappedAdaptationSet provides the default xml unmarshal
 // to take care of the majority of our unmarshalling
 type wrappedAdaptationSet AdaptationSet
@@ -192,6 +173,7 @@ type AdaptationSet struct {
 	AccessibilityElems []*Accessibility  `xml:"Accessibility,omitempty"`
 	Labels             []string          `xml:"Label,omitempty"`
 	BaseURL            []string          `xml:"BaseURL,omitempty"`
+	period             *Period
 }
 
 func (as *AdaptationSet) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
@@ -365,6 +347,18 @@ type SegmentTemplate struct {
 	Timescale              *int64           `xml:"timescale,attr"`
 }
 
+// wrappedRepresentation provides the default xml unmarshal
+// to take care of the majority of our unmarshalling
+type wrappedRepresentation Representation
+
+// dtoRepresentation parses the items out of Representation
+// that give us trouble:
+// * Content Protection interface
+type dtoRepresentation struct {
+	wrappedRepresentation
+	ContentProtection contentProtections `xml:"ContentProtection,omitempty"`
+}
+
 type Representation struct {
 	CommonAttributesAndElements
 	AdaptationSet             *AdaptationSet             `xml:"-"`
@@ -382,6 +376,19 @@ type Representation struct {
 	SegmentTemplate           *SegmentTemplate           `xml:"SegmentTemplate,omitempty"`
 }
 
+func (r *Representation) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var n dtoRepresentation
+	if err := d.DecodeElement(&n, &start); err != nil {
+		return err
+	}
+	*r = Representation(n.wrappedRepresentation)
+	r.ContentProtection = make([]ContentProtectioner, len(n.ContentProtection))
+	for i := range n.ContentProtection {
+		r.ContentProtection[i] = n.ContentProtection[i]
+	}
+	return nil
+}
+
 type Accessibility struct {
 	AdaptationSet *AdaptationSet `xml:"-"`
 	SchemeIdUri   *string        `xml:"schemeIdUri,attr,omitempty"`
@@ -414,6 +421,7 @@ func NewMPD(profile DashProfile, mediaPresentationDuration, minBufferTime string
 		period:                    period,
 		Periods:                   []*Period{period},
 	}
+	period.mpd = mpd
 
 	for i := range attributes {
 		switch attr := attributes[i].(type) {
@@ -440,8 +448,9 @@ func NewDynamicMPD(profile DashProfile, availabilityStartTime, minBufferTime str
 		MinBufferTime:         Strptr(minBufferTime),
 		period:                period,
 		Periods:               []*Period{period},
-		UTCTiming:             &DescriptorType{},
+		UTCTiming:             []*DescriptorType{{}},
 	}
+	period.mpd = mpd
 
 	for i := range attributes {
 		switch attr := attributes[i].(type) {
@@ -462,7 +471,7 @@ func (m *MPD) AddNewPeriod() *Period {
 	if m.period != nil && m.period.ID == "" && m.period.AdaptationSets == nil {
 		return m.GetCurrentPeriod()
 	}
-	period := &Period{}
+	period := &Period{mpd: m}
 	m.Periods = append(m.Periods, period)
 	m.period = period
 	return period
@@ -698,6 +707,7 @@ func (period *Period) addAdaptationSet(as *AdaptationSet) error {
 	if as == nil {
 		return ErrAdaptationSetNil
 	}
+	as.period = period
 	period.AdaptationSets = append(period.AdaptationSets, as)
 	return nil
 }
@@ -733,6 +743,19 @@ func (as *AdaptationSet) AddNewContentProtectionRootLegacyUUID(defaultKIDHex str
 // This ContentProtection tag does not include signaling for any particular DRM scheme.
 // defaultKIDHex - Default Key ID as a Hex String.
 func (as *AdaptationSet) AddNewContentProtectionRoot(defaultKIDHex string) (*CENCContentProtection, error) {
+	cp, err := newCENCContentProtection(defaultKIDHex)
+	if err != nil {
+		return nil, err
+	}
+
+	err = as.AddContentProtection(cp)
+	if err != nil {
+		return nil, err
+	}
+	return cp, nil
+}
+
+func newCENCContentProtection(defaultKIDHex string) (*CENCContentProtection, error) {
 	if len(defaultKIDHex) != 32 || defaultKIDHex == "" {
 		return nil, ErrInvalidDefaultKID
 	}
@@ -747,10 +770,6 @@ func (as *AdaptationSet) AddNewContentProtectionRoot(defaultKIDHex string) (*CEN
 	cp.SchemeIDURI = Strptr(CONTENT_PROTECTION_ROOT_SCHEME_ID_URI)
 	cp.XMLNS = Strptr(CENC_XMLNS)
 
-	err := as.AddContentProtection(cp)
-	if err != nil {
-		return nil, err
-	}
 	return cp, nil
 }
 
@@ -758,6 +777,7 @@ func (as *AdaptationSet) AddNewContentProtectionRoot(defaultKIDHex string) (*CEN
 // a <cenc:pssh> element that contains a Base64 encoded PSSH box
 // wvHeader - binary representation of Widevine Header
 // !!! Note: this function will accept any byte slice as a wvHeader value !!!
+// Use AddNewContentProtectionSchemeWidevineWithPSSHData to build the header from its fields.
 func (as *AdaptationSet) AddNewContentProtectionSchemeWidevineWithPSSH(wvHeader []byte) (*WidevineContentProtection, error) {
 	cp, err := NewWidevineContentProtection(wvHeader)
 	if err != nil {
@@ -854,26 +874,26 @@ func newPlayreadyContentProtection(pro string, schemeIDURI string) (*PlayreadyCo
 // will include both ms:pro and cenc:pssh subelements
 // pro - PlayReady Object Header, as a Base64 encoded string.
 func (as *AdaptationSet) AddNewContentProtectionSchemePlayreadyWithPSSH(pro string) (*PlayreadyContentProtection, error) {
-	cp, err := newPlayreadyContentProtection(pro, CONTENT_PROTECTION_PLAYREADY_SCHEME_ID)
+	cp, err := newPlayreadyContentProtectionWithPSSH(pro, CONTENT_PROTECTION_PLAYREADY_SCHEME_ID, CONTENT_PROTECTION_PLAYREADY_SCHEME_HEX)
 	if err != nil {
 		return nil, err
 	}
-	cp.XMLNS = Strptr(CENC_XMLNS)
-	prSystemID, err := hex.DecodeString(CONTENT_PROTECTION_PLAYREADY_SCHEME_HEX)
-	if err != nil {
-		panic(err.Error())
-	}
 
-	proBin, err := base64.StdEncoding.DecodeString(pro)
+	err = as.AddContentProtection(cp)
 	if err != nil {
 		return nil, err
 	}
+	return cp, nil
+}
 
-	psshBox, err := MakePSSHBox(prSystemID, proBin)
+// AddNewContentProtectionSchemePlayreadyV10WithPSSH adds a new content protection scheme for PlayReady v1.0 DRM. The scheme
+// will include both ms:pro and cenc:pssh subelements
+// pro - PlayReady Object Header, as a Base64 encoded string.
+func (as *AdaptationSet) AddNewContentProtectionSchemePlayreadyV10WithPSSH(pro string) (*PlayreadyContentProtection, error) {
+	cp, err := newPlayreadyContentProtectionWithPSSH(pro, CONTENT_PROTECTION_PLAYREADY_SCHEME_V10_ID, CONTENT_PROTECTION_PLAYREADY_SCHEME_V10_HEX)
 	if err != nil {
 		return nil, err
 	}
-	cp.PSSH = Strptr(base64.StdEncoding.EncodeToString(psshBox))
 
 	err = as.AddContentProtection(cp)
 	if err != nil {
@@ -882,16 +902,13 @@ func (as *AdaptationSet) AddNewContentProtectionSchemePlayreadyWithPSSH(pro stri
 	return cp, nil
 }
 
-// AddNewContentProtectionSchemePlayreadyV10WithPSSH adds a new content protection scheme for PlayReady v1.0 DRM. The scheme
-// will include both ms:pro and cenc:pssh subelements
-// pro - PlayReady Object Header, as a Base64 encoded string.
-func (as *AdaptationSet) AddNewContentProtectionSchemePlayreadyV10WithPSSH(pro string) (*PlayreadyContentProtection, error) {
-	cp, err := newPlayreadyContentProtection(pro, CONTENT_PROTECTION_PLAYREADY_SCHEME_V10_ID)
+func newPlayreadyContentProtectionWithPSSH(pro string, schemeIDURI string, systemIDHex string) (*PlayreadyContentProtection, error) {
+	cp, err := newPlayreadyContentProtection(pro, schemeIDURI)
 	if err != nil {
 		return nil, err
 	}
 	cp.XMLNS = Strptr(CENC_XMLNS)
-	prSystemID, err := hex.DecodeString(CONTENT_PROTECTION_PLAYREADY_SCHEME_V10_HEX)
+	prSystemID, err := hex.DecodeString(systemIDHex)
 	if err != nil {
 		panic(err.Error())
 	}
@@ -907,10 +924,6 @@ func (as *AdaptationSet) AddNewContentProtectionSchemePlayreadyV10WithPSSH(pro s
 	}
 	cp.PSSH = Strptr(base64.StdEncoding.EncodeToString(psshBox))
 
-	err = as.AddContentProtection(cp)
-	if err != nil {
-		return nil, err
-	}
 	return cp, nil
 }
 
@@ -920,10 +933,18 @@ func (as *AdaptationSet) AddContentProtection(cp ContentProtectioner) error {
 		return ErrContentProtectionNil
 	}
 
+	setContentProtectionParent(cp, as)
 	as.ContentProtection = append(as.ContentProtection, cp)
 	return nil
 }
 
+// Removes the ContentProtections of an AdaptationSet with the given schemeIdUri, compared
+// case-insensitively.
+// schemeIDURI - scheme to remove (i.e. urn:mpeg:dash:mp4protection:2011).
+func (as *AdaptationSet) RemoveContentProtection(schemeIDURI string) {
+	as.ContentProtection = removeContentProtection(as.ContentProtection, schemeIDURI)
+}
+
 // Sets up a new SegmentTemplate for an AdaptationSet.
 // duration - relative to timescale (i.e. 2000).
 // init - template string for init segment (i.e. $RepresentationID$/audio/en/init.mp4).
@@ -1008,9 +1029,12 @@ func (as *AdaptationSet) AddNewRepresentationThumbnails(id, val, uri string, ban
 // Adds a new Audio representation to an AdaptationSet.
 // samplingRate - in Hz (i.e. 44100).
 // bandwidth - in Bits/s (i.e. 67095).
-// codecs - codec string for Audio Only (in RFC6381, https://tools.ietf.org/html/rfc6381) (i.e. mp4a.40.2).
+// codecs - codec string for Audio Only (in RFC6381, https://tools.ietf.org/html/rfc6381) (i.e. mp4a.40.2), malformed strings are rejected.
 // id - ID for this representation, will get used as $RepresentationID$ in template strings.
 func (as *AdaptationSet) AddNewRepresentationAudio(samplingRate int64, bandwidth int64, codecs string, id string) (*Representation, error) {
+	if err := validateCodecs(&codecs); err != nil {
+		return nil, err
+	}
 	r := &Representation{
 		AudioSamplingRate: Int64ptr(samplingRate),
 		Bandwidth:         Int64ptr(bandwidth),
@@ -1027,12 +1051,15 @@ func (as *AdaptationSet) AddNewRepresentationAudio(samplingRate int64, bandwidth
 
 // Adds a new Video representation to an AdaptationSet.
 // bandwidth - in Bits/s (i.e. 1518664).
-// codecs - codec string for Audio Only (in RFC6381, https://tools.ietf.org/html/rfc6381) (i.e. avc1.4d401f).
+// codecs - codec string for Video Only (in RFC6381, https://tools.ietf.org/html/rfc6381) (i.e. avc1.4d401f), malformed strings are rejected.
 // id - ID for this representation, will get used as $RepresentationID$ in template strings.
 // frameRate - video frame rate (as a fraction) (i.e. 30000/1001).
 // width - width of the video (i.e. 1280).
 // height - height of the video (i.e 720).
 func (as *AdaptationSet) AddNewRepresentationVideo(bandwidth int64, codecs string, id string, frameRate string, width int64, height int64) (*Representation, error) {
+	if err := validateCodecs(&codecs); err != nil {
+		return nil, err
+	}
 	r := &Representation{
 		Bandwidth: Int64ptr(bandwidth),
 		Codecs:    Strptr(codecs),
@@ -1221,3 +1248,124 @@ func (r *Representation) AddNewInbandEventStream(uri string, val string) error {
 	r.InbandEventStream = append(r.InbandEventStream, evt)
 	return nil
 }
+
+// Adds a ContentProtection tag at the root level of a Representation.
+// This ContentProtection tag does not include signaling for any particular DRM scheme.
+// Use this when the Representations of an AdaptationSet are encrypted with different keys.
+// defaultKIDHex - Default Key ID as a Hex String.
+func (r *Representation) AddNewContentProtectionRoot(defaultKIDHex string) (*CENCContentProtection, error) {
+	cp, err := newCENCContentProtection(defaultKIDHex)
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.AddContentProtection(cp)
+	if err != nil {
+		return nil, err
+	}
+	return cp, nil
+}
+
+// AddNewContentProtectionSchemeWidevineWithPSSH adds a new content protection scheme for Widevine DRM to the
+// representation. With a <cenc:pssh> element that contains a Base64 encoded PSSH box
+// wvHeader - binary representation of Widevine Header
+// !!! Note: this function will accept any byte slice as a wvHeader value !!!
+// Use AddNewContentProtectionSchemeWidevineWithPSSHData to build the header from its fields.
+func (r *Representation) AddNewContentProtectionSchemeWidevineWithPSSH(wvHeader []byte) (*WidevineContentProtection, error) {
+	cp, err := NewWidevineContentProtection(wvHeader)
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.AddContentProtection(cp)
+	if err != nil {
+		return nil, err
+	}
+	return cp, nil
+}
+
+// AddNewContentProtectionSchemeWidevine adds a new content protection scheme for Widevine DRM to the representation.
+func (r *Representation) AddNewContentProtectionSchemeWidevine() (*WidevineContentProtection, error) {
+	return r.AddNewContentProtectionSchemeWidevineWithPSSH(nil)
+}
+
+// AddNewContentProtectionSchemePlayready adds a new content protection scheme for PlayReady DRM to the representation.
+// pro - PlayReady Object Header, as a Base64 encoded string.
+func (r *Representation) AddNewContentProtectionSchemePlayready(pro string) (*PlayreadyContentProtection, error) {
+	cp, err := newPlayreadyContentProtection(pro, CONTENT_PROTECTION_PLAYREADY_SCHEME_ID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.AddContentProtection(cp)
+	if err != nil {
+		return nil, err
+	}
+	return cp, nil
+}
+
+// AddNewContentProtectionSchemePlayreadyV10 adds a new content protection scheme for PlayReady v1.0 DRM to the
+// representation.
+// pro - PlayReady Object Header, as a Base64 encoded string.
+func (r *Representation) AddNewContentProtectionSchemePlayreadyV10(pro string) (*PlayreadyContentProtection, error) {
+	cp, err := newPlayreadyContentProtection(pro, CONTENT_PROTECTION_PLAYREADY_SCHEME_V10_ID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.AddContentProtection(cp)
+	if err != nil {
+		return nil, err
+	}
+	return cp, nil
+}
+
+// AddNewContentProtectionSchemePlayreadyWithPSSH adds a new content protection scheme for PlayReady DRM to the
+// representation. The scheme will include both ms:pro and cenc:pssh subelements
+// pro - PlayReady Object Header, as a Base64 encoded string.
+func (r *Representation) AddNewContentProtectionSchemePlayreadyWithPSSH(pro string) (*PlayreadyContentProtection, error) {
+	cp, err := newPlayreadyContentProtectionWithPSSH(pro, CONTENT_PROTECTION_PLAYREADY_SCHEME_ID, CONTENT_PROTECTION_PLAYREADY_SCHEME_HEX)
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.AddContentProtection(cp)
+	if err != nil {
+		return nil, err
+	}
+	return cp, nil
+}
+
+// AddNewContentProtectionSchemePlayreadyV10WithPSSH adds a new content protection scheme for PlayReady v1.0 DRM to
+// the representation. The scheme will include both ms:pro and cenc:pssh subelements
+// pro - PlayReady Object Header, as a Base64 encoded string.
+func (r *Representation) AddNewContentProtectionSchemePlayreadyV10WithPSSH(pro string) (*PlayreadyContentProtection, error) {
+	cp, err := newPlayreadyContentProtectionWithPSSH(pro, CONTENT_PROTECTION_PLAYREADY_SCHEME_V10_ID, CONTENT_PROTECTION_PLAYREADY_SCHEME_V10_HEX)
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.AddContentProtection(cp)
+	if err != nil {
+		return nil, err
+	}
+	return cp, nil
+}
+
+// Helper method for adding a ContentProtection to a Representation.
+func (r *Representation) AddContentProtection(cp ContentProtectioner) error {
+	if cp == nil {
+		return ErrContentProtectionNil
+	}
+
+	setContentProtectionParent(cp, r.AdaptationSet)
+	r.ContentProtection = append(r.ContentProtection, cp)
+	return nil
+}
+
+// Removes the ContentProtections of a Representation with the given schemeIdUri, compared
+// case-insensitively.
+// schemeIDURI - scheme to remove (i.e. urn:mpeg:dash:mp4protection:2011).
+func (r *Representation) RemoveContentProtection(schemeIDURI string) {
+	r.ContentProtection = removeContentProtection(r.ContentProtection, schemeIDURI)
+}
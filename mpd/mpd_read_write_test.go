@@ -1,6 +1,7 @@
 package mpd
 
 import (
+	"fmt"
 	"os"
 	"testing"
 	"time"
@@ -500,3 +501,53 @@ func TestWriteToFileTruncate(t *testing.T) {
 	xmlStr = testfixtures.LoadFixture(out)
 	testfixtures.CompareFixture(t, "fixtures/truncate_short.mpd", xmlStr)
 }
+
+func RepresentationContentProtectionProfile() *MPD {
+	m := NewMPD(DASH_PROFILE_LIVE, VALID_MEDIA_PRESENTATION_DURATION, VALID_MIN_BUFFER_TIME)
+
+	videoAS, _ := m.AddNewAdaptationSetVideoWithID("7357", DASH_MIME_TYPE_VIDEO_MP4, VALID_SCAN_TYPE, VALID_SEGMENT_ALIGNMENT, VALID_START_WITH_SAP)
+	_, _ = videoAS.SetNewSegmentTemplate(1968, "$RepresentationID$/video/1/init.mp4", "$RepresentationID$/video/1/seg-$Number$.m4f", 0, 1000)
+
+	sd, _ := videoAS.AddNewRepresentationVideo(1518664, "avc1.4d401f", "800", "30000/1001", 960, 540)
+	_, _ = sd.AddNewContentProtectionRoot("08e367028f33436ca5dd60ffe5571e60")
+	_, _ = sd.AddNewContentProtectionSchemeWidevineWithPSSH(getValidWVHeaderBytes())
+	_, _ = sd.AddNewContentProtectionSchemePlayreadyWithPSSH(VALID_PLAYREADY_PRO)
+
+	hd, _ := videoAS.AddNewRepresentationVideo(2780732, "avc1.4d401f", "1500", "30000/1001", 1280, 720)
+	_, _ = hd.AddNewContentProtectionRoot("5abdd52f554a4f2ab8d061f761425155")
+	_, _ = hd.AddNewContentProtectionSchemeWidevine()
+	_, _ = hd.AddNewContentProtectionSchemePlayreadyV10(VALID_PLAYREADY_PRO)
+
+	return m
+}
+
+func TestRepresentationContentProtectionWriteToString(t *testing.T) {
+	m := RepresentationContentProtectionProfile()
+	require.NotNil(t, m)
+	xmlStr, err := m.WriteToString()
+	require.NoError(t, err)
+	testfixtures.CompareFixture(t, "fixtures/representation_content_protection.mpd", xmlStr)
+}
+
+func TestRepresentationContentProtectionRead(t *testing.T) {
+	m, err := ReadFromFile("fixtures/representation_content_protection.mpd")
+	require.NoError(t, err)
+
+	reps := m.Periods[0].AdaptationSets[0].Representations
+	require.EqualInt(t, 2, len(reps))
+
+	expected := []struct {
+		defaultKID string
+		types      []string
+	}{
+		{defaultKID: "08e36702-8f33-436c-a5dd-60ffe5571e60", types: []string{"*mpd.CENCContentProtection", "*mpd.WidevineContentProtection", "*mpd.PlayreadyContentProtection"}},
+		{defaultKID: "5abdd52f-554a-4f2a-b8d0-61f761425155", types: []string{"*mpd.CENCContentProtection", "*mpd.WidevineContentProtection", "*mpd.PlayreadyContentProtection"}},
+	}
+	for i, e := range expected {
+		require.EqualInt(t, len(e.types), len(reps[i].ContentProtection))
+		for j := range e.types {
+			require.EqualString(t, e.types[j], fmt.Sprintf("%T", reps[i].ContentProtection[j]))
+		}
+		require.EqualStringPtr(t, ptrs.Strptr(e.defaultKID), reps[i].ContentProtection[0].(*CENCContentProtection).DefaultKID)
+	}
+}
@@ -89,7 +89,7 @@ func TestNewDynamicMPDLive(t *testing.T) {
 		MinimumUpdatePeriod:       Strptr(VALID_MINIMUM_UPDATE_PERIOD),
 		period:                    &Period{},
 		Periods:                   []*Period{{}},
-		UTCTiming:                 &DescriptorType{},
+		UTCTiming:                 []*DescriptorType{{}},
 		PublishTime:               Strptr(VALID_PUBLISH_TIME),
 	}
 
@@ -269,6 +269,21 @@ func TestAddNewContentProtectionRootErrorInvalidLengthDefaultKID(t *testing.T) {
 	require.Nil(t, cp)
 }
 
+func TestRemoveContentProtection(t *testing.T) {
+	m := NewMPD(DASH_PROFILE_LIVE, VALID_MEDIA_PRESENTATION_DURATION, VALID_MIN_BUFFER_TIME)
+	s, _ := m.AddNewAdaptationSetVideoWithID("7357", DASH_MIME_TYPE_VIDEO_MP4, VALID_SCAN_TYPE, VALID_SEGMENT_ALIGNMENT, VALID_START_WITH_SAP)
+	_, _ = s.AddNewContentProtectionRoot("08e367028f33436ca5dd60ffe5571e60")
+	_, _ = s.AddNewContentProtectionSchemeWidevine()
+	r, _ := s.AddNewRepresentationVideo(VALID_VIDEO_BITRATE, VALID_VIDEO_CODEC, VALID_VIDEO_ID, VALID_VIDEO_FRAMERATE, VALID_VIDEO_WIDTH, VALID_VIDEO_HEIGHT)
+	_, _ = r.AddNewContentProtectionRoot("08e367028f33436ca5dd60ffe5571e60")
+
+	s.RemoveContentProtection("URN:UUID:EDEF8BA9-79D6-4ACE-A3C8-27DCD51D21ED")
+	require.EqualInt(t, 1, len(s.ContentProtection))
+	require.EqualStringPtr(t, Strptr(CONTENT_PROTECTION_ROOT_SCHEME_ID_URI), s.ContentProtection[0].(*CENCContentProtection).SchemeIDURI)
+	r.RemoveContentProtection(CONTENT_PROTECTION_ROOT_SCHEME_ID_URI)
+	require.EqualInt(t, 0, len(r.ContentProtection))
+}
+
 func TestAddNewContentProtectionRootErrorEmptyDefaultKID(t *testing.T) {
 	m := NewMPD(DASH_PROFILE_LIVE, VALID_MEDIA_PRESENTATION_DURATION, VALID_MIN_BUFFER_TIME)
 	s, _ := m.AddNewAdaptationSetVideoWithID("7357", DASH_MIME_TYPE_VIDEO_MP4, VALID_SCAN_TYPE, VALID_SEGMENT_ALIGNMENT, VALID_START_WITH_SAP)
@@ -609,3 +624,34 @@ func TestSetInbandEventStreamError(t *testing.T) {
 
 	require.EqualErr(t, ErrInbandEventStreamSchemeUriEmpty, err)
 }
+
+func TestAddNewContentProtectionRootToRepresentationErrorInvalidLengthDefaultKID(t *testing.T) {
+	m := NewMPD(DASH_PROFILE_LIVE, VALID_MEDIA_PRESENTATION_DURATION, VALID_MIN_BUFFER_TIME)
+	s, _ := m.AddNewAdaptationSetVideoWithID("7357", DASH_MIME_TYPE_VIDEO_MP4, VALID_SCAN_TYPE, VALID_SEGMENT_ALIGNMENT, VALID_START_WITH_SAP)
+	r, _ := s.AddNewRepresentationVideo(VALID_VIDEO_BITRATE, VALID_VIDEO_CODEC, VALID_VIDEO_ID, VALID_VIDEO_FRAMERATE, VALID_VIDEO_WIDTH, VALID_VIDEO_HEIGHT)
+
+	cp, err := r.AddNewContentProtectionRoot("invalidkid")
+	require.NotNil(t, err)
+	require.EqualErr(t, ErrInvalidDefaultKID, err)
+	require.Nil(t, cp)
+}
+
+func TestAddNewContentProtectionSchemePlayreadyToRepresentationErrorEmptyPRO(t *testing.T) {
+	m := NewMPD(DASH_PROFILE_LIVE, VALID_MEDIA_PRESENTATION_DURATION, VALID_MIN_BUFFER_TIME)
+	s, _ := m.AddNewAdaptationSetVideoWithID("7357", DASH_MIME_TYPE_VIDEO_MP4, VALID_SCAN_TYPE, VALID_SEGMENT_ALIGNMENT, VALID_START_WITH_SAP)
+	r, _ := s.AddNewRepresentationVideo(VALID_VIDEO_BITRATE, VALID_VIDEO_CODEC, VALID_VIDEO_ID, VALID_VIDEO_FRAMERATE, VALID_VIDEO_WIDTH, VALID_VIDEO_HEIGHT)
+
+	cp, err := r.AddNewContentProtectionSchemePlayreadyWithPSSH("")
+	require.NotNil(t, err)
+	require.EqualErr(t, ErrPROEmpty, err)
+	require.Nil(t, cp)
+}
+
+func TestAddContentProtectionToRepresentationErrorNil(t *testing.T) {
+	m := NewMPD(DASH_PROFILE_LIVE, VALID_MEDIA_PRESENTATION_DURATION, VALID_MIN_BUFFER_TIME)
+	s, _ := m.AddNewAdaptationSetVideoWithID("7357", DASH_MIME_TYPE_VIDEO_MP4, VALID_SCAN_TYPE, VALID_SEGMENT_ALIGNMENT, VALID_START_WITH_SAP)
+	r, _ := s.AddNewRepresentationVideo(VALID_VIDEO_BITRATE, VALID_VIDEO_CODEC, VALID_VIDEO_ID, VALID_VIDEO_FRAMERATE, VALID_VIDEO_WIDTH, VALID_VIDEO_HEIGHT)
+
+	err := r.AddContentProtection(nil)
+	require.EqualErr(t, ErrContentProtectionNil, err)
+}
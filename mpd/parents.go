@@ -0,0 +1,97 @@
+package mpd
+
+import "encoding/xml"
+
+// wrappedMPD provides the default xml unmarshal of an MPD
+type wrappedMPD MPD
+
+func (m *MPD) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var n wrappedMPD
+	if err := d.DecodeElement(&n, &start); err != nil {
+		return err
+	}
+	*m = MPD(n)
+	m.setParents()
+	if len(m.Periods) > 0 {
+		m.period = m.Periods[len(m.Periods)-1]
+	}
+	return nil
+}
+
+// setParents links every Period to the MPD and every element with an AdaptationSet back-reference
+// to its AdaptationSet, as the builder functions do.
+func (m *MPD) setParents() {
+	for _, period := range m.Periods {
+		if period == nil {
+			continue
+		}
+		period.mpd = m
+		for _, as := range period.AdaptationSets {
+			if as != nil {
+				as.period = period
+				as.setParents()
+			}
+		}
+	}
+}
+
+func (as *AdaptationSet) setParents() {
+	for _, cp := range as.ContentProtection {
+		setContentProtectionParent(cp, as)
+	}
+	for _, role := range as.Roles {
+		if role != nil {
+			role.AdaptationSet = as
+		}
+	}
+	for _, a := range as.AccessibilityElems {
+		if a != nil {
+			a.AdaptationSet = as
+		}
+	}
+	if as.SegmentTemplate != nil {
+		as.SegmentTemplate.AdaptationSet = as
+	}
+	for _, r := range as.Representations {
+		if r == nil {
+			continue
+		}
+		r.AdaptationSet = as
+		for _, cp := range r.ContentProtection {
+			setContentProtectionParent(cp, as)
+		}
+		if r.SegmentTemplate != nil {
+			r.SegmentTemplate.AdaptationSet = as
+		}
+	}
+}
+
+// contentProtectionParent is implemented by ContentProtection, and so by the types embedding it.
+type contentProtectionParent interface {
+	setAdaptationSet(as *AdaptationSet)
+}
+
+func (s *ContentProtection) setAdaptationSet(as *AdaptationSet) {
+	s.AdaptationSet = as
+}
+
+func setContentProtectionParent(cp ContentProtectioner, as *AdaptationSet) {
+	if p, ok := cp.(contentProtectionParent); ok {
+		p.setAdaptationSet(as)
+	}
+}
+
+// Parent returns the AdaptationSet of the Representation, nil when it was not added to one.
+func (r *Representation) Parent() *AdaptationSet {
+	return r.AdaptationSet
+}
+
+// Period returns the Period of the AdaptationSet, nil when it was not added to one.
+func (as *AdaptationSet) Period() *Period {
+	return as.period
+}
+
+// MPD returns the MPD of the Period, nil when it was not added to one.
+func (period *Period) MPD() *MPD {
+	return period.mpd
+}
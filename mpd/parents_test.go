@@ -0,0 +1,89 @@
+package mpd
+
+import (
+	"testing"
+
+	"github.com/zencoder/go-dash/v3/helpers/require"
+)
+
+func TestReadSetsParents(t *testing.T) {
+	m, err := ReadFromFile("fixtures/live_profile.mpd")
+	require.NoError(t, err)
+
+	p := m.Periods[0]
+	if p.MPD() != m {
+		t.Errorf("Expected the Period to link to the MPD")
+	}
+	for _, as := range p.AdaptationSets {
+		if as.Period() != p {
+			t.Errorf("Expected AdaptationSet %s to link to its Period", *as.ID)
+		}
+		if as.SegmentTemplate != nil && as.SegmentTemplate.AdaptationSet != as {
+			t.Errorf("Expected SegmentTemplate to link to AdaptationSet %s", *as.ID)
+		}
+		for _, r := range as.Representations {
+			if r.Parent() != as {
+				t.Errorf("Expected Representation %s to link to AdaptationSet %s", *r.ID, *as.ID)
+			}
+		}
+		for _, role := range as.Roles {
+			if role.AdaptationSet != as {
+				t.Errorf("Expected Role to link to AdaptationSet %s", *as.ID)
+			}
+		}
+		for _, a := range as.AccessibilityElems {
+			if a.AdaptationSet != as {
+				t.Errorf("Expected Accessibility to link to AdaptationSet %s", *as.ID)
+			}
+		}
+	}
+
+	cenc, ok := p.AdaptationSets[0].ContentProtection[0].(*CENCContentProtection)
+	if !ok || cenc.AdaptationSet != p.AdaptationSets[0] {
+		t.Errorf("Expected ContentProtection to link to its AdaptationSet")
+	}
+	if m.GetCurrentPeriod() != p {
+		t.Errorf("Expected the current Period to be the decoded one")
+	}
+}
+
+func TestReadCurrentPeriodIsLast(t *testing.T) {
+	m, err := ReadFromFile("fixtures/segment_timeline_multi_period.mpd")
+	require.NoError(t, err)
+	if m.GetCurrentPeriod() != m.Periods[len(m.Periods)-1] {
+		t.Errorf("Expected the current Period to be the last one")
+	}
+
+	// The builder API works on decoded manifests
+	as, err := m.AddNewAdaptationSetAudioWithID("9", DASH_MIME_TYPE_AUDIO_MP4, VALID_SEGMENT_ALIGNMENT, VALID_START_WITH_SAP, VALID_LANG)
+	require.NoError(t, err)
+	r, err := as.AddNewRepresentationAudio(VALID_AUDIO_SAMPLE_RATE, VALID_AUDIO_BITRATE, VALID_AUDIO_CODEC, VALID_AUDIO_ID)
+	require.NoError(t, err)
+	last := m.Periods[len(m.Periods)-1]
+	require.EqualString(t, "9", *last.AdaptationSets[len(last.AdaptationSets)-1].ID)
+	if as.Period() != last || r.Parent() != as || as.Period().MPD() != m {
+		t.Errorf("Expected the new AdaptationSet to be linked into the last Period")
+	}
+}
+
+func TestBuilderSetsParents(t *testing.T) {
+	m := NewDynamicMPD(DASH_PROFILE_LIVE, VALID_AVAILABILITY_START_TIME, VALID_MIN_BUFFER_TIME)
+	as, err := m.AddNewAdaptationSetVideo(DASH_MIME_TYPE_VIDEO_MP4, VALID_SCAN_TYPE, VALID_SEGMENT_ALIGNMENT, VALID_START_WITH_SAP)
+	require.NoError(t, err)
+	cp, err := as.AddNewContentProtectionRoot("08e367028f33436ca5dd60ffe5571e60")
+	require.NoError(t, err)
+	if m.Periods[0].MPD() != m || as.Period() != m.Periods[0] || cp.AdaptationSet != as {
+		t.Errorf("Expected builder functions to link parents")
+	}
+
+	p := m.AddNewPeriod()
+	p.ID = "p1"
+	if p.MPD() != m {
+		t.Errorf("Expected the new Period to link to the MPD")
+	}
+
+	clone := m.Clone()
+	if clone.Periods[1].MPD() != clone || clone.Periods[0].AdaptationSets[0].Period() != clone.Periods[0] {
+		t.Errorf("Expected the clone to link to its own Periods")
+	}
+}
@@ -0,0 +1,438 @@
+package mpd
+
+import (
+	"bytes"
+	"crypto/aes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+)
+
+// Constants for the PlayReady Header (WRMHEADER) versions
+const (
+	PLAYREADY_HEADER_VERSION_4_0 = "4.0.0.0"
+	PLAYREADY_HEADER_VERSION_4_1 = "4.1.0.0"
+	PLAYREADY_HEADER_VERSION_4_2 = "4.2.0.0"
+	PLAYREADY_HEADER_VERSION_4_3 = "4.3.0.0"
+	PLAYREADY_HEADER_XMLNS       = "http://schemas.microsoft.com/DRM/2007/03/PlayReadyHeader"
+)
+
+// Constants for the PlayReady content encryption algorithms
+const (
+	PLAYREADY_ALGID_AESCTR = "AESCTR"
+	PLAYREADY_ALGID_AESCBC = "AESCBC"
+)
+
+// PlayReady Object record types
+const (
+	playreadyRecordRightsManagementHeader = 1
+)
+
+var (
+	ErrPlayreadyHeaderNil       = errors.New("PlayReady header nil")
+	ErrPlayreadyNoKIDs          = errors.New("PlayReady header has no KIDs")
+	ErrPlayreadyInvalidKID      = errors.New("Invalid PlayReady KID, should be 16 bytes")
+	ErrPlayreadyInvalidVersion  = errors.New("Invalid PlayReady header version")
+	ErrPlayreadyTooManyKIDs     = errors.New("PlayReady header versions 4.0 and 4.1 only support a single KID")
+	ErrPlayreadyInvalidAlgID    = errors.New("PlayReady algorithm ID not supported by header version")
+	ErrPlayreadyPROTruncated    = errors.New("PlayReady Object truncated")
+	ErrPlayreadyNoHeaderRecord  = errors.New("PlayReady Object has no rights management header record")
+	ErrPlayreadyPRONotAvailable = errors.New("PlayReady Content Protection has no PRO or PSSH")
+	ErrPlayreadyPROTooLarge     = errors.New("PlayReady Object header record larger than 65535 bytes")
+)
+
+// PlayreadyKID is a key ID in a PlayReady Header.
+type PlayreadyKID struct {
+	// KID in UUID byte order, as used by cenc:default_KID. It is converted to and from
+	// the little-endian GUID byte order used by PlayReady when encoding and parsing.
+	KID []byte
+	// Algorithm is the encryption algorithm, either AESCTR or AESCBC. It is required for
+	// header versions 4.0 to 4.2 and defaults to AESCTR.
+	Algorithm string
+	// Checksum is the optional key checksum. When it is empty and ContentKey is set, the
+	// checksum is computed for AESCTR keys.
+	Checksum []byte
+	// ContentKey is only used to compute the checksum, it is never written into the header.
+	ContentKey []byte
+}
+
+// String returns the KID formatted like cenc:default_KID.
+func (k PlayreadyKID) String() string {
+	h := hex.EncodeToString(k.KID)
+	if len(h) != 32 {
+		return h
+	}
+	return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:32]
+}
+
+// PlayreadyHeader is the PlayReady Header (WRMHEADER) carried in a PlayReady Object (PRO).
+type PlayreadyHeader struct {
+	// Version is one of the PLAYREADY_HEADER_VERSION constants. When empty, the lowest version
+	// that can express the KIDs and algorithms is used.
+	Version          string
+	KIDs             []PlayreadyKID
+	LAURL            string // License acquisition URL
+	LUIURL           string // License UI URL
+	DSID             string // Domain service ID
+	CustomAttributes string // Raw XML content of the CUSTOMATTRIBUTES element
+	DecryptorSetup   string // i.e. ONDEMAND
+}
+
+type wrmHeader struct {
+	XMLName xml.Name `xml:"WRMHEADER"`
+	XMLNS   string   `xml:"xmlns,attr"`
+	Version string   `xml:"version,attr"`
+	Data    wrmData  `xml:"DATA"`
+}
+
+type wrmData struct {
+	ProtectInfo      *wrmProtectInfo      `xml:"PROTECTINFO,omitempty"`
+	KID              string               `xml:"KID,omitempty"`      // 4.0 only
+	Checksum         string               `xml:"CHECKSUM,omitempty"` // 4.0 only
+	LAURL            string               `xml:"LA_URL,omitempty"`
+	LUIURL           string               `xml:"LUI_URL,omitempty"`
+	DSID             string               `xml:"DS_ID,omitempty"`
+	CustomAttributes *wrmCustomAttributes `xml:"CUSTOMATTRIBUTES,omitempty"`
+	DecryptorSetup   string               `xml:"DECRYPTORSETUP,omitempty"`
+}
+
+type wrmProtectInfo struct {
+	KeyLen string   `xml:"KEYLEN,omitempty"` // 4.0 only
+	AlgID  string   `xml:"ALGID,omitempty"`  // 4.0 only
+	KID    *wrmKID  `xml:"KID,omitempty"`    // 4.1 only
+	KIDs   *wrmKIDs `xml:"KIDS,omitempty"`   // 4.2 and later
+}
+
+type wrmKIDs struct {
+	KID []wrmKID `xml:"KID"`
+}
+
+type wrmKID struct {
+	AlgID    string `xml:"ALGID,attr,omitempty"`
+	Checksum string `xml:"CHECKSUM,attr,omitempty"`
+	Value    string `xml:"VALUE,attr"`
+}
+
+type wrmCustomAttributes struct {
+	InnerXML string `xml:",innerxml"`
+}
+
+// uuidToGUID swaps the first three fields of a UUID into the little-endian GUID byte order, and back.
+func uuidToGUID(kid []byte) []byte {
+	g := make([]byte, 16)
+	copy(g, kid)
+	g[0], g[1], g[2], g[3] = kid[3], kid[2], kid[1], kid[0]
+	g[4], g[5] = kid[5], kid[4]
+	g[6], g[7] = kid[7], kid[6]
+	return g
+}
+
+// checksum returns the explicit checksum, or computes it from the content key for AESCTR keys:
+// the first 8 bytes of the GUID KID encrypted with the content key using AES-ECB.
+func (k PlayreadyKID) checksum() ([]byte, error) {
+	if len(k.Checksum) > 0 || len(k.ContentKey) == 0 || (k.Algorithm != "" && k.Algorithm != PLAYREADY_ALGID_AESCTR) {
+		return k.Checksum, nil
+	}
+	block, err := aes.NewCipher(k.ContentKey)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, 16)
+	block.Encrypt(out, uuidToGUID(k.KID))
+	return out[:8], nil
+}
+
+func (h *PlayreadyHeader) version() string {
+	if h.Version != "" {
+		return h.Version
+	}
+	for _, k := range h.KIDs {
+		if k.Algorithm != "" && k.Algorithm != PLAYREADY_ALGID_AESCTR {
+			return PLAYREADY_HEADER_VERSION_4_3
+		}
+	}
+	if len(h.KIDs) > 1 {
+		return PLAYREADY_HEADER_VERSION_4_2
+	}
+	return PLAYREADY_HEADER_VERSION_4_0
+}
+
+// MarshalWRMHeader encodes the PlayReady Header as UTF-16LE XML.
+func (h *PlayreadyHeader) MarshalWRMHeader() ([]byte, error) {
+	version := h.version()
+	if len(h.KIDs) == 0 {
+		return nil, ErrPlayreadyNoKIDs
+	}
+
+	kids := make([]wrmKID, len(h.KIDs))
+	for i, k := range h.KIDs {
+		if len(k.KID) != 16 {
+			return nil, ErrPlayreadyInvalidKID
+		}
+		checksum, err := k.checksum()
+		if err != nil {
+			return nil, err
+		}
+		algID := k.Algorithm
+		if algID == "" && version != PLAYREADY_HEADER_VERSION_4_3 {
+			algID = PLAYREADY_ALGID_AESCTR
+		}
+		if algID != "" && algID != PLAYREADY_ALGID_AESCTR && (algID != PLAYREADY_ALGID_AESCBC || version != PLAYREADY_HEADER_VERSION_4_3) {
+			return nil, ErrPlayreadyInvalidAlgID
+		}
+		kids[i] = wrmKID{AlgID: algID, Value: base64.StdEncoding.EncodeToString(uuidToGUID(k.KID))}
+		if len(checksum) > 0 {
+			kids[i].Checksum = base64.StdEncoding.EncodeToString(checksum)
+		}
+	}
+
+	header := wrmHeader{
+		XMLNS:   PLAYREADY_HEADER_XMLNS,
+		Version: version,
+		Data: wrmData{
+			LAURL:          h.LAURL,
+			LUIURL:         h.LUIURL,
+			DSID:           h.DSID,
+			DecryptorSetup: h.DecryptorSetup,
+		},
+	}
+	if h.CustomAttributes != "" {
+		header.Data.CustomAttributes = &wrmCustomAttributes{InnerXML: h.CustomAttributes}
+	}
+
+	switch version {
+	case PLAYREADY_HEADER_VERSION_4_0:
+		if len(kids) > 1 {
+			return nil, ErrPlayreadyTooManyKIDs
+		}
+		header.Data.ProtectInfo = &wrmProtectInfo{KeyLen: "16", AlgID: kids[0].AlgID}
+		header.Data.KID = kids[0].Value
+		header.Data.Checksum = kids[0].Checksum
+	case PLAYREADY_HEADER_VERSION_4_1:
+		if len(kids) > 1 {
+			return nil, ErrPlayreadyTooManyKIDs
+		}
+		header.Data.ProtectInfo = &wrmProtectInfo{KID: &kids[0]}
+	case PLAYREADY_HEADER_VERSION_4_2, PLAYREADY_HEADER_VERSION_4_3:
+		header.Data.ProtectInfo = &wrmProtectInfo{KIDs: &wrmKIDs{KID: kids}}
+	default:
+		return nil, ErrPlayreadyInvalidVersion
+	}
+
+	b, err := xml.Marshal(header)
+	if err != nil {
+		return nil, err
+	}
+	return encodeUTF16LE(string(b)), nil
+}
+
+// MarshalPRO encodes the PlayReady Header into a PlayReady Object with a single rights management
+// header record. The record length is limited to 65535 bytes.
+func (h *PlayreadyHeader) MarshalPRO() ([]byte, error) {
+	wrm, err := h.MarshalWRMHeader()
+	if err != nil {
+		return nil, err
+	}
+	if len(wrm) > 0xFFFF {
+		return nil, ErrPlayreadyPROTooLarge
+	}
+	buf := &bytes.Buffer{}
+	_ = binary.Write(buf, binary.LittleEndian, uint32(4+2+2+2+len(wrm)))
+	_ = binary.Write(buf, binary.LittleEndian, uint16(1))
+	_ = binary.Write(buf, binary.LittleEndian, uint16(playreadyRecordRightsManagementHeader))
+	_ = binary.Write(buf, binary.LittleEndian, uint16(len(wrm)))
+	buf.Write(wrm)
+	return buf.Bytes(), nil
+}
+
+// EncodePRO returns the PlayReady Object as a Base64 string, as used for <mspr:pro>.
+func (h *PlayreadyHeader) EncodePRO() (string, error) {
+	pro, err := h.MarshalPRO()
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(pro), nil
+}
+
+// HasKID reports whether the header contains the given key ID, formatted as a cenc:default_KID
+// or as a 32 character hex string.
+func (h *PlayreadyHeader) HasKID(defaultKID string) bool {
+	want := strings.ToLower(strings.ReplaceAll(defaultKID, "-", ""))
+	for _, k := range h.KIDs {
+		if hex.EncodeToString(k.KID) == want {
+			return true
+		}
+	}
+	return false
+}
+
+// ParsePRO decodes the PlayReady Header from a binary PlayReady Object.
+func ParsePRO(pro []byte) (*PlayreadyHeader, error) {
+	if len(pro) < 6 {
+		return nil, ErrPlayreadyPROTruncated
+	}
+	length := binary.LittleEndian.Uint32(pro[0:4])
+	if int(length) != len(pro) {
+		return nil, fmt.Errorf("PlayReady Object length mismatch, header: %d, actual: %d", length, len(pro))
+	}
+	count := int(binary.LittleEndian.Uint16(pro[4:6]))
+	offset := 6
+	for i := 0; i < count; i++ {
+		if len(pro) < offset+4 {
+			return nil, ErrPlayreadyPROTruncated
+		}
+		recordType := binary.LittleEndian.Uint16(pro[offset : offset+2])
+		recordLength := int(binary.LittleEndian.Uint16(pro[offset+2 : offset+4]))
+		offset += 4
+		if len(pro) < offset+recordLength {
+			return nil, ErrPlayreadyPROTruncated
+		}
+		if recordType == playreadyRecordRightsManagementHeader {
+			return ParseWRMHeader(pro[offset : offset+recordLength])
+		}
+		offset += recordLength
+	}
+	return nil, ErrPlayreadyNoHeaderRecord
+}
+
+// DecodePRO decodes the PlayReady Header from a Base64 PlayReady Object, as used for <mspr:pro>.
+func DecodePRO(pro string) (*PlayreadyHeader, error) {
+	b, err := base64.StdEncoding.DecodeString(pro)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePRO(b)
+}
+
+// ParseWRMHeader decodes a UTF-16LE PlayReady Header.
+func ParseWRMHeader(wrm []byte) (*PlayreadyHeader, error) {
+	if len(wrm)%2 != 0 {
+		return nil, ErrPlayreadyPROTruncated
+	}
+	var header wrmHeader
+	if err := xml.Unmarshal([]byte(decodeUTF16LE(wrm)), &header); err != nil {
+		return nil, err
+	}
+
+	h := &PlayreadyHeader{
+		Version:        header.Version,
+		LAURL:          header.Data.LAURL,
+		LUIURL:         header.Data.LUIURL,
+		DSID:           header.Data.DSID,
+		DecryptorSetup: header.Data.DecryptorSetup,
+	}
+	if header.Data.CustomAttributes != nil {
+		h.CustomAttributes = header.Data.CustomAttributes.InnerXML
+	}
+
+	var kids []wrmKID
+	switch header.Version {
+	case PLAYREADY_HEADER_VERSION_4_0:
+		kid := wrmKID{Value: header.Data.KID, Checksum: header.Data.Checksum}
+		if header.Data.ProtectInfo != nil {
+			kid.AlgID = header.Data.ProtectInfo.AlgID
+		}
+		kids = []wrmKID{kid}
+	case PLAYREADY_HEADER_VERSION_4_1:
+		if header.Data.ProtectInfo != nil && header.Data.ProtectInfo.KID != nil {
+			kids = []wrmKID{*header.Data.ProtectInfo.KID}
+		}
+	case PLAYREADY_HEADER_VERSION_4_2, PLAYREADY_HEADER_VERSION_4_3:
+		if header.Data.ProtectInfo != nil && header.Data.ProtectInfo.KIDs != nil {
+			kids = header.Data.ProtectInfo.KIDs.KID
+		}
+	default:
+		return nil, ErrPlayreadyInvalidVersion
+	}
+
+	for _, k := range kids {
+		guid, err := base64.StdEncoding.DecodeString(k.Value)
+		if err != nil {
+			return nil, err
+		}
+		if len(guid) != 16 {
+			return nil, ErrPlayreadyInvalidKID
+		}
+		kid := PlayreadyKID{KID: uuidToGUID(guid), Algorithm: k.AlgID}
+		if k.Checksum != "" {
+			kid.Checksum, err = base64.StdEncoding.DecodeString(k.Checksum)
+			if err != nil {
+				return nil, err
+			}
+		}
+		h.KIDs = append(h.KIDs, kid)
+	}
+	return h, nil
+}
+
+func encodeUTF16LE(s string) []byte {
+	u := utf16.Encode([]rune(s))
+	b := make([]byte, len(u)*2)
+	for i, c := range u {
+		binary.LittleEndian.PutUint16(b[i*2:], c)
+	}
+	return b
+}
+
+func decodeUTF16LE(b []byte) string {
+	u := make([]uint16, len(b)/2)
+	for i := range u {
+		u[i] = binary.LittleEndian.Uint16(b[i*2:])
+	}
+	// Drop the byte order mark some encoders write
+	if len(u) > 0 && u[0] == 0xfeff {
+		u = u[1:]
+	}
+	return string(utf16.Decode(u))
+}
+
+// Header decodes the PlayReady Header from the <mspr:pro> element, or from the <cenc:pssh>
+// element when there is no PRO.
+func (s *PlayreadyContentProtection) Header() (*PlayreadyHeader, error) {
+	if s.PRO != nil {
+		return DecodePRO(*s.PRO)
+	}
+	if s.PSSH == nil {
+		return nil, ErrPlayreadyPRONotAvailable
+	}
+	box, err := base64.StdEncoding.DecodeString(*s.PSSH)
+	if err != nil {
+		return nil, err
+	}
+	pssh, err := ParsePSSHBox(box)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePRO(pssh.Data)
+}
+
+// AddNewContentProtectionSchemePlayreadyWithHeader adds a new content protection scheme for PlayReady DRM to the
+// adaptation set. The PRO is generated from the header and signalled in both mspr:pro and cenc:pssh subelements.
+func (as *AdaptationSet) AddNewContentProtectionSchemePlayreadyWithHeader(h *PlayreadyHeader) (*PlayreadyContentProtection, error) {
+	if h == nil {
+		return nil, ErrPlayreadyHeaderNil
+	}
+	pro, err := h.EncodePRO()
+	if err != nil {
+		return nil, err
+	}
+	return as.AddNewContentProtectionSchemePlayreadyWithPSSH(pro)
+}
+
+// AddNewContentProtectionSchemePlayreadyWithHeader adds a new content protection scheme for PlayReady DRM to the
+// representation. The PRO is generated from the header and signalled in both mspr:pro and cenc:pssh subelements.
+func (r *Representation) AddNewContentProtectionSchemePlayreadyWithHeader(h *PlayreadyHeader) (*PlayreadyContentProtection, error) {
+	if h == nil {
+		return nil, ErrPlayreadyHeaderNil
+	}
+	pro, err := h.EncodePRO()
+	if err != nil {
+		return nil, err
+	}
+	return r.AddNewContentProtectionSchemePlayreadyWithPSSH(pro)
+}
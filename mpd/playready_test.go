@@ -0,0 +1,194 @@
+package mpd
+
+import (
+	"crypto/aes"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/zencoder/go-dash/v3/helpers/require"
+)
+
+func TestDecodePRO(t *testing.T) {
+	h, err := DecodePRO(VALID_PLAYREADY_PRO)
+	require.NoError(t, err)
+
+	require.EqualString(t, PLAYREADY_HEADER_VERSION_4_0, h.Version)
+	require.EqualInt(t, 1, len(h.KIDs))
+	require.EqualString(t, "5abdd52f-554a-4f2a-b8d0-61f761425155", h.KIDs[0].String())
+	require.EqualString(t, PLAYREADY_ALGID_AESCTR, h.KIDs[0].Algorithm)
+	require.EqualString(t, "IKzY2HZLAlI=", base64.StdEncoding.EncodeToString(h.KIDs[0].Checksum))
+	require.EqualString(t, "", h.LAURL)
+}
+
+func TestPlayreadyHeaderRoundTrip(t *testing.T) {
+	h, err := DecodePRO(VALID_PLAYREADY_PRO)
+	require.NoError(t, err)
+
+	pro, err := h.EncodePRO()
+	require.NoError(t, err)
+	require.EqualString(t, VALID_PLAYREADY_PRO, pro)
+}
+
+func TestPlayreadyHeaderVersions(t *testing.T) {
+	kid1, _ := hex.DecodeString("08e367028f33436ca5dd60ffe5571e60")
+	kid2, _ := hex.DecodeString("5abdd52f554a4f2ab8d061f761425155")
+
+	testCases := []struct {
+		name     string
+		header   *PlayreadyHeader
+		expected string
+	}{
+		{
+			name:     "4.0 default",
+			header:   &PlayreadyHeader{KIDs: []PlayreadyKID{{KID: kid1}}, LAURL: "https://pr.example.com/rightsmanager.asmx"},
+			expected: `<WRMHEADER xmlns="http://schemas.microsoft.com/DRM/2007/03/PlayReadyHeader" version="4.0.0.0"><DATA><PROTECTINFO><KEYLEN>16</KEYLEN><ALGID>AESCTR</ALGID></PROTECTINFO><KID>AmfjCDOPbEOl3WD/5VceYA==</KID><LA_URL>https://pr.example.com/rightsmanager.asmx</LA_URL></DATA></WRMHEADER>`,
+		},
+		{
+			name:     "4.1",
+			header:   &PlayreadyHeader{Version: PLAYREADY_HEADER_VERSION_4_1, KIDs: []PlayreadyKID{{KID: kid1}}},
+			expected: `<WRMHEADER xmlns="http://schemas.microsoft.com/DRM/2007/03/PlayReadyHeader" version="4.1.0.0"><DATA><PROTECTINFO><KID ALGID="AESCTR" VALUE="AmfjCDOPbEOl3WD/5VceYA=="></KID></PROTECTINFO></DATA></WRMHEADER>`,
+		},
+		{
+			name:     "4.2 default for multiple KIDs",
+			header:   &PlayreadyHeader{KIDs: []PlayreadyKID{{KID: kid1}, {KID: kid2}}},
+			expected: `<WRMHEADER xmlns="http://schemas.microsoft.com/DRM/2007/03/PlayReadyHeader" version="4.2.0.0"><DATA><PROTECTINFO><KIDS><KID ALGID="AESCTR" VALUE="AmfjCDOPbEOl3WD/5VceYA=="></KID><KID ALGID="AESCTR" VALUE="L9W9WkpVKk+40GH3YUJRVQ=="></KID></KIDS></PROTECTINFO></DATA></WRMHEADER>`,
+		},
+		{
+			name: "4.3 default for AESCBC",
+			header: &PlayreadyHeader{
+				KIDs:             []PlayreadyKID{{KID: kid1, Algorithm: PLAYREADY_ALGID_AESCBC}},
+				LUIURL:           "https://pr.example.com/ui",
+				CustomAttributes: "<ContentID>content-1</ContentID>",
+			},
+			expected: `<WRMHEADER xmlns="http://schemas.microsoft.com/DRM/2007/03/PlayReadyHeader" version="4.3.0.0"><DATA><PROTECTINFO><KIDS><KID ALGID="AESCBC" VALUE="AmfjCDOPbEOl3WD/5VceYA=="></KID></KIDS></PROTECTINFO><LUI_URL>https://pr.example.com/ui</LUI_URL><CUSTOMATTRIBUTES><ContentID>content-1</ContentID></CUSTOMATTRIBUTES></DATA></WRMHEADER>`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			wrm, err := tc.header.MarshalWRMHeader()
+			require.NoError(t, err)
+			require.EqualString(t, tc.expected, decodeUTF16LE(wrm))
+
+			h, err := ParseWRMHeader(wrm)
+			require.NoError(t, err)
+			require.EqualInt(t, len(tc.header.KIDs), len(h.KIDs))
+			for i := range h.KIDs {
+				require.EqualString(t, hex.EncodeToString(tc.header.KIDs[i].KID), hex.EncodeToString(h.KIDs[i].KID))
+			}
+			require.EqualString(t, tc.header.LAURL, h.LAURL)
+			require.EqualString(t, tc.header.LUIURL, h.LUIURL)
+			require.EqualString(t, tc.header.CustomAttributes, h.CustomAttributes)
+		})
+	}
+}
+
+func TestPlayreadyHeaderErrors(t *testing.T) {
+	kid, _ := hex.DecodeString("08e367028f33436ca5dd60ffe5571e60")
+
+	testCases := []struct {
+		name   string
+		header *PlayreadyHeader
+		err    error
+	}{
+		{name: "no KIDs", header: &PlayreadyHeader{}, err: ErrPlayreadyNoKIDs},
+		{name: "short KID", header: &PlayreadyHeader{KIDs: []PlayreadyKID{{KID: kid[:8]}}}, err: ErrPlayreadyInvalidKID},
+		{name: "multiple KIDs in 4.0", header: &PlayreadyHeader{Version: PLAYREADY_HEADER_VERSION_4_0, KIDs: []PlayreadyKID{{KID: kid}, {KID: kid}}}, err: ErrPlayreadyTooManyKIDs},
+		{name: "AESCBC in 4.2", header: &PlayreadyHeader{Version: PLAYREADY_HEADER_VERSION_4_2, KIDs: []PlayreadyKID{{KID: kid, Algorithm: PLAYREADY_ALGID_AESCBC}}}, err: ErrPlayreadyInvalidAlgID},
+		{name: "unknown version", header: &PlayreadyHeader{Version: "5.0.0.0", KIDs: []PlayreadyKID{{KID: kid}}}, err: ErrPlayreadyInvalidVersion},
+		{name: "long LA_URL", header: &PlayreadyHeader{KIDs: []PlayreadyKID{{KID: kid}}, LAURL: "https://example.com/?" + strings.Repeat("a", 0x8000)}, err: ErrPlayreadyPROTooLarge},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := tc.header.MarshalPRO()
+			require.EqualErr(t, tc.err, err)
+		})
+	}
+
+	// The object length is 32 bits, only the record is limited to 65535 bytes
+	h := &PlayreadyHeader{KIDs: []PlayreadyKID{{KID: kid}}, LAURL: "https://example.com/?"}
+	wrm, err := h.MarshalWRMHeader()
+	require.NoError(t, err)
+	h.LAURL += strings.Repeat("a", (0xFFFF-len(wrm))/2)
+	pro, err := h.MarshalPRO()
+	require.NoError(t, err)
+	if len(pro) <= 0xFFFF {
+		t.Errorf("Expected a PlayReady Object larger than 65535 bytes, got %d", len(pro))
+	}
+}
+
+func TestPlayreadyKIDChecksum(t *testing.T) {
+	kid, _ := hex.DecodeString("08e367028f33436ca5dd60ffe5571e60")
+	key, _ := hex.DecodeString("00112233445566778899aabbccddeeff")
+
+	h := &PlayreadyHeader{KIDs: []PlayreadyKID{{KID: kid, ContentKey: key}}}
+	pro, err := h.MarshalPRO()
+	require.NoError(t, err)
+
+	parsed, err := ParsePRO(pro)
+	require.NoError(t, err)
+
+	block, _ := aes.NewCipher(key)
+	expected := make([]byte, 16)
+	guid, _ := hex.DecodeString("0267e308338f6c43a5dd60ffe5571e60")
+	block.Encrypt(expected, guid)
+	require.EqualString(t, hex.EncodeToString(expected[:8]), hex.EncodeToString(parsed.KIDs[0].Checksum))
+}
+
+func TestParsePROErrors(t *testing.T) {
+	_, err := ParsePRO([]byte{0x01})
+	require.EqualErr(t, ErrPlayreadyPROTruncated, err)
+
+	_, err = ParsePRO([]byte{0x08, 0x00, 0x00, 0x00, 0x01, 0x00, 0x03, 0x00})
+	require.EqualErr(t, ErrPlayreadyPROTruncated, err)
+
+	_, err = ParsePRO([]byte{0x0a, 0x00, 0x00, 0x00, 0x01, 0x00, 0x03, 0x00, 0x00, 0x00})
+	require.EqualErr(t, ErrPlayreadyNoHeaderRecord, err)
+
+	_, err = ParsePRO([]byte{0x0b, 0x00, 0x00, 0x00, 0x00, 0x00})
+	require.EqualError(t, err, "PlayReady Object length mismatch, header: 11, actual: 6")
+}
+
+func TestPlayreadyHeaderHasKID(t *testing.T) {
+	h, err := DecodePRO(VALID_PLAYREADY_PRO)
+	require.NoError(t, err)
+
+	if !h.HasKID("5abdd52f-554a-4f2a-b8d0-61f761425155") {
+		t.Errorf("Expected header to contain the default KID")
+	}
+	if !h.HasKID("5ABDD52F554A4F2AB8D061F761425155") {
+		t.Errorf("Expected header to contain the hex KID")
+	}
+	if h.HasKID("08e36702-8f33-436c-a5dd-60ffe5571e60") {
+		t.Errorf("Expected header not to contain an unrelated KID")
+	}
+}
+
+func TestAddNewContentProtectionSchemePlayreadyWithHeader(t *testing.T) {
+	m := NewMPD(DASH_PROFILE_LIVE, VALID_MEDIA_PRESENTATION_DURATION, VALID_MIN_BUFFER_TIME)
+	as, _ := m.AddNewAdaptationSetVideo(DASH_MIME_TYPE_VIDEO_MP4, VALID_SCAN_TYPE, VALID_SEGMENT_ALIGNMENT, VALID_START_WITH_SAP)
+
+	_, err := as.AddNewContentProtectionSchemePlayreadyWithHeader(nil)
+	require.EqualErr(t, ErrPlayreadyHeaderNil, err)
+
+	kid, _ := hex.DecodeString("5abdd52f554a4f2ab8d061f761425155")
+	cp, err := as.AddNewContentProtectionSchemePlayreadyWithHeader(&PlayreadyHeader{KIDs: []PlayreadyKID{{KID: kid}}})
+	require.NoError(t, err)
+	require.NotNil(t, cp.PSSH)
+
+	h, err := cp.Header()
+	require.NoError(t, err)
+	require.EqualString(t, "5abdd52f-554a-4f2a-b8d0-61f761425155", h.KIDs[0].String())
+
+	// The header is also recoverable from the PSSH alone
+	cp.PRO = nil
+	h, err = cp.Header()
+	require.NoError(t, err)
+	require.EqualString(t, "5abdd52f-554a-4f2a-b8d0-61f761425155", h.KIDs[0].String())
+
+	r, _ := as.AddNewRepresentationVideo(VALID_VIDEO_BITRATE, VALID_VIDEO_CODEC, VALID_VIDEO_ID, VALID_VIDEO_FRAMERATE, VALID_VIDEO_WIDTH, VALID_VIDEO_HEIGHT)
+	_, err = r.AddNewContentProtectionSchemePlayreadyWithHeader(&PlayreadyHeader{KIDs: []PlayreadyKID{{KID: kid}}})
+	require.NoError(t, err)
+	require.EqualInt(t, 1, len(r.ContentProtection))
+}
@@ -39,3 +39,55 @@ func MakePSSHBox(systemID, payload []byte) ([]byte, error) {
 
 	return psshBuf.Bytes(), nil
 }
+
+// PSSHBox is a decoded Protection System Specific Header box (ISO/IEC 23001-7 8.1).
+type PSSHBox struct {
+	Version  uint8
+	SystemID []byte
+	KIDs     [][]byte // Only present in version 1 boxes
+	Data     []byte
+}
+
+// ParsePSSHBox decodes a binary pssh box, such as the contents of a <cenc:pssh> element.
+func ParsePSSHBox(box []byte) (*PSSHBox, error) {
+	if len(box) < 32 {
+		return nil, fmt.Errorf("PSSH box too short: %d bytes", len(box))
+	}
+	size := binary.BigEndian.Uint32(box[0:4])
+	if int(size) != len(box) {
+		return nil, fmt.Errorf("PSSH box size mismatch, header: %d, actual: %d", size, len(box))
+	}
+	if string(box[4:8]) != "pssh" {
+		return nil, fmt.Errorf("Not a PSSH box, type was: %q", box[4:8])
+	}
+
+	p := &PSSHBox{
+		Version:  box[8],
+		SystemID: box[12:28],
+	}
+	offset := 28
+	if p.Version > 0 {
+		if len(box) < offset+4 {
+			return nil, fmt.Errorf("PSSH box truncated reading KID count")
+		}
+		kidCount := int(binary.BigEndian.Uint32(box[offset : offset+4]))
+		offset += 4
+		if len(box) < offset+kidCount*16 {
+			return nil, fmt.Errorf("PSSH box truncated reading %d KIDs", kidCount)
+		}
+		for i := 0; i < kidCount; i++ {
+			p.KIDs = append(p.KIDs, box[offset:offset+16])
+			offset += 16
+		}
+	}
+	if len(box) < offset+4 {
+		return nil, fmt.Errorf("PSSH box truncated reading data size")
+	}
+	dataSize := int(binary.BigEndian.Uint32(box[offset : offset+4]))
+	offset += 4
+	if len(box) != offset+dataSize {
+		return nil, fmt.Errorf("PSSH box data size mismatch, header: %d, actual: %d", dataSize, len(box)-offset)
+	}
+	p.Data = box[offset:]
+	return p, nil
+}
@@ -61,3 +61,43 @@ func TestMakePSSHBox_NilSystemID(t *testing.T) {
 	_, err := MakePSSHBox(nil, nil)
 	require.EqualError(t, err, "SystemID must be 16 bytes, was: 0")
 }
+
+func TestParsePSSHBox_Widevine(t *testing.T) {
+	payload := getValidWVHeaderBytes()
+	wvSystemID, _ := hex.DecodeString(CONTENT_PROTECTION_WIDEVINE_SCHEME_HEX)
+	psshBox, err := MakePSSHBox(wvSystemID, payload)
+	require.NoError(t, err)
+
+	p, err := ParsePSSHBox(psshBox)
+	require.NoError(t, err)
+	require.EqualInt(t, 0, int(p.Version))
+	require.EqualString(t, CONTENT_PROTECTION_WIDEVINE_SCHEME_HEX, hex.EncodeToString(p.SystemID))
+	require.EqualInt(t, 0, len(p.KIDs))
+	require.EqualString(t, string(payload), string(p.Data))
+}
+
+func TestParsePSSHBox_Version1(t *testing.T) {
+	// Version 1 ClearKey box with a single KID and no data
+	box, err := hex.DecodeString("00000034707373680100000010773ba1b0484e6b9f51d2f8d6e8e9d4000000015abdd52f554a4f2ab8d061f76142515500000000")
+	require.NoError(t, err)
+
+	p, err := ParsePSSHBox(box)
+	require.NoError(t, err)
+	require.EqualInt(t, 1, int(p.Version))
+	require.EqualInt(t, 1, len(p.KIDs))
+	require.EqualString(t, "5abdd52f554a4f2ab8d061f761425155", hex.EncodeToString(p.KIDs[0]))
+	require.EqualInt(t, 0, len(p.Data))
+}
+
+func TestParsePSSHBox_Errors(t *testing.T) {
+	_, err := ParsePSSHBox([]byte("short"))
+	require.EqualError(t, err, "PSSH box too short: 5 bytes")
+
+	box, _ := MakePSSHBox(make([]byte, 16), []byte("data"))
+	_, err = ParsePSSHBox(box[:len(box)-1])
+	require.EqualError(t, err, "PSSH box size mismatch, header: 36, actual: 35")
+
+	copy(box[4:8], "moov")
+	_, err = ParsePSSHBox(box)
+	require.EqualError(t, err, "Not a PSSH box, type was: \"moov\"")
+}
@@ -0,0 +1,344 @@
+package mpd
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Known error variables
+var (
+	ErrNoSegmentAddressing = errors.New("Representation has no SegmentTemplate, SegmentList, SegmentBase or BaseURL")
+	ErrSegmentCountUnknown = errors.New("Segment count needs a SegmentTimeline or a period duration")
+	ErrTemplateInvalid     = errors.New("Invalid segment template identifier")
+	ErrTooManySegments     = errors.New("Too many segments")
+)
+
+// Most segments expanded for a Representation, bounding the memory used by untrusted manifests
+const MAX_SEGMENTS = 1 << 20
+
+// RepresentationSegments is the expanded segment addressing of a Representation.
+type RepresentationSegments struct {
+	Timescale              uint64
+	PresentationTimeOffset uint64
+	Initialization         string // Init segment URL, empty when it is the media file itself (SegmentBase)
+	InitializationRange    string // Init segment byte range (i.e. 0-628), empty for the whole file
+	Media                  []MediaSegment
+}
+
+// MediaSegment is a single media segment, URLs are relative to the BaseURL of the Representation.
+type MediaSegment struct {
+	Number   int64
+	Time     uint64 // Media time in Timescale units, as substituted for $Time$
+	Duration uint64 // In Timescale units
+	URL      string
+	Range    string // Byte range (i.e. 757-1234), empty for the whole file
+}
+
+// Segments expands the segment addressing of a Representation, inherited from its AdaptationSet
+// where the Representation has none, into a list of media segments.
+// periodDuration - duration of the Period, used with @duration and for SegmentTimeline S@r of -1.
+// Zero when unknown.
+func (r *Representation) Segments(periodDuration time.Duration) (*RepresentationSegments, error) {
+	if st := r.segmentTemplate(); st != nil {
+		return r.templateSegments(st, periodDuration)
+	}
+	sl := r.SegmentList
+	if sl == nil && r.AdaptationSet != nil {
+		sl = r.AdaptationSet.SegmentList
+	}
+	if sl != nil {
+		return listSegments(sl, periodDuration)
+	}
+
+	sb := r.SegmentBase
+	if sb == nil && r.AdaptationSet != nil {
+		sb = r.AdaptationSet.SegmentBase
+	}
+	if sb == nil && len(r.BaseURL) == 0 {
+		return nil, ErrNoSegmentAddressing
+	}
+	rs := &RepresentationSegments{Timescale: 1}
+	if sb != nil {
+		rs.applySegmentBase(sb)
+		if sb.Initialization != nil {
+			rs.InitializationRange = strOrEmpty(sb.Initialization.Range)
+			rs.Initialization = strOrEmpty(sb.Initialization.SourceURL)
+		}
+	}
+	rs.Media = []MediaSegment{{
+		Number:   1,
+		Time:     rs.PresentationTimeOffset,
+		Duration: scaledDuration(periodDuration, rs.Timescale),
+	}}
+	return rs, nil
+}
+
+// segmentTemplate merges the SegmentTemplate of the Representation with the one of its
+// AdaptationSet, the Representation attributes taking precedence.
+func (r *Representation) segmentTemplate() *SegmentTemplate {
+	var parent *SegmentTemplate
+	if r.AdaptationSet != nil {
+		parent = r.AdaptationSet.SegmentTemplate
+	}
+	if r.SegmentTemplate == nil || parent == nil {
+		if r.SegmentTemplate != nil {
+			return r.SegmentTemplate
+		}
+		return parent
+	}
+
+	st := *r.SegmentTemplate
+	if st.SegmentTimeline == nil {
+		st.SegmentTimeline = parent.SegmentTimeline
+	}
+	if st.PresentationTimeOffset == nil {
+		st.PresentationTimeOffset = parent.PresentationTimeOffset
+	}
+	if st.Duration == nil {
+		st.Duration = parent.Duration
+	}
+	if st.Initialization == nil {
+		st.Initialization = parent.Initialization
+	}
+	if st.Media == nil {
+		st.Media = parent.Media
+	}
+	if st.StartNumber == nil {
+		st.StartNumber = parent.StartNumber
+	}
+	if st.Timescale == nil {
+		st.Timescale = parent.Timescale
+	}
+	return &st
+}
+
+func (r *Representation) templateSegments(st *SegmentTemplate, periodDuration time.Duration) (*RepresentationSegments, error) {
+	rs := &RepresentationSegments{Timescale: 1}
+	if st.Timescale != nil && *st.Timescale > 0 {
+		rs.Timescale = uint64(*st.Timescale)
+	}
+	if st.PresentationTimeOffset != nil {
+		rs.PresentationTimeOffset = *st.PresentationTimeOffset
+	}
+	startNumber := int64(1)
+	if st.StartNumber != nil {
+		startNumber = *st.StartNumber
+	}
+
+	var bandwidth int64
+	if r.Bandwidth != nil {
+		bandwidth = *r.Bandwidth
+	}
+	id := strOrEmpty(r.ID)
+	if st.Initialization != nil {
+		initURL, err := expandTemplate(*st.Initialization, id, bandwidth, 0, 0)
+		if err != nil {
+			return nil, err
+		}
+		rs.Initialization = initURL
+	}
+
+	var timeline []MediaSegment
+	var err error
+	if st.SegmentTimeline != nil {
+		timeline, err = expandTimeline(st.SegmentTimeline, startNumber, rs.PresentationTimeOffset, scaledDuration(periodDuration, rs.Timescale))
+	} else if st.Duration != nil && *st.Duration > 0 {
+		timeline, err = expandDuration(uint64(*st.Duration), startNumber, rs.PresentationTimeOffset, scaledDuration(periodDuration, rs.Timescale))
+	} else {
+		err = ErrSegmentCountUnknown
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	media := strOrEmpty(st.Media)
+	for i := range timeline {
+		timeline[i].URL, err = expandTemplate(media, id, bandwidth, timeline[i].Number, timeline[i].Time)
+		if err != nil {
+			return nil, err
+		}
+	}
+	rs.Media = timeline
+	return rs, nil
+}
+
+func listSegments(sl *SegmentList, periodDuration time.Duration) (*RepresentationSegments, error) {
+	rs := &RepresentationSegments{Timescale: 1}
+	rs.applySegmentBase(&sl.SegmentBase)
+	if sl.Initialization != nil {
+		rs.Initialization = strOrEmpty(sl.Initialization.SourceURL)
+		rs.InitializationRange = strOrEmpty(sl.Initialization.Range)
+	}
+	startNumber := int64(1)
+	if sl.StartNumber != nil {
+		startNumber = int64(*sl.StartNumber)
+	}
+
+	var timeline []MediaSegment
+	var err error
+	if sl.SegmentTimeline != nil {
+		timeline, err = expandTimeline(sl.SegmentTimeline, startNumber, rs.PresentationTimeOffset, scaledDuration(periodDuration, rs.Timescale))
+	} else if sl.Duration != nil && *sl.Duration > 0 {
+		// Every SegmentURL is a segment, the last one may be shorter
+		timeline, err = expandDuration(uint64(*sl.Duration), startNumber, rs.PresentationTimeOffset, uint64(*sl.Duration)*uint64(len(sl.SegmentURLs)))
+	} else {
+		timeline = make([]MediaSegment, len(sl.SegmentURLs))
+		for i := range timeline {
+			timeline[i].Number = startNumber + int64(i)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(timeline) > len(sl.SegmentURLs) {
+		timeline = timeline[:len(sl.SegmentURLs)]
+	}
+	for i := range timeline {
+		timeline[i].URL = strOrEmpty(sl.SegmentURLs[i].Media)
+		timeline[i].Range = strOrEmpty(sl.SegmentURLs[i].MediaRange)
+	}
+	rs.Media = timeline
+	return rs, nil
+}
+
+func (rs *RepresentationSegments) applySegmentBase(sb *SegmentBase) {
+	if sb.Timescale != nil && *sb.Timescale > 0 {
+		rs.Timescale = uint64(*sb.Timescale)
+	}
+	if sb.PresentationTimeOffset != nil {
+		rs.PresentationTimeOffset = *sb.PresentationTimeOffset
+	}
+}
+
+// expandTimeline lists the segments of a SegmentTimeline. A negative S@r repeats until the next
+// S@t, or until the end of the period for the last S.
+func expandTimeline(stl *SegmentTimeline, startNumber int64, pto uint64, periodDuration uint64) ([]MediaSegment, error) {
+	var segments []MediaSegment
+	number := startNumber
+	t := pto
+	for i, s := range stl.Segments {
+		if s.StartTime != nil {
+			t = *s.StartTime
+		}
+		if s.Duration == 0 {
+			return nil, fmt.Errorf("SegmentTimeline S %d has no duration", i)
+		}
+
+		repeat := 0
+		if s.RepeatCount != nil {
+			repeat = *s.RepeatCount
+		}
+		if repeat < 0 {
+			var end uint64
+			if i+1 < len(stl.Segments) && stl.Segments[i+1].StartTime != nil {
+				end = *stl.Segments[i+1].StartTime
+			} else if periodDuration > 0 {
+				end = pto + periodDuration
+			} else {
+				return nil, ErrSegmentCountUnknown
+			}
+			var count uint64
+			if end > t {
+				count = (end - t + s.Duration - 1) / s.Duration
+			}
+			if count > MAX_SEGMENTS {
+				return nil, fmt.Errorf("%w: more than %d", ErrTooManySegments, MAX_SEGMENTS)
+			}
+			repeat = int(count) - 1
+		}
+		if repeat >= MAX_SEGMENTS-len(segments) {
+			return nil, fmt.Errorf("%w: more than %d", ErrTooManySegments, MAX_SEGMENTS)
+		}
+
+		for j := 0; j <= repeat; j++ {
+			segments = append(segments, MediaSegment{Number: number, Time: t, Duration: s.Duration})
+			number++
+			t += s.Duration
+		}
+	}
+	return segments, nil
+}
+
+// expandDuration lists the segments of a constant @duration, the last one shortened to the end of
+// the period.
+func expandDuration(duration uint64, startNumber int64, pto uint64, periodDuration uint64) ([]MediaSegment, error) {
+	if periodDuration == 0 {
+		return nil, ErrSegmentCountUnknown
+	}
+	count := (periodDuration + duration - 1) / duration
+	if count > MAX_SEGMENTS {
+		return nil, fmt.Errorf("%w: more than %d", ErrTooManySegments, MAX_SEGMENTS)
+	}
+	segments := make([]MediaSegment, count)
+	for i := range segments {
+		d := duration
+		if remaining := periodDuration - uint64(i)*duration; remaining < d {
+			d = remaining
+		}
+		segments[i] = MediaSegment{
+			Number:   startNumber + int64(i),
+			Time:     pto + uint64(i)*duration,
+			Duration: d,
+		}
+	}
+	return segments, nil
+}
+
+// scaledDuration converts a duration to timescale units.
+func scaledDuration(d time.Duration, timescale uint64) uint64 {
+	if d <= 0 {
+		return 0
+	}
+	return uint64(d.Seconds()*float64(timescale) + 0.5)
+}
+
+// expandTemplate substitutes the identifiers of ISO 23009-1 5.3.9.4.4 in a SegmentTemplate
+// string, including width formatting (i.e. $Number%05d$).
+func expandTemplate(template string, id string, bandwidth int64, number int64, t uint64) (string, error) {
+	var sb strings.Builder
+	for {
+		start := strings.IndexByte(template, '$')
+		if start < 0 {
+			sb.WriteString(template)
+			return sb.String(), nil
+		}
+		end := strings.IndexByte(template[start+1:], '$')
+		if end < 0 {
+			return "", fmt.Errorf("%w: unterminated $ in %q", ErrTemplateInvalid, template)
+		}
+		end += start + 1
+		sb.WriteString(template[:start])
+
+		ident := template[start+1 : end]
+		format := "%d"
+		if i := strings.IndexByte(ident, '%'); i >= 0 {
+			format = ident[i:]
+			ident = ident[:i]
+			if len(format) < 3 || format[1] != '0' || format[len(format)-1] != 'd' {
+				return "", fmt.Errorf("%w: format %q", ErrTemplateInvalid, format)
+			}
+			if _, err := strconv.Atoi(format[2 : len(format)-1]); err != nil {
+				return "", fmt.Errorf("%w: format %q", ErrTemplateInvalid, format)
+			}
+		}
+
+		switch {
+		case ident == "" && format == "%d":
+			sb.WriteString("$")
+		case ident == "RepresentationID" && format == "%d":
+			sb.WriteString(id)
+		case ident == "Number":
+			sb.WriteString(fmt.Sprintf(format, number))
+		case ident == "Bandwidth":
+			sb.WriteString(fmt.Sprintf(format, bandwidth))
+		case ident == "Time":
+			sb.WriteString(fmt.Sprintf(format, t))
+		default:
+			return "", fmt.Errorf("%w: $%s$", ErrTemplateInvalid, template[start+1:end])
+		}
+		template = template[end+1:]
+	}
+}
@@ -0,0 +1,151 @@
+package mpd
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/zencoder/go-dash/v3/helpers/ptrs"
+	"github.com/zencoder/go-dash/v3/helpers/require"
+)
+
+func TestSegmentsTemplateDuration(t *testing.T) {
+	m := NewMPD(DASH_PROFILE_LIVE, VALID_MEDIA_PRESENTATION_DURATION, VALID_MIN_BUFFER_TIME)
+	audioAS, _ := m.AddNewAdaptationSetAudio(DASH_MIME_TYPE_AUDIO_MP4, VALID_SEGMENT_ALIGNMENT, VALID_START_WITH_SAP, VALID_LANG)
+	_, _ = audioAS.SetNewSegmentTemplate(2000, "$RepresentationID$/init.mp4", "$RepresentationID$/$Bandwidth$/seg-$Number%05d$.m4f", 1, 1000)
+	r, _ := audioAS.AddNewRepresentationAudio(VALID_AUDIO_SAMPLE_RATE, VALID_AUDIO_BITRATE, VALID_AUDIO_CODEC, VALID_AUDIO_ID)
+
+	rs, err := r.Segments(5 * time.Second)
+	require.NoError(t, err)
+	require.EqualString(t, "800/init.mp4", rs.Initialization)
+	require.EqualInt(t, 3, len(rs.Media))
+	require.EqualString(t, "800/67095/seg-00001.m4f", rs.Media[0].URL)
+	require.EqualString(t, "800/67095/seg-00003.m4f", rs.Media[2].URL)
+	require.EqualUInt64(t, 1000, rs.Media[2].Duration)
+	require.EqualUInt64(t, 4000, rs.Media[2].Time)
+
+	_, err = r.Segments(0)
+	require.EqualErr(t, ErrSegmentCountUnknown, err)
+}
+
+func TestSegmentsTemplateTimeline(t *testing.T) {
+	m := NewMPD(DASH_PROFILE_LIVE, VALID_MEDIA_PRESENTATION_DURATION, VALID_MIN_BUFFER_TIME)
+	videoAS, _ := m.AddNewAdaptationSetVideo(DASH_MIME_TYPE_VIDEO_MP4, VALID_SCAN_TYPE, VALID_SEGMENT_ALIGNMENT, VALID_START_WITH_SAP)
+	videoAS.SegmentTemplate = &SegmentTemplate{
+		Initialization:         Strptr("$RepresentationID$/init.mp4"),
+		Media:                  Strptr("$RepresentationID$/$Time$.m4s"),
+		Timescale:              Int64ptr(90000),
+		PresentationTimeOffset: Uint64ptr(900000),
+		SegmentTimeline: &SegmentTimeline{Segments: []*SegmentTimelineSegment{
+			{StartTime: Uint64ptr(900000), Duration: 180000, RepeatCount: Intptr(1)},
+			{StartTime: Uint64ptr(1350000), Duration: 90000},
+			{Duration: 180000, RepeatCount: Intptr(-1)},
+		}},
+	}
+	r, _ := videoAS.AddNewRepresentationVideo(VALID_VIDEO_BITRATE, VALID_VIDEO_CODEC, VALID_VIDEO_ID, VALID_VIDEO_FRAMERATE, VALID_VIDEO_WIDTH, VALID_VIDEO_HEIGHT)
+	// The Representation only overrides startNumber
+	r.SegmentTemplate = &SegmentTemplate{StartNumber: Int64ptr(10)}
+
+	rs, err := r.Segments(11 * time.Second)
+	require.NoError(t, err)
+	require.EqualUInt64(t, 90000, rs.Timescale)
+	require.EqualInt(t, 6, len(rs.Media))
+	require.EqualString(t, "800/900000.m4s", rs.Media[0].URL)
+	require.EqualString(t, "800/1350000.m4s", rs.Media[2].URL)
+	require.EqualString(t, "800/1800000.m4s", rs.Media[5].URL)
+	require.EqualInt(t, 15, int(rs.Media[5].Number))
+
+	_, err = r.Segments(0)
+	require.EqualErr(t, ErrSegmentCountUnknown, err)
+}
+
+func TestSegmentsTooMany(t *testing.T) {
+	for _, st := range []*SegmentTemplate{
+		{Media: Strptr("$Number$.m4s"), SegmentTimeline: &SegmentTimeline{Segments: []*SegmentTimelineSegment{
+			{StartTime: Uint64ptr(0), Duration: 1, RepeatCount: Intptr(2147483647)},
+		}}},
+		{Media: Strptr("$Number$.m4s"), SegmentTimeline: &SegmentTimeline{Segments: []*SegmentTimelineSegment{
+			{StartTime: Uint64ptr(0), Duration: 1, RepeatCount: Intptr(-1)},
+			{StartTime: Uint64ptr(1 << 62), Duration: 1},
+		}}},
+		{Media: Strptr("$Number$.m4s"), SegmentTimeline: &SegmentTimeline{Segments: []*SegmentTimelineSegment{
+			{Duration: 1, RepeatCount: Intptr(MAX_SEGMENTS - 1)},
+			{Duration: 1},
+		}}},
+		{Media: Strptr("$Number$.m4s"), Duration: Int64ptr(1), Timescale: Int64ptr(1000000)},
+	} {
+		r := &Representation{ID: Strptr("800"), SegmentTemplate: st}
+		_, err := r.Segments(time.Hour)
+		if !errors.Is(err, ErrTooManySegments) {
+			t.Errorf("Expected ErrTooManySegments, got %v", err)
+		}
+	}
+
+	// Up to MAX_SEGMENTS is fine
+	r := &Representation{ID: Strptr("800"), SegmentTemplate: &SegmentTemplate{Media: Strptr("$Number$.m4s"), SegmentTimeline: &SegmentTimeline{Segments: []*SegmentTimelineSegment{
+		{Duration: 1, RepeatCount: Intptr(MAX_SEGMENTS - 1)},
+	}}}}
+	rs, err := r.Segments(0)
+	require.NoError(t, err)
+	require.EqualInt(t, MAX_SEGMENTS, len(rs.Media))
+}
+
+func TestSegmentsList(t *testing.T) {
+	r := &Representation{
+		AdaptationSet: &AdaptationSet{},
+		SegmentList: &SegmentList{
+			MultipleSegmentBase: MultipleSegmentBase{
+				SegmentBase: SegmentBase{
+					Initialization: &URL{Range: Strptr(VALID_INIT_RANGE)},
+					Timescale:      Uint32ptr(1000),
+				},
+				Duration: Uint32ptr(4000),
+			},
+			SegmentURLs: []*SegmentURL{
+				{MediaRange: Strptr("757-1000")},
+				{MediaRange: Strptr("1001-2000")},
+			},
+		},
+	}
+
+	rs, err := r.Segments(0)
+	require.NoError(t, err)
+	require.EqualString(t, VALID_INIT_RANGE, rs.InitializationRange)
+	require.EqualInt(t, 2, len(rs.Media))
+	require.EqualString(t, "1001-2000", rs.Media[1].Range)
+	require.EqualUInt64(t, 4000, rs.Media[1].Time)
+	require.EqualInt(t, 2, int(rs.Media[1].Number))
+}
+
+func TestSegmentsBase(t *testing.T) {
+	m := NewMPD(DASH_PROFILE_ONDEMAND, VALID_MEDIA_PRESENTATION_DURATION, VALID_MIN_BUFFER_TIME)
+	videoAS, _ := m.AddNewAdaptationSetVideo(DASH_MIME_TYPE_VIDEO_MP4, VALID_SCAN_TYPE, VALID_SEGMENT_ALIGNMENT, VALID_START_WITH_SAP)
+	r, _ := videoAS.AddNewRepresentationVideo(VALID_VIDEO_BITRATE, VALID_VIDEO_CODEC, VALID_VIDEO_ID, VALID_VIDEO_FRAMERATE, VALID_VIDEO_WIDTH, VALID_VIDEO_HEIGHT)
+
+	_, err := r.Segments(0)
+	require.EqualErr(t, ErrNoSegmentAddressing, err)
+
+	_ = r.SetNewBaseURL(VALID_BASE_URL_VIDEO)
+	_, _ = r.AddNewSegmentBase(VALID_INDEX_RANGE, VALID_INIT_RANGE)
+	rs, err := r.Segments(376 * time.Second)
+	require.NoError(t, err)
+	require.EqualString(t, VALID_INIT_RANGE, rs.InitializationRange)
+	require.EqualInt(t, 1, len(rs.Media))
+	require.EqualUInt64(t, 376, rs.Media[0].Duration)
+	require.EqualString(t, "", rs.Media[0].URL)
+}
+
+func TestExpandTemplate(t *testing.T) {
+	s, err := expandTemplate("$$$RepresentationID$-$Time%010d$", "v1", 0, 0, 42)
+	require.NoError(t, err)
+	require.EqualString(t, "$v1-0000000042", s)
+
+	_, err = expandTemplate("$Number", "v1", 0, 1, 0)
+	require.EqualError(t, err, `Invalid segment template identifier: unterminated $ in "$Number"`)
+
+	_, err = expandTemplate("$Index$", "v1", 0, 1, 0)
+	require.EqualError(t, err, "Invalid segment template identifier: $Index$")
+
+	_, err = expandTemplate("$Number%5x$", "v1", 0, 1, 0)
+	require.EqualError(t, err, `Invalid segment template identifier: format "%5x"`)
+}
@@ -0,0 +1,244 @@
+package mpd
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	. "github.com/zencoder/go-dash/v3/helpers/ptrs"
+)
+
+// Known error variables
+var (
+	ErrMPDNotDynamic         = errors.New("MPD is not dynamic")
+	ErrPeriodDurationUnknown = errors.New("Period duration needs a Period@duration, a SegmentTimeline or the start of the next Period")
+	ErrNoPeriods             = errors.New("MPD has no Period")
+)
+
+// StaticOption is used to configure ToStatic and EndLive.
+type StaticOption func(o *staticOptions)
+
+type staticOptions struct {
+	dropAllEventStreams bool
+	dropSchemeIDURIs    []string
+}
+
+// DropEventStreams removes the Period EventStreams with the given schemeIdUris, or all of them
+// when none is given. EventStreams are kept by default.
+func DropEventStreams(schemeIDURIs ...string) StaticOption {
+	return func(o *staticOptions) {
+		if len(schemeIDURIs) == 0 {
+			o.dropAllEventStreams = true
+		}
+		o.dropSchemeIDURIs = append(o.dropSchemeIDURIs, schemeIDURIs...)
+	}
+}
+
+// periodExtent is where the media of a Period is in the live presentation.
+type periodExtent struct {
+	start    time.Duration // Period@start, from MPD@availabilityStartTime
+	offset   time.Duration // From the Period start to the earliest media every Representation has
+	duration time.Duration // From the earliest media to the end of the Period
+}
+
+// ToStatic converts a dynamic MPD, such as the last snapshot of a live event, to a static one.
+// Periods are laid out from zero, each starting at the earliest time every one of its
+// Representations has segments, which trims the media a sliding window already removed. Their
+// duration is Period@duration, the end of their last segment or the start of the next Period, and
+// mediaPresentationDuration their sum. The live-only availabilityStartTime, minimumUpdatePeriod,
+// timeShiftBufferDepth, suggestedPresentationDelay and UTCTiming are removed.
+func (m *MPD) ToStatic(options ...StaticOption) error {
+	extents, err := m.periodExtents()
+	if err != nil {
+		return err
+	}
+
+	var start time.Duration
+	for i, p := range m.Periods {
+		extent := extents[i]
+		if extent.offset > 0 {
+			p.trimStart(extent.offset)
+		}
+		p.Start = durationPtr(start)
+		p.Duration = Duration(extent.duration)
+		start += extent.duration
+	}
+	m.applyStaticOptions(options)
+
+	m.Type = Strptr("static")
+	m.MediaPresentationDuration = Strptr(durationPtr(start).String())
+	m.AvailabilityStartTime = nil
+	m.MinimumUpdatePeriod = nil
+	m.TimeShiftBufferDepth = nil
+	m.SuggestedPresentationDelay = nil
+	m.UTCTiming = nil
+	return nil
+}
+
+// EndLive marks the end of a live presentation while keeping it dynamic: mediaPresentationDuration
+// is set to the end of the last Period, so that players stop there, and minimumUpdatePeriod is
+// removed, as the MPD doesn't change anymore. Periods keep their start.
+func (m *MPD) EndLive(options ...StaticOption) error {
+	extents, err := m.periodExtents()
+	if err != nil {
+		return err
+	}
+	last := extents[len(extents)-1]
+	m.applyStaticOptions(options)
+	m.MediaPresentationDuration = Strptr(durationPtr(last.start + last.offset + last.duration).String())
+	m.MinimumUpdatePeriod = nil
+	return nil
+}
+
+// periodExtents returns the extent of every Period of a dynamic MPD.
+func (m *MPD) periodExtents() ([]periodExtent, error) {
+	if m.Type == nil || *m.Type != "dynamic" {
+		return nil, ErrMPDNotDynamic
+	}
+	if len(m.Periods) == 0 {
+		return nil, ErrNoPeriods
+	}
+	extents := make([]periodExtent, len(m.Periods))
+	var end time.Duration
+	for i, p := range m.Periods {
+		extent := periodExtent{start: end}
+		if p.Start != nil {
+			extent.start = time.Duration(*p.Start)
+		}
+		first, last, ok := p.mediaExtent()
+		switch {
+		case p.Duration != 0:
+			if ok {
+				extent.offset = min(first, time.Duration(p.Duration))
+			}
+			extent.duration = time.Duration(p.Duration) - extent.offset
+		case ok:
+			extent.offset = first
+			extent.duration = last - first
+		case i+1 < len(m.Periods) && m.Periods[i+1].Start != nil:
+			extent.duration = time.Duration(*m.Periods[i+1].Start) - extent.start
+		default:
+			return nil, fmt.Errorf("Period %q: %w", p.ID, ErrPeriodDurationUnknown)
+		}
+		if extent.duration < 0 {
+			return nil, fmt.Errorf("Period %q: %w", p.ID, ErrPeriodDurationUnknown)
+		}
+		extents[i] = extent
+		end = extent.start + extent.offset + extent.duration
+	}
+	return extents, nil
+}
+
+// mediaExtent returns the latest first segment start and the latest last segment end of the
+// Representations of the Period, from its start. ok is false when no Representation lists its
+// segments.
+func (p *Period) mediaExtent() (first, last time.Duration, ok bool) {
+	for _, as := range p.AdaptationSets {
+		for _, r := range as.Representations {
+			rs, err := r.Segments(time.Duration(p.Duration))
+			if err != nil || len(rs.Media) == 0 {
+				continue
+			}
+			start := rs.Media[0].Time
+			end := rs.Media[len(rs.Media)-1].Time + rs.Media[len(rs.Media)-1].Duration
+			if end <= start || start < rs.PresentationTimeOffset {
+				continue
+			}
+			repFirst := scaledTime(start-rs.PresentationTimeOffset, rs.Timescale)
+			repLast := scaledTime(end-rs.PresentationTimeOffset, rs.Timescale)
+			if !ok {
+				first, last, ok = repFirst, repLast, true
+				continue
+			}
+			first, last = max(first, repFirst), max(last, repLast)
+		}
+	}
+	return first, last, ok
+}
+
+// trimStart moves the start of the Period later by offset, shifting the presentationTimeOffset of
+// its segment addressing and the presentation time of its events, and dropping the events that end
+// before the new start.
+func (p *Period) trimStart(offset time.Duration) {
+	for _, as := range p.AdaptationSets {
+		// Representations first, as they may inherit the presentationTimeOffset of the
+		// AdaptationSet
+		for _, r := range as.Representations {
+			if r.SegmentTemplate != nil {
+				st := r.segmentTemplate()
+				r.SegmentTemplate.PresentationTimeOffset = shiftedPTO(st.PresentationTimeOffset, st.Timescale, offset)
+			}
+			if r.SegmentList != nil {
+				r.SegmentList.shiftPTO(offset)
+			}
+			if r.SegmentBase != nil {
+				r.SegmentBase.shiftPTO(offset)
+			}
+		}
+		if st := as.SegmentTemplate; st != nil {
+			st.PresentationTimeOffset = shiftedPTO(st.PresentationTimeOffset, st.Timescale, offset)
+		}
+		if as.SegmentList != nil {
+			as.SegmentList.shiftPTO(offset)
+		}
+		if as.SegmentBase != nil {
+			as.SegmentBase.shiftPTO(offset)
+		}
+	}
+
+	for i := range p.EventStreams {
+		p.EventStreams[i].clip(offset, math.MaxInt64)
+	}
+}
+
+func (sb *SegmentBase) shiftPTO(offset time.Duration) {
+	var timescale *int64
+	if sb.Timescale != nil {
+		timescale = Int64ptr(int64(*sb.Timescale))
+	}
+	sb.PresentationTimeOffset = shiftedPTO(sb.PresentationTimeOffset, timescale, offset)
+}
+
+// shiftedPTO returns a presentationTimeOffset later by offset.
+func shiftedPTO(pto *uint64, timescale *int64, offset time.Duration) *uint64 {
+	ts := uint64(1)
+	if timescale != nil && *timescale > 0 {
+		ts = uint64(*timescale)
+	}
+	var shifted uint64
+	if pto != nil {
+		shifted = *pto
+	}
+	return Uint64ptr(shifted + scaledDuration(offset, ts))
+}
+
+// scaledTime converts timescale units to a duration.
+func scaledTime(t uint64, timescale uint64) time.Duration {
+	return time.Duration(float64(t) / float64(timescale) * float64(time.Second))
+}
+
+func (m *MPD) applyStaticOptions(options []StaticOption) {
+	o := &staticOptions{}
+	for _, option := range options {
+		option(o)
+	}
+	if !o.dropAllEventStreams && len(o.dropSchemeIDURIs) == 0 {
+		return
+	}
+	for _, p := range m.Periods {
+		var eventStreams []EventStream
+		for _, es := range p.EventStreams {
+			if !o.dropAllEventStreams && !slices.Contains(o.dropSchemeIDURIs, strOrEmpty(es.SchemeIDURI)) {
+				eventStreams = append(eventStreams, es)
+			}
+		}
+		p.EventStreams = eventStreams
+	}
+}
+
+func durationPtr(d time.Duration) *Duration {
+	v := Duration(d)
+	return &v
+}
@@ -0,0 +1,68 @@
+package mpd
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/zencoder/go-dash/v3/helpers/ptrs"
+	"github.com/zencoder/go-dash/v3/helpers/require"
+	"github.com/zencoder/go-dash/v3/helpers/testfixtures"
+)
+
+func TestToStatic(t *testing.T) {
+	m, err := ReadFromFile("fixtures/live_snapshot.mpd")
+	require.NoError(t, err)
+	require.NoError(t, m.ToStatic())
+
+	got, err := m.WriteToString()
+	require.NoError(t, err)
+	testfixtures.CompareFixture(t, "fixtures/live_snapshot_static.mpd", got)
+
+	// The trimmed segments start at the new Period start
+	rs, err := m.Periods[0].AdaptationSets[1].Representations[0].Segments(0)
+	require.NoError(t, err)
+	require.EqualUInt64(t, 96000, rs.PresentationTimeOffset)
+	require.EqualUInt64(t, 90000, rs.Media[0].Time)
+}
+
+func TestEndLive(t *testing.T) {
+	m, err := ReadFromFile("fixtures/live_snapshot.mpd")
+	require.NoError(t, err)
+	require.NoError(t, m.EndLive(DropEventStreams(SCTE352014SchemeIdUri)))
+
+	got, err := m.WriteToString()
+	require.NoError(t, err)
+	testfixtures.CompareFixture(t, "fixtures/live_snapshot_ended.mpd", got)
+}
+
+func TestToStaticDropEventStreams(t *testing.T) {
+	m, err := ReadFromFile("fixtures/live_snapshot.mpd")
+	require.NoError(t, err)
+	require.NoError(t, m.ToStatic(DropEventStreams()))
+	require.EqualInt(t, 0, len(m.Periods[0].EventStreams))
+	require.EqualInt(t, 0, len(m.Periods[1].EventStreams))
+}
+
+func TestToStaticPeriodDurations(t *testing.T) {
+	m := NewDynamicMPD(DASH_PROFILE_LIVE, VALID_AVAILABILITY_START_TIME, VALID_MIN_BUFFER_TIME)
+	as, err := m.AddNewAdaptationSetAudio(DASH_MIME_TYPE_AUDIO_MP4, true, 1, "en")
+	require.NoError(t, err)
+	_, err = as.SetNewSegmentTemplate(2, "$Number$.m4s", "init.mp4", 1, 1)
+	require.NoError(t, err)
+	_, err = as.AddNewRepresentationAudio(48000, 128000, "mp4a.40.2", "audio")
+	require.NoError(t, err)
+
+	// SegmentTemplate@duration segments follow the clock, the last Period has no end
+	if err := m.ToStatic(); !errors.Is(err, ErrPeriodDurationUnknown) {
+		t.Fatalf("Expected ErrPeriodDurationUnknown, got %v", err)
+	}
+
+	m.Periods[0].Start = durationPtr(0)
+	m.Periods = append(m.Periods, &Period{ID: "p1", Start: durationPtr(30e9), Duration: Duration(15e9)})
+	require.NoError(t, m.ToStatic())
+	require.EqualStringPtr(t, ptrs.Strptr("PT45S"), m.MediaPresentationDuration)
+	require.EqualString(t, "PT30S", m.Periods[1].Start.String())
+
+	require.EqualErr(t, ErrMPDNotDynamic, m.ToStatic())
+	require.EqualErr(t, ErrNoPeriods, (&MPD{Type: ptrs.Strptr("dynamic")}).EndLive())
+}
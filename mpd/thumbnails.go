@@ -0,0 +1,197 @@
+package mpd
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	. "github.com/zencoder/go-dash/v3/helpers/ptrs"
+)
+
+// Constants for the DASH-IF thumbnail tile EssentialProperty (DASH-IF IOP 6.2.6)
+const (
+	THUMBNAIL_TILE_SCHEME_ID = "http://dashif.org/thumbnail_tile"
+	// Scheme of earlier DASH-IF guidelines, still recognized when reading
+	THUMBNAIL_TILE_GUIDELINES_SCHEME_ID = "http://dashif.org/guidelines/thumbnail_tile"
+	// Most thumbnails in the grid of an image
+	MAX_THUMBNAIL_TILES = 1 << 16
+)
+
+// Known error variables
+var (
+	ErrInvalidThumbnailTile = errors.New("Invalid thumbnail tile grid")
+	ErrThumbnailNotFound    = errors.New("No thumbnail at this time")
+)
+
+// ThumbnailTile is the grid of thumbnails of every image of a thumbnail Representation. The
+// thumbnails are laid out row by row, each covering an equal share of the image duration.
+type ThumbnailTile struct {
+	Columns int
+	Rows    int
+}
+
+// ParseThumbnailTile parses a thumbnail tile EssentialProperty value (i.e. 5x4 for 5 columns and 4
+// rows).
+func ParseThumbnailTile(value string) (ThumbnailTile, error) {
+	columns, rows, ok := strings.Cut(strings.TrimSpace(value), "x")
+	c, err1 := strconv.Atoi(columns)
+	r, err2 := strconv.Atoi(rows)
+	tile := ThumbnailTile{Columns: c, Rows: r}
+	if !ok || err1 != nil || err2 != nil || !tile.valid() {
+		return ThumbnailTile{}, fmt.Errorf("%w: %q", ErrInvalidThumbnailTile, value)
+	}
+	return tile, nil
+}
+
+// valid reports whether the grid has at least one and at most MAX_THUMBNAIL_TILES thumbnails.
+func (t ThumbnailTile) valid() bool {
+	return t.Columns >= 1 && t.Rows >= 1 && t.Columns <= MAX_THUMBNAIL_TILES/t.Rows
+}
+
+func (t ThumbnailTile) String() string {
+	return strconv.Itoa(t.Columns) + "x" + strconv.Itoa(t.Rows)
+}
+
+// Thumbnail is a single thumbnail: a rectangle of a tile image and the time it covers.
+type Thumbnail struct {
+	URL      string // Image URL, relative to the BaseURL of the Representation
+	X        int64  // Left of the thumbnail in the image, in pixels
+	Y        int64  // Top of the thumbnail in the image, in pixels
+	Width    int64
+	Height   int64
+	Start    time.Duration // From the Period start
+	Duration time.Duration
+}
+
+// Adds a new thumbnail Representation with a typed tile grid to an AdaptationSet.
+// id - ID for this representation, will get used as $RepresentationID$ in template strings.
+// tile - thumbnail grid of every image (i.e. ThumbnailTile{Columns: 5, Rows: 4}).
+// bandwidth - in Bits/s (i.e. 50000).
+// width - width of the whole tile image (i.e. 1600).
+// height - height of the whole tile image (i.e. 720).
+func (as *AdaptationSet) AddNewRepresentationThumbnailTiles(id string, tile ThumbnailTile, bandwidth, width, height int64) (*Representation, error) {
+	if !tile.valid() {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidThumbnailTile, tile)
+	}
+	return as.AddNewRepresentationThumbnails(id, tile.String(), THUMBNAIL_TILE_SCHEME_ID, bandwidth, width, height)
+}
+
+// ThumbnailTile returns the tile grid of a thumbnail Representation, from its EssentialProperty or
+// the one of its AdaptationSet. Images without one hold a single thumbnail.
+func (r *Representation) ThumbnailTile() (ThumbnailTile, error) {
+	properties := r.EssentialProperty
+	if r.AdaptationSet != nil {
+		properties = append(properties[:len(properties):len(properties)], r.AdaptationSet.EssentialProperty...)
+	}
+	for _, property := range properties {
+		scheme := strOrEmpty(property.SchemeIDURI)
+		if scheme == THUMBNAIL_TILE_SCHEME_ID || scheme == THUMBNAIL_TILE_GUIDELINES_SCHEME_ID {
+			return ParseThumbnailTile(strOrEmpty(property.Value))
+		}
+	}
+	return ThumbnailTile{Columns: 1, Rows: 1}, nil
+}
+
+// SetThumbnailTile sets the tile grid EssentialProperty of a thumbnail Representation, replacing
+// any existing one.
+func (r *Representation) SetThumbnailTile(tile ThumbnailTile) error {
+	if !tile.valid() {
+		return fmt.Errorf("%w: %s", ErrInvalidThumbnailTile, tile)
+	}
+	var properties []DescriptorType
+	for _, property := range r.EssentialProperty {
+		scheme := strOrEmpty(property.SchemeIDURI)
+		if scheme != THUMBNAIL_TILE_SCHEME_ID && scheme != THUMBNAIL_TILE_GUIDELINES_SCHEME_ID {
+			properties = append(properties, property)
+		}
+	}
+	r.EssentialProperty = append(properties, DescriptorType{
+		SchemeIDURI: Strptr(THUMBNAIL_TILE_SCHEME_ID),
+		Value:       Strptr(tile.String()),
+	})
+	return nil
+}
+
+// ThumbnailDuration returns the time covered by each thumbnail of a Representation addressed with
+// a SegmentTemplate@duration, the image duration divided by the number of tiles.
+func (r *Representation) ThumbnailDuration() (time.Duration, error) {
+	tile, err := r.ThumbnailTile()
+	if err != nil {
+		return 0, err
+	}
+	st := r.segmentTemplate()
+	if st == nil || st.Duration == nil || *st.Duration <= 0 {
+		return 0, ErrSegmentTimesUnknown
+	}
+	timescale := uint64(1)
+	if st.Timescale != nil && *st.Timescale > 0 {
+		timescale = uint64(*st.Timescale)
+	}
+	return scaledTime(uint64(*st.Duration), timescale) / time.Duration(tile.Columns*tile.Rows), nil
+}
+
+// ThumbnailAt returns the thumbnail of a Representation shown at a time of its Period.
+// t - time from the Period start (i.e. 95*time.Second).
+// periodDuration - duration of the Period, needed with SegmentTemplate@duration. Zero when unknown.
+func (r *Representation) ThumbnailAt(t, periodDuration time.Duration) (*Thumbnail, error) {
+	tile, err := r.ThumbnailTile()
+	if err != nil {
+		return nil, err
+	}
+	rs, err := r.Segments(periodDuration)
+	if err != nil {
+		return nil, err
+	}
+	at := rs.PresentationTimeOffset + scaledDuration(t, rs.Timescale)
+	for _, s := range rs.Media {
+		if t < 0 || at < s.Time || at >= s.Time+s.Duration {
+			continue
+		}
+		tiles := uint64(tile.Columns * tile.Rows)
+		index := min((at-s.Time)*tiles/s.Duration, tiles-1)
+		start := s.Time + s.Duration*index/tiles
+		end := s.Time + s.Duration*(index+1)/tiles
+		thumbnail := &Thumbnail{
+			URL:      s.URL,
+			Start:    scaledTime(start-rs.PresentationTimeOffset, rs.Timescale),
+			Duration: scaledTime(end-start, rs.Timescale),
+		}
+		if r.Width != nil && r.Height != nil {
+			thumbnail.Width = *r.Width / int64(tile.Columns)
+			thumbnail.Height = *r.Height / int64(tile.Rows)
+			thumbnail.X = int64(index%uint64(tile.Columns)) * thumbnail.Width
+			thumbnail.Y = int64(index/uint64(tile.Columns)) * thumbnail.Height
+		}
+		return thumbnail, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrThumbnailNotFound, t)
+}
+
+// ThumbnailAt returns the thumbnail shown at a time of the presentation, from the thumbnail
+// Representation with the given id, or the first one of the Period when id is empty. The Start of
+// the thumbnail is from the start of its Period.
+// t - time from the start of the presentation (i.e. 95*time.Second).
+func (m *MPD) ThumbnailAt(t time.Duration, id string) (*Thumbnail, error) {
+	bounds, err := m.periodBounds()
+	if err != nil {
+		return nil, err
+	}
+	for i, p := range m.Periods {
+		if t < bounds[i].start || t >= bounds[i].start+bounds[i].duration {
+			continue
+		}
+		for _, as := range p.AdaptationSets {
+			if strOrEmpty(as.ContentType) != DASH_CONTENT_TYPE_IMAGE {
+				continue
+			}
+			for _, r := range as.Representations {
+				if id == "" || strOrEmpty(r.ID) == id {
+					return r.ThumbnailAt(t-bounds[i].start, bounds[i].duration)
+				}
+			}
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrThumbnailNotFound, t)
+}
@@ -0,0 +1,156 @@
+package mpd
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/zencoder/go-dash/v3/helpers/ptrs"
+	"github.com/zencoder/go-dash/v3/helpers/require"
+)
+
+func newThumbnailsMPD(t *testing.T) (*MPD, *Representation) {
+	m := NewMPD(DASH_PROFILE_LIVE, "PT4M0S", VALID_MIN_BUFFER_TIME)
+	as, err := m.AddNewAdaptationSetThumbnails("image/jpeg")
+	require.NoError(t, err)
+	_, err = as.SetNewSegmentTemplateThumbnails(100, "$RepresentationID$/tile_$Number$.jpg", 1, 1)
+	require.NoError(t, err)
+	r, err := as.AddNewRepresentationThumbnailTiles("thumbnails_320x180", ThumbnailTile{Columns: 5, Rows: 4}, 50000, 1600, 720)
+	require.NoError(t, err)
+	return m, r
+}
+
+func TestParseThumbnailTile(t *testing.T) {
+	tile, err := ParseThumbnailTile("5x4")
+	require.NoError(t, err)
+	require.EqualInt(t, 5, tile.Columns)
+	require.EqualInt(t, 4, tile.Rows)
+	require.EqualString(t, "5x4", tile.String())
+
+	for _, value := range []string{"", "5", "5x", "x4", "0x4", "5x-1", "5*4", "4294967296x4294967296", "65537x1", "257x256"} {
+		_, err := ParseThumbnailTile(value)
+		if !errors.Is(err, ErrInvalidThumbnailTile) {
+			t.Errorf("Expected ErrInvalidThumbnailTile for %q, got %v", value, err)
+		}
+	}
+}
+
+func TestAddNewRepresentationThumbnailTiles(t *testing.T) {
+	_, r := newThumbnailsMPD(t)
+	require.EqualInt(t, 1, len(r.EssentialProperty))
+	require.EqualStringPtr(t, ptrs.Strptr(THUMBNAIL_TILE_SCHEME_ID), r.EssentialProperty[0].SchemeIDURI)
+	require.EqualStringPtr(t, ptrs.Strptr("5x4"), r.EssentialProperty[0].Value)
+
+	tile, err := r.ThumbnailTile()
+	require.NoError(t, err)
+	require.EqualString(t, "5x4", tile.String())
+	d, err := r.ThumbnailDuration()
+	require.NoError(t, err)
+	require.EqualInt(t, int(5*time.Second), int(d))
+
+	_, err = r.AdaptationSet.AddNewRepresentationThumbnailTiles("invalid", ThumbnailTile{Columns: 0, Rows: 4}, 50000, 1600, 720)
+	require.EqualError(t, err, "Invalid thumbnail tile grid: 0x4")
+	_, err = r.AdaptationSet.AddNewRepresentationThumbnailTiles("invalid", ThumbnailTile{Columns: 1 << 32, Rows: 1 << 32}, 50000, 1600, 720)
+	require.EqualError(t, err, "Invalid thumbnail tile grid: 4294967296x4294967296")
+	require.EqualError(t, r.SetThumbnailTile(ThumbnailTile{Columns: 1 << 16, Rows: 2}), "Invalid thumbnail tile grid: 65536x2")
+}
+
+func TestThumbnailTile(t *testing.T) {
+	r := &Representation{}
+	tile, err := r.ThumbnailTile()
+	require.NoError(t, err)
+	require.EqualString(t, "1x1", tile.String(), "Images without a grid hold a single thumbnail")
+
+	// The scheme of earlier guidelines is still read, and replaced when setting the grid
+	r.EssentialProperty = []DescriptorType{
+		{SchemeIDURI: ptrs.Strptr("urn:example:other"), Value: ptrs.Strptr("1")},
+		{SchemeIDURI: ptrs.Strptr(THUMBNAIL_TILE_GUIDELINES_SCHEME_ID), Value: ptrs.Strptr("10x1")},
+	}
+	tile, err = r.ThumbnailTile()
+	require.NoError(t, err)
+	require.EqualString(t, "10x1", tile.String())
+
+	require.NoError(t, r.SetThumbnailTile(ThumbnailTile{Columns: 2, Rows: 3}))
+	require.EqualInt(t, 2, len(r.EssentialProperty))
+	require.EqualStringPtr(t, ptrs.Strptr("urn:example:other"), r.EssentialProperty[0].SchemeIDURI)
+	require.EqualStringPtr(t, ptrs.Strptr(THUMBNAIL_TILE_SCHEME_ID), r.EssentialProperty[1].SchemeIDURI)
+	require.EqualStringPtr(t, ptrs.Strptr("2x3"), r.EssentialProperty[1].Value)
+
+	// Inherited from the AdaptationSet
+	r = &Representation{AdaptationSet: &AdaptationSet{CommonAttributesAndElements: CommonAttributesAndElements{
+		EssentialProperty: []DescriptorType{{SchemeIDURI: ptrs.Strptr(THUMBNAIL_TILE_SCHEME_ID), Value: ptrs.Strptr("4x4")}},
+	}}}
+	tile, err = r.ThumbnailTile()
+	require.NoError(t, err)
+	require.EqualString(t, "4x4", tile.String())
+
+	r.AdaptationSet.EssentialProperty[0].Value = ptrs.Strptr("4by4")
+	_, err = r.ThumbnailTile()
+	require.EqualError(t, err, `Invalid thumbnail tile grid: "4by4"`)
+	r.AdaptationSet.EssentialProperty[0].Value = ptrs.Strptr("4294967296x4294967296")
+	_, err = r.ThumbnailAt(0, 0)
+	require.EqualError(t, err, `Invalid thumbnail tile grid: "4294967296x4294967296"`)
+	_, err = r.ThumbnailDuration()
+	require.EqualError(t, err, `Invalid thumbnail tile grid: "4294967296x4294967296"`)
+}
+
+func TestRepresentationThumbnailAt(t *testing.T) {
+	_, r := newThumbnailsMPD(t)
+
+	thumbnail, err := r.ThumbnailAt(163*time.Second, 240*time.Second)
+	require.NoError(t, err)
+	require.EqualString(t, "thumbnails_320x180/tile_2.jpg", thumbnail.URL)
+	require.EqualInt(t, 640, int(thumbnail.X))
+	require.EqualInt(t, 360, int(thumbnail.Y))
+	require.EqualInt(t, 320, int(thumbnail.Width))
+	require.EqualInt(t, 180, int(thumbnail.Height))
+	require.EqualInt(t, int(160*time.Second), int(thumbnail.Start))
+	require.EqualInt(t, int(5*time.Second), int(thumbnail.Duration))
+
+	thumbnail, err = r.ThumbnailAt(99*time.Second, 240*time.Second)
+	require.NoError(t, err)
+	require.EqualString(t, "thumbnails_320x180/tile_1.jpg", thumbnail.URL)
+	require.EqualInt(t, 1280, int(thumbnail.X))
+	require.EqualInt(t, 540, int(thumbnail.Y))
+
+	_, err = r.ThumbnailAt(300*time.Second, 240*time.Second)
+	require.EqualError(t, err, "No thumbnail at this time: 5m0s")
+	_, err = r.ThumbnailAt(-time.Second, 240*time.Second)
+	require.EqualError(t, err, "No thumbnail at this time: -1s")
+}
+
+func TestMPDThumbnailAt(t *testing.T) {
+	m, _ := newThumbnailsMPD(t)
+	p := m.AddNewPeriod()
+	m.Periods[0].Duration = Duration(200 * time.Second)
+	p.ID = "2"
+	as, err := p.AddNewAdaptationSetThumbnails("image/jpeg")
+	require.NoError(t, err)
+	_, err = as.SetNewSegmentTemplateThumbnails(10000, "p2/$RepresentationID$/$Number$.jpg", 0, 1000)
+	require.NoError(t, err)
+	_, err = as.AddNewRepresentationThumbnailTiles("small", ThumbnailTile{Columns: 2, Rows: 1}, 10000, 320, 90)
+	require.NoError(t, err)
+	_, err = as.AddNewRepresentationThumbnailTiles("large", ThumbnailTile{Columns: 2, Rows: 1}, 50000, 640, 180)
+	require.NoError(t, err)
+
+	thumbnail, err := m.ThumbnailAt(150*time.Second, "")
+	require.NoError(t, err)
+	require.EqualString(t, "thumbnails_320x180/tile_2.jpg", thumbnail.URL)
+
+	// In the second Period, at 17s of its own time
+	thumbnail, err = m.ThumbnailAt(217*time.Second, "")
+	require.NoError(t, err)
+	require.EqualString(t, "p2/small/1.jpg", thumbnail.URL)
+	require.EqualInt(t, 160, int(thumbnail.X))
+	require.EqualInt(t, int(15*time.Second), int(thumbnail.Start))
+
+	thumbnail, err = m.ThumbnailAt(217*time.Second, "large")
+	require.NoError(t, err)
+	require.EqualString(t, "p2/large/1.jpg", thumbnail.URL)
+	require.EqualInt(t, 320, int(thumbnail.X))
+
+	_, err = m.ThumbnailAt(217*time.Second, "thumbnails_320x180")
+	require.EqualError(t, err, "No thumbnail at this time: 3m37s")
+	_, err = m.ThumbnailAt(5*time.Minute, "")
+	require.EqualError(t, err, "No thumbnail at this time: 5m0s")
+}
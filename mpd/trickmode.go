@@ -0,0 +1,176 @@
+package mpd
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	. "github.com/zencoder/go-dash/v3/helpers/ptrs"
+)
+
+// Scheme of the EssentialProperty of trick mode AdaptationSets (DASH-IF IOP 3.2.9)
+const TRICK_MODE_SCHEME_ID = "http://dashif.org/guidelines/trickmode"
+
+// Known error variables
+var (
+	ErrTrickModeMainAdaptationSetNotFound = errors.New("Trick mode main AdaptationSet not found")
+	ErrInvalidMaxPlayoutRate              = errors.New("Invalid maxPlayoutRate")
+)
+
+// Create a new trick mode Adaptation Set, holding I-frame or reduced frame rate Representations of
+// a main video Adaptation Set for fast-forward and rewind.
+// id - ID of the new Adaptation Set (i.e. 2).
+// mainAdaptationSetID - ID of the main video Adaptation Set, in the same Period (i.e. 1).
+// mimeType - MIME Type (i.e. video/mp4).
+// scanType - Scan Type (i.e.progressive).
+// segmentAlignment - Segment Alignment(i.e. true).
+// startWithSAP - Starts With SAP (i.e. 1).
+func (m *MPD) AddNewAdaptationSetTrickMode(id, mainAdaptationSetID, mimeType, scanType string, segmentAlignment bool, startWithSAP int64) (*AdaptationSet, error) {
+	return m.period.AddNewAdaptationSetTrickMode(id, mainAdaptationSetID, mimeType, scanType, segmentAlignment, startWithSAP)
+}
+
+// Create a new trick mode Adaptation Set, holding I-frame or reduced frame rate Representations of
+// a main video Adaptation Set for fast-forward and rewind.
+// id - ID of the new Adaptation Set (i.e. 2).
+// mainAdaptationSetID - ID of the main video Adaptation Set, in this Period (i.e. 1).
+// mimeType - MIME Type (i.e. video/mp4).
+// scanType - Scan Type (i.e.progressive).
+// segmentAlignment - Segment Alignment(i.e. true).
+// startWithSAP - Starts With SAP (i.e. 1).
+func (period *Period) AddNewAdaptationSetTrickMode(id, mainAdaptationSetID, mimeType, scanType string, segmentAlignment bool, startWithSAP int64) (*AdaptationSet, error) {
+	if period.adaptationSetByID(mainAdaptationSetID) == nil {
+		return nil, fmt.Errorf("%w: %q", ErrTrickModeMainAdaptationSetNotFound, mainAdaptationSetID)
+	}
+	as := &AdaptationSet{
+		SegmentAlignment: Boolptr(segmentAlignment),
+		ID:               Strptr(id),
+		CommonAttributesAndElements: CommonAttributesAndElements{
+			MimeType:     Strptr(mimeType),
+			StartWithSAP: Int64ptr(startWithSAP),
+			ScanType:     Strptr(scanType),
+			EssentialProperty: []DescriptorType{
+				{
+					SchemeIDURI: Strptr(TRICK_MODE_SCHEME_ID),
+					Value:       Strptr(mainAdaptationSetID),
+				},
+			},
+		},
+	}
+	err := period.addAdaptationSet(as)
+	if err != nil {
+		return nil, err
+	}
+	return as, nil
+}
+
+// Adds a new trick mode Video representation to an AdaptationSet.
+// bandwidth - in Bits/s (i.e. 250000).
+// codecs - codec string for Video Only (in RFC6381, https://tools.ietf.org/html/rfc6381) (i.e. avc1.4d401f), malformed strings are rejected.
+// id - ID for this representation, will get used as $RepresentationID$ in template strings.
+// frameRate - video frame rate (as a fraction) (i.e. 1/2 for an I-frame every 2 seconds).
+// width - width of the video (i.e. 640).
+// height - height of the video (i.e 360).
+// maxPlayoutRate - maximum playout rate over the normal one (i.e. 32).
+// codingDependency - whether pictures depend on other ones, false for I-frame only (i.e. false).
+func (as *AdaptationSet) AddNewRepresentationTrickMode(bandwidth int64, codecs, id, frameRate string, width, height int64, maxPlayoutRate float64, codingDependency bool) (*Representation, error) {
+	if maxPlayoutRate <= 0 {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMaxPlayoutRate, maxPlayoutRate)
+	}
+	if err := validateCodecs(&codecs); err != nil {
+		return nil, err
+	}
+	r := &Representation{
+		Bandwidth: Int64ptr(bandwidth),
+		Codecs:    Strptr(codecs),
+		ID:        Strptr(id),
+		FrameRate: Strptr(frameRate),
+		Width:     Int64ptr(width),
+		Height:    Int64ptr(height),
+		CommonAttributesAndElements: CommonAttributesAndElements{
+			MaxPlayoutRate:   Strptr(strconv.FormatFloat(maxPlayoutRate, 'f', -1, 64)),
+			CodingDependency: Boolptr(codingDependency),
+		},
+	}
+
+	err := as.addRepresentation(r)
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// TrickModeFor returns the ID of the main AdaptationSet of a trick mode AdaptationSet, from its
+// trick mode EssentialProperty. ok is false for other AdaptationSets.
+func (as *AdaptationSet) TrickModeFor() (id string, ok bool) {
+	for _, property := range as.EssentialProperty {
+		if strOrEmpty(property.SchemeIDURI) == TRICK_MODE_SCHEME_ID {
+			return strings.TrimSpace(strOrEmpty(property.Value)), true
+		}
+	}
+	return "", false
+}
+
+// TrickModeMain returns the main AdaptationSet of a trick mode AdaptationSet, or nil for other
+// AdaptationSets or when the Period has no AdaptationSet with the referenced ID.
+func (as *AdaptationSet) TrickModeMain() *AdaptationSet {
+	id, ok := as.TrickModeFor()
+	if !ok || as.period == nil {
+		return nil
+	}
+	return as.period.adaptationSetByID(id)
+}
+
+// TrickModeAdaptationSets returns the trick mode AdaptationSets of the Period referencing the
+// AdaptationSet as their main one, such as its fast-forward tracks.
+func (as *AdaptationSet) TrickModeAdaptationSets() []*AdaptationSet {
+	if as.period == nil || as.ID == nil {
+		return nil
+	}
+	var trickModes []*AdaptationSet
+	for _, other := range as.period.AdaptationSets {
+		if id, ok := other.TrickModeFor(); ok && id == *as.ID && other != as {
+			trickModes = append(trickModes, other)
+		}
+	}
+	return trickModes
+}
+
+// PlayoutRate returns the maxPlayoutRate of a Representation, inherited from its AdaptationSet,
+// which is 1 when unset.
+func (r *Representation) PlayoutRate() (float64, error) {
+	rate := r.MaxPlayoutRate
+	if rate == nil && r.AdaptationSet != nil {
+		rate = r.AdaptationSet.MaxPlayoutRate
+	}
+	if rate == nil {
+		return 1, nil
+	}
+	f, err := strconv.ParseFloat(strings.TrimSpace(*rate), 64)
+	if err != nil || f <= 0 {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidMaxPlayoutRate, *rate)
+	}
+	return f, nil
+}
+
+// validateTrickMode checks that a trick mode AdaptationSet references an AdaptationSet of its
+// Period.
+func (p *Period) validateTrickMode(as *AdaptationSet) error {
+	id, ok := as.TrickModeFor()
+	if ok && p.adaptationSetByID(id) == nil {
+		return fmt.Errorf("%w: %q", ErrTrickModeMainAdaptationSetNotFound, id)
+	}
+	return nil
+}
+
+func (p *Period) adaptationSetByID(id string) *AdaptationSet {
+	if id == "" {
+		return nil
+	}
+	for _, as := range p.AdaptationSets {
+		if as.ID != nil && *as.ID == id {
+			return as
+		}
+	}
+	return nil
+}
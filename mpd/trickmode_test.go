@@ -0,0 +1,116 @@
+package mpd
+
+import (
+	"testing"
+
+	. "github.com/zencoder/go-dash/v3/helpers/ptrs"
+	"github.com/zencoder/go-dash/v3/helpers/require"
+	"github.com/zencoder/go-dash/v3/helpers/testfixtures"
+)
+
+func newTrickModeMPD(t *testing.T) *MPD {
+	m := NewMPD(DASH_PROFILE_LIVE, VALID_MEDIA_PRESENTATION_DURATION, VALID_MIN_BUFFER_TIME)
+	videoAS, err := m.AddNewAdaptationSetVideoWithID("1", DASH_MIME_TYPE_VIDEO_MP4, VALID_SCAN_TYPE, VALID_SEGMENT_ALIGNMENT, VALID_START_WITH_SAP)
+	require.NoError(t, err)
+	_, err = videoAS.SetNewSegmentTemplate(1968, "$RepresentationID$/video-1.mp4", "$RepresentationID$/video-1/seg-$Number$.m4f", 0, 1000)
+	require.NoError(t, err)
+	_, err = videoAS.AddNewRepresentationVideo(VALID_VIDEO_BITRATE, VALID_VIDEO_CODEC, VALID_VIDEO_ID, VALID_VIDEO_FRAMERATE, VALID_VIDEO_WIDTH, VALID_VIDEO_HEIGHT)
+	require.NoError(t, err)
+
+	trickAS, err := m.AddNewAdaptationSetTrickMode("2", "1", DASH_MIME_TYPE_VIDEO_MP4, VALID_SCAN_TYPE, VALID_SEGMENT_ALIGNMENT, VALID_START_WITH_SAP)
+	require.NoError(t, err)
+	_, err = trickAS.SetNewSegmentTemplate(1968, "$RepresentationID$/video-1.mp4", "$RepresentationID$/video-1/seg-$Number$.m4f", 0, 1000)
+	require.NoError(t, err)
+	_, err = trickAS.AddNewRepresentationTrickMode(250000, VALID_VIDEO_CODEC, "trick-iframe", "1/2", 640, 360, 32, false)
+	require.NoError(t, err)
+	_, err = trickAS.AddNewRepresentationTrickMode(400000, VALID_VIDEO_CODEC, "trick-4x", "15/2", 640, 360, 4.5, true)
+	require.NoError(t, err)
+	return m
+}
+
+func TestAddNewAdaptationSetTrickMode(t *testing.T) {
+	m := newTrickModeMPD(t)
+	require.NoError(t, m.Validate())
+	got, err := m.WriteToString()
+	require.NoError(t, err)
+	testfixtures.CompareFixture(t, "fixtures/trick_mode.mpd", got)
+
+	trickAS := m.Periods[0].AdaptationSets[1]
+	require.EqualStringPtr(t, Strptr(TRICK_MODE_SCHEME_ID), trickAS.EssentialProperty[0].SchemeIDURI)
+	require.EqualStringPtr(t, Strptr("1"), trickAS.EssentialProperty[0].Value)
+	r := trickAS.Representations[0]
+	require.EqualStringPtr(t, Strptr("32"), r.MaxPlayoutRate)
+	if r.CodingDependency == nil || *r.CodingDependency {
+		t.Errorf("Expected codingDependency false, got %v", r.CodingDependency)
+	}
+}
+
+func TestAddNewAdaptationSetTrickModeErrors(t *testing.T) {
+	m := NewMPD(DASH_PROFILE_LIVE, VALID_MEDIA_PRESENTATION_DURATION, VALID_MIN_BUFFER_TIME)
+	as, err := m.AddNewAdaptationSetTrickMode("2", "1", DASH_MIME_TYPE_VIDEO_MP4, VALID_SCAN_TYPE, VALID_SEGMENT_ALIGNMENT, VALID_START_WITH_SAP)
+	require.Nil(t, as)
+	require.EqualError(t, err, `Trick mode main AdaptationSet not found: "1"`)
+
+	videoAS, _ := m.AddNewAdaptationSetVideoWithID("1", DASH_MIME_TYPE_VIDEO_MP4, VALID_SCAN_TYPE, VALID_SEGMENT_ALIGNMENT, VALID_START_WITH_SAP)
+	r, err := videoAS.AddNewRepresentationTrickMode(250000, VALID_VIDEO_CODEC, "trick", "1/2", 640, 360, 0, false)
+	require.Nil(t, r)
+	require.EqualError(t, err, "Invalid maxPlayoutRate: 0")
+	r, err = videoAS.AddNewRepresentationTrickMode(250000, "avc1.4d40", "trick", "1/2", 640, 360, 8, false)
+	require.Nil(t, r)
+	require.EqualError(t, err, `Malformed codec string "avc1.4d40": profile, constraints and level should be 6 hex digits`)
+	require.EqualInt(t, 0, len(videoAS.Representations))
+}
+
+func TestReadTrickMode(t *testing.T) {
+	m, err := ReadFromFile("fixtures/trick_mode.mpd")
+	require.NoError(t, err)
+	videoAS, trickAS := m.Periods[0].AdaptationSets[0], m.Periods[0].AdaptationSets[1]
+
+	_, ok := videoAS.TrickModeFor()
+	if ok {
+		t.Errorf("Expected the main AdaptationSet not to be a trick mode one")
+	}
+	id, ok := trickAS.TrickModeFor()
+	require.EqualString(t, "1", id)
+	if !ok {
+		t.Errorf("Expected a trick mode AdaptationSet")
+	}
+	require.Nil(t, videoAS.TrickModeMain())
+	if trickAS.TrickModeMain() != videoAS {
+		t.Errorf("Expected the main AdaptationSet of the trick mode one to be %v", videoAS)
+	}
+	trickModes := videoAS.TrickModeAdaptationSets()
+	require.EqualInt(t, 1, len(trickModes))
+	if trickModes[0] != trickAS {
+		t.Errorf("Expected the trick mode AdaptationSet of the main one to be %v", trickAS)
+	}
+	require.EqualInt(t, 0, len(trickAS.TrickModeAdaptationSets()))
+
+	rate, err := trickAS.Representations[1].PlayoutRate()
+	require.NoError(t, err)
+	require.EqualFloat64(t, 4.5, rate)
+	rate, err = videoAS.Representations[0].PlayoutRate()
+	require.NoError(t, err)
+	require.EqualFloat64(t, 1, rate)
+
+	// Inherited from the AdaptationSet
+	r := trickAS.Representations[0]
+	r.MaxPlayoutRate = nil
+	trickAS.MaxPlayoutRate = Strptr("16")
+	rate, err = r.PlayoutRate()
+	require.NoError(t, err)
+	require.EqualFloat64(t, 16, rate)
+	trickAS.MaxPlayoutRate = Strptr("fast")
+	_, err = r.PlayoutRate()
+	require.EqualError(t, err, `Invalid maxPlayoutRate: "fast"`)
+}
+
+func TestValidateTrickMode(t *testing.T) {
+	m := newTrickModeMPD(t)
+	m.Periods[0].AdaptationSets[1].EssentialProperty[0].Value = Strptr("3")
+	require.EqualError(t, m.Validate(), `AdaptationSet 2: Trick mode main AdaptationSet not found: "3"`)
+
+	m = newTrickModeMPD(t)
+	m.Periods[0].AdaptationSets = m.Periods[0].AdaptationSets[1:]
+	require.EqualError(t, m.Validate(), `AdaptationSet 2: Trick mode main AdaptationSet not found: "1"`)
+}
@@ -0,0 +1,26 @@
+package mpd
+
+import . "github.com/zencoder/go-dash/v3/helpers/ptrs"
+
+// Constants for the UTCTiming schemes of ISO 23009-1 Table 32
+const (
+	UTC_TIMING_HTTP_XSDATE_SCHEME_ID = "urn:mpeg:dash:utc:http-xsdate:2014"
+	UTC_TIMING_HTTP_ISO_SCHEME_ID    = "urn:mpeg:dash:utc:http-iso:2014"
+	UTC_TIMING_HTTP_HEAD_SCHEME_ID   = "urn:mpeg:dash:utc:http-head:2014"
+	UTC_TIMING_HTTP_NTP_SCHEME_ID    = "urn:mpeg:dash:utc:http-ntp:2014"
+	UTC_TIMING_DIRECT_SCHEME_ID      = "urn:mpeg:dash:utc:direct:2014"
+	UTC_TIMING_NTP_SCHEME_ID         = "urn:mpeg:dash:utc:ntp:2014"
+)
+
+// Adds a new UTCTiming element to the MPD. Clients try the UTCTiming elements in document order.
+// schemeIDURI - timing scheme (i.e. UTC_TIMING_HTTP_ISO_SCHEME_ID).
+// value - white-space separated time source URLs or servers, or the time for the direct scheme
+// (i.e. https://time.akamai.com/?iso).
+func (m *MPD) AddNewUTCTiming(schemeIDURI, value string) *DescriptorType {
+	utcTiming := &DescriptorType{
+		SchemeIDURI: Strptr(schemeIDURI),
+		Value:       Strptr(value),
+	}
+	m.UTCTiming = append(m.UTCTiming, utcTiming)
+	return utcTiming
+}
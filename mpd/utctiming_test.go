@@ -0,0 +1,45 @@
+package mpd
+
+import (
+	"testing"
+
+	"github.com/zencoder/go-dash/v3/helpers/ptrs"
+	"github.com/zencoder/go-dash/v3/helpers/require"
+	"github.com/zencoder/go-dash/v3/helpers/testfixtures"
+)
+
+func newUTCTimingMPD() *MPD {
+	m := NewMPD(DASH_PROFILE_LIVE, VALID_MEDIA_PRESENTATION_DURATION, VALID_MIN_BUFFER_TIME)
+	m.Type = ptrs.Strptr("dynamic")
+	m.AddNewUTCTiming(UTC_TIMING_HTTP_XSDATE_SCHEME_ID, "https://time.example.com/xsdate https://time2.example.com/xsdate")
+	m.AddNewUTCTiming(UTC_TIMING_HTTP_HEAD_SCHEME_ID, "https://time.example.com/head")
+	m.AddNewUTCTiming(UTC_TIMING_DIRECT_SCHEME_ID, "2024-01-01T00:00:00Z")
+	return m
+}
+
+func TestAddNewUTCTiming(t *testing.T) {
+	m := &MPD{}
+	utcTiming := m.AddNewUTCTiming(UTC_TIMING_NTP_SCHEME_ID, "time.example.com")
+	require.EqualInt(t, 1, len(m.UTCTiming))
+	require.EqualStringPtr(t, ptrs.Strptr(UTC_TIMING_NTP_SCHEME_ID), utcTiming.SchemeIDURI)
+	require.EqualStringPtr(t, ptrs.Strptr("time.example.com"), utcTiming.Value)
+}
+
+func TestUTCTimingsWriteToString(t *testing.T) {
+	got, err := newUTCTimingMPD().WriteToString()
+	require.NoError(t, err)
+
+	testfixtures.CompareFixture(t, "fixtures/utc_timing.mpd", got)
+}
+
+func TestReadUTCTimings(t *testing.T) {
+	m, err := ReadFromFile("fixtures/utc_timing.mpd")
+	require.NoError(t, err)
+	require.EqualInt(t, 3, len(m.UTCTiming))
+	require.EqualStringPtr(t, ptrs.Strptr(UTC_TIMING_HTTP_HEAD_SCHEME_ID), m.UTCTiming[1].SchemeIDURI)
+
+	got, err := m.WriteToString()
+	require.NoError(t, err)
+
+	testfixtures.CompareFixture(t, "fixtures/utc_timing.mpd", got)
+}
@@ -1,9 +1,48 @@
 package mpd
 
+import (
+	"fmt"
+
+	"github.com/zencoder/go-dash/v3/codecs"
+)
+
 // Validate checks for incomplete MPD object
 func (m *MPD) Validate() error {
 	if m.Profiles == nil {
 		return ErrNoDASHProfileSet
 	}
+	for _, p := range m.Periods {
+		for _, as := range p.AdaptationSets {
+			if err := validateCodecs(as.Codecs); err != nil {
+				return fmt.Errorf("AdaptationSet %s: %w", strOrEmpty(as.ID), err)
+			}
+			if err := p.validateTrickMode(as); err != nil {
+				return fmt.Errorf("AdaptationSet %s: %w", strOrEmpty(as.ID), err)
+			}
+			if err := validateCaptions(as); err != nil {
+				return fmt.Errorf("AdaptationSet %s: %w", strOrEmpty(as.ID), err)
+			}
+			for _, r := range as.Representations {
+				if err := validateCodecs(r.Codecs); err != nil {
+					return fmt.Errorf("Representation %s: %w", strOrEmpty(r.ID), err)
+				}
+			}
+		}
+	}
 	return nil
 }
+
+// validateCodecs checks an optional @codecs attribute, an empty value is treated as unset.
+func validateCodecs(s *string) error {
+	if s == nil || *s == "" {
+		return nil
+	}
+	return codecs.Validate(*s)
+}
+
+func strOrEmpty(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
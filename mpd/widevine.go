@@ -0,0 +1,267 @@
+package mpd
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// WidevineAlgorithm is the deprecated algorithm field of the Widevine PSSH data.
+type WidevineAlgorithm uint32
+
+const (
+	WIDEVINE_ALGORITHM_UNENCRYPTED WidevineAlgorithm = 0
+	WIDEVINE_ALGORITHM_AESCTR      WidevineAlgorithm = 1
+)
+
+// Constants for the Common Encryption protection schemes
+const (
+	PROTECTION_SCHEME_CENC = "cenc"
+	PROTECTION_SCHEME_CBC1 = "cbc1"
+	PROTECTION_SCHEME_CENS = "cens"
+	PROTECTION_SCHEME_CBCS = "cbcs"
+)
+
+// Field numbers of the WidevinePsshData protobuf message
+const (
+	widevineFieldAlgorithm         = 1
+	widevineFieldKeyID             = 2
+	widevineFieldProvider          = 3
+	widevineFieldContentID         = 4
+	widevineFieldPolicy            = 6
+	widevineFieldCryptoPeriodIndex = 7
+	widevineFieldProtectionScheme  = 9
+)
+
+// Protobuf wire types
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// Wire types of the supported WidevinePsshData fields
+var widevineFieldWireTypes = map[uint64]uint64{
+	widevineFieldAlgorithm:         wireVarint,
+	widevineFieldKeyID:             wireBytes,
+	widevineFieldProvider:          wireBytes,
+	widevineFieldContentID:         wireBytes,
+	widevineFieldPolicy:            wireBytes,
+	widevineFieldCryptoPeriodIndex: wireVarint,
+	widevineFieldProtectionScheme:  wireVarint,
+}
+
+var (
+	ErrWidevinePSSHDataNil       = errors.New("Widevine PSSH data nil")
+	ErrInvalidWidevineKeyID      = errors.New("Invalid Widevine key ID, should be 16 bytes")
+	ErrInvalidProtectionScheme   = errors.New("Invalid protection scheme, should be one of cenc, cbc1, cens or cbcs")
+	ErrWidevinePSSHDataTruncated = errors.New("Widevine PSSH data truncated")
+	ErrWidevinePSSHEmpty         = errors.New("Widevine PSSH empty")
+	ErrWidevineWireType          = errors.New("Widevine PSSH data field has the wrong protobuf wire type")
+	ErrNotWidevinePSSH           = errors.New("PSSH box is not for the Widevine system ID")
+)
+
+// WidevinePSSHData is the WidevinePsshData message carried in the data field of a Widevine pssh box.
+// Only the fields needed to signal content keys are supported, anything else is skipped when parsing.
+type WidevinePSSHData struct {
+	Algorithm         *WidevineAlgorithm // Deprecated, set by some legacy packagers
+	KeyIDs            [][]byte           // 16 byte key IDs
+	Provider          string             // Content provider name (i.e. widevine_test)
+	ContentID         []byte
+	Policy            string  // Deprecated
+	CryptoPeriodIndex *uint32 // Key rotation period, if rotating keys
+	ProtectionScheme  string  // i.e. cenc or cbcs, empty for the default (cenc)
+}
+
+// MarshalBinary encodes the PSSH data in protobuf wire format, with fields in ascending field
+// number order.
+func (d *WidevinePSSHData) MarshalBinary() ([]byte, error) {
+	var b []byte
+	if d.Algorithm != nil {
+		b = appendVarintField(b, widevineFieldAlgorithm, uint64(*d.Algorithm))
+	}
+	for _, kid := range d.KeyIDs {
+		if len(kid) != 16 {
+			return nil, ErrInvalidWidevineKeyID
+		}
+		b = appendBytesField(b, widevineFieldKeyID, kid)
+	}
+	if d.Provider != "" {
+		b = appendBytesField(b, widevineFieldProvider, []byte(d.Provider))
+	}
+	if len(d.ContentID) > 0 {
+		b = appendBytesField(b, widevineFieldContentID, d.ContentID)
+	}
+	if d.Policy != "" {
+		b = appendBytesField(b, widevineFieldPolicy, []byte(d.Policy))
+	}
+	if d.CryptoPeriodIndex != nil {
+		b = appendVarintField(b, widevineFieldCryptoPeriodIndex, uint64(*d.CryptoPeriodIndex))
+	}
+	if d.ProtectionScheme != "" {
+		fourCC, err := protectionSchemeFourCC(d.ProtectionScheme)
+		if err != nil {
+			return nil, err
+		}
+		b = appendVarintField(b, widevineFieldProtectionScheme, uint64(fourCC))
+	}
+	return b, nil
+}
+
+// ParseWidevinePSSHData decodes the data field of a Widevine pssh box.
+func ParseWidevinePSSHData(b []byte) (*WidevinePSSHData, error) {
+	d := &WidevinePSSHData{}
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		if n <= 0 {
+			return nil, ErrWidevinePSSHDataTruncated
+		}
+		b = b[n:]
+		field, wireType := key>>3, key&0x7
+
+		var (
+			varint uint64
+			value  []byte
+		)
+		switch wireType {
+		case wireVarint:
+			varint, n = binary.Uvarint(b)
+			if n <= 0 {
+				return nil, ErrWidevinePSSHDataTruncated
+			}
+			b = b[n:]
+		case wireBytes:
+			length, n := binary.Uvarint(b)
+			if n <= 0 || uint64(len(b)-n) < length {
+				return nil, ErrWidevinePSSHDataTruncated
+			}
+			value = b[n : n+int(length)]
+			b = b[n+int(length):]
+		case wireFixed64:
+			if len(b) < 8 {
+				return nil, ErrWidevinePSSHDataTruncated
+			}
+			b = b[8:]
+		case wireFixed32:
+			if len(b) < 4 {
+				return nil, ErrWidevinePSSHDataTruncated
+			}
+			b = b[4:]
+		default:
+			return nil, fmt.Errorf("Unsupported protobuf wire type %d for field %d", wireType, field)
+		}
+		if expected, ok := widevineFieldWireTypes[field]; ok && wireType != expected {
+			return nil, fmt.Errorf("%w: %d for field %d", ErrWidevineWireType, wireType, field)
+		}
+
+		switch field {
+		case widevineFieldAlgorithm:
+			algorithm := WidevineAlgorithm(varint)
+			d.Algorithm = &algorithm
+		case widevineFieldKeyID:
+			if len(value) != 16 {
+				return nil, ErrInvalidWidevineKeyID
+			}
+			d.KeyIDs = append(d.KeyIDs, value)
+		case widevineFieldProvider:
+			d.Provider = string(value)
+		case widevineFieldContentID:
+			d.ContentID = value
+		case widevineFieldPolicy:
+			d.Policy = string(value)
+		case widevineFieldCryptoPeriodIndex:
+			index := uint32(varint)
+			d.CryptoPeriodIndex = &index
+		case widevineFieldProtectionScheme:
+			var fourCC [4]byte
+			binary.BigEndian.PutUint32(fourCC[:], uint32(varint))
+			d.ProtectionScheme = string(fourCC[:])
+		}
+	}
+	return d, nil
+}
+
+func protectionSchemeFourCC(scheme string) (uint32, error) {
+	switch scheme {
+	case PROTECTION_SCHEME_CENC, PROTECTION_SCHEME_CBC1, PROTECTION_SCHEME_CENS, PROTECTION_SCHEME_CBCS:
+		return binary.BigEndian.Uint32([]byte(scheme)), nil
+	default:
+		return 0, ErrInvalidProtectionScheme
+	}
+}
+
+func appendVarintField(b []byte, field int, v uint64) []byte {
+	b = binary.AppendUvarint(b, uint64(field)<<3|wireVarint)
+	return binary.AppendUvarint(b, v)
+}
+
+func appendBytesField(b []byte, field int, v []byte) []byte {
+	b = binary.AppendUvarint(b, uint64(field)<<3|wireBytes)
+	b = binary.AppendUvarint(b, uint64(len(v)))
+	return append(b, v...)
+}
+
+// NewWidevineContentProtectionWithPSSHData creates a Widevine ContentProtection with a <cenc:pssh>
+// element built from the given PSSH data.
+func NewWidevineContentProtectionWithPSSHData(data *WidevinePSSHData) (*WidevineContentProtection, error) {
+	if data == nil {
+		return nil, ErrWidevinePSSHDataNil
+	}
+	wvHeader, err := data.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return NewWidevineContentProtection(wvHeader)
+}
+
+// AddNewContentProtectionSchemeWidevineWithPSSHData adds a new content protection scheme for Widevine DRM to the
+// adaptation set, with a <cenc:pssh> element built from the given PSSH data.
+func (as *AdaptationSet) AddNewContentProtectionSchemeWidevineWithPSSHData(data *WidevinePSSHData) (*WidevineContentProtection, error) {
+	cp, err := NewWidevineContentProtectionWithPSSHData(data)
+	if err != nil {
+		return nil, err
+	}
+
+	err = as.AddContentProtection(cp)
+	if err != nil {
+		return nil, err
+	}
+	return cp, nil
+}
+
+// AddNewContentProtectionSchemeWidevineWithPSSHData adds a new content protection scheme for Widevine DRM to the
+// representation, with a <cenc:pssh> element built from the given PSSH data.
+func (r *Representation) AddNewContentProtectionSchemeWidevineWithPSSHData(data *WidevinePSSHData) (*WidevineContentProtection, error) {
+	cp, err := NewWidevineContentProtectionWithPSSHData(data)
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.AddContentProtection(cp)
+	if err != nil {
+		return nil, err
+	}
+	return cp, nil
+}
+
+// PSSHData decodes the Widevine PSSH data from the <cenc:pssh> element.
+func (s *WidevineContentProtection) PSSHData() (*WidevinePSSHData, error) {
+	if s.PSSH == nil {
+		return nil, ErrWidevinePSSHEmpty
+	}
+	box, err := base64.StdEncoding.DecodeString(*s.PSSH)
+	if err != nil {
+		return nil, err
+	}
+	pssh, err := ParsePSSHBox(box)
+	if err != nil {
+		return nil, err
+	}
+	if hex.EncodeToString(pssh.SystemID) != CONTENT_PROTECTION_WIDEVINE_SCHEME_HEX {
+		return nil, ErrNotWidevinePSSH
+	}
+	return ParseWidevinePSSHData(pssh.Data)
+}
@@ -0,0 +1,140 @@
+package mpd
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	. "github.com/zencoder/go-dash/v3/helpers/ptrs"
+	"github.com/zencoder/go-dash/v3/helpers/require"
+)
+
+func TestParseWidevinePSSHData(t *testing.T) {
+	d, err := ParseWidevinePSSHData(getValidWVHeaderBytes())
+	require.NoError(t, err)
+
+	require.EqualInt(t, int(WIDEVINE_ALGORITHM_AESCTR), int(*d.Algorithm))
+	require.EqualInt(t, 1, len(d.KeyIDs))
+	require.EqualString(t, "5abdd52f554a4f2ab8d061f761425155", hex.EncodeToString(d.KeyIDs[0]))
+	require.EqualString(t, "castlabs", d.Provider)
+	require.EqualString(t, "Wr3VL1VKTyq40GH3YUJRVQ==", string(d.ContentID))
+	require.EqualString(t, "default", d.Policy)
+	require.Nil(t, d.CryptoPeriodIndex)
+	require.EqualString(t, "", d.ProtectionScheme)
+}
+
+func TestWidevinePSSHDataRoundTrip(t *testing.T) {
+	d, err := ParseWidevinePSSHData(getValidWVHeaderBytes())
+	require.NoError(t, err)
+
+	b, err := d.MarshalBinary()
+	require.NoError(t, err)
+	require.EqualString(t, VALID_WV_HEADER, base64.StdEncoding.EncodeToString(b))
+}
+
+func TestWidevinePSSHDataMarshalBinary(t *testing.T) {
+	kid, _ := hex.DecodeString("08e367028f33436ca5dd60ffe5571e60")
+	d := &WidevinePSSHData{
+		KeyIDs:            [][]byte{kid},
+		Provider:          "widevine_test",
+		ContentID:         []byte("content-1"),
+		CryptoPeriodIndex: Uint32ptr(300),
+		ProtectionScheme:  PROTECTION_SCHEME_CBCS,
+	}
+	b, err := d.MarshalBinary()
+	require.NoError(t, err)
+	require.EqualString(t, "121008e367028f33436ca5dd60ffe5571e601a0d7769646576696e655f746573742209636f6e74656e742d3138ac0248f3c6899b06", hex.EncodeToString(b))
+
+	parsed, err := ParseWidevinePSSHData(b)
+	require.NoError(t, err)
+	require.Nil(t, parsed.Algorithm)
+	require.EqualString(t, "widevine_test", parsed.Provider)
+	require.EqualString(t, "content-1", string(parsed.ContentID))
+	require.EqualUInt32(t, 300, *parsed.CryptoPeriodIndex)
+	require.EqualString(t, PROTECTION_SCHEME_CBCS, parsed.ProtectionScheme)
+}
+
+func TestWidevinePSSHDataMarshalBinaryErrors(t *testing.T) {
+	_, err := (&WidevinePSSHData{KeyIDs: [][]byte{[]byte("short")}}).MarshalBinary()
+	require.EqualErr(t, ErrInvalidWidevineKeyID, err)
+
+	_, err = (&WidevinePSSHData{ProtectionScheme: "ctr"}).MarshalBinary()
+	require.EqualErr(t, ErrInvalidProtectionScheme, err)
+}
+
+func TestParseWidevinePSSHDataErrors(t *testing.T) {
+	_, err := ParseWidevinePSSHData([]byte{0x12, 0x10, 0x01})
+	require.EqualErr(t, ErrWidevinePSSHDataTruncated, err)
+
+	_, err = ParseWidevinePSSHData([]byte{0x0b})
+	require.EqualError(t, err, "Unsupported protobuf wire type 3 for field 1")
+
+	// key_id and content_id as varints, algorithm as bytes
+	for _, b := range [][]byte{{0x10, 0x01}, {0x20, 0x01}, {0x0a, 0x01, 0x01}} {
+		_, err = ParseWidevinePSSHData(b)
+		if !errors.Is(err, ErrWidevineWireType) {
+			t.Errorf("Expected %v for %x, got %v", ErrWidevineWireType, b, err)
+		}
+	}
+	_, err = ParseWidevinePSSHData([]byte{0x10, 0x01})
+	require.EqualError(t, err, "Widevine PSSH data field has the wrong protobuf wire type: 0 for field 2")
+
+	// A key_id of 15 bytes
+	_, err = ParseWidevinePSSHData(append([]byte{0x12, 0x0f}, make([]byte, 15)...))
+	require.EqualErr(t, ErrInvalidWidevineKeyID, err)
+}
+
+func TestParseWidevinePSSHDataSkipsUnknownFields(t *testing.T) {
+	// field 5 (fixed32), field 8 (bytes) and field 10 (varint), followed by the provider
+	b, _ := hex.DecodeString("2d01020304420161500a1a0161")
+	d, err := ParseWidevinePSSHData(b)
+	require.NoError(t, err)
+	require.EqualString(t, "a", d.Provider)
+}
+
+func TestAddNewContentProtectionSchemeWidevineWithPSSHData(t *testing.T) {
+	m := NewMPD(DASH_PROFILE_LIVE, VALID_MEDIA_PRESENTATION_DURATION, VALID_MIN_BUFFER_TIME)
+	s, _ := m.AddNewAdaptationSetVideoWithID("7357", DASH_MIME_TYPE_VIDEO_MP4, VALID_SCAN_TYPE, VALID_SEGMENT_ALIGNMENT, VALID_START_WITH_SAP)
+
+	expected, err := ParseWidevinePSSHData(getValidWVHeaderBytes())
+	require.NoError(t, err)
+
+	cp, err := s.AddNewContentProtectionSchemeWidevineWithPSSHData(expected)
+	require.NoError(t, err)
+
+	legacy, err := NewWidevineContentProtection(getValidWVHeaderBytes())
+	require.NoError(t, err)
+	require.EqualStringPtr(t, legacy.PSSH, cp.PSSH)
+
+	actual, err := cp.PSSHData()
+	require.NoError(t, err)
+	require.EqualString(t, expected.Provider, actual.Provider)
+	require.EqualString(t, hex.EncodeToString(expected.KeyIDs[0]), hex.EncodeToString(actual.KeyIDs[0]))
+}
+
+func TestAddNewContentProtectionSchemeWidevineWithPSSHDataToRepresentation(t *testing.T) {
+	m := NewMPD(DASH_PROFILE_LIVE, VALID_MEDIA_PRESENTATION_DURATION, VALID_MIN_BUFFER_TIME)
+	s, _ := m.AddNewAdaptationSetVideoWithID("7357", DASH_MIME_TYPE_VIDEO_MP4, VALID_SCAN_TYPE, VALID_SEGMENT_ALIGNMENT, VALID_START_WITH_SAP)
+	r, _ := s.AddNewRepresentationVideo(VALID_VIDEO_BITRATE, VALID_VIDEO_CODEC, VALID_VIDEO_ID, VALID_VIDEO_FRAMERATE, VALID_VIDEO_WIDTH, VALID_VIDEO_HEIGHT)
+
+	cp, err := r.AddNewContentProtectionSchemeWidevineWithPSSHData(nil)
+	require.EqualErr(t, ErrWidevinePSSHDataNil, err)
+	require.Nil(t, cp)
+
+	cp, err = r.AddNewContentProtectionSchemeWidevineWithPSSHData(&WidevinePSSHData{ProtectionScheme: PROTECTION_SCHEME_CENC})
+	require.NoError(t, err)
+	require.EqualInt(t, 1, len(r.ContentProtection))
+	require.EqualStringPtr(t, Strptr(CONTENT_PROTECTION_WIDEVINE_SCHEME_ID), cp.SchemeIDURI)
+}
+
+func TestWidevineContentProtectionPSSHDataErrors(t *testing.T) {
+	_, err := (&WidevineContentProtection{}).PSSHData()
+	require.EqualErr(t, ErrWidevinePSSHEmpty, err)
+
+	prSystemID, _ := hex.DecodeString(CONTENT_PROTECTION_PLAYREADY_SCHEME_HEX)
+	box, _ := MakePSSHBox(prSystemID, []byte("data"))
+	cp := &WidevineContentProtection{PSSH: Strptr(base64.StdEncoding.EncodeToString(box))}
+	_, err = cp.PSSHData()
+	require.EqualErr(t, ErrNotWidevinePSSH, err)
+}
@@ -0,0 +1,189 @@
+package probe
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// box is a single ISO BMFF box. Payload excludes the box header.
+type box struct {
+	Type    string
+	Offset  int64 // Offset of the box header from the start of the parsed buffer
+	Size    int64 // Size of the box including the header
+	Payload []byte
+}
+
+// parseBoxes splits a buffer into consecutive boxes. offset is added to each box's Offset so
+// that child boxes report their position in the file.
+func parseBoxes(b []byte, offset int64) ([]box, error) {
+	var boxes []box
+	for len(b) > 0 {
+		if len(b) < 8 {
+			return nil, fmt.Errorf("Box header truncated at offset %d", offset)
+		}
+		size := int64(binary.BigEndian.Uint32(b[0:4]))
+		typ := string(b[4:8])
+		headerSize := int64(8)
+		switch size {
+		case 0:
+			size = int64(len(b))
+		case 1:
+			if len(b) < 16 {
+				return nil, fmt.Errorf("Box %q largesize truncated at offset %d", typ, offset)
+			}
+			size = int64(binary.BigEndian.Uint64(b[8:16]))
+			headerSize = 16
+		}
+		if size < headerSize || size > int64(len(b)) {
+			return nil, fmt.Errorf("Box %q size %d invalid at offset %d", typ, size, offset)
+		}
+		boxes = append(boxes, box{
+			Type:    typ,
+			Offset:  offset,
+			Size:    size,
+			Payload: b[headerSize:size],
+		})
+		b = b[size:]
+		offset += size
+	}
+	return boxes, nil
+}
+
+// children parses the payload of a container box, skipping skip bytes of fields that precede the
+// child boxes.
+func (bx box) children(skip int) ([]box, error) {
+	if len(bx.Payload) < skip {
+		return nil, fmt.Errorf("Box %q truncated", bx.Type)
+	}
+	return parseBoxes(bx.Payload[skip:], bx.Offset+bx.Size-int64(len(bx.Payload))+int64(skip))
+}
+
+// findBox returns the first box of the given type, or nil if there is none.
+func findBox(boxes []box, typ string) *box {
+	for i := range boxes {
+		if boxes[i].Type == typ {
+			return &boxes[i]
+		}
+	}
+	return nil
+}
+
+// findPath walks a path of container box types (i.e. "mdia", "minf", "stbl") starting at boxes.
+func findPath(boxes []box, path ...string) (*box, error) {
+	var found *box
+	for i, typ := range path {
+		found = findBox(boxes, typ)
+		if found == nil {
+			return nil, fmt.Errorf("Box %q not found", typ)
+		}
+		if i == len(path)-1 {
+			break
+		}
+		var err error
+		boxes, err = found.children(0)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return found, nil
+}
+
+// fullBoxHeader returns the version and flags of a FullBox payload.
+func fullBoxHeader(bx *box) (uint8, uint32, error) {
+	if len(bx.Payload) < 4 {
+		return 0, 0, fmt.Errorf("Box %q truncated", bx.Type)
+	}
+	return bx.Payload[0], binary.BigEndian.Uint32(bx.Payload[0:4]) & 0xffffff, nil
+}
+
+// reader reads big-endian fields from a box payload, recording the first out of range read.
+type reader struct {
+	b   []byte
+	pos int
+	err error
+}
+
+func newReader(b []byte) *reader {
+	return &reader{b: b}
+}
+
+func (r *reader) bytes(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || r.pos+n > len(r.b) {
+		r.err = fmt.Errorf("Read of %d bytes at offset %d past end of %d byte payload", n, r.pos, len(r.b))
+		return nil
+	}
+	v := r.b[r.pos : r.pos+n]
+	r.pos += n
+	return v
+}
+
+func (r *reader) skip(n int) {
+	r.bytes(n)
+}
+
+func (r *reader) uint8() uint8 {
+	b := r.bytes(1)
+	if b == nil {
+		return 0
+	}
+	return b[0]
+}
+
+func (r *reader) uint16() uint16 {
+	b := r.bytes(2)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint16(b)
+}
+
+func (r *reader) uint24() uint32 {
+	b := r.bytes(3)
+	if b == nil {
+		return 0
+	}
+	return uint32(b[0])<<16 | uint32(b[1])<<8 | uint32(b[2])
+}
+
+func (r *reader) uint32() uint32 {
+	b := r.bytes(4)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint32(b)
+}
+
+func (r *reader) uint64() uint64 {
+	b := r.bytes(8)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint64(b)
+}
+
+// cstring reads a null terminated string.
+func (r *reader) cstring() string {
+	if r.err != nil {
+		return ""
+	}
+	for i := r.pos; i < len(r.b); i++ {
+		if r.b[i] == 0 {
+			s := string(r.b[r.pos:i])
+			r.pos = i + 1
+			return s
+		}
+	}
+	s := string(r.b[r.pos:])
+	r.pos = len(r.b)
+	return s
+}
+
+func (r *reader) remaining() []byte {
+	if r.err != nil {
+		return nil
+	}
+	return r.b[r.pos:]
+}
@@ -0,0 +1,106 @@
+package probe
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/zencoder/go-dash/v3/helpers/require"
+)
+
+func TestParseBoxes(t *testing.T) {
+	b := concat(
+		mkbox("ftyp", []byte("cmfc"), u32(0)),
+		mkbox("moov", mkbox("mvhd", make([]byte, 4))),
+	)
+	boxes, err := parseBoxes(b, 0)
+	require.NoError(t, err)
+	require.EqualInt(t, 2, len(boxes))
+	require.EqualString(t, "ftyp", boxes[0].Type)
+	require.EqualInt(t, 16, int(boxes[0].Size))
+	require.EqualString(t, "moov", boxes[1].Type)
+	require.EqualInt(t, 16, int(boxes[1].Offset))
+
+	children, err := boxes[1].children(0)
+	require.NoError(t, err)
+	require.EqualInt(t, 1, len(children))
+	require.EqualString(t, "mvhd", children[0].Type)
+	require.EqualInt(t, 24, int(children[0].Offset))
+}
+
+func TestParseBoxesLargeSize(t *testing.T) {
+	b := concat(u32(1), []byte("mdat"), u64(20), []byte{1, 2, 3, 4})
+	boxes, err := parseBoxes(b, 0)
+	require.NoError(t, err)
+	require.EqualInt(t, 1, len(boxes))
+	require.EqualInt(t, 20, int(boxes[0].Size))
+	require.EqualInt(t, 4, len(boxes[0].Payload))
+}
+
+func TestParseBoxesSizeZeroExtendsToEnd(t *testing.T) {
+	b := concat(u32(0), []byte("mdat"), []byte{1, 2, 3})
+	boxes, err := parseBoxes(b, 0)
+	require.NoError(t, err)
+	require.EqualInt(t, 3, len(boxes[0].Payload))
+}
+
+func TestParseBoxesErrors(t *testing.T) {
+	_, err := parseBoxes([]byte{0, 0, 0}, 0)
+	require.EqualError(t, err, "Box header truncated at offset 0")
+
+	_, err = parseBoxes(concat(u32(100), []byte("moov")), 0)
+	require.EqualError(t, err, `Box "moov" size 100 invalid at offset 0`)
+}
+
+func TestFindPath(t *testing.T) {
+	boxes, err := parseBoxes(mkbox("moov", mkbox("trak", mkbox("mdia", mkbox("mdhd", make([]byte, 4))))), 0)
+	require.NoError(t, err)
+
+	mdhd, err := findPath(boxes, "moov", "trak", "mdia", "mdhd")
+	require.NoError(t, err)
+	require.EqualString(t, "mdhd", mdhd.Type)
+
+	_, err = findPath(boxes, "moov", "mvex")
+	require.EqualError(t, err, `Box "mvex" not found`)
+}
+
+func TestReaderOutOfRange(t *testing.T) {
+	r := newReader([]byte{1, 2, 3})
+	require.EqualInt(t, 0x0102, int(r.uint16()))
+	require.EqualInt(t, 0, int(r.uint32()))
+	require.EqualError(t, r.err, "Read of 4 bytes at offset 2 past end of 3 byte payload")
+}
+
+// Helpers for building ISO BMFF structures in tests
+
+func mkbox(typ string, parts ...[]byte) []byte {
+	payload := concat(parts...)
+	return concat(u32(uint32(8+len(payload))), []byte(typ), payload)
+}
+
+func fullbox(typ string, version uint8, flags uint32, parts ...[]byte) []byte {
+	return mkbox(typ, append([][]byte{u32(uint32(version)<<24 | flags)}, parts...)...)
+}
+
+func concat(parts ...[]byte) []byte {
+	var b []byte
+	for _, p := range parts {
+		b = append(b, p...)
+	}
+	return b
+}
+
+func u8(v uint8) []byte {
+	return []byte{v}
+}
+
+func u16(v uint16) []byte {
+	return binary.BigEndian.AppendUint16(nil, v)
+}
+
+func u32(v uint32) []byte {
+	return binary.BigEndian.AppendUint32(nil, v)
+}
+
+func u64(v uint64) []byte {
+	return binary.BigEndian.AppendUint64(nil, v)
+}
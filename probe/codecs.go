@@ -0,0 +1,309 @@
+package probe
+
+import (
+	"fmt"
+	"math/bits"
+
+	"github.com/zencoder/go-dash/v3/codecs"
+)
+
+// avcCodecs builds an RFC 6381 codec string (i.e. avc1.4d401f) from an avcC box.
+func avcCodecs(sampleEntry string, avcC []byte) (string, error) {
+	if len(avcC) < 4 {
+		return "", fmt.Errorf("avcC truncated")
+	}
+	c := &codecs.Codec{
+		SampleEntry:        sampleEntry,
+		Family:             codecs.FAMILY_AVC,
+		Profile:            int(avcC[1]),
+		ConstraintSetFlags: avcC[2],
+		Level:              int(avcC[3]),
+	}
+	return c.String(), nil
+}
+
+// hevcCodecs builds an ISO/IEC 14496-15 Annex E codec string (i.e. hvc1.1.6.L93.B0) from an hvcC box.
+func hevcCodecs(sampleEntry string, hvcC []byte) (string, error) {
+	r := newReader(hvcC)
+	r.skip(1) // configurationVersion
+	b := r.uint8()
+	compatibility := r.uint32()
+	constraints := r.bytes(6)
+	level := r.uint8()
+	if r.err != nil {
+		return "", fmt.Errorf("hvcC truncated")
+	}
+
+	tier := "L"
+	if b&0x20 != 0 {
+		tier = "H"
+	}
+	c := &codecs.Codec{
+		SampleEntry:        sampleEntry,
+		Family:             codecs.FAMILY_HEVC,
+		ProfileSpace:       int(b >> 6),
+		Profile:            int(b & 0x1f),
+		CompatibilityFlags: bits.Reverse32(compatibility),
+		Tier:               tier,
+		Level:              int(level),
+		ConstraintFlags:    constraints,
+	}
+	return c.String(), nil
+}
+
+// av1Codecs builds the short form AV1 codec string (i.e. av01.0.04M.08) from an av1C box.
+func av1Codecs(av1C []byte) (string, error) {
+	if len(av1C) < 3 {
+		return "", fmt.Errorf("av1C truncated")
+	}
+	profile := av1C[1] >> 5
+	tier := "M"
+	if av1C[2]&0x80 != 0 {
+		tier = "H"
+	}
+	bitDepth := 8
+	if av1C[2]&0x40 != 0 {
+		bitDepth = 10
+		if profile == 2 && av1C[2]&0x20 != 0 {
+			bitDepth = 12
+		}
+	}
+	c := &codecs.Codec{
+		SampleEntry: "av01",
+		Family:      codecs.FAMILY_AV1,
+		Profile:     int(profile),
+		Level:       int(av1C[1] & 0x1f),
+		Tier:        tier,
+		BitDepth:    bitDepth,
+	}
+	return c.String(), nil
+}
+
+// vpCodecs builds the short form VP codec string (i.e. vp09.00.10.08) from a vpcC box.
+func vpCodecs(sampleEntry string, vpcC []byte) (string, error) {
+	// vpcC is a FullBox, the version and flags precede the configuration
+	if len(vpcC) < 7 {
+		return "", fmt.Errorf("vpcC truncated")
+	}
+	c := &codecs.Codec{
+		SampleEntry: sampleEntry,
+		Family:      codecs.FAMILY_VP9,
+		Profile:     int(vpcC[4]),
+		Level:       int(vpcC[5]),
+		BitDepth:    int(vpcC[6] >> 4),
+	}
+	if sampleEntry == "vp08" {
+		c.Family = codecs.FAMILY_VP8
+	}
+	return c.String(), nil
+}
+
+// esdsConfig is the part of an MPEG-4 elementary stream descriptor needed to signal an audio track.
+type esdsConfig struct {
+	ObjectTypeIndication uint8
+	AudioObjectType      uint8 // Zero when there is no AudioSpecificConfig
+	ChannelConfiguration uint8
+	MaxBitrate           uint32
+	AvgBitrate           uint32
+}
+
+// Codecs returns the RFC 6381 codec string (i.e. mp4a.40.2).
+func (c *esdsConfig) Codecs() string {
+	codec := &codecs.Codec{SampleEntry: "mp4a", Family: codecs.FAMILY_MPEG4_AUDIO, ObjectTypeIndication: c.ObjectTypeIndication}
+	if c.ObjectTypeIndication == 0x40 {
+		codec.Profile = int(c.AudioObjectType)
+	}
+	return codec.String()
+}
+
+// Descriptor tags from ISO/IEC 14496-1
+const (
+	esDescrTag            = 0x03
+	decoderConfigDescrTag = 0x04
+	decSpecificInfoTag    = 0x05
+)
+
+// parseEsds decodes the ES_Descriptor of an esds box.
+func parseEsds(esds []byte) (*esdsConfig, error) {
+	if len(esds) < 4 {
+		return nil, fmt.Errorf("esds truncated")
+	}
+	tag, es, err := readDescriptor(newReader(esds[4:]))
+	if err != nil {
+		return nil, err
+	}
+	if tag != esDescrTag {
+		return nil, fmt.Errorf("esds has no ES_Descriptor, tag was 0x%02x", tag)
+	}
+
+	r := newReader(es)
+	r.skip(2) // ES_ID
+	flags := r.uint8()
+	if flags&0x80 != 0 {
+		r.skip(2) // dependsOn_ES_ID
+	}
+	if flags&0x40 != 0 {
+		r.skip(int(r.uint8())) // URLstring
+	}
+	if flags&0x20 != 0 {
+		r.skip(2) // OCR_ES_Id
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	c := &esdsConfig{}
+	for r.err == nil && len(r.remaining()) > 0 {
+		tag, payload, err := readDescriptor(r)
+		if err != nil {
+			return nil, err
+		}
+		if tag != decoderConfigDescrTag {
+			continue
+		}
+		dcd := newReader(payload)
+		c.ObjectTypeIndication = dcd.uint8()
+		dcd.skip(4) // streamType, upStream, reserved, bufferSizeDB
+		c.MaxBitrate = dcd.uint32()
+		c.AvgBitrate = dcd.uint32()
+		if dcd.err != nil {
+			return nil, fmt.Errorf("DecoderConfigDescriptor truncated")
+		}
+		for len(dcd.remaining()) > 0 {
+			tag, asc, err := readDescriptor(dcd)
+			if err != nil {
+				return nil, err
+			}
+			if tag == decSpecificInfoTag && c.ObjectTypeIndication == 0x40 {
+				c.AudioObjectType, c.ChannelConfiguration = parseAudioSpecificConfig(asc)
+			}
+		}
+		return c, nil
+	}
+	return nil, fmt.Errorf("esds has no DecoderConfigDescriptor")
+}
+
+// readDescriptor reads an ISO/IEC 14496-1 descriptor tag and its variable length size.
+func readDescriptor(r *reader) (uint8, []byte, error) {
+	tag := r.uint8()
+	size := 0
+	for i := 0; i < 4; i++ {
+		b := r.uint8()
+		size = size<<7 | int(b&0x7f)
+		if b&0x80 == 0 {
+			break
+		}
+	}
+	payload := r.bytes(size)
+	if r.err != nil {
+		return 0, nil, fmt.Errorf("Descriptor 0x%02x truncated", tag)
+	}
+	return tag, payload, nil
+}
+
+// parseAudioSpecificConfig returns the audio object type and channel configuration.
+func parseAudioSpecificConfig(asc []byte) (uint8, uint8) {
+	br := &bitReader{b: asc}
+	aot := br.read(5)
+	if aot == 31 {
+		aot = 32 + br.read(6)
+	}
+	if br.read(4) == 0xf { // samplingFrequencyIndex
+		br.read(24)
+	}
+	channels := br.read(4)
+	if br.err {
+		return 0, 0
+	}
+	return uint8(aot), uint8(channels)
+}
+
+type bitReader struct {
+	b   []byte
+	pos int
+	err bool
+}
+
+func (br *bitReader) read(n int) uint32 {
+	var v uint32
+	for i := 0; i < n; i++ {
+		if br.pos/8 >= len(br.b) {
+			br.err = true
+			return 0
+		}
+		bit := (br.b[br.pos/8] >> (7 - uint(br.pos%8))) & 1
+		v = v<<1 | uint32(bit)
+		br.pos++
+	}
+	return v
+}
+
+// Dolby channel location bits for the tag:dolby.com,2014:dash:audio_channel_configuration:2011 scheme
+const (
+	dolbyChannelL   = 0x8000
+	dolbyChannelC   = 0x4000
+	dolbyChannelR   = 0x2000
+	dolbyChannelLs  = 0x1000
+	dolbyChannelRs  = 0x0800
+	dolbyChannelCs  = 0x0100
+	dolbyChannelLFE = 0x0001
+)
+
+// AC-3 audio coding modes, indexed by acmod
+var ac3Modes = []struct {
+	channels int
+	mask     uint16
+}{
+	{2, dolbyChannelL | dolbyChannelR}, // 1+1 dual mono
+	{1, dolbyChannelC},
+	{2, dolbyChannelL | dolbyChannelR},
+	{3, dolbyChannelL | dolbyChannelC | dolbyChannelR},
+	{3, dolbyChannelL | dolbyChannelR | dolbyChannelCs},
+	{4, dolbyChannelL | dolbyChannelC | dolbyChannelR | dolbyChannelCs},
+	{4, dolbyChannelL | dolbyChannelR | dolbyChannelLs | dolbyChannelRs},
+	{5, dolbyChannelL | dolbyChannelC | dolbyChannelR | dolbyChannelLs | dolbyChannelRs},
+}
+
+// dolbyChannels returns the channel count and Dolby channel mask for an acmod and lfeon pair.
+func dolbyChannels(acmod, lfeon uint32) (int, uint16) {
+	mode := ac3Modes[acmod&0x7]
+	if lfeon != 0 {
+		return mode.channels + 1, mode.mask | dolbyChannelLFE
+	}
+	return mode.channels, mode.mask
+}
+
+// parseDac3 returns the channel count and Dolby channel mask from a dac3 box.
+func parseDac3(dac3 []byte) (int, uint16, error) {
+	br := &bitReader{b: dac3}
+	br.read(2) // fscod
+	br.read(5) // bsid
+	br.read(3) // bsmod
+	acmod := br.read(3)
+	lfeon := br.read(1)
+	if br.err {
+		return 0, 0, fmt.Errorf("dac3 truncated")
+	}
+	channels, mask := dolbyChannels(acmod, lfeon)
+	return channels, mask, nil
+}
+
+// parseDec3 returns the channel count and Dolby channel mask of the first independent substream
+// from a dec3 box. Channels carried in dependent substreams are not included.
+func parseDec3(dec3 []byte) (int, uint16, error) {
+	br := &bitReader{b: dec3}
+	br.read(13) // data_rate
+	br.read(3)  // num_ind_sub
+	br.read(2)  // fscod
+	br.read(5)  // bsid
+	br.read(1)  // reserved
+	br.read(1)  // asvc
+	br.read(3)  // bsmod
+	acmod := br.read(3)
+	lfeon := br.read(1)
+	if br.err {
+		return 0, 0, fmt.Errorf("dec3 truncated")
+	}
+	channels, mask := dolbyChannels(acmod, lfeon)
+	return channels, mask, nil
+}
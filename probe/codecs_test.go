@@ -0,0 +1,138 @@
+package probe
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/zencoder/go-dash/v3/helpers/require"
+)
+
+// Config box payloads shared by the tests
+var (
+	testAvcC = []byte{0x01, 0x4d, 0x40, 0x1f, 0xff, 0xe1, 0x00, 0x00}
+	testHvcC = concat(
+		u8(0x01),        // configurationVersion
+		u8(0x01),        // profile_space 0, tier Main, profile_idc 1
+		u32(0x60000000), // compatibility flags 1 and 2
+		[]byte{0xb0, 0x00, 0x00, 0x00, 0x00, 0x00},
+		u8(93),
+	)
+	testAv1C = []byte{0x81, 0x04, 0x0c, 0x00}
+	testVpcC = concat(u32(0x01000000), []byte{0x00, 0x0a, 0x82, 0x01, 0x01, 0x01}, u16(0))
+	testEsds = concat(u32(0), esDescriptor(0x40, []byte{0x11, 0x90}, 128000, 96000))
+	testDac3 = []byte{0x10, 0x3d, 0xe0}
+	testDec3 = []byte{0x05, 0x00, 0x20, 0x0f, 0x00}
+)
+
+func TestCodecs(t *testing.T) {
+	testCases := []struct {
+		name     string
+		codecs   func() (string, error)
+		expected string
+	}{
+		{name: "avc1", codecs: func() (string, error) { return avcCodecs("avc1", testAvcC) }, expected: "avc1.4d401f"},
+		{name: "avc3", codecs: func() (string, error) { return avcCodecs("avc3", testAvcC) }, expected: "avc3.4d401f"},
+		{name: "hvc1", codecs: func() (string, error) { return hevcCodecs("hvc1", testHvcC) }, expected: "hvc1.1.6.L93.B0"},
+		{name: "av01", codecs: func() (string, error) { return av1Codecs(testAv1C) }, expected: "av01.0.04M.08"},
+		{name: "vp09", codecs: func() (string, error) { return vpCodecs("vp09", testVpcC) }, expected: "vp09.00.10.08"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			codecs, err := tc.codecs()
+			require.NoError(t, err)
+			require.EqualString(t, tc.expected, codecs)
+		})
+	}
+}
+
+func TestHevcCodecsHighTierProfileSpace(t *testing.T) {
+	hvcC := concat(u8(0x01), u8(0x62), u32(0x20000000), []byte{0x90, 0x00, 0x00, 0x00, 0x00, 0x00}, u8(120))
+	codecs, err := hevcCodecs("hev1", hvcC)
+	require.NoError(t, err)
+	require.EqualString(t, "hev1.A2.4.H120.90", codecs)
+}
+
+func TestAv1CodecsTenBit(t *testing.T) {
+	codecs, err := av1Codecs([]byte{0x81, 0x08, 0xcc, 0x00})
+	require.NoError(t, err)
+	require.EqualString(t, "av01.0.08H.10", codecs)
+}
+
+func TestCodecsTruncated(t *testing.T) {
+	_, err := avcCodecs("avc1", []byte{0x01})
+	require.EqualError(t, err, "avcC truncated")
+	_, err = hevcCodecs("hvc1", []byte{0x01})
+	require.EqualError(t, err, "hvcC truncated")
+	_, err = av1Codecs([]byte{0x81})
+	require.EqualError(t, err, "av1C truncated")
+	_, err = vpCodecs("vp09", []byte{0x01})
+	require.EqualError(t, err, "vpcC truncated")
+}
+
+func TestParseEsds(t *testing.T) {
+	c, err := parseEsds(testEsds)
+	require.NoError(t, err)
+	require.EqualString(t, "mp4a.40.2", c.Codecs())
+	require.EqualInt(t, 2, int(c.ChannelConfiguration))
+	require.EqualInt(t, 128000, int(c.MaxBitrate))
+	require.EqualInt(t, 96000, int(c.AvgBitrate))
+}
+
+func TestParseEsdsHEAACv2(t *testing.T) {
+	// Explicitly signalled AOT 29 (PS), 48kHz, stereo
+	c, err := parseEsds(concat(u32(0), esDescriptor(0x40, []byte{0xe9, 0x91, 0x00}, 0, 0)))
+	require.NoError(t, err)
+	require.EqualString(t, "mp4a.40.29", c.Codecs())
+}
+
+func TestParseEsdsMP3(t *testing.T) {
+	c, err := parseEsds(concat(u32(0), esDescriptor(0x6b, nil, 0, 0)))
+	require.NoError(t, err)
+	require.EqualString(t, "mp4a.6B", c.Codecs())
+}
+
+func TestParseEsdsErrors(t *testing.T) {
+	_, err := parseEsds([]byte{0})
+	require.EqualError(t, err, "esds truncated")
+
+	_, err = parseEsds(concat(u32(0), []byte{0x04, 0x00}))
+	require.EqualError(t, err, "esds has no ES_Descriptor, tag was 0x04")
+
+	_, err = parseEsds(concat(u32(0), []byte{0x03, 0x10, 0x00}))
+	require.EqualError(t, err, "Descriptor 0x03 truncated")
+}
+
+func TestParseDolby(t *testing.T) {
+	channels, mask, err := parseDac3(testDac3)
+	require.NoError(t, err)
+	require.EqualInt(t, 6, channels)
+	require.EqualString(t, "F801", fmt.Sprintf("%04X", mask))
+
+	channels, mask, err = parseDec3(testDec3)
+	require.NoError(t, err)
+	require.EqualInt(t, 6, channels)
+	require.EqualString(t, "F801", fmt.Sprintf("%04X", mask))
+
+	channels, mask = dolbyChannels(2, 0)
+	require.EqualInt(t, 2, channels)
+	require.EqualString(t, "A000", fmt.Sprintf("%04X", mask))
+
+	_, _, err = parseDac3([]byte{0x10})
+	require.EqualError(t, err, "dac3 truncated")
+}
+
+// esDescriptor builds an ES_Descriptor containing a DecoderConfigDescriptor and, when asc is
+// set, a DecoderSpecificInfo.
+func esDescriptor(oti uint8, asc []byte, maxBitrate, avgBitrate uint32) []byte {
+	dcd := concat(u8(oti), u8(0x15), []byte{0, 0, 0}, u32(maxBitrate), u32(avgBitrate))
+	if asc != nil {
+		dcd = concat(dcd, descriptor(0x05, asc))
+	}
+	return descriptor(0x03, concat(u16(1), u8(0), descriptor(0x04, dcd)))
+}
+
+// descriptor uses the 4 byte size form some muxers write, to exercise the variable length size.
+func descriptor(tag uint8, payload []byte) []byte {
+	size := len(payload)
+	return concat(u8(tag), []byte{0x80, 0x80, 0x80, byte(size)}, payload)
+}
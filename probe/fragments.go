@@ -0,0 +1,417 @@
+package probe
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	. "github.com/zencoder/go-dash/v3/helpers/ptrs"
+	"github.com/zencoder/go-dash/v3/mpd"
+)
+
+// Known error variables
+var (
+	ErrNoFragments         = errors.New("No fragments matched the pattern")
+	ErrNoTrackFragment     = errors.New("Fragment has no traf for the track")
+	ErrNoTfdt              = errors.New("Fragment has no tfdt box")
+	ErrNoSampleDuration    = errors.New("Fragment has samples with no duration in trun, tfhd or trex")
+	ErrMediaTemplateNoTime = errors.New("Media template should contain $Time$ or $Number$")
+	ErrDuplicateDecodeTime = errors.New("Fragments have the same decode time")
+	ErrZeroDuration        = errors.New("Fragment has a zero duration")
+)
+
+// Fragment is a single CMAF fragment (one or more moof + mdat pairs) of a track.
+type Fragment struct {
+	Name           string // Path of the file within the fs.FS
+	Number         int64  // Last run of digits in the file name, -1 if there is none
+	SequenceNumber uint32 // mfhd sequence number of the first moof
+	DecodeTime     uint64 // tfdt baseMediaDecodeTime of the first moof
+	Duration       uint64 // Sum of the sample durations of every moof
+}
+
+// Discontinuity is a gap or overlap between two consecutive fragments.
+type Discontinuity struct {
+	Previous, Next string // File names
+	Expected       uint64 // Decode time at the end of Previous
+	Actual         uint64 // Decode time at the start of Next
+}
+
+// Gap reports whether the discontinuity is a gap rather than an overlap.
+func (d Discontinuity) Gap() bool {
+	return d.Actual > d.Expected
+}
+
+func (d Discontinuity) String() string {
+	if d.Gap() {
+		return fmt.Sprintf("gap of %d between %q and %q", d.Actual-d.Expected, d.Previous, d.Next)
+	}
+	return fmt.Sprintf("overlap of %d between %q and %q", d.Expected-d.Actual, d.Previous, d.Next)
+}
+
+// FragmentTimeline is the timing of a directory of fragments of a single track, in decode order.
+type FragmentTimeline struct {
+	Track           *Track
+	Fragments       []*Fragment
+	Discontinuities []Discontinuity
+}
+
+// Reads an init segment and every fragment matching a pattern from a file system. Only the first
+// track of the init segment is used. Fragments are ordered by decode time, which must differ
+// between fragments, and must have a duration.
+// fsys - File system holding the recording (i.e. os.DirFS("/recordings/1")).
+// initName - Path of the init segment within fsys (i.e. video/init.mp4).
+// pattern - fs.Glob pattern matching the fragments (i.e. video/*.m4s).
+func ReadFragmentTimeline(fsys fs.FS, initName string, pattern string) (*FragmentTimeline, error) {
+	b, err := fs.ReadFile(fsys, initName)
+	if err != nil {
+		return nil, err
+	}
+	seg, err := ParseInitSegment(b)
+	if err != nil {
+		return nil, err
+	}
+
+	names, err := fs.Glob(fsys, pattern)
+	if err != nil {
+		return nil, err
+	}
+	ft := &FragmentTimeline{Track: seg.Tracks[0]}
+	for _, name := range names {
+		if name == initName {
+			continue
+		}
+		b, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+		f, err := ParseFragment(b, ft.Track)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		if f.Duration == 0 {
+			return nil, fmt.Errorf("%s: %w", name, ErrZeroDuration)
+		}
+		f.Name = name
+		f.Number = fileNumber(name)
+		ft.Fragments = append(ft.Fragments, f)
+	}
+	if len(ft.Fragments) == 0 {
+		return nil, ErrNoFragments
+	}
+
+	sort.SliceStable(ft.Fragments, func(i, j int) bool {
+		return ft.Fragments[i].DecodeTime < ft.Fragments[j].DecodeTime
+	})
+	for i := 1; i < len(ft.Fragments); i++ {
+		prev, next := ft.Fragments[i-1], ft.Fragments[i]
+		if prev.DecodeTime == next.DecodeTime {
+			return nil, fmt.Errorf("%w: %q and %q", ErrDuplicateDecodeTime, prev.Name, next.Name)
+		}
+		if end := prev.DecodeTime + prev.Duration; end != next.DecodeTime {
+			ft.Discontinuities = append(ft.Discontinuities, Discontinuity{
+				Previous: prev.Name,
+				Next:     next.Name,
+				Expected: end,
+				Actual:   next.DecodeTime,
+			})
+		}
+	}
+	return ft, nil
+}
+
+// fileNumber returns the last run of digits in the base name of a file, or -1.
+func fileNumber(name string) int64 {
+	base := strings.TrimSuffix(path.Base(name), path.Ext(name))
+	end := strings.LastIndexAny(base, "0123456789")
+	if end < 0 {
+		return -1
+	}
+	start := end
+	for start > 0 && base[start-1] >= '0' && base[start-1] <= '9' {
+		start--
+	}
+	n, err := strconv.ParseInt(base[start:end+1], 10, 64)
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+// Track fragment and track run flags from ISO/IEC 14496-12
+const (
+	tfhdBaseDataOffset         = 0x000001
+	tfhdSampleDescriptionIndex = 0x000002
+	tfhdDefaultSampleDuration  = 0x000008
+	trunDataOffset             = 0x000001
+	trunFirstSampleFlags       = 0x000004
+	trunSampleDuration         = 0x000100
+	trunSampleSize             = 0x000200
+	trunSampleFlags            = 0x000400
+	trunSampleCompositionTime  = 0x000800
+)
+
+// ParseFragment reads the decode time and duration of a track from the moof boxes of a fragment.
+// Sample durations fall back to the tfhd default and then the trex default of the track.
+func ParseFragment(b []byte, track *Track) (*Fragment, error) {
+	boxes, err := parseBoxes(b, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &Fragment{}
+	first := true
+	for _, moof := range boxes {
+		if moof.Type != "moof" {
+			continue
+		}
+		children, err := moof.children(0)
+		if err != nil {
+			return nil, err
+		}
+		traf, err := findTraf(children, track.ID)
+		if err != nil {
+			return nil, err
+		}
+		trafChildren, err := traf.children(0)
+		if err != nil {
+			return nil, err
+		}
+
+		defaultDuration := track.DefaultSampleDuration
+		if tfhd := findBox(trafChildren, "tfhd"); tfhd != nil {
+			if d, ok := tfhdDefaultDuration(tfhd); ok {
+				defaultDuration = d
+			}
+		}
+
+		if first {
+			if mfhd := findBox(children, "mfhd"); mfhd != nil && len(mfhd.Payload) >= 8 {
+				r := newReader(mfhd.Payload)
+				r.skip(4)
+				f.SequenceNumber = r.uint32()
+			}
+			tfdt := findBox(trafChildren, "tfdt")
+			if tfdt == nil {
+				return nil, ErrNoTfdt
+			}
+			version, _, err := fullBoxHeader(tfdt)
+			if err != nil {
+				return nil, err
+			}
+			r := newReader(tfdt.Payload)
+			r.skip(4)
+			if version == 1 {
+				f.DecodeTime = r.uint64()
+			} else {
+				f.DecodeTime = uint64(r.uint32())
+			}
+			if r.err != nil {
+				return nil, fmt.Errorf("Box %q truncated", "tfdt")
+			}
+			first = false
+		}
+
+		for _, trun := range trafChildren {
+			if trun.Type != "trun" {
+				continue
+			}
+			d, err := trunDuration(&trun, defaultDuration)
+			if err != nil {
+				return nil, err
+			}
+			f.Duration += d
+		}
+	}
+	if first {
+		return nil, ErrNoTrackFragment
+	}
+	return f, nil
+}
+
+// findTraf returns the traf of a moof for the given track ID.
+func findTraf(moofChildren []box, trackID uint32) (*box, error) {
+	for i := range moofChildren {
+		if moofChildren[i].Type != "traf" {
+			continue
+		}
+		children, err := moofChildren[i].children(0)
+		if err != nil {
+			return nil, err
+		}
+		tfhd := findBox(children, "tfhd")
+		if tfhd == nil || len(tfhd.Payload) < 8 {
+			continue
+		}
+		if newReader(tfhd.Payload[4:]).uint32() == trackID {
+			return &moofChildren[i], nil
+		}
+	}
+	return nil, ErrNoTrackFragment
+}
+
+// tfhdDefaultDuration returns the default_sample_duration of a tfhd, if present.
+func tfhdDefaultDuration(tfhd *box) (uint32, bool) {
+	_, flags, err := fullBoxHeader(tfhd)
+	if err != nil || flags&tfhdDefaultSampleDuration == 0 {
+		return 0, false
+	}
+	r := newReader(tfhd.Payload)
+	r.skip(8) // version, flags, track_ID
+	if flags&tfhdBaseDataOffset != 0 {
+		r.skip(8)
+	}
+	if flags&tfhdSampleDescriptionIndex != 0 {
+		r.skip(4)
+	}
+	d := r.uint32()
+	return d, r.err == nil
+}
+
+// trunDuration sums the sample durations of a trun.
+func trunDuration(trun *box, defaultDuration uint32) (uint64, error) {
+	_, flags, err := fullBoxHeader(trun)
+	if err != nil {
+		return 0, err
+	}
+	r := newReader(trun.Payload)
+	r.skip(4)
+	count := r.uint32()
+	if flags&trunDataOffset != 0 {
+		r.skip(4)
+	}
+	if flags&trunFirstSampleFlags != 0 {
+		r.skip(4)
+	}
+	if flags&trunSampleDuration == 0 {
+		if r.err != nil {
+			return 0, fmt.Errorf("Box %q truncated", "trun")
+		}
+		if count > 0 && defaultDuration == 0 {
+			return 0, ErrNoSampleDuration
+		}
+		return uint64(count) * uint64(defaultDuration), nil
+	}
+
+	var total uint64
+	for i := uint32(0); i < count && r.err == nil; i++ {
+		total += uint64(r.uint32())
+		if flags&trunSampleSize != 0 {
+			r.skip(4)
+		}
+		if flags&trunSampleFlags != 0 {
+			r.skip(4)
+		}
+		if flags&trunSampleCompositionTime != 0 {
+			r.skip(4)
+		}
+	}
+	if r.err != nil {
+		return 0, fmt.Errorf("Box %q truncated", "trun")
+	}
+	return total, nil
+}
+
+// SegmentTimeline builds a compacted SegmentTimeline. Gaps start a new S element with an explicit
+// @t. Overlaps can't be expressed in a SegmentTimeline, so the earlier fragment's duration is
+// shortened to end where the next fragment starts, and a fragment with the same decode time as the
+// next one is left out.
+func (ft *FragmentTimeline) SegmentTimeline() *mpd.SegmentTimeline {
+	st := &mpd.SegmentTimeline{}
+	var last *mpd.SegmentTimelineSegment
+	var end uint64
+	for i, f := range ft.Fragments {
+		d := ft.timelineDuration(i)
+		if d == 0 {
+			continue
+		}
+		contiguous := last != nil && end == f.DecodeTime
+		end = f.DecodeTime + d
+		if contiguous && last.Duration == d {
+			if last.RepeatCount == nil {
+				last.RepeatCount = Intptr(0)
+			}
+			*last.RepeatCount++
+			continue
+		}
+		last = &mpd.SegmentTimelineSegment{Duration: d}
+		if !contiguous {
+			last.StartTime = Uint64ptr(f.DecodeTime)
+		}
+		st.Segments = append(st.Segments, last)
+	}
+	return st
+}
+
+// timelineDuration returns the duration of fragment i in the SegmentTimeline, shortened to end
+// where the next fragment starts. Fragments with a zero duration are left out of the timeline.
+func (ft *FragmentTimeline) timelineDuration(i int) uint64 {
+	f := ft.Fragments[i]
+	d := f.Duration
+	if i+1 < len(ft.Fragments) {
+		if next := ft.Fragments[i+1].DecodeTime; next < f.DecodeTime+d {
+			d = next - f.DecodeTime
+		}
+	}
+	return d
+}
+
+// NewSegmentTemplate builds a SegmentTemplate with the timeline. The addressing mode is taken from
+// the media template: with $Number$ the startNumber is the number in the first fragment's file name,
+// and the fragment file names must be numbered consecutively. presentationTimeOffset is set to the
+// first decode time so the Period starts with the first fragment.
+// init - template string for init segment (i.e. $RepresentationID$/init.mp4).
+// media - template string for media segments (i.e. $RepresentationID$/$Number$.m4s).
+func (ft *FragmentTimeline) NewSegmentTemplate(init string, media string) (*mpd.SegmentTemplate, error) {
+	st := &mpd.SegmentTemplate{
+		Initialization:  Strptr(init),
+		Media:           Strptr(media),
+		Timescale:       Int64ptr(int64(ft.Track.Timescale)),
+		SegmentTimeline: ft.SegmentTimeline(),
+	}
+	if first := ft.Fragments[0].DecodeTime; first > 0 {
+		st.PresentationTimeOffset = Uint64ptr(first)
+	}
+
+	switch {
+	case strings.Contains(media, "$Number"):
+		// Fragments left out of the timeline don't take a number
+		var start, next int64
+		numbered := false
+		for i, f := range ft.Fragments {
+			if ft.timelineDuration(i) == 0 {
+				continue
+			}
+			if !numbered {
+				start, next, numbered = f.Number, f.Number, true
+			}
+			if f.Number != next {
+				return nil, fmt.Errorf("Fragment %q has number %d, expected %d for $Number$ addressing", f.Name, f.Number, next)
+			}
+			next++
+		}
+		st.StartNumber = Int64ptr(start)
+	case strings.Contains(media, "$Time"):
+	default:
+		return nil, ErrMediaTemplateNoTime
+	}
+	return st, nil
+}
+
+// SetSegmentTemplate sets a SegmentTemplate with the timeline on an AdaptationSet.
+// See NewSegmentTemplate for the addressing modes.
+func (ft *FragmentTimeline) SetSegmentTemplate(as *mpd.AdaptationSet, init string, media string) (*mpd.SegmentTemplate, error) {
+	if as == nil {
+		return nil, ErrAdaptationSetNil
+	}
+	st, err := ft.NewSegmentTemplate(init, media)
+	if err != nil {
+		return nil, err
+	}
+	st.AdaptationSet = as
+	as.SegmentTemplate = st
+	return st, nil
+}
@@ -0,0 +1,231 @@
+package probe
+
+import (
+	"testing"
+	"testing/fstest"
+
+	. "github.com/zencoder/go-dash/v3/helpers/ptrs"
+	"github.com/zencoder/go-dash/v3/helpers/require"
+	"github.com/zencoder/go-dash/v3/helpers/testfixtures"
+	"github.com/zencoder/go-dash/v3/mpd"
+)
+
+func TestParseFragment(t *testing.T) {
+	track := &Track{ID: 1}
+
+	// Explicit per sample durations, split over two moofs
+	b := concat(
+		testFragment(1, 1, 90000, []uint32{3000, 3000, 3003}, 0),
+		testFragment(2, 1, 99003, []uint32{3000}, 0),
+	)
+	f, err := ParseFragment(b, track)
+	require.NoError(t, err)
+	require.EqualInt(t, 1, int(f.SequenceNumber))
+	require.EqualUInt64(t, 90000, f.DecodeTime)
+	require.EqualUInt64(t, 12003, f.Duration)
+}
+
+func TestParseFragmentDefaultDurations(t *testing.T) {
+	// tfhd default duration
+	f, err := ParseFragment(testFragment(1, 1, 0, nil, 1024, 1024, 1024), &Track{ID: 1})
+	require.NoError(t, err)
+	require.EqualUInt64(t, 2048, f.Duration)
+
+	// trex default duration
+	f, err = ParseFragment(testFragment(1, 1, 0, nil, 0, 1024, 1024), &Track{ID: 1, DefaultSampleDuration: 960})
+	require.NoError(t, err)
+	require.EqualUInt64(t, 1920, f.Duration)
+
+	_, err = ParseFragment(testFragment(1, 1, 0, nil, 0, 1024), &Track{ID: 1})
+	require.EqualErr(t, ErrNoSampleDuration, err)
+}
+
+func TestParseFragmentErrors(t *testing.T) {
+	_, err := ParseFragment(testFragment(1, 2, 0, []uint32{1000}, 0), &Track{ID: 1})
+	require.EqualErr(t, ErrNoTrackFragment, err)
+
+	_, err = ParseFragment(mkbox("mdat"), &Track{ID: 1})
+	require.EqualErr(t, ErrNoTrackFragment, err)
+
+	noTfdt := mkbox("moof", fullbox("mfhd", 0, 0, u32(1)), mkbox("traf", fullbox("tfhd", 0, 0x020000, u32(1))))
+	_, err = ParseFragment(noTfdt, &Track{ID: 1})
+	require.EqualErr(t, ErrNoTfdt, err)
+}
+
+func TestReadFragmentTimeline(t *testing.T) {
+	fsys := testRecording(map[string]uint64{
+		"video/seg-1.m4s": 0,
+		"video/seg-2.m4s": 180000,
+		"video/seg-3.m4s": 360000,
+		"video/seg-4.m4s": 540000,
+	}, 180000)
+
+	ft, err := ReadFragmentTimeline(fsys, "video/init.mp4", "video/*.m4s")
+	require.NoError(t, err)
+	require.EqualInt(t, 4, len(ft.Fragments))
+	require.EqualInt(t, 0, len(ft.Discontinuities))
+	require.EqualString(t, "video/seg-1.m4s", ft.Fragments[0].Name)
+	require.EqualInt(t, 1, int(ft.Fragments[0].Number))
+
+	st := ft.SegmentTimeline()
+	require.EqualInt(t, 1, len(st.Segments))
+	require.EqualUInt64Ptr(t, Uint64ptr(0), st.Segments[0].StartTime)
+	require.EqualIntPtr(t, Intptr(3), st.Segments[0].RepeatCount)
+}
+
+func TestReadFragmentTimelineDiscontinuities(t *testing.T) {
+	fsys := testRecording(map[string]uint64{
+		"video/seg-10.m4s": 900000,
+		"video/seg-11.m4s": 1080000,
+		"video/seg-12.m4s": 1350000, // 90000 gap
+		"video/seg-13.m4s": 1500000, // 30000 overlap
+		"video/seg-14.m4s": 1680000,
+	}, 180000)
+
+	ft, err := ReadFragmentTimeline(fsys, "video/init.mp4", "video/*.m4s")
+	require.NoError(t, err)
+	require.EqualInt(t, 2, len(ft.Discontinuities))
+	require.EqualString(t, `gap of 90000 between "video/seg-11.m4s" and "video/seg-12.m4s"`, ft.Discontinuities[0].String())
+	require.EqualString(t, `overlap of 30000 between "video/seg-12.m4s" and "video/seg-13.m4s"`, ft.Discontinuities[1].String())
+
+	m := mpd.NewMPD(mpd.DASH_PROFILE_LIVE, "PT9S", "PT2S")
+	as, _ := m.AddNewAdaptationSetVideo(mpd.DASH_MIME_TYPE_VIDEO_MP4, "progressive", true, 1)
+	_, err = ft.SetSegmentTemplate(as, "$RepresentationID$/init.mp4", "$RepresentationID$/seg-$Number$.m4s")
+	require.NoError(t, err)
+	_, _ = ft.Track.AddRepresentation(as, "video", 1518664)
+
+	xml, err := m.WriteToString()
+	require.NoError(t, err)
+	testfixtures.CompareFixture(t, "fixtures/fragment_timeline.mpd", xml)
+}
+
+func TestFragmentTimelineSegmentTemplate(t *testing.T) {
+	fsys := testRecording(map[string]uint64{
+		"video/0.m4s":      0,
+		"video/180000.m4s": 180000,
+		"video/360000.m4s": 360000,
+	}, 180000)
+	ft, err := ReadFragmentTimeline(fsys, "video/init.mp4", "video/*.m4s")
+	require.NoError(t, err)
+
+	st, err := ft.NewSegmentTemplate("init.mp4", "$Time$.m4s")
+	require.NoError(t, err)
+	require.Nil(t, st.StartNumber)
+	require.Nil(t, st.Duration)
+	require.Nil(t, st.PresentationTimeOffset)
+	require.EqualInt(t, 90000, int(*st.Timescale))
+
+	_, err = ft.NewSegmentTemplate("init.mp4", "$Number$.m4s")
+	require.EqualError(t, err, `Fragment "video/180000.m4s" has number 180000, expected 1 for $Number$ addressing`)
+
+	_, err = ft.NewSegmentTemplate("init.mp4", "segment.m4s")
+	require.EqualErr(t, ErrMediaTemplateNoTime, err)
+
+	_, err = ft.SetSegmentTemplate(nil, "init.mp4", "$Time$.m4s")
+	require.EqualErr(t, ErrAdaptationSetNil, err)
+}
+
+func TestReadFragmentTimelineErrors(t *testing.T) {
+	fsys := testRecording(nil, 0)
+	_, err := ReadFragmentTimeline(fsys, "video/init.mp4", "video/*.m4s")
+	require.EqualErr(t, ErrNoFragments, err)
+
+	_, err = ReadFragmentTimeline(fsys, "audio/init.mp4", "audio/*.m4s")
+	if err == nil {
+		t.Errorf("Expected an error for a missing init segment")
+	}
+
+	fsys["video/bad.m4s"] = &fstest.MapFile{Data: testFragment(1, 2, 0, []uint32{1000}, 0)}
+	_, err = ReadFragmentTimeline(fsys, "video/init.mp4", "video/*.m4s")
+	require.EqualError(t, err, "video/bad.m4s: Fragment has no traf for the track")
+
+	fsys = testRecording(map[string]uint64{"video/1.m4s": 0, "video/2.m4s": 180000, "video/3.m4s": 180000}, 180000)
+	_, err = ReadFragmentTimeline(fsys, "video/init.mp4", "video/*.m4s")
+	require.EqualError(t, err, `Fragments have the same decode time: "video/2.m4s" and "video/3.m4s"`)
+
+	fsys = testRecording(map[string]uint64{"video/1.m4s": 0}, 0)
+	_, err = ReadFragmentTimeline(fsys, "video/init.mp4", "video/*.m4s")
+	require.EqualError(t, err, "video/1.m4s: Fragment has a zero duration")
+}
+
+func TestSegmentTimelineDuplicateDecodeTime(t *testing.T) {
+	ft := &FragmentTimeline{Fragments: []*Fragment{
+		{DecodeTime: 0, Duration: 10},
+		{DecodeTime: 50, Duration: 10},
+		{DecodeTime: 50, Duration: 10},
+		{DecodeTime: 60, Duration: 10},
+	}}
+	st := ft.SegmentTimeline()
+	require.EqualInt(t, 2, len(st.Segments))
+	require.EqualUInt64Ptr(t, Uint64ptr(50), st.Segments[1].StartTime)
+	require.EqualUInt64(t, 10, st.Segments[1].Duration)
+	require.EqualIntPtr(t, Intptr(1), st.Segments[1].RepeatCount)
+
+	// The fragment left out of the timeline doesn't take a $Number$
+	for i, number := range []int64{1, 9, 2, 3} {
+		ft.Fragments[i].Number = number
+	}
+	ft.Track = &Track{Timescale: 1000}
+	template, err := ft.NewSegmentTemplate("init.mp4", "$Number$.m4s")
+	require.NoError(t, err)
+	require.EqualInt(t, 1, int(*template.StartNumber))
+}
+
+func TestFileNumber(t *testing.T) {
+	require.EqualInt(t, 12, int(fileNumber("video/seg-12.m4s")))
+	require.EqualInt(t, 7, int(fileNumber("dir1/chunk_0007.m4s")))
+	require.EqualInt(t, -1, int(fileNumber("video1/segment.m4s")))
+}
+
+// testRecording builds a file system with a 90kHz video init segment and one fragment of the given
+// duration per entry, with 2 second samples.
+func testRecording(fragments map[string]uint64, duration uint32) fstest.MapFS {
+	fsys := fstest.MapFS{
+		"video/init.mp4": &fstest.MapFile{Data: testInitSegment(testTrack{
+			id: 1, handler: HANDLER_TYPE_VIDEO, timescale: 90000, sampleDuration: 3000,
+			entry: visualSampleEntry("avc1", 960, 540, mkbox("avcC", testAvcC)),
+		})},
+	}
+	for name, decodeTime := range fragments {
+		fsys[name] = &fstest.MapFile{Data: testFragment(1, 1, decodeTime, []uint32{duration}, 0)}
+	}
+	return fsys
+}
+
+// testFragment builds a moof + mdat for a track. When durations is nil, sampleSizes samples are
+// written without durations and defaultDuration, if set, goes in the tfhd.
+func testFragment(sequence, trackID uint32, decodeTime uint64, durations []uint32, defaultDuration uint32, sampleSizes ...uint32) []byte {
+	tfhdFlags := uint32(0x020000) // default-base-is-moof
+	tfhd := []byte{}
+	if defaultDuration > 0 {
+		tfhdFlags |= tfhdDefaultSampleDuration
+		tfhd = u32(defaultDuration)
+	}
+
+	var trun []byte
+	if durations != nil {
+		var samples [][]byte
+		for _, d := range durations {
+			samples = append(samples, u32(d), u32(100))
+		}
+		trun = fullbox("trun", 0, trunSampleDuration|trunSampleSize, u32(uint32(len(durations))), concat(samples...))
+	} else {
+		var samples [][]byte
+		for _, s := range sampleSizes {
+			samples = append(samples, u32(s))
+		}
+		trun = fullbox("trun", 0, trunSampleSize, u32(uint32(len(sampleSizes))), concat(samples...))
+	}
+
+	return concat(
+		mkbox("moof",
+			fullbox("mfhd", 0, 0, u32(sequence)),
+			mkbox("traf",
+				fullbox("tfhd", 0, tfhdFlags, u32(trackID), tfhd),
+				fullbox("tfdt", 1, 0, u64(decodeTime)),
+				trun,
+			),
+		),
+		mkbox("mdat", make([]byte, 16)),
+	)
+}
@@ -0,0 +1,450 @@
+// Package probe reads ISO BMFF (fMP4 / CMAF) media files and describes them in the terms
+// needed to build or verify an MPD.
+package probe
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Constants for the track handler types
+const (
+	HANDLER_TYPE_VIDEO    = "vide"
+	HANDLER_TYPE_AUDIO    = "soun"
+	HANDLER_TYPE_SUBTITLE = "subt"
+	HANDLER_TYPE_TEXT     = "text"
+)
+
+// Known error variables
+var (
+	ErrNoMoov   = errors.New("Init segment has no moov box")
+	ErrNoTracks = errors.New("Init segment has no tracks")
+)
+
+// InitSegment describes an fMP4 / CMAF initialization segment (ftyp + moov).
+type InitSegment struct {
+	MajorBrand       string
+	CompatibleBrands []string
+	Tracks           []*Track
+}
+
+// Track describes a single track of an init segment.
+type Track struct {
+	ID          uint32
+	HandlerType string // One of the HANDLER_TYPE constants
+	Timescale   uint32
+	Language    string // ISO 639-2/T code from mdhd, "und" if unset
+	SampleEntry string // Sample entry type (i.e. avc1, mp4a, encv)
+	Codecs      string // RFC 6381 codec string (i.e. avc1.4d401f)
+
+	// DefaultSampleDuration is taken from trex, zero when unset
+	DefaultSampleDuration uint32
+
+	// Video
+	Width  int64
+	Height int64
+
+	// Audio
+	SampleRate       int64
+	Channels         int64
+	DolbyChannelMask uint16 // Only set for ac-3 and ec-3
+
+	// Subtitles, the stpp namespace list (i.e. http://www.w3.org/ns/ttml)
+	Namespace string
+
+	// MaxBitrate and AvgBitrate are taken from btrt or esds, zero when unknown
+	MaxBitrate int64
+	AvgBitrate int64
+
+	// Encryption is nil for clear tracks
+	Encryption *Encryption
+}
+
+// Encryption describes the sinf box of a protected sample entry.
+type Encryption struct {
+	OriginalFormat  string // Sample entry type of the clear content (i.e. avc1)
+	Scheme          string // Protection scheme (i.e. cenc, cbcs)
+	IsProtected     bool
+	PerSampleIVSize uint8
+	DefaultKID      []byte
+	ConstantIV      []byte
+}
+
+// DefaultKIDHex returns the tenc default KID as a 32 character hex string, as accepted by
+// AddNewContentProtectionRoot.
+func (e *Encryption) DefaultKIDHex() string {
+	return hex.EncodeToString(e.DefaultKID)
+}
+
+// Reads an init segment from disk.
+// path - File path to an init segment on disk
+func ReadInitSegmentFromFile(path string) (*InitSegment, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ReadInitSegment(f)
+}
+
+// Reads an init segment from an io.Reader interface.
+// r - Must implement the io.Reader interface.
+func ReadInitSegment(r io.Reader) (*InitSegment, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return ParseInitSegment(b)
+}
+
+// ParseInitSegment parses the ftyp and moov boxes of an init segment. Any other top level boxes
+// are ignored.
+func ParseInitSegment(b []byte) (*InitSegment, error) {
+	boxes, err := parseBoxes(b, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	seg := &InitSegment{}
+	if ftyp := findBox(boxes, "ftyp"); ftyp != nil {
+		if len(ftyp.Payload) < 8 {
+			return nil, fmt.Errorf("Box %q truncated", "ftyp")
+		}
+		seg.MajorBrand = string(ftyp.Payload[0:4])
+		for i := 8; i+4 <= len(ftyp.Payload); i += 4 {
+			seg.CompatibleBrands = append(seg.CompatibleBrands, string(ftyp.Payload[i:i+4]))
+		}
+	}
+
+	moov := findBox(boxes, "moov")
+	if moov == nil {
+		return nil, ErrNoMoov
+	}
+	moovChildren, err := moov.children(0)
+	if err != nil {
+		return nil, err
+	}
+
+	trex := map[uint32]uint32{}
+	if mvex := findBox(moovChildren, "mvex"); mvex != nil {
+		mvexChildren, err := mvex.children(0)
+		if err != nil {
+			return nil, err
+		}
+		for _, bx := range mvexChildren {
+			if bx.Type != "trex" {
+				continue
+			}
+			r := newReader(bx.Payload)
+			r.skip(4)
+			trackID := r.uint32()
+			r.skip(4) // default_sample_description_index
+			trex[trackID] = r.uint32()
+			if r.err != nil {
+				return nil, fmt.Errorf("Box %q truncated", "trex")
+			}
+		}
+	}
+
+	for _, bx := range moovChildren {
+		if bx.Type != "trak" {
+			continue
+		}
+		t, err := parseTrak(bx)
+		if err != nil {
+			return nil, err
+		}
+		t.DefaultSampleDuration = trex[t.ID]
+		seg.Tracks = append(seg.Tracks, t)
+	}
+	if len(seg.Tracks) == 0 {
+		return nil, ErrNoTracks
+	}
+	return seg, nil
+}
+
+// Track returns the first track with the given handler type, or nil if there is none.
+func (s *InitSegment) Track(handlerType string) *Track {
+	for _, t := range s.Tracks {
+		if t.HandlerType == handlerType {
+			return t
+		}
+	}
+	return nil
+}
+
+func parseTrak(trak box) (*Track, error) {
+	children, err := trak.children(0)
+	if err != nil {
+		return nil, err
+	}
+	t := &Track{}
+
+	tkhd := findBox(children, "tkhd")
+	if tkhd == nil {
+		return nil, fmt.Errorf("Box %q not found", "tkhd")
+	}
+	version, _, err := fullBoxHeader(tkhd)
+	if err != nil {
+		return nil, err
+	}
+	r := newReader(tkhd.Payload)
+	r.skip(4)
+	if version == 1 {
+		r.skip(16) // creation_time, modification_time
+	} else {
+		r.skip(8)
+	}
+	t.ID = r.uint32()
+	if r.err != nil {
+		return nil, fmt.Errorf("Box %q truncated", "tkhd")
+	}
+
+	mdhd, err := findPath(children, "mdia", "mdhd")
+	if err != nil {
+		return nil, err
+	}
+	version, _, err = fullBoxHeader(mdhd)
+	if err != nil {
+		return nil, err
+	}
+	r = newReader(mdhd.Payload)
+	r.skip(4)
+	if version == 1 {
+		r.skip(16)
+		t.Timescale = r.uint32()
+		r.skip(8)
+	} else {
+		r.skip(8)
+		t.Timescale = r.uint32()
+		r.skip(4)
+	}
+	t.Language = decodeLanguage(r.uint16())
+	if r.err != nil {
+		return nil, fmt.Errorf("Box %q truncated", "mdhd")
+	}
+
+	hdlr, err := findPath(children, "mdia", "hdlr")
+	if err != nil {
+		return nil, err
+	}
+	if len(hdlr.Payload) < 12 {
+		return nil, fmt.Errorf("Box %q truncated", "hdlr")
+	}
+	t.HandlerType = string(hdlr.Payload[8:12])
+
+	stsd, err := findPath(children, "mdia", "minf", "stbl", "stsd")
+	if err != nil {
+		return nil, err
+	}
+	entries, err := stsd.children(8) // version, flags, entry_count
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("Track %d has no sample entries", t.ID)
+	}
+	if err := t.parseSampleEntry(entries[0]); err != nil {
+		return nil, fmt.Errorf("Track %d: %w", t.ID, err)
+	}
+	return t, nil
+}
+
+// decodeLanguage unpacks the three 5 bit characters of an mdhd language code.
+func decodeLanguage(code uint16) string {
+	if code == 0 || code == 0x7fff {
+		return "und"
+	}
+	return string([]byte{
+		byte(code>>10&0x1f) + 0x60,
+		byte(code>>5&0x1f) + 0x60,
+		byte(code&0x1f) + 0x60,
+	})
+}
+
+// Sizes of the fixed sample entry fields that precede the child boxes
+const (
+	sampleEntryHeaderSize       = 8
+	visualSampleEntryHeaderSize = sampleEntryHeaderSize + 70
+	audioSampleEntryHeaderSize  = sampleEntryHeaderSize + 20
+)
+
+func (t *Track) parseSampleEntry(entry box) error {
+	t.SampleEntry = entry.Type
+	format := entry.Type
+
+	var (
+		children []box
+		err      error
+	)
+	switch t.HandlerType {
+	case HANDLER_TYPE_VIDEO:
+		r := newReader(entry.Payload)
+		r.skip(sampleEntryHeaderSize + 16)
+		t.Width = int64(r.uint16())
+		t.Height = int64(r.uint16())
+		if r.err != nil {
+			return fmt.Errorf("Visual sample entry %q truncated", entry.Type)
+		}
+		children, err = entry.children(visualSampleEntryHeaderSize)
+	case HANDLER_TYPE_AUDIO:
+		r := newReader(entry.Payload)
+		r.skip(sampleEntryHeaderSize + 8)
+		t.Channels = int64(r.uint16())
+		r.skip(6) // samplesize, pre_defined, reserved
+		t.SampleRate = int64(r.uint32() >> 16)
+		if r.err != nil {
+			return fmt.Errorf("Audio sample entry %q truncated", entry.Type)
+		}
+		children, err = entry.children(audioSampleEntryHeaderSize)
+	default:
+		return t.parseTextSampleEntry(entry)
+	}
+	if err != nil {
+		return err
+	}
+
+	if sinf := findBox(children, "sinf"); sinf != nil {
+		t.Encryption, err = parseSinf(*sinf)
+		if err != nil {
+			return err
+		}
+		format = t.Encryption.OriginalFormat
+	}
+
+	if btrt := findBox(children, "btrt"); btrt != nil {
+		r := newReader(btrt.Payload)
+		r.skip(4) // bufferSizeDB
+		t.MaxBitrate = int64(r.uint32())
+		t.AvgBitrate = int64(r.uint32())
+	}
+
+	switch format {
+	case "avc1", "avc3":
+		if avcC := findBox(children, "avcC"); avcC != nil {
+			t.Codecs, err = avcCodecs(format, avcC.Payload)
+		}
+	case "hvc1", "hev1":
+		if hvcC := findBox(children, "hvcC"); hvcC != nil {
+			t.Codecs, err = hevcCodecs(format, hvcC.Payload)
+		}
+	case "av01":
+		if av1C := findBox(children, "av1C"); av1C != nil {
+			t.Codecs, err = av1Codecs(av1C.Payload)
+		}
+	case "vp08", "vp09":
+		if vpcC := findBox(children, "vpcC"); vpcC != nil {
+			t.Codecs, err = vpCodecs(format, vpcC.Payload)
+		}
+	case "mp4a":
+		if esds := findBox(children, "esds"); esds != nil {
+			var c *esdsConfig
+			c, err = parseEsds(esds.Payload)
+			if c != nil {
+				t.Codecs = c.Codecs()
+				if c.ChannelConfiguration > 0 {
+					t.Channels = int64(c.ChannelConfiguration)
+				}
+				if t.MaxBitrate == 0 {
+					t.MaxBitrate = int64(c.MaxBitrate)
+					t.AvgBitrate = int64(c.AvgBitrate)
+				}
+			}
+		}
+	case "ac-3":
+		t.Codecs = "ac-3"
+		if dac3 := findBox(children, "dac3"); dac3 != nil {
+			var channels int
+			channels, t.DolbyChannelMask, err = parseDac3(dac3.Payload)
+			t.Channels = int64(channels)
+		}
+	case "ec-3":
+		t.Codecs = "ec-3"
+		if dec3 := findBox(children, "dec3"); dec3 != nil {
+			var channels int
+			channels, t.DolbyChannelMask, err = parseDec3(dec3.Payload)
+			t.Channels = int64(channels)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if t.Codecs == "" {
+		t.Codecs = format
+	}
+	return nil
+}
+
+// parseTextSampleEntry handles the stpp (TTML) and wvtt (WebVTT) sample entries.
+func (t *Track) parseTextSampleEntry(entry box) error {
+	switch entry.Type {
+	case "stpp":
+		r := newReader(entry.Payload)
+		r.skip(sampleEntryHeaderSize)
+		t.Namespace = r.cstring()
+		if r.err != nil {
+			return fmt.Errorf("XML subtitle sample entry truncated")
+		}
+		t.Codecs = "stpp"
+	default:
+		t.Codecs = entry.Type
+	}
+	return nil
+}
+
+// parseSinf decodes the frma, schm and tenc boxes of a protection scheme information box.
+func parseSinf(sinf box) (*Encryption, error) {
+	children, err := sinf.children(0)
+	if err != nil {
+		return nil, err
+	}
+	e := &Encryption{}
+	if frma := findBox(children, "frma"); frma != nil && len(frma.Payload) >= 4 {
+		e.OriginalFormat = string(frma.Payload[0:4])
+	}
+	if schm := findBox(children, "schm"); schm != nil && len(schm.Payload) >= 8 {
+		e.Scheme = string(schm.Payload[4:8])
+	}
+	tenc, err := findPath(children, "schi", "tenc")
+	if err != nil {
+		return e, nil
+	}
+	r := newReader(tenc.Payload)
+	r.skip(6) // version, flags, reserved, crypt/skip byte block
+	e.IsProtected = r.uint8() == 1
+	e.PerSampleIVSize = r.uint8()
+	e.DefaultKID = r.bytes(16)
+	if e.IsProtected && e.PerSampleIVSize == 0 {
+		e.ConstantIV = r.bytes(int(r.uint8()))
+	}
+	if r.err != nil {
+		return nil, fmt.Errorf("Box %q truncated", "tenc")
+	}
+	return e, nil
+}
+
+// FrameRate returns the frame rate derived from the trex default sample duration as a DASH
+// @frameRate value (i.e. 25 or 30000/1001), or an empty string if it is unknown.
+func (t *Track) FrameRate() string {
+	if t.HandlerType != HANDLER_TYPE_VIDEO || t.DefaultSampleDuration == 0 || t.Timescale == 0 {
+		return ""
+	}
+	num, den := uint64(t.Timescale), uint64(t.DefaultSampleDuration)
+	g := gcd(num, den)
+	num, den = num/g, den/g
+	if den == 1 {
+		return fmt.Sprintf("%d", num)
+	}
+	return fmt.Sprintf("%d/%d", num, den)
+}
+
+func gcd(a, b uint64) uint64 {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
@@ -0,0 +1,228 @@
+package probe
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/zencoder/go-dash/v3/helpers/require"
+)
+
+const (
+	testLanguageEng = 0x15c7
+	testKIDHex      = "5abdd52f554a4f2ab8d061f761425155"
+)
+
+var testKID = []byte{0x5a, 0xbd, 0xd5, 0x2f, 0x55, 0x4a, 0x4f, 0x2a, 0xb8, 0xd0, 0x61, 0xf7, 0x61, 0x42, 0x51, 0x55}
+
+func TestParseInitSegmentVideo(t *testing.T) {
+	b := testInitSegment(testTrack{
+		id: 1, handler: HANDLER_TYPE_VIDEO, timescale: 30000, sampleDuration: 1001,
+		entry: visualSampleEntry("avc1", 960, 540, mkbox("avcC", testAvcC), mkbox("btrt", u32(0), u32(2000000), u32(1500000))),
+	})
+	s, err := ParseInitSegment(b)
+	require.NoError(t, err)
+
+	require.EqualString(t, "cmfc", s.MajorBrand)
+	require.EqualStringSlice(t, []string{"iso6", "cmfc"}, s.CompatibleBrands)
+	require.EqualInt(t, 1, len(s.Tracks))
+
+	track := s.Track(HANDLER_TYPE_VIDEO)
+	require.NotNil(t, track)
+	require.EqualInt(t, 1, int(track.ID))
+	require.EqualInt(t, 30000, int(track.Timescale))
+	require.EqualString(t, "und", track.Language)
+	require.EqualString(t, "avc1", track.SampleEntry)
+	require.EqualString(t, "avc1.4d401f", track.Codecs)
+	require.EqualInt(t, 960, int(track.Width))
+	require.EqualInt(t, 540, int(track.Height))
+	require.EqualInt(t, 2000000, int(track.MaxBitrate))
+	require.EqualInt(t, 1500000, int(track.AvgBitrate))
+	require.EqualString(t, "30000/1001", track.FrameRate())
+	require.Nil(t, track.Encryption)
+	require.Nil(t, s.Track(HANDLER_TYPE_AUDIO))
+}
+
+func TestParseInitSegmentVideoCodecs(t *testing.T) {
+	testCases := []struct {
+		entry    []byte
+		expected string
+	}{
+		{entry: visualSampleEntry("hvc1", 3840, 2160, mkbox("hvcC", testHvcC)), expected: "hvc1.1.6.L93.B0"},
+		{entry: visualSampleEntry("av01", 1920, 1080, mkbox("av1C", testAv1C)), expected: "av01.0.04M.08"},
+		{entry: visualSampleEntry("vp09", 1280, 720, fullbox("vpcC", 1, 0, testVpcC[4:])), expected: "vp09.00.10.08"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.expected, func(t *testing.T) {
+			s, err := ParseInitSegment(testInitSegment(testTrack{id: 1, handler: HANDLER_TYPE_VIDEO, timescale: 90000, entry: tc.entry}))
+			require.NoError(t, err)
+			require.EqualString(t, tc.expected, s.Tracks[0].Codecs)
+			require.EqualString(t, "", s.Tracks[0].FrameRate())
+		})
+	}
+}
+
+func TestParseInitSegmentAudio(t *testing.T) {
+	testCases := []struct {
+		entry            []byte
+		codecs           string
+		channels         int
+		dolbyChannelMask int
+		maxBitrate       int
+	}{
+		{entry: audioSampleEntry("mp4a", 2, 48000, mkbox("esds", testEsds)), codecs: "mp4a.40.2", channels: 2, maxBitrate: 128000},
+		{entry: audioSampleEntry("ac-3", 2, 48000, mkbox("dac3", testDac3)), codecs: "ac-3", channels: 6, dolbyChannelMask: 0xf801},
+		{entry: audioSampleEntry("ec-3", 2, 48000, mkbox("dec3", testDec3)), codecs: "ec-3", channels: 6, dolbyChannelMask: 0xf801},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.codecs, func(t *testing.T) {
+			s, err := ParseInitSegment(testInitSegment(testTrack{
+				id: 2, handler: HANDLER_TYPE_AUDIO, timescale: 48000, language: testLanguageEng, entry: tc.entry,
+			}))
+			require.NoError(t, err)
+			track := s.Track(HANDLER_TYPE_AUDIO)
+			require.EqualString(t, tc.codecs, track.Codecs)
+			require.EqualString(t, "eng", track.Language)
+			require.EqualInt(t, 48000, int(track.SampleRate))
+			require.EqualInt(t, tc.channels, int(track.Channels))
+			require.EqualInt(t, tc.dolbyChannelMask, int(track.DolbyChannelMask))
+			require.EqualInt(t, tc.maxBitrate, int(track.MaxBitrate))
+		})
+	}
+}
+
+func TestParseInitSegmentSubtitles(t *testing.T) {
+	stpp := mkbox("stpp", make([]byte, 8), []byte("http://www.w3.org/ns/ttml\x00\x00\x00"))
+	wvtt := mkbox("wvtt", make([]byte, 8), mkbox("vttC", []byte("WEBVTT")))
+
+	s, err := ParseInitSegment(testInitSegment(
+		testTrack{id: 3, handler: HANDLER_TYPE_SUBTITLE, timescale: 1000, entry: stpp},
+		testTrack{id: 4, handler: HANDLER_TYPE_TEXT, timescale: 1000, entry: wvtt},
+	))
+	require.NoError(t, err)
+	require.EqualInt(t, 2, len(s.Tracks))
+	require.EqualString(t, "stpp", s.Tracks[0].Codecs)
+	require.EqualString(t, "http://www.w3.org/ns/ttml", s.Tracks[0].Namespace)
+	require.EqualString(t, "wvtt", s.Tracks[1].Codecs)
+}
+
+func TestParseInitSegmentEncrypted(t *testing.T) {
+	constantIV := bytes.Repeat([]byte{0x01}, 16)
+	entry := visualSampleEntry("encv", 1920, 1080,
+		mkbox("avcC", testAvcC),
+		testSinf("avc1", "cbcs", fullbox("tenc", 1, 0, u8(0), u8(0x19), u8(1), u8(0), testKID, u8(16), constantIV)),
+	)
+	s, err := ParseInitSegment(testInitSegment(testTrack{id: 1, handler: HANDLER_TYPE_VIDEO, timescale: 90000, entry: entry}))
+	require.NoError(t, err)
+
+	track := s.Tracks[0]
+	require.EqualString(t, "encv", track.SampleEntry)
+	require.EqualString(t, "avc1.4d401f", track.Codecs)
+	require.NotNil(t, track.Encryption)
+	require.EqualString(t, "avc1", track.Encryption.OriginalFormat)
+	require.EqualString(t, "cbcs", track.Encryption.Scheme)
+	require.EqualString(t, testKIDHex, track.Encryption.DefaultKIDHex())
+	require.EqualInt(t, 0, int(track.Encryption.PerSampleIVSize))
+	require.EqualInt(t, 16, len(track.Encryption.ConstantIV))
+}
+
+func TestParseInitSegmentErrors(t *testing.T) {
+	_, err := ParseInitSegment(mkbox("ftyp", []byte("cmfc"), u32(0)))
+	require.EqualErr(t, ErrNoMoov, err)
+
+	_, err = ParseInitSegment(mkbox("moov", fullbox("mvhd", 0, 0)))
+	require.EqualErr(t, ErrNoTracks, err)
+
+	_, err = ParseInitSegment(mkbox("moov", mkbox("trak", fullbox("tkhd", 0, 0, make([]byte, 20)))))
+	require.EqualError(t, err, `Box "mdia" not found`)
+}
+
+func TestReadInitSegmentFromFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "init.mp4")
+	b := testInitSegment(testTrack{id: 1, handler: HANDLER_TYPE_AUDIO, timescale: 48000, entry: audioSampleEntry("mp4a", 2, 48000, mkbox("esds", testEsds))})
+	require.NoError(t, os.WriteFile(path, b, 0644))
+
+	s, err := ReadInitSegmentFromFile(path)
+	require.NoError(t, err)
+	require.EqualString(t, "mp4a.40.2", s.Tracks[0].Codecs)
+
+	_, err = ReadInitSegmentFromFile(filepath.Join(t.TempDir(), "missing.mp4"))
+	if err == nil {
+		t.Errorf("Expected an error reading a missing file")
+	}
+}
+
+func TestFrameRate(t *testing.T) {
+	testCases := []struct {
+		timescale, sampleDuration uint32
+		expected                  string
+	}{
+		{timescale: 90000, sampleDuration: 3600, expected: "25"},
+		{timescale: 60000, sampleDuration: 1001, expected: "60000/1001"},
+		{timescale: 48000, sampleDuration: 0, expected: ""},
+	}
+	for _, tc := range testCases {
+		track := &Track{HandlerType: HANDLER_TYPE_VIDEO, Timescale: tc.timescale, DefaultSampleDuration: tc.sampleDuration}
+		require.EqualString(t, tc.expected, track.FrameRate())
+	}
+}
+
+// Helpers for building init segments in tests
+
+type testTrack struct {
+	id             uint32
+	handler        string
+	timescale      uint32
+	language       uint16
+	sampleDuration uint32
+	entry          []byte
+}
+
+func testInitSegment(tracks ...testTrack) []byte {
+	var traks, trexs [][]byte
+	for _, t := range tracks {
+		traks = append(traks, mkbox("trak",
+			fullbox("tkhd", 0, 3, u32(0), u32(0), u32(t.id), u32(0), u32(0), make([]byte, 60)),
+			mkbox("mdia",
+				fullbox("mdhd", 0, 0, u32(0), u32(0), u32(t.timescale), u32(0), u16(t.language), u16(0)),
+				fullbox("hdlr", 0, 0, u32(0), []byte(t.handler), make([]byte, 12), []byte("\x00")),
+				mkbox("minf", mkbox("stbl", fullbox("stsd", 0, 0, u32(1), t.entry))),
+			),
+		))
+		trexs = append(trexs, fullbox("trex", 0, 0, u32(t.id), u32(1), u32(t.sampleDuration), u32(0), u32(0)))
+	}
+	return concat(
+		mkbox("ftyp", []byte("cmfc"), u32(0), []byte("iso6"), []byte("cmfc")),
+		mkbox("moov", fullbox("mvhd", 0, 0, make([]byte, 96)), concat(traks...), mkbox("mvex", trexs...)),
+	)
+}
+
+func visualSampleEntry(typ string, width, height uint16, children ...[]byte) []byte {
+	return mkbox(typ,
+		make([]byte, 6), u16(1), // reserved, data_reference_index
+		make([]byte, 16),
+		u16(width), u16(height),
+		u32(0x00480000), u32(0x00480000), u32(0), u16(1),
+		make([]byte, 32), u16(0x18), u16(0xffff),
+		concat(children...),
+	)
+}
+
+func audioSampleEntry(typ string, channels uint16, sampleRate uint32, children ...[]byte) []byte {
+	return mkbox(typ,
+		make([]byte, 6), u16(1), // reserved, data_reference_index
+		make([]byte, 8),
+		u16(channels), u16(16), u16(0), u16(0),
+		u32(sampleRate<<16),
+		concat(children...),
+	)
+}
+
+func testSinf(originalFormat, scheme string, tenc []byte) []byte {
+	return mkbox("sinf",
+		mkbox("frma", []byte(originalFormat)),
+		fullbox("schm", 0, 0, []byte(scheme), u32(0x00010000)),
+		mkbox("schi", tenc),
+	)
+}
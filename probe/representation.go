@@ -0,0 +1,186 @@
+package probe
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	. "github.com/zencoder/go-dash/v3/helpers/ptrs"
+	"github.com/zencoder/go-dash/v3/mpd"
+)
+
+// Known error variables
+var (
+	ErrAdaptationSetNil     = errors.New("AdaptationSet nil")
+	ErrRepresentationNil    = errors.New("Representation nil")
+	ErrUnsupportedTrackType = errors.New("Track handler type is not video, audio or subtitle")
+)
+
+// MimeType returns the DASH @mimeType for the track (i.e. video/mp4).
+func (t *Track) MimeType() string {
+	switch t.HandlerType {
+	case HANDLER_TYPE_VIDEO:
+		return mpd.DASH_MIME_TYPE_VIDEO_MP4
+	case HANDLER_TYPE_AUDIO:
+		return mpd.DASH_MIME_TYPE_AUDIO_MP4
+	default:
+		return "application/mp4"
+	}
+}
+
+// AddRepresentation adds a Representation describing the track to an AdaptationSet. The codec
+// string, resolution, frame rate, sampling rate, audio channel configuration and, for encrypted
+// tracks, the root ContentProtection with the tenc default KID are all taken from the init segment.
+// as - AdaptationSet to add the Representation to.
+// id - ID for this representation, will get used as $RepresentationID$ in template strings.
+// bandwidth - in Bits/s (i.e. 1518664). Pass 0 to use the btrt or esds max bitrate.
+func (t *Track) AddRepresentation(as *mpd.AdaptationSet, id string, bandwidth int64) (*mpd.Representation, error) {
+	if as == nil {
+		return nil, ErrAdaptationSetNil
+	}
+	if bandwidth == 0 {
+		bandwidth = t.MaxBitrate
+	}
+
+	var (
+		r   *mpd.Representation
+		err error
+	)
+	switch t.HandlerType {
+	case HANDLER_TYPE_VIDEO:
+		r, err = as.AddNewRepresentationVideo(bandwidth, t.Codecs, id, t.FrameRate(), t.Width, t.Height)
+		if err == nil && r.FrameRate != nil && *r.FrameRate == "" {
+			r.FrameRate = nil
+		}
+	case HANDLER_TYPE_AUDIO:
+		r, err = as.AddNewRepresentationAudio(t.SampleRate, bandwidth, t.Codecs, id)
+		if err == nil {
+			err = t.addAudioChannelConfiguration(r)
+		}
+	case HANDLER_TYPE_SUBTITLE, HANDLER_TYPE_TEXT:
+		r, err = as.AddNewRepresentationSubtitle(bandwidth, id)
+		if err == nil {
+			r.Codecs = Strptr(t.Codecs)
+		}
+	default:
+		return nil, ErrUnsupportedTrackType
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if t.Encryption != nil && len(t.Encryption.DefaultKID) > 0 {
+		cp, err := r.AddNewContentProtectionRoot(t.Encryption.DefaultKIDHex())
+		if err != nil {
+			return nil, err
+		}
+		if t.Encryption.Scheme != "" {
+			cp.Value = Strptr(t.Encryption.Scheme)
+		}
+	}
+	return r, nil
+}
+
+func (t *Track) addAudioChannelConfiguration(r *mpd.Representation) error {
+	switch {
+	case t.DolbyChannelMask != 0:
+		_, err := r.AddNewAudioChannelConfiguration(mpd.AUDIO_CHANNEL_CONFIGURATION_MPEG_DOLBY, fmt.Sprintf("%04X", t.DolbyChannelMask))
+		return err
+	case t.Channels > 0:
+		_, err := r.AddNewAudioChannelConfiguration(mpd.AUDIO_CHANNEL_CONFIGURATION_MPEG_DASH, strconv.FormatInt(t.Channels, 10))
+		return err
+	}
+	return nil
+}
+
+// Verify checks that a Representation agrees with the track. Codecs, width, height and
+// audioSamplingRate are inherited from the parent AdaptationSet when the Representation does not
+// set them. Every mismatch found is returned, joined into a single error.
+func (t *Track) Verify(r *mpd.Representation) error {
+	if r == nil {
+		return ErrRepresentationNil
+	}
+	var common *mpd.CommonAttributesAndElements
+	if r.AdaptationSet != nil {
+		common = &r.AdaptationSet.CommonAttributesAndElements
+	}
+
+	var errs []error
+	codecs := r.Codecs
+	if codecs == nil && common != nil {
+		codecs = common.Codecs
+	}
+	if codecs != nil && *codecs != t.Codecs {
+		errs = append(errs, fmt.Errorf("codecs mismatch, representation: %q, init segment: %q", *codecs, t.Codecs))
+	}
+
+	switch t.HandlerType {
+	case HANDLER_TYPE_VIDEO:
+		width, height := r.Width, r.Height
+		if width == nil && common != nil && common.Width != nil {
+			width = parseInt64ptr(*common.Width)
+		}
+		if height == nil && common != nil && common.Height != nil {
+			height = parseInt64ptr(*common.Height)
+		}
+		if width != nil && *width != t.Width {
+			errs = append(errs, fmt.Errorf("width mismatch, representation: %d, init segment: %d", *width, t.Width))
+		}
+		if height != nil && *height != t.Height {
+			errs = append(errs, fmt.Errorf("height mismatch, representation: %d, init segment: %d", *height, t.Height))
+		}
+	case HANDLER_TYPE_AUDIO:
+		rate := r.AudioSamplingRate
+		if rate == nil && common != nil && common.AudioSamplingRate != nil {
+			rate = parseInt64ptr(*common.AudioSamplingRate)
+		}
+		if rate != nil && *rate != t.SampleRate {
+			errs = append(errs, fmt.Errorf("audioSamplingRate mismatch, representation: %d, init segment: %d", *rate, t.SampleRate))
+		}
+	}
+
+	if t.Encryption != nil && len(t.Encryption.DefaultKID) > 0 {
+		if kid := defaultKID(r); kid != "" && kid != t.Encryption.DefaultKIDHex() {
+			errs = append(errs, fmt.Errorf("default_KID mismatch, representation: %s, init segment: %s", kid, t.Encryption.DefaultKIDHex()))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// defaultKID returns the cenc:default_KID signalled on the Representation or its AdaptationSet
+// as a hex string, or an empty string if there is none.
+func defaultKID(r *mpd.Representation) string {
+	cps := r.ContentProtection
+	if len(cps) == 0 && r.AdaptationSet != nil {
+		cps = r.AdaptationSet.ContentProtection
+	}
+	for _, cp := range cps {
+		if cenc, ok := cp.(*mpd.CENCContentProtection); ok && cenc.DefaultKID != nil {
+			return normalizeKID(*cenc.DefaultKID)
+		}
+	}
+	return ""
+}
+
+func normalizeKID(kid string) string {
+	b := make([]byte, 0, 32)
+	for i := 0; i < len(kid); i++ {
+		c := kid[i]
+		switch {
+		case c == '-':
+		case c >= 'A' && c <= 'F':
+			b = append(b, c+'a'-'A')
+		default:
+			b = append(b, c)
+		}
+	}
+	return string(b)
+}
+
+func parseInt64ptr(s string) *int64 {
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return nil
+	}
+	return Int64ptr(v)
+}
@@ -0,0 +1,148 @@
+package probe
+
+import (
+	"testing"
+
+	. "github.com/zencoder/go-dash/v3/helpers/ptrs"
+	"github.com/zencoder/go-dash/v3/helpers/require"
+	"github.com/zencoder/go-dash/v3/mpd"
+)
+
+func newTestMPD() *mpd.MPD {
+	return mpd.NewMPD(mpd.DASH_PROFILE_LIVE, "PT6M16S", "PT1.97S")
+}
+
+func TestAddRepresentationVideo(t *testing.T) {
+	m := newTestMPD()
+	track := &Track{
+		HandlerType: HANDLER_TYPE_VIDEO, Timescale: 30000, DefaultSampleDuration: 1001,
+		Codecs: "avc1.4d401f", Width: 960, Height: 540, MaxBitrate: 2000000,
+	}
+	as, err := m.AddNewAdaptationSetVideo(track.MimeType(), "progressive", true, 1)
+	require.NoError(t, err)
+
+	r, err := track.AddRepresentation(as, "800", 0)
+	require.NoError(t, err)
+	require.EqualStringPtr(t, Strptr("avc1.4d401f"), r.Codecs)
+	require.EqualStringPtr(t, Strptr("30000/1001"), r.FrameRate)
+	require.EqualInt(t, 960, int(*r.Width))
+	require.EqualInt(t, 540, int(*r.Height))
+	require.EqualInt(t, 2000000, int(*r.Bandwidth))
+	require.NoError(t, track.Verify(r))
+}
+
+func TestAddRepresentationVideoWithoutFrameRate(t *testing.T) {
+	m := newTestMPD()
+	track := &Track{HandlerType: HANDLER_TYPE_VIDEO, Timescale: 90000, Codecs: "hvc1.1.6.L93.B0", Width: 3840, Height: 2160}
+	as, _ := m.AddNewAdaptationSetVideo(track.MimeType(), "progressive", true, 1)
+
+	r, err := track.AddRepresentation(as, "2160p", 16000000)
+	require.NoError(t, err)
+	require.Nil(t, r.FrameRate)
+	require.EqualInt(t, 16000000, int(*r.Bandwidth))
+}
+
+func TestAddRepresentationAudio(t *testing.T) {
+	testCases := []struct {
+		track  *Track
+		scheme mpd.AudioChannelConfigurationScheme
+		value  string
+	}{
+		{
+			track:  &Track{HandlerType: HANDLER_TYPE_AUDIO, Codecs: "mp4a.40.2", SampleRate: 48000, Channels: 2},
+			scheme: mpd.AUDIO_CHANNEL_CONFIGURATION_MPEG_DASH,
+			value:  "2",
+		},
+		{
+			track:  &Track{HandlerType: HANDLER_TYPE_AUDIO, Codecs: "ec-3", SampleRate: 48000, Channels: 6, DolbyChannelMask: 0xf801},
+			scheme: mpd.AUDIO_CHANNEL_CONFIGURATION_MPEG_DOLBY,
+			value:  "F801",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.track.Codecs, func(t *testing.T) {
+			m := newTestMPD()
+			as, _ := m.AddNewAdaptationSetAudio(tc.track.MimeType(), true, 1, "en")
+
+			r, err := tc.track.AddRepresentation(as, "audio", 128000)
+			require.NoError(t, err)
+			require.EqualStringPtr(t, Strptr(tc.track.Codecs), r.Codecs)
+			require.EqualInt(t, 48000, int(*r.AudioSamplingRate))
+			require.NotNil(t, r.AudioChannelConfiguration)
+			require.EqualStringPtr(t, Strptr(string(tc.scheme)), r.AudioChannelConfiguration.SchemeIDURI)
+			require.EqualStringPtr(t, Strptr(tc.value), r.AudioChannelConfiguration.Value)
+			require.NoError(t, tc.track.Verify(r))
+		})
+	}
+}
+
+func TestAddRepresentationSubtitle(t *testing.T) {
+	m := newTestMPD()
+	track := &Track{HandlerType: HANDLER_TYPE_SUBTITLE, Codecs: "stpp"}
+	require.EqualString(t, "application/mp4", track.MimeType())
+	as, _ := m.AddNewAdaptationSetSubtitle(track.MimeType(), "en", "English")
+
+	r, err := track.AddRepresentation(as, "subs", 256)
+	require.NoError(t, err)
+	require.EqualStringPtr(t, Strptr("stpp"), r.Codecs)
+}
+
+func TestAddRepresentationEncrypted(t *testing.T) {
+	m := newTestMPD()
+	track := &Track{
+		HandlerType: HANDLER_TYPE_VIDEO, Codecs: "avc1.4d401f", Width: 960, Height: 540,
+		Encryption: &Encryption{OriginalFormat: "avc1", Scheme: "cbcs", IsProtected: true, DefaultKID: testKID},
+	}
+	as, _ := m.AddNewAdaptationSetVideo(track.MimeType(), "progressive", true, 1)
+
+	r, err := track.AddRepresentation(as, "800", 1518664)
+	require.NoError(t, err)
+	require.EqualInt(t, 1, len(r.ContentProtection))
+	cp := r.ContentProtection[0].(*mpd.CENCContentProtection)
+	require.EqualStringPtr(t, Strptr("5abdd52f-554a-4f2a-b8d0-61f761425155"), cp.DefaultKID)
+	require.EqualStringPtr(t, Strptr("cbcs"), cp.Value)
+	require.NoError(t, track.Verify(r))
+}
+
+func TestAddRepresentationErrors(t *testing.T) {
+	track := &Track{HandlerType: HANDLER_TYPE_VIDEO}
+	_, err := track.AddRepresentation(nil, "800", 0)
+	require.EqualErr(t, ErrAdaptationSetNil, err)
+
+	m := newTestMPD()
+	as, _ := m.AddNewAdaptationSetVideo(mpd.DASH_MIME_TYPE_VIDEO_MP4, "progressive", true, 1)
+	hint := &Track{HandlerType: "hint"}
+	_, err = hint.AddRepresentation(as, "800", 0)
+	require.EqualErr(t, ErrUnsupportedTrackType, err)
+}
+
+func TestVerifyMismatches(t *testing.T) {
+	m := newTestMPD()
+	as, _ := m.AddNewAdaptationSetVideo(mpd.DASH_MIME_TYPE_VIDEO_MP4, "progressive", true, 1)
+	r, _ := as.AddNewRepresentationVideo(1518664, "avc1.4d401e", "800", "30000/1001", 960, 540)
+	_, _ = as.AddNewContentProtectionRoot("08e367028f33436ca5dd60ffe5571e60")
+
+	track := &Track{
+		HandlerType: HANDLER_TYPE_VIDEO, Codecs: "avc1.4d401f", Width: 1280, Height: 540,
+		Encryption: &Encryption{DefaultKID: testKID},
+	}
+	err := track.Verify(r)
+	require.EqualError(t, err, `codecs mismatch, representation: "avc1.4d401e", init segment: "avc1.4d401f"
+width mismatch, representation: 960, init segment: 1280
+default_KID mismatch, representation: 08e367028f33436ca5dd60ffe5571e60, init segment: 5abdd52f554a4f2ab8d061f761425155`)
+
+	require.EqualErr(t, ErrRepresentationNil, track.Verify(nil))
+}
+
+func TestVerifyInheritsFromAdaptationSet(t *testing.T) {
+	m := newTestMPD()
+	as, _ := m.AddNewAdaptationSetAudio(mpd.DASH_MIME_TYPE_AUDIO_MP4, true, 1, "en")
+	as.Codecs = Strptr("mp4a.40.5")
+	as.AudioSamplingRate = Strptr("44100")
+	r, _ := as.AddNewRepresentationSubtitle(128000, "audio")
+
+	track := &Track{HandlerType: HANDLER_TYPE_AUDIO, Codecs: "mp4a.40.2", SampleRate: 48000}
+	err := track.Verify(r)
+	require.EqualError(t, err, `codecs mismatch, representation: "mp4a.40.5", init segment: "mp4a.40.2"
+audioSamplingRate mismatch, representation: 44100, init segment: 48000`)
+}
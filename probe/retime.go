@@ -0,0 +1,293 @@
+package probe
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Known error variables
+var (
+	ErrRetimeTimescaleZero = errors.New("Retime timescale must be greater than 0")
+)
+
+// RetimeFragment returns a copy of a fragment with offset added to the tfdt baseMediaDecodeTime of
+// every traf, and the mfhd sequence numbers renumbered from sequenceNumber. Version 0 tfdt boxes
+// are rewritten as version 1 so that the new decode times fit, with the moof sizes and the trun
+// data offsets updated for the moved samples. The earliest presentation time of top level sidx
+// boxes and the presentation time of version 1 emsg boxes are moved by the same offset, converted
+// to their own timescale, and the sidx referenced sizes follow the grown moofs.
+// sequenceNumber - mfhd sequence number of the first moof.
+// offset - ticks added to the decode times, in the timescale of each track (may be negative).
+// timescale - timescale of offset, used for the sidx and emsg boxes (i.e. 90000).
+func RetimeFragment(b []byte, sequenceNumber uint32, offset int64, timescale uint32) ([]byte, error) {
+	if timescale == 0 {
+		return nil, ErrRetimeTimescaleZero
+	}
+	boxes, err := parseBoxes(b, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	// A first pass finds how much each box grows, since the sidx references and the absolute tfhd
+	// base data offsets depend on the size of the boxes around them.
+	growth := make([]int64, len(boxes))
+	for i, bx := range boxes {
+		switch bx.Type {
+		case "moof":
+			moof, err := retimeMoof(bx, sequenceNumber, offset, 0, 0)
+			if err != nil {
+				return nil, err
+			}
+			growth[i] = int64(len(moof)) - bx.Size
+		case "sidx":
+			sidx, err := retimeSidx(bx, offset, timescale, func(start, end int64) int64 { return 0 })
+			if err != nil {
+				return nil, err
+			}
+			growth[i] = int64(len(sidx)) - bx.Size
+		}
+	}
+	grown := func(start, end int64) int64 {
+		var n int64
+		for i, bx := range boxes {
+			if bx.Offset >= start && bx.Offset < end {
+				n += growth[i]
+			}
+		}
+		return n
+	}
+
+	out := make([]byte, 0, len(b)+64)
+	var shift int64 // growth of the boxes written so far, moving the boxes that follow
+	for i, bx := range boxes {
+		shift += growth[i]
+		switch bx.Type {
+		case "moof":
+			// The trun data offsets are fixed size fields, so knowing the growth of the moof
+			// produces a moof of the same size as the first pass.
+			moof, err := retimeMoof(bx, sequenceNumber, offset, growth[i], shift)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, moof...)
+			sequenceNumber++
+		case "sidx":
+			sidx, err := retimeSidx(bx, offset, timescale, grown)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, sidx...)
+		case "emsg":
+			emsg, err := retimeEmsg(bx, offset, timescale)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, emsg...)
+		default:
+			out = append(out, b[bx.Offset:bx.Offset+bx.Size]...)
+		}
+	}
+	return out, nil
+}
+
+// retimeSidx rebuilds a sidx box as version 1 with the earliest presentation time moved by offset.
+// grown returns the growth of the boxes starting in a byte range of the original fragment, added
+// to the first offset and to the referenced sizes covering those boxes.
+func retimeSidx(sidx box, offset int64, timescale uint32, grown func(start, end int64) int64) ([]byte, error) {
+	version, flags, err := fullBoxHeader(&sidx)
+	if err != nil {
+		return nil, err
+	}
+	r := newReader(sidx.Payload)
+	r.skip(4)
+	referenceID := r.uint32()
+	sidxTimescale := r.uint32()
+	var ept, firstOffset uint64
+	if version == 0 {
+		ept = uint64(r.uint32())
+		firstOffset = uint64(r.uint32())
+	} else {
+		ept = r.uint64()
+		firstOffset = r.uint64()
+	}
+	r.skip(2) // reserved
+	count := int(r.uint16())
+	if r.err != nil || sidxTimescale == 0 {
+		return nil, fmt.Errorf("Box %q truncated", sidx.Type)
+	}
+
+	end := sidx.Offset + sidx.Size
+	start := end + int64(firstOffset)
+	p := binary.BigEndian.AppendUint32(nil, 1<<24|flags)
+	p = binary.BigEndian.AppendUint32(p, referenceID)
+	p = binary.BigEndian.AppendUint32(p, sidxTimescale)
+	p = binary.BigEndian.AppendUint64(p, uint64(int64(ept)+rescale(offset, timescale, sidxTimescale)))
+	p = binary.BigEndian.AppendUint64(p, uint64(int64(firstOffset)+grown(end, start)))
+	p = binary.BigEndian.AppendUint16(p, 0)
+	p = binary.BigEndian.AppendUint16(p, uint16(count))
+	for i := 0; i < count; i++ {
+		typeAndSize := r.uint32()
+		duration := r.uint32()
+		sap := r.uint32()
+		if r.err != nil {
+			return nil, fmt.Errorf("Box %q truncated", sidx.Type)
+		}
+		refSize := int64(typeAndSize & 0x7fffffff)
+		size := refSize + grown(start, start+refSize)
+		if size > 0x7fffffff {
+			return nil, fmt.Errorf("Box %q referenced size %d too large", sidx.Type, size)
+		}
+		p = binary.BigEndian.AppendUint32(p, typeAndSize&0x80000000|uint32(size))
+		p = binary.BigEndian.AppendUint32(p, duration)
+		p = binary.BigEndian.AppendUint32(p, sap)
+		start += refSize
+	}
+	return encodeBox(sidx.Type, p), nil
+}
+
+// retimeEmsg moves the presentation time of a version 1 emsg box by offset. Version 0 boxes are
+// timed relative to the segment and are copied as they are.
+func retimeEmsg(emsg box, offset int64, timescale uint32) ([]byte, error) {
+	version, _, err := fullBoxHeader(&emsg)
+	if err != nil {
+		return nil, err
+	}
+	if version != 1 {
+		return encodeBox(emsg.Type, emsg.Payload), nil
+	}
+	r := newReader(emsg.Payload)
+	r.skip(4)
+	emsgTimescale := r.uint32()
+	presentationTime := r.uint64()
+	if r.err != nil || emsgTimescale == 0 {
+		return nil, fmt.Errorf("Box %q truncated", emsg.Type)
+	}
+	p := append([]byte(nil), emsg.Payload...)
+	binary.BigEndian.PutUint64(p[8:16], uint64(int64(presentationTime)+rescale(offset, timescale, emsgTimescale)))
+	return encodeBox(emsg.Type, p), nil
+}
+
+// rescale converts ticks between timescales, rounding towards zero.
+func rescale(ticks int64, from, to uint32) int64 {
+	if from == to {
+		return ticks
+	}
+	neg := ticks < 0
+	u := uint64(ticks)
+	if neg {
+		u = -u
+	}
+	v := int64(u/uint64(from)*uint64(to) + u%uint64(from)*uint64(to)/uint64(from))
+	if neg {
+		return -v
+	}
+	return v
+}
+
+// retimeMoof rebuilds a moof box. growth is the size difference of the rebuilt moof, added to the
+// moof relative trun data offsets, and shift is the size difference of all the moofs up to this
+// one, added to the absolute tfhd base data offsets.
+func retimeMoof(moof box, sequenceNumber uint32, offset, growth, shift int64) ([]byte, error) {
+	children, err := moof.children(0)
+	if err != nil {
+		return nil, err
+	}
+	var payload []byte
+	for _, c := range children {
+		switch c.Type {
+		case "mfhd":
+			if len(c.Payload) < 8 {
+				return nil, fmt.Errorf("Box %q truncated", c.Type)
+			}
+			mfhd := append([]byte(nil), c.Payload...)
+			binary.BigEndian.PutUint32(mfhd[4:8], sequenceNumber)
+			payload = append(payload, encodeBox(c.Type, mfhd)...)
+		case "traf":
+			traf, err := retimeTraf(c, offset, growth, shift)
+			if err != nil {
+				return nil, err
+			}
+			payload = append(payload, traf...)
+		default:
+			payload = append(payload, encodeBox(c.Type, c.Payload)...)
+		}
+	}
+	return encodeBox(moof.Type, payload), nil
+}
+
+func retimeTraf(traf box, offset, growth, shift int64) ([]byte, error) {
+	children, err := traf.children(0)
+	if err != nil {
+		return nil, err
+	}
+	var payload []byte
+	hasTfdt := false
+	hasBaseDataOffset := false
+	for _, c := range children {
+		switch c.Type {
+		case "tfhd":
+			_, flags, err := fullBoxHeader(&c)
+			if err != nil {
+				return nil, err
+			}
+			tfhd := append([]byte(nil), c.Payload...)
+			if flags&tfhdBaseDataOffset != 0 {
+				if len(tfhd) < 16 {
+					return nil, fmt.Errorf("Box %q truncated", c.Type)
+				}
+				hasBaseDataOffset = true
+				binary.BigEndian.PutUint64(tfhd[8:16], uint64(int64(binary.BigEndian.Uint64(tfhd[8:16]))+shift))
+			}
+			payload = append(payload, encodeBox(c.Type, tfhd)...)
+		case "tfdt":
+			version, flags, err := fullBoxHeader(&c)
+			if err != nil {
+				return nil, err
+			}
+			r := newReader(c.Payload)
+			r.skip(4)
+			var decodeTime uint64
+			if version == 1 {
+				decodeTime = r.uint64()
+			} else {
+				decodeTime = uint64(r.uint32())
+			}
+			if r.err != nil {
+				return nil, fmt.Errorf("Box %q truncated", c.Type)
+			}
+			tfdt := make([]byte, 12)
+			binary.BigEndian.PutUint32(tfdt[0:4], 1<<24|flags)
+			binary.BigEndian.PutUint64(tfdt[4:12], uint64(int64(decodeTime)+offset))
+			payload = append(payload, encodeBox(c.Type, tfdt)...)
+			hasTfdt = true
+		case "trun":
+			_, flags, err := fullBoxHeader(&c)
+			if err != nil {
+				return nil, err
+			}
+			trun := append([]byte(nil), c.Payload...)
+			if flags&trunDataOffset != 0 && !hasBaseDataOffset {
+				if len(trun) < 12 {
+					return nil, fmt.Errorf("Box %q truncated", c.Type)
+				}
+				binary.BigEndian.PutUint32(trun[8:12], uint32(int32(binary.BigEndian.Uint32(trun[8:12]))+int32(growth)))
+			}
+			payload = append(payload, encodeBox(c.Type, trun)...)
+		default:
+			payload = append(payload, encodeBox(c.Type, c.Payload)...)
+		}
+	}
+	if !hasTfdt {
+		return nil, ErrNoTfdt
+	}
+	return encodeBox(traf.Type, payload), nil
+}
+
+// encodeBox writes a box with a compact header.
+func encodeBox(typ string, payload []byte) []byte {
+	b := make([]byte, 8, 8+len(payload))
+	binary.BigEndian.PutUint32(b[0:4], uint32(8+len(payload)))
+	copy(b[4:8], typ)
+	return append(b, payload...)
+}
@@ -0,0 +1,164 @@
+package probe
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/zencoder/go-dash/v3/helpers/require"
+)
+
+func TestRetimeFragment(t *testing.T) {
+	b := concat(
+		testFragment(1, 1, 90000, []uint32{3000, 3000}, 0),
+		testFragment(2, 1, 96000, []uint32{3000}, 0),
+	)
+	retimed, err := RetimeFragment(b, 41, 1000000, 90000)
+	require.NoError(t, err)
+	require.EqualInt(t, len(b), len(retimed))
+
+	f, err := ParseFragment(retimed, &Track{ID: 1})
+	require.NoError(t, err)
+	require.EqualUInt32(t, 41, f.SequenceNumber)
+	require.EqualUInt64(t, 1090000, f.DecodeTime)
+	require.EqualUInt64(t, 9000, f.Duration)
+
+	boxes, err := parseBoxes(retimed, 0)
+	require.NoError(t, err)
+	second, err := boxes[2].children(0)
+	require.NoError(t, err)
+	require.EqualUInt32(t, 42, binary.BigEndian.Uint32(findBox(second, "mfhd").Payload[4:8]))
+	tfdt, err := findPath(second, "traf", "tfdt")
+	require.NoError(t, err)
+	require.EqualUInt64(t, 1096000, binary.BigEndian.Uint64(tfdt.Payload[4:12]))
+}
+
+func TestRetimeFragmentNegativeOffset(t *testing.T) {
+	retimed, err := RetimeFragment(testFragment(1, 1, 90000, []uint32{3000}, 0), 1, -90000, 90000)
+	require.NoError(t, err)
+	f, err := ParseFragment(retimed, &Track{ID: 1})
+	require.NoError(t, err)
+	require.EqualUInt64(t, 0, f.DecodeTime)
+}
+
+func TestRetimeFragmentUpgradesTfdt(t *testing.T) {
+	samples := []byte("sample data")
+	moof := func(dataOffset uint32) []byte {
+		return mkbox("moof",
+			fullbox("mfhd", 0, 0, u32(7)),
+			mkbox("traf",
+				fullbox("tfhd", 0, 0x020000, u32(1)),
+				fullbox("tfdt", 0, 0, u32(1000)),
+				fullbox("trun", 0, trunDataOffset|trunSampleDuration|trunSampleSize, u32(1), u32(dataOffset), u32(512), u32(uint32(len(samples)))),
+			),
+		)
+	}
+	size := uint32(len(moof(0)))
+	b := concat(moof(size+8), mkbox("mdat", samples))
+
+	retimed, err := RetimeFragment(b, 1, 1<<32, 90000)
+	require.NoError(t, err)
+	require.EqualInt(t, len(b)+4, len(retimed))
+
+	f, err := ParseFragment(retimed, &Track{ID: 1})
+	require.NoError(t, err)
+	require.EqualUInt64(t, 1<<32+1000, f.DecodeTime)
+	require.EqualUInt64(t, 512, f.Duration)
+
+	boxes, err := parseBoxes(retimed, 0)
+	require.NoError(t, err)
+	trun, err := findPath(boxes, "moof", "traf", "trun")
+	require.NoError(t, err)
+	dataOffset := binary.BigEndian.Uint32(trun.Payload[8:12])
+	require.EqualUInt32(t, size+12, dataOffset)
+	if !bytes.Equal(samples, retimed[dataOffset:int(dataOffset)+len(samples)]) {
+		t.Errorf("Data offset %d doesn't point to the samples", dataOffset)
+	}
+}
+
+func TestRetimeFragmentBaseDataOffset(t *testing.T) {
+	moof := func(baseDataOffset uint64) []byte {
+		return mkbox("moof",
+			fullbox("mfhd", 0, 0, u32(1)),
+			mkbox("traf",
+				fullbox("tfhd", 0, tfhdBaseDataOffset, u32(1), u64(baseDataOffset)),
+				fullbox("tfdt", 0, 0, u32(0)),
+				fullbox("trun", 0, trunDataOffset|trunSampleDuration, u32(1), u32(0), u32(512)),
+			),
+		)
+	}
+	size := uint64(len(moof(0)))
+	b := concat(moof(size+8), mkbox("mdat", u32(0)))
+
+	retimed, err := RetimeFragment(b, 1, 0, 90000)
+	require.NoError(t, err)
+	boxes, err := parseBoxes(retimed, 0)
+	require.NoError(t, err)
+	tfhd, err := findPath(boxes, "moof", "traf", "tfhd")
+	require.NoError(t, err)
+	require.EqualUInt64(t, size+12, binary.BigEndian.Uint64(tfhd.Payload[8:16]))
+	trun, err := findPath(boxes, "moof", "traf", "trun")
+	require.NoError(t, err)
+	require.EqualUInt32(t, 0, binary.BigEndian.Uint32(trun.Payload[8:12]))
+}
+
+func TestRetimeFragmentErrors(t *testing.T) {
+	noTfdt := mkbox("moof", fullbox("mfhd", 0, 0, u32(1)), mkbox("traf", fullbox("tfhd", 0, 0x020000, u32(1))))
+	_, err := RetimeFragment(noTfdt, 1, 0, 90000)
+	require.EqualErr(t, ErrNoTfdt, err)
+
+	_, err = RetimeFragment([]byte{0, 0, 0}, 1, 0, 90000)
+	require.NotNil(t, err)
+
+	_, err = RetimeFragment(testFragment(1, 1, 0, []uint32{3000}, 0), 1, 0, 0)
+	require.EqualErr(t, ErrRetimeTimescaleZero, err)
+}
+
+func TestRetimeFragmentSidxAndEmsg(t *testing.T) {
+	subsegment := func(decodeTime uint32) []byte {
+		return concat(
+			mkbox("moof",
+				fullbox("mfhd", 0, 0, u32(1)),
+				mkbox("traf",
+					fullbox("tfhd", 0, 0x020000, u32(1)),
+					fullbox("tfdt", 0, 0, u32(decodeTime)),
+					fullbox("trun", 0, trunSampleDuration, u32(1), u32(3000)),
+				),
+			),
+			mkbox("mdat", make([]byte, 16)),
+		)
+	}
+	emsg := fullbox("emsg", 1, 0, u32(1000), u64(10), u32(2000), u32(1), []byte("urn:test\x00\x00"))
+	first, second := subsegment(1000), subsegment(4000)
+	sidx := testSidx(0, uint32(len(emsg)), 90000, 1000, []testReference{
+		{size: uint32(len(first)), duration: 3000},
+		{size: uint32(len(second)), duration: 3000},
+	})
+	b := concat(sidx, emsg, first, second)
+
+	retimed, err := RetimeFragment(b, 1, 1<<32, 90000)
+	require.NoError(t, err)
+	// The sidx and both tfdt boxes are upgraded to version 1
+	require.EqualInt(t, len(b)+8+4+4, len(retimed))
+
+	moov := mkbox("moov")
+	idx, err := ReadSegmentIndex(bytes.NewReader(concat(moov, retimed)))
+	require.NoError(t, err)
+	require.EqualUInt32(t, 90000, idx.Timescale)
+	require.EqualUInt64(t, 1<<32+1000, idx.EarliestPresentationTime)
+	require.EqualInt(t, 2, len(idx.References))
+
+	boxes, err := parseBoxes(retimed, int64(len(moov)))
+	require.NoError(t, err)
+	require.EqualString(t, "emsg", boxes[1].Type)
+	require.EqualUInt64(t, 10+47721858, binary.BigEndian.Uint64(boxes[1].Payload[8:16]))
+	for i, ref := range idx.References {
+		moof, mdat := boxes[2+2*i], boxes[3+2*i]
+		require.EqualString(t, "moof", moof.Type)
+		require.EqualInt(t, int(moof.Offset), int(ref.Start))
+		require.EqualInt(t, int(mdat.Offset+mdat.Size-1), int(ref.End))
+	}
+	f, err := ParseFragment(retimed[boxes[4].Offset-int64(len(moov)):], &Track{ID: 1})
+	require.NoError(t, err)
+	require.EqualUInt64(t, 1<<32+4000, f.DecodeTime)
+}
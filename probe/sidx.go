@@ -0,0 +1,322 @@
+package probe
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+
+	. "github.com/zencoder/go-dash/v3/helpers/ptrs"
+	"github.com/zencoder/go-dash/v3/mpd"
+)
+
+// Known error variables
+var (
+	ErrNoSidx               = errors.New("File has no sidx box before the first moof")
+	ErrNoSidxReferences     = errors.New("sidx has no media references")
+	ErrSidxReferenceInvalid = errors.New("Hierarchical sidx reference does not point to a sidx box")
+)
+
+// SegmentIndex describes the byte layout of a single file (on-demand) fMP4, as needed for
+// SegmentBase and SegmentList addressing.
+type SegmentIndex struct {
+	InitStart, InitEnd   int64 // Byte range of ftyp + moov, end inclusive
+	IndexStart, IndexEnd int64 // Byte range of the top level sidx, end inclusive
+	Timescale            uint32
+
+	// EarliestPresentationTime of the first subsegment, in Timescale units
+	EarliestPresentationTime uint64
+
+	// References are the media subsegments. Hierarchical sidx boxes are flattened.
+	References []SegmentReference
+}
+
+// SegmentReference is a single media subsegment (moof + mdat) of a sidx.
+type SegmentReference struct {
+	Start, End    int64 // Byte range, end inclusive
+	Duration      uint32
+	StartsWithSAP bool
+	SAPType       uint8
+}
+
+// InitRange returns the byte range of the init boxes (i.e. 0-628).
+func (idx *SegmentIndex) InitRange() string {
+	return formatRange(idx.InitStart, idx.InitEnd)
+}
+
+// IndexRange returns the byte range of the sidx box (i.e. 629-756).
+func (idx *SegmentIndex) IndexRange() string {
+	return formatRange(idx.IndexStart, idx.IndexEnd)
+}
+
+func formatRange(start, end int64) string {
+	return fmt.Sprintf("%d-%d", start, end)
+}
+
+// Scans a single file fMP4 for the moov and sidx boxes. Only the top level box headers and the
+// sidx boxes are read, scanning stops at the first moof so the media data is never loaded.
+// r - Must implement the io.ReaderAt interface (i.e. *os.File).
+func ReadSegmentIndex(r io.ReaderAt) (*SegmentIndex, error) {
+	idx := &SegmentIndex{InitStart: -1, IndexStart: -1}
+	var offset int64
+	for {
+		typ, size, err := readBoxHeaderAt(r, offset)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch typ {
+		case "ftyp":
+			if idx.InitStart < 0 {
+				idx.InitStart = offset
+			}
+		case "moov":
+			if idx.InitStart < 0 {
+				idx.InitStart = offset
+			}
+			idx.InitEnd = offset + size - 1
+		case "sidx":
+			if idx.IndexStart < 0 {
+				if err := idx.readSidx(r, offset, size, true); err != nil {
+					return nil, err
+				}
+			}
+		}
+		if typ == "moof" || size == 0 {
+			break
+		}
+		offset += size
+	}
+
+	if idx.InitEnd == 0 {
+		return nil, ErrNoMoov
+	}
+	if idx.IndexStart < 0 {
+		return nil, ErrNoSidx
+	}
+	if len(idx.References) == 0 {
+		return nil, ErrNoSidxReferences
+	}
+	return idx, nil
+}
+
+// Largest possible sidx box: a 64-bit size header, version 1 times and 65535 references
+const maxSidxSize = 16 + 4 + 4 + 4 + 16 + 2 + 2 + 12*0xffff
+
+// readerSize returns the length of readers that know it, such as *os.File and *bytes.Reader.
+func readerSize(r io.ReaderAt) (int64, bool) {
+	switch r := r.(type) {
+	case interface{ Size() int64 }:
+		return r.Size(), true
+	case interface{ Stat() (fs.FileInfo, error) }:
+		if info, err := r.Stat(); err == nil && info.Mode().IsRegular() {
+			return info.Size(), true
+		}
+	}
+	return 0, false
+}
+
+// readBoxHeaderAt returns the type and total size of the box at offset. A size of zero means the
+// box extends to the end of the file.
+func readBoxHeaderAt(r io.ReaderAt, offset int64) (string, int64, error) {
+	var header [16]byte
+	n, err := r.ReadAt(header[:8], offset)
+	if n == 0 && err == io.EOF {
+		return "", 0, io.EOF
+	}
+	if n < 8 {
+		return "", 0, fmt.Errorf("Box header truncated at offset %d", offset)
+	}
+	typ := string(header[4:8])
+	size := int64(binary.BigEndian.Uint32(header[0:4]))
+	if size == 1 {
+		if _, err := r.ReadAt(header[8:16], offset+8); err != nil {
+			return "", 0, fmt.Errorf("Box %q largesize truncated at offset %d", typ, offset)
+		}
+		size = int64(binary.BigEndian.Uint64(header[8:16]))
+		if size < 16 {
+			return "", 0, fmt.Errorf("Box %q size %d invalid at offset %d", typ, size, offset)
+		}
+		return typ, size, nil
+	}
+	if size != 0 && size < 8 {
+		return "", 0, fmt.Errorf("Box %q size %d invalid at offset %d", typ, size, offset)
+	}
+	return typ, size, nil
+}
+
+// readSidx decodes the sidx at offset, following references to further sidx boxes.
+// The size, which comes from the file, is checked before anything is allocated.
+func (idx *SegmentIndex) readSidx(r io.ReaderAt, offset, size int64, top bool) error {
+	if size == 0 || size > maxSidxSize {
+		return fmt.Errorf("Box %q size %d invalid at offset %d", "sidx", size, offset)
+	}
+	if length, ok := readerSize(r); ok && offset+size > length {
+		return fmt.Errorf("Box %q truncated at offset %d", "sidx", offset)
+	}
+	b := make([]byte, size)
+	if _, err := r.ReadAt(b, offset); err != nil {
+		return fmt.Errorf("Box %q truncated at offset %d", "sidx", offset)
+	}
+	boxes, err := parseBoxes(b, offset)
+	if err != nil {
+		return err
+	}
+	if len(boxes) == 0 {
+		return fmt.Errorf("Box %q truncated at offset %d", "sidx", offset)
+	}
+	sidx := boxes[0]
+	version, _, err := fullBoxHeader(&sidx)
+	if err != nil {
+		return err
+	}
+
+	br := newReader(sidx.Payload)
+	br.skip(4)
+	br.skip(4) // reference_ID
+	timescale := br.uint32()
+	var ept, firstOffset uint64
+	if version == 0 {
+		ept = uint64(br.uint32())
+		firstOffset = uint64(br.uint32())
+	} else {
+		ept = br.uint64()
+		firstOffset = br.uint64()
+	}
+	br.skip(2) // reserved
+	count := int(br.uint16())
+	if br.err != nil {
+		return fmt.Errorf("Box %q truncated at offset %d", "sidx", offset)
+	}
+
+	if top {
+		idx.IndexStart = offset
+		idx.IndexEnd = offset + size - 1
+		idx.Timescale = timescale
+		idx.EarliestPresentationTime = ept
+	}
+
+	start := offset + size + int64(firstOffset)
+	for i := 0; i < count; i++ {
+		typeAndSize := br.uint32()
+		duration := br.uint32()
+		sap := br.uint32()
+		if br.err != nil {
+			return fmt.Errorf("Box %q truncated at offset %d", "sidx", offset)
+		}
+		refSize := int64(typeAndSize & 0x7fffffff)
+		if typeAndSize&0x80000000 != 0 {
+			typ, childSize, err := readBoxHeaderAt(r, start)
+			if err != nil {
+				return err
+			}
+			// The referenced size covers the child sidx and all of the media it indexes
+			if typ != "sidx" || childSize > refSize {
+				return ErrSidxReferenceInvalid
+			}
+			if err := idx.readSidx(r, start, childSize, false); err != nil {
+				return err
+			}
+		} else {
+			idx.References = append(idx.References, SegmentReference{
+				Start:         start,
+				End:           start + refSize - 1,
+				Duration:      duration,
+				StartsWithSAP: sap&0x80000000 != 0,
+				SAPType:       uint8(sap >> 28 & 0x7),
+			})
+		}
+		start += refSize
+	}
+	return nil
+}
+
+// AddSegmentBase sets a SegmentBase on the Representation with the initialization and index
+// ranges, timescale and presentationTimeOffset taken from the index.
+func (idx *SegmentIndex) AddSegmentBase(r *mpd.Representation) (*mpd.SegmentBase, error) {
+	if r == nil {
+		return nil, ErrRepresentationNil
+	}
+	sb, err := r.AddNewSegmentBase(idx.IndexRange(), idx.InitRange())
+	if err != nil {
+		return nil, err
+	}
+	sb.Timescale = Uint32ptr(idx.Timescale)
+	if idx.EarliestPresentationTime > 0 {
+		sb.PresentationTimeOffset = Uint64ptr(idx.EarliestPresentationTime)
+	}
+	return sb, nil
+}
+
+// AddSegmentList sets a SegmentList on the Representation with a SegmentURL and mediaRange for
+// every sidx reference.
+// timeline - Signal segment durations with a SegmentTimeline. When false, @duration is used if
+// every segment but the last has the same duration, otherwise a SegmentTimeline is used anyway.
+func (idx *SegmentIndex) AddSegmentList(r *mpd.Representation, timeline bool) (*mpd.SegmentList, error) {
+	if r == nil {
+		return nil, ErrRepresentationNil
+	}
+
+	sl := &mpd.SegmentList{}
+	sl.Initialization = &mpd.URL{Range: Strptr(idx.InitRange())}
+	sl.Timescale = Uint32ptr(idx.Timescale)
+	if idx.EarliestPresentationTime > 0 {
+		sl.PresentationTimeOffset = Uint64ptr(idx.EarliestPresentationTime)
+	}
+
+	durations := make([]uint64, len(idx.References))
+	for i, ref := range idx.References {
+		durations[i] = uint64(ref.Duration)
+		sl.SegmentURLs = append(sl.SegmentURLs, &mpd.SegmentURL{MediaRange: Strptr(formatRange(ref.Start, ref.End))})
+	}
+
+	if d, ok := constantDuration(durations); ok && !timeline {
+		sl.Duration = Uint32ptr(uint32(d))
+	} else {
+		sl.SegmentTimeline = newSegmentTimeline(idx.EarliestPresentationTime, durations)
+	}
+
+	r.SegmentList = sl
+	return sl, nil
+}
+
+// constantDuration reports whether every duration but the last is the same, and the last is no
+// longer, as required for @duration addressing.
+func constantDuration(durations []uint64) (uint64, bool) {
+	if len(durations) == 0 {
+		return 0, false
+	}
+	d := durations[0]
+	for i, v := range durations {
+		if v != d && (i != len(durations)-1 || v > d) {
+			return 0, false
+		}
+	}
+	return d, true
+}
+
+// newSegmentTimeline builds a compacted SegmentTimeline, with runs of equal durations merged
+// into a single S element using @r.
+func newSegmentTimeline(start uint64, durations []uint64) *mpd.SegmentTimeline {
+	st := &mpd.SegmentTimeline{}
+	var last *mpd.SegmentTimelineSegment
+	for i, d := range durations {
+		if last != nil && last.Duration == d {
+			if last.RepeatCount == nil {
+				last.RepeatCount = Intptr(0)
+			}
+			*last.RepeatCount++
+			continue
+		}
+		last = &mpd.SegmentTimelineSegment{Duration: d}
+		if i == 0 {
+			last.StartTime = Uint64ptr(start)
+		}
+		st.Segments = append(st.Segments, last)
+	}
+	return st
+}
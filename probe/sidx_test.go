@@ -0,0 +1,203 @@
+package probe
+
+import (
+	"bytes"
+	"strconv"
+	"testing"
+
+	. "github.com/zencoder/go-dash/v3/helpers/ptrs"
+	"github.com/zencoder/go-dash/v3/helpers/require"
+	"github.com/zencoder/go-dash/v3/helpers/testfixtures"
+	"github.com/zencoder/go-dash/v3/mpd"
+)
+
+type testReference struct {
+	size     uint32
+	duration uint32
+	sidx     bool
+}
+
+func TestReadSegmentIndex(t *testing.T) {
+	initSegment, file := testOnDemandFile(1000, 0, []uint32{4000, 4000, 2000})
+	idx, err := ReadSegmentIndex(bytes.NewReader(file))
+	require.NoError(t, err)
+
+	require.EqualString(t, formatRange(0, int64(len(initSegment)-1)), idx.InitRange())
+	require.EqualString(t, formatRange(int64(len(initSegment)), int64(len(initSegment)+32+3*12-1)), idx.IndexRange())
+	require.EqualInt(t, 1000, int(idx.Timescale))
+	require.EqualInt(t, 3, len(idx.References))
+
+	start := int64(len(initSegment) + 32 + 3*12)
+	for i, ref := range idx.References {
+		require.EqualInt(t, int(start), int(ref.Start))
+		require.EqualInt(t, int(start)+99, int(ref.End))
+		require.EqualInt(t, []int{4000, 4000, 2000}[i], int(ref.Duration))
+		if !ref.StartsWithSAP || ref.SAPType != 1 {
+			t.Errorf("Expected reference %d to start with a type 1 SAP", i)
+		}
+		start += 100
+	}
+}
+
+func TestReadSegmentIndexHierarchical(t *testing.T) {
+	initSegment := testInitSegment(testTrack{id: 1, handler: HANDLER_TYPE_VIDEO, timescale: 90000, entry: visualSampleEntry("avc1", 960, 540, mkbox("avcC", testAvcC))})
+	child1 := testSidx(0, 0, 90000, 0, []testReference{{size: 100, duration: 180000}, {size: 100, duration: 180000}})
+	child2 := testSidx(0, 0, 90000, 360000, []testReference{{size: 100, duration: 90000}})
+	top := testSidx(1, 0, 90000, 0, []testReference{
+		{size: uint32(len(child1) + 200), duration: 360000, sidx: true},
+		{size: uint32(len(child2) + 100), duration: 90000, sidx: true},
+	})
+	file := concat(initSegment, top, child1, testSubsegment(100), testSubsegment(100), child2, testSubsegment(100))
+
+	idx, err := ReadSegmentIndex(bytes.NewReader(file))
+	require.NoError(t, err)
+	require.EqualString(t, formatRange(int64(len(initSegment)), int64(len(initSegment)+len(top)-1)), idx.IndexRange())
+	require.EqualInt(t, 3, len(idx.References))
+
+	child2Start := int64(len(initSegment) + len(top) + len(child1) + 200)
+	require.EqualInt(t, int(child2Start)+len(child2), int(idx.References[2].Start))
+	require.EqualInt(t, 90000, int(idx.References[2].Duration))
+}
+
+func TestReadSegmentIndexErrors(t *testing.T) {
+	initSegment := testInitSegment(testTrack{id: 1, handler: HANDLER_TYPE_AUDIO, timescale: 48000, entry: audioSampleEntry("mp4a", 2, 48000, mkbox("esds", testEsds))})
+
+	_, err := ReadSegmentIndex(bytes.NewReader(concat(initSegment, testSubsegment(100))))
+	require.EqualErr(t, ErrNoSidx, err)
+
+	_, err = ReadSegmentIndex(bytes.NewReader(concat(testSidx(0, 0, 1000, 0, nil), testSubsegment(100))))
+	require.EqualErr(t, ErrNoMoov, err)
+
+	_, err = ReadSegmentIndex(bytes.NewReader(concat(initSegment, testSidx(0, 0, 1000, 0, nil))))
+	require.EqualErr(t, ErrNoSidxReferences, err)
+
+	_, err = ReadSegmentIndex(bytes.NewReader(concat(initSegment, testSidx(0, 0, 1000, 0, []testReference{{size: 100, sidx: true}}), testSubsegment(100))))
+	require.EqualErr(t, ErrSidxReferenceInvalid, err)
+
+	_, err = ReadSegmentIndex(bytes.NewReader(concat(initSegment, []byte{0, 0, 0})))
+	require.EqualError(t, err, "Box header truncated at offset "+strconv.Itoa(len(initSegment)))
+
+	// Sizes from the file are checked before reading the sidx
+	offset := strconv.Itoa(len(initSegment))
+	_, err = ReadSegmentIndex(bytes.NewReader(concat(initSegment, u32(0), []byte("sidx"))))
+	require.EqualError(t, err, `Box "sidx" size 0 invalid at offset `+offset)
+	_, err = ReadSegmentIndex(bytes.NewReader(concat(initSegment, u32(0xfffffff0), []byte("sidx"))))
+	require.EqualError(t, err, `Box "sidx" size 4294967280 invalid at offset `+offset)
+	_, err = ReadSegmentIndex(bytes.NewReader(concat(initSegment, u32(1000), []byte("sidx"), make([]byte, 100))))
+	require.EqualError(t, err, `Box "sidx" truncated at offset `+offset)
+}
+
+func TestAddSegmentBase(t *testing.T) {
+	_, file := testOnDemandFile(48000, 1024, []uint32{96000, 96000, 48000})
+	idx, err := ReadSegmentIndex(bytes.NewReader(file))
+	require.NoError(t, err)
+
+	m := mpd.NewMPD(mpd.DASH_PROFILE_ONDEMAND, "PT5S", "PT2S")
+	as, _ := m.AddNewAdaptationSetAudio(mpd.DASH_MIME_TYPE_AUDIO_MP4, true, 1, "en")
+	r, _ := as.AddNewRepresentationAudio(48000, 128000, "mp4a.40.2", "audio")
+	_ = r.SetNewBaseURL("audio.mp4")
+
+	sb, err := idx.AddSegmentBase(r)
+	require.NoError(t, err)
+	require.EqualStringPtr(t, Strptr(idx.IndexRange()), sb.IndexRange)
+	require.EqualStringPtr(t, Strptr(idx.InitRange()), sb.Initialization.Range)
+	require.EqualUInt32(t, 48000, *sb.Timescale)
+	require.EqualUInt64Ptr(t, Uint64ptr(1024), sb.PresentationTimeOffset)
+
+	_, err = idx.AddSegmentBase(nil)
+	require.EqualErr(t, ErrRepresentationNil, err)
+}
+
+func TestAddSegmentList(t *testing.T) {
+	_, file := testOnDemandFile(1000, 0, []uint32{4000, 4000, 2000})
+	idx, err := ReadSegmentIndex(bytes.NewReader(file))
+	require.NoError(t, err)
+
+	m := mpd.NewMPD(mpd.DASH_PROFILE_ONDEMAND, "PT10S", "PT4S")
+	as, _ := m.AddNewAdaptationSetVideo(mpd.DASH_MIME_TYPE_VIDEO_MP4, "progressive", true, 1)
+
+	r, _ := as.AddNewRepresentationVideo(1518664, "avc1.4d401f", "duration", "30000/1001", 960, 540)
+	_ = r.SetNewBaseURL("video.mp4")
+	sl, err := idx.AddSegmentList(r, false)
+	require.NoError(t, err)
+	require.EqualUInt32(t, 4000, *sl.Duration)
+	require.Nil(t, sl.SegmentTimeline)
+
+	r, _ = as.AddNewRepresentationVideo(1518664, "avc1.4d401f", "timeline", "30000/1001", 960, 540)
+	_ = r.SetNewBaseURL("video.mp4")
+	sl, err = idx.AddSegmentList(r, true)
+	require.NoError(t, err)
+	require.Nil(t, sl.Duration)
+
+	xml, err := m.WriteToString()
+	require.NoError(t, err)
+	testfixtures.CompareFixture(t, "fixtures/sidx_segment_list.mpd", xml)
+}
+
+func TestAddSegmentListVariableDuration(t *testing.T) {
+	_, file := testOnDemandFile(1000, 0, []uint32{4000, 3000, 4000})
+	idx, err := ReadSegmentIndex(bytes.NewReader(file))
+	require.NoError(t, err)
+
+	m := mpd.NewMPD(mpd.DASH_PROFILE_ONDEMAND, "PT11S", "PT4S")
+	as, _ := m.AddNewAdaptationSetVideo(mpd.DASH_MIME_TYPE_VIDEO_MP4, "progressive", true, 1)
+	r, _ := as.AddNewRepresentationVideo(1518664, "avc1.4d401f", "800", "30000/1001", 960, 540)
+
+	sl, err := idx.AddSegmentList(r, false)
+	require.NoError(t, err)
+	require.Nil(t, sl.Duration)
+	require.EqualInt(t, 3, len(sl.SegmentTimeline.Segments))
+
+	// The Representation doesn't need an AdaptationSet
+	sl, err = idx.AddSegmentList(&mpd.Representation{}, false)
+	require.NoError(t, err)
+	require.EqualInt(t, 3, len(sl.SegmentURLs))
+	_, err = idx.AddSegmentList(nil, false)
+	require.EqualErr(t, ErrRepresentationNil, err)
+}
+
+func TestNewSegmentTimeline(t *testing.T) {
+	st := newSegmentTimeline(10, []uint64{4, 4, 4, 2, 4, 4})
+	require.EqualInt(t, 3, len(st.Segments))
+	require.EqualUInt64Ptr(t, Uint64ptr(10), st.Segments[0].StartTime)
+	require.EqualUInt64(t, 4, st.Segments[0].Duration)
+	require.EqualIntPtr(t, Intptr(2), st.Segments[0].RepeatCount)
+	require.Nil(t, st.Segments[1].StartTime)
+	require.Nil(t, st.Segments[1].RepeatCount)
+	require.EqualIntPtr(t, Intptr(1), st.Segments[2].RepeatCount)
+}
+
+// testOnDemandFile builds an init segment, a sidx and one 100 byte subsegment per duration. It
+// returns the init segment and the whole file.
+func testOnDemandFile(timescale uint32, ept uint64, durations []uint32) ([]byte, []byte) {
+	initSegment := testInitSegment(testTrack{id: 1, handler: HANDLER_TYPE_VIDEO, timescale: timescale, entry: visualSampleEntry("avc1", 960, 540, mkbox("avcC", testAvcC))})
+	refs := make([]testReference, len(durations))
+	var media [][]byte
+	for i, d := range durations {
+		refs[i] = testReference{size: 100, duration: d}
+		media = append(media, testSubsegment(100))
+	}
+	return initSegment, concat(initSegment, testSidx(0, 0, timescale, ept, refs), concat(media...))
+}
+
+func testSidx(version uint8, firstOffset uint32, timescale uint32, ept uint64, refs []testReference) []byte {
+	var times []byte
+	if version == 0 {
+		times = concat(u32(uint32(ept)), u32(firstOffset))
+	} else {
+		times = concat(u64(ept), u64(uint64(firstOffset)))
+	}
+	var entries [][]byte
+	for _, ref := range refs {
+		typeAndSize := ref.size
+		if ref.sidx {
+			typeAndSize |= 0x80000000
+		}
+		entries = append(entries, concat(u32(typeAndSize), u32(ref.duration), u32(0x90000000)))
+	}
+	return fullbox("sidx", version, 0, u32(1), u32(timescale), times, u16(0), u16(uint16(len(refs))), concat(entries...))
+}
+
+func testSubsegment(size int) []byte {
+	return concat(mkbox("moof", make([]byte, 24)), mkbox("mdat", make([]byte, size-40)))
+}
@@ -0,0 +1,131 @@
+// Package proxy serves MPEG-DASH manifests loaded from an origin, after applying a chain of
+// transforms such as bitrate caps, language filtering, BaseURL rewriting and token injection.
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/zencoder/go-dash/v3/mpd"
+)
+
+// Known error variables
+var (
+	ErrNotFound         = errors.New("Manifest not found")
+	ErrInvalidParameter = errors.New("Invalid query parameter")
+	ErrNoManifest       = errors.New("No manifest")
+)
+
+// Fetcher loads origin manifests. The Handler never modifies the MPDs it returns, so they can be
+// cached and shared between requests.
+type Fetcher interface {
+	// Fetch returns the manifest at the path of a request (i.e. /live/channel1.mpd), or an error
+	// wrapping ErrNotFound when there is none.
+	Fetch(ctx context.Context, path string) (*mpd.MPD, error)
+}
+
+// FetcherFunc adapts a function to the Fetcher interface.
+type FetcherFunc func(ctx context.Context, path string) (*mpd.MPD, error)
+
+func (f FetcherFunc) Fetch(ctx context.Context, path string) (*mpd.MPD, error) {
+	return f(ctx, path)
+}
+
+// FSFetcher loads manifests from a file system, with request paths relative to its root.
+type FSFetcher struct {
+	FS fs.FS
+}
+
+// Creates a new FSFetcher.
+// fsys - file system holding the manifests (i.e. os.DirFS("/var/www/manifests")).
+func NewFSFetcher(fsys fs.FS) *FSFetcher {
+	return &FSFetcher{FS: fsys}
+}
+
+func (f *FSFetcher) Fetch(ctx context.Context, path string) (*mpd.MPD, error) {
+	name := strings.TrimPrefix(path, "/")
+	if !fs.ValidPath(name) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, path)
+	}
+	file, err := f.FS.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return mpd.Read(file)
+}
+
+// HTTPFetcher loads manifests from an origin server, with request paths joined onto the path of its
+// URL.
+type HTTPFetcher struct {
+	Origin *url.URL
+	Client *http.Client // http.DefaultClient when nil
+}
+
+// Creates a new HTTPFetcher.
+// origin - origin server URL (i.e. https://origin.example.com/manifests/).
+func NewHTTPFetcher(origin string) (*HTTPFetcher, error) {
+	u, err := url.Parse(origin)
+	if err != nil {
+		return nil, err
+	}
+	return &HTTPFetcher{Origin: u}, nil
+}
+
+func (f *HTTPFetcher) Fetch(ctx context.Context, path string) (*mpd.MPD, error) {
+	u, err := f.originURL(path)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	client := f.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, u)
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("%s: %s", u, resp.Status)
+	}
+	return mpd.Read(resp.Body)
+}
+
+// originURL joins a request path onto the path of the Origin. Paths with a .. segment, or that are
+// URLs or scheme-relative references of their own, are rejected so that requests can't reach
+// another host or leave the Origin path.
+func (f *HTTPFetcher) originURL(requestPath string) (*url.URL, error) {
+	ref, err := url.Parse(requestPath)
+	if err != nil || ref.Scheme != "" || ref.Host != "" || ref.User != nil || ref.Opaque != "" ||
+		strings.HasPrefix(requestPath, "//") {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, requestPath)
+	}
+	for _, segment := range strings.Split(requestPath, "/") {
+		if segment == ".." {
+			return nil, fmt.Errorf("%w: %s", ErrNotFound, requestPath)
+		}
+	}
+	u := *f.Origin
+	u.Path = strings.TrimSuffix(f.Origin.Path, "/") + path.Clean("/"+requestPath)
+	u.RawPath = ""
+	u.Fragment = ""
+	return &u, nil
+}
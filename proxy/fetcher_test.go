@@ -0,0 +1,91 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/zencoder/go-dash/v3/helpers/require"
+)
+
+const FIXTURES = "../mpd/fixtures"
+
+func TestFSFetcher(t *testing.T) {
+	f := NewFSFetcher(os.DirFS(FIXTURES))
+	m, err := f.Fetch(context.Background(), "/live_profile.mpd")
+	require.NoError(t, err)
+	require.EqualInt(t, 3, len(m.Periods[0].AdaptationSets))
+
+	_, err = f.Fetch(context.Background(), "/missing.mpd")
+	require.EqualError(t, err, "Manifest not found: /missing.mpd")
+	_, err = f.Fetch(context.Background(), "/../go.mod")
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound outside of the file system, got %v", err)
+	}
+}
+
+func TestHTTPFetcher(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.Handle("/manifests/", http.StripPrefix("/manifests/", http.FileServer(http.Dir(FIXTURES))))
+	mux.HandleFunc("/manifests/broken.mpd", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "oops", http.StatusInternalServerError)
+	})
+	origin := httptest.NewServer(mux)
+	defer origin.Close()
+
+	f, err := NewHTTPFetcher(origin.URL + "/manifests/")
+	require.NoError(t, err)
+	m, err := f.Fetch(context.Background(), "/ondemand_profile.mpd")
+	require.NoError(t, err)
+	require.EqualString(t, "static", *m.Type)
+
+	_, err = f.Fetch(context.Background(), "/missing.mpd")
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+	_, err = f.Fetch(context.Background(), "/broken.mpd")
+	require.EqualError(t, err, origin.URL+"/manifests/broken.mpd: 500 Internal Server Error")
+}
+
+func TestHTTPFetcherStaysOnOrigin(t *testing.T) {
+	evil := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("Unexpected request to another host: %s", r.URL)
+		http.ServeFile(w, r, FIXTURES+"/live_profile.mpd")
+	}))
+	defer evil.Close()
+	var requested []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		requested = append(requested, r.URL.Path)
+		http.ServeFile(w, r, FIXTURES+"/live_profile.mpd")
+	})
+	origin := httptest.NewServer(mux)
+	defer origin.Close()
+
+	f, err := NewHTTPFetcher(origin.URL + "/manifests/")
+	require.NoError(t, err)
+	evilHost := strings.TrimPrefix(evil.URL, "http://")
+	for _, path := range []string{
+		"///" + evilHost + "/x.mpd",
+		"//" + evilHost + "/x.mpd",
+		evil.URL + "/x.mpd",
+		"/../../secret.mpd",
+		"/live/../../secret.mpd",
+		"/..",
+	} {
+		_, err := f.Fetch(context.Background(), path)
+		if !errors.Is(err, ErrNotFound) {
+			t.Errorf("Expected ErrNotFound for %q, got %v", path, err)
+		}
+	}
+	require.EqualInt(t, 0, len(requested))
+
+	// Other paths are cleaned and joined onto the origin path
+	_, err = f.Fetch(context.Background(), "/live/./channel 1.mpd")
+	require.NoError(t, err)
+	require.EqualStringSlice(t, []string{"/manifests/live/channel 1.mpd"}, requested)
+}
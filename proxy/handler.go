@@ -0,0 +1,136 @@
+package proxy
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zencoder/go-dash/v3/mpd"
+)
+
+// Constants for the served manifests
+const (
+	CONTENT_TYPE_DASH       = "application/dash+xml"
+	DEFAULT_STATIC_MAX_AGE  = 5 * time.Minute
+	DEFAULT_DYNAMIC_MAX_AGE = 2 * time.Second
+)
+
+// Transform modifies the manifest served for a request. It may modify m in place, and returns the
+// manifest to serve. Errors wrapping ErrInvalidParameter are served as 400 Bad Request.
+type Transform func(r *http.Request, m *mpd.MPD) (*mpd.MPD, error)
+
+// Handler serves the manifests of a Fetcher after applying its Transforms in order.
+type Handler struct {
+	Fetcher    Fetcher
+	Transforms []Transform
+	// Cache-Control max-age of static manifests
+	StaticMaxAge time.Duration
+	// Cache-Control max-age of dynamic manifests, lowered to their @minimumUpdatePeriod
+	DynamicMaxAge time.Duration
+}
+
+// Creates a new Handler with the default cache durations.
+// fetcher - loads the origin manifests.
+// transforms - applied in order to a copy of each origin manifest.
+func NewHandler(fetcher Fetcher, transforms ...Transform) *Handler {
+	return &Handler{
+		Fetcher:       fetcher,
+		Transforms:    transforms,
+		StaticMaxAge:  DEFAULT_STATIC_MAX_AGE,
+		DynamicMaxAge: DEFAULT_DYNAMIC_MAX_AGE,
+	}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	origin, err := h.Fetcher.Fetch(r.Context(), r.URL.Path)
+	if err != nil {
+		serveError(w, err, http.StatusBadGateway)
+		return
+	}
+	if origin == nil {
+		serveError(w, ErrNoManifest, http.StatusBadGateway)
+		return
+	}
+	m := origin.Clone()
+	for _, transform := range h.Transforms {
+		if m, err = transform(r, m); err != nil {
+			serveError(w, err, http.StatusInternalServerError)
+			return
+		}
+		if m == nil {
+			serveError(w, ErrNoManifest, http.StatusInternalServerError)
+			return
+		}
+	}
+	body, err := m.WriteToString()
+	if err != nil {
+		serveError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	sum := sha256.Sum256([]byte(body))
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+	header := w.Header()
+	header.Set("Content-Type", CONTENT_TYPE_DASH)
+	header.Set("Cache-Control", "max-age="+strconv.Itoa(int(h.maxAge(m).Seconds())))
+	header.Set("ETag", etag)
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	header.Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(http.StatusOK)
+	if r.Method != http.MethodHead {
+		_, _ = w.Write([]byte(body))
+	}
+}
+
+// maxAge returns how long the manifest can be cached: the StaticMaxAge for static manifests, and
+// the DynamicMaxAge for dynamic ones, lowered to @minimumUpdatePeriod.
+func (h *Handler) maxAge(m *mpd.MPD) time.Duration {
+	if m.Type == nil || *m.Type != "dynamic" {
+		return h.StaticMaxAge
+	}
+	maxAge := h.DynamicMaxAge
+	if m.MinimumUpdatePeriod != nil {
+		if mup, err := mpd.ParseDuration(*m.MinimumUpdatePeriod); err == nil && mup < maxAge {
+			maxAge = mup
+		}
+	}
+	return maxAge
+}
+
+// serveError maps ErrNotFound to 404 Not Found and ErrInvalidParameter to 400 Bad Request, other
+// errors are served with status.
+func serveError(w http.ResponseWriter, err error, status int) {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, ErrInvalidParameter):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	http.Error(w, http.StatusText(status), status)
+}
+
+// etagMatches reports whether an If-None-Match header matches the ETag, using the weak comparison
+// of RFC 9110 13.1.2.
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, tag := range strings.Split(ifNoneMatch, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,107 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/zencoder/go-dash/v3/helpers/require"
+	"github.com/zencoder/go-dash/v3/mpd"
+)
+
+func serve(h http.Handler, method, target string, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, nil)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w
+}
+
+func TestHandler(t *testing.T) {
+	h := NewHandler(NewFSFetcher(os.DirFS(FIXTURES)))
+
+	w := serve(h, http.MethodGet, "/live_profile.mpd", nil)
+	require.EqualInt(t, http.StatusOK, w.Code)
+	require.EqualString(t, CONTENT_TYPE_DASH, w.Header().Get("Content-Type"))
+	require.EqualString(t, "max-age=300", w.Header().Get("Cache-Control"))
+	etag := w.Header().Get("ETag")
+	require.EqualInt(t, 34, len(etag))
+	m, err := mpd.ReadFromString(w.Body.String())
+	require.NoError(t, err)
+	require.EqualInt(t, 3, len(m.Periods[0].AdaptationSets))
+
+	w = serve(h, http.MethodGet, "/live_profile.mpd", http.Header{"If-None-Match": {`"other", W/` + etag}})
+	require.EqualInt(t, http.StatusNotModified, w.Code)
+	require.EqualString(t, "", w.Body.String())
+
+	w = serve(h, http.MethodHead, "/live_profile.mpd", nil)
+	require.EqualInt(t, http.StatusOK, w.Code)
+	require.EqualString(t, etag, w.Header().Get("ETag"))
+	require.EqualString(t, "", w.Body.String())
+
+	w = serve(h, http.MethodPost, "/live_profile.mpd", nil)
+	require.EqualInt(t, http.StatusMethodNotAllowed, w.Code)
+	require.EqualString(t, "GET, HEAD", w.Header().Get("Allow"))
+
+	w = serve(h, http.MethodGet, "/missing.mpd", nil)
+	require.EqualInt(t, http.StatusNotFound, w.Code)
+}
+
+func TestHandlerDynamicMaxAge(t *testing.T) {
+	h := NewHandler(NewFSFetcher(os.DirFS(FIXTURES)))
+	w := serve(h, http.MethodGet, "/live_profile_dynamic.mpd", nil)
+	require.EqualString(t, "max-age=2", w.Header().Get("Cache-Control"))
+
+	// Lowered to @minimumUpdatePeriod
+	h.DynamicMaxAge = time.Minute
+	w = serve(h, http.MethodGet, "/live_profile_dynamic.mpd", nil)
+	require.EqualString(t, "max-age=5", w.Header().Get("Cache-Control"))
+}
+
+func TestHandlerErrors(t *testing.T) {
+	h := NewHandler(FetcherFunc(func(ctx context.Context, path string) (*mpd.MPD, error) {
+		return nil, errors.New("connection refused")
+	}))
+	w := serve(h, http.MethodGet, "/live.mpd", nil)
+	require.EqualInt(t, http.StatusBadGateway, w.Code)
+
+	// A Fetcher or Transform returning no manifest
+	h = NewHandler(FetcherFunc(func(ctx context.Context, path string) (*mpd.MPD, error) {
+		return nil, nil
+	}))
+	w = serve(h, http.MethodGet, "/live.mpd", nil)
+	require.EqualInt(t, http.StatusBadGateway, w.Code)
+	h = NewHandler(NewFSFetcher(os.DirFS(FIXTURES)), func(r *http.Request, m *mpd.MPD) (*mpd.MPD, error) {
+		return nil, nil
+	})
+	w = serve(h, http.MethodGet, "/live_profile.mpd", nil)
+	require.EqualInt(t, http.StatusInternalServerError, w.Code)
+
+	h = NewHandler(NewFSFetcher(os.DirFS(FIXTURES)), BandwidthFromQuery("", "max_bitrate"))
+	w = serve(h, http.MethodGet, "/live_profile.mpd?max_bitrate=fast", nil)
+	require.EqualInt(t, http.StatusBadRequest, w.Code)
+	require.EqualString(t, "Invalid query parameter max_bitrate: \"fast\"\n", w.Body.String())
+}
+
+func TestHandlerDoesNotModifyOrigin(t *testing.T) {
+	origin, err := mpd.ReadFromFile(FIXTURES + "/live_profile.mpd")
+	require.NoError(t, err)
+	expected, err := origin.WriteToString()
+	require.NoError(t, err)
+
+	h := NewHandler(FetcherFunc(func(ctx context.Context, path string) (*mpd.MPD, error) {
+		return origin, nil
+	}), InjectToken("token"), SetBaseURL("https://cdn.example.com/"), BandwidthFromQuery("", "max_bitrate"))
+	w := serve(h, http.MethodGet, "/live.mpd?token=abc&max_bitrate=2000000", nil)
+	require.EqualInt(t, http.StatusOK, w.Code)
+
+	actual, err := origin.WriteToString()
+	require.NoError(t, err)
+	require.EqualString(t, expected, actual)
+}
@@ -0,0 +1,179 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/zencoder/go-dash/v3/mpd"
+)
+
+// BandwidthFromQuery keeps the Representations within the bandwidth range of query parameters
+// (i.e. ?max_bitrate=3000000), in bits per second. Missing parameters don't limit the range.
+// minParam - query parameter of the minimum bandwidth, empty for none.
+// maxParam - query parameter of the maximum bandwidth, empty for none.
+func BandwidthFromQuery(minParam, maxParam string) Transform {
+	return func(r *http.Request, m *mpd.MPD) (*mpd.MPD, error) {
+		minBandwidth, err := queryInt(r, minParam)
+		if err != nil {
+			return nil, err
+		}
+		maxBandwidth, err := queryInt(r, maxParam)
+		if err != nil {
+			return nil, err
+		}
+		if minBandwidth == 0 && maxBandwidth == 0 {
+			return m, nil
+		}
+		return m.Filter(mpd.WithBandwidth(minBandwidth, maxBandwidth)), nil
+	}
+}
+
+// queryInt returns the positive integer value of a query parameter, 0 when it is missing.
+func queryInt(r *http.Request, param string) (int64, error) {
+	if param == "" {
+		return 0, nil
+	}
+	s := r.URL.Query().Get(param)
+	if s == "" {
+		return 0, nil
+	}
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || v < 0 {
+		return 0, fmt.Errorf("%w %s: %q", ErrInvalidParameter, param, s)
+	}
+	return v, nil
+}
+
+// LanguagesFromQuery keeps the AdaptationSets in the languages of a comma separated query
+// parameter (i.e. ?lang=en,fr), and the ones without a language such as video.
+// param - query parameter of the languages.
+func LanguagesFromQuery(param string) Transform {
+	return func(r *http.Request, m *mpd.MPD) (*mpd.MPD, error) {
+		var langs []string
+		for _, lang := range strings.Split(r.URL.Query().Get(param), ",") {
+			if lang = strings.TrimSpace(lang); lang != "" {
+				langs = append(langs, lang)
+			}
+		}
+		if len(langs) == 0 {
+			return m, nil
+		}
+		return m.Filter(mpd.WithLanguages(langs...)), nil
+	}
+}
+
+// SetBaseURL resolves the MPD level BaseURLs against baseURL, or sets it as the MPD BaseURL when
+// there are none, so that relative segment URLs point to the origin or a CDN rather than to the
+// proxy.
+// baseURL - absolute URL (i.e. https://cdn.example.com/content/).
+func SetBaseURL(baseURL string) Transform {
+	return func(r *http.Request, m *mpd.MPD) (*mpd.MPD, error) {
+		base, err := url.Parse(baseURL)
+		if err != nil {
+			return nil, err
+		}
+		if len(m.BaseURL) == 0 {
+			m.BaseURL = []string{base.String()}
+			return m, nil
+		}
+		for i, s := range m.BaseURL {
+			ref, err := url.Parse(s)
+			if err != nil {
+				return nil, err
+			}
+			m.BaseURL[i] = base.ResolveReference(ref).String()
+		}
+		return m, nil
+	}
+}
+
+// RewriteBaseURLs replaces every BaseURL, at MPD, Period, AdaptationSet and Representation level.
+// rewrite - returns the new BaseURL for the request.
+func RewriteBaseURLs(rewrite func(r *http.Request, baseURL string) string) Transform {
+	return func(r *http.Request, m *mpd.MPD) (*mpd.MPD, error) {
+		rewriteAll := func(baseURLs []string) {
+			for i := range baseURLs {
+				baseURLs[i] = rewrite(r, baseURLs[i])
+			}
+		}
+		rewriteAll(m.BaseURL)
+		for _, p := range m.Periods {
+			rewriteAll(p.BaseURL)
+			for _, as := range p.AdaptationSets {
+				rewriteAll(as.BaseURL)
+				for _, rep := range as.Representations {
+					rewriteAll(rep.BaseURL)
+				}
+			}
+		}
+		return m, nil
+	}
+}
+
+// InjectToken copies a query parameter of the request (i.e. ?token=abc) to the segment URLs: the
+// SegmentTemplate and SegmentList URLs, and the BaseURLs of Representations addressed by
+// SegmentBase. The manifest is unchanged when the request has no such parameter.
+// param - query parameter of the token.
+func InjectToken(param string) Transform {
+	return func(r *http.Request, m *mpd.MPD) (*mpd.MPD, error) {
+		token := r.URL.Query().Get(param)
+		if token == "" {
+			return m, nil
+		}
+		inject := func(s *string) {
+			if s != nil && *s != "" {
+				*s = addQuery(*s, param, token)
+			}
+		}
+		injectTemplate := func(st *mpd.SegmentTemplate) {
+			if st != nil {
+				inject(st.Initialization)
+				inject(st.Media)
+			}
+		}
+		injectList := func(sl *mpd.SegmentList) {
+			if sl == nil {
+				return
+			}
+			if sl.Initialization != nil {
+				inject(sl.Initialization.SourceURL)
+			}
+			for _, u := range sl.SegmentURLs {
+				inject(u.Media)
+			}
+		}
+
+		for _, p := range m.Periods {
+			injectTemplate(p.SegmentTemplate)
+			injectList(p.SegmentList)
+			for _, as := range p.AdaptationSets {
+				injectTemplate(as.SegmentTemplate)
+				injectList(as.SegmentList)
+				for _, rep := range as.Representations {
+					injectTemplate(rep.SegmentTemplate)
+					injectList(rep.SegmentList)
+					if rep.SegmentTemplate == nil && as.SegmentTemplate == nil && p.SegmentTemplate == nil &&
+						rep.SegmentList == nil && as.SegmentList == nil && p.SegmentList == nil {
+						for i := range rep.BaseURL {
+							inject(&rep.BaseURL[i])
+						}
+					}
+				}
+			}
+		}
+		return m, nil
+	}
+}
+
+// addQuery appends a query parameter to a URL or segment template, escaping the value so that it
+// can't contain template identifiers.
+func addQuery(s, key, value string) string {
+	sep := "?"
+	if strings.Contains(s, "?") {
+		sep = "&"
+	}
+	return s + sep + url.QueryEscape(key) + "=" + url.QueryEscape(value)
+}
@@ -0,0 +1,77 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/zencoder/go-dash/v3/helpers/require"
+	"github.com/zencoder/go-dash/v3/mpd"
+)
+
+func applyTransform(t *testing.T, transform Transform, fixture, target string) *mpd.MPD {
+	m, err := mpd.ReadFromFile(FIXTURES + "/" + fixture)
+	require.NoError(t, err)
+	m, err = transform(httptest.NewRequest(http.MethodGet, target, nil), m)
+	require.NoError(t, err)
+	return m
+}
+
+func TestBandwidthFromQuery(t *testing.T) {
+	transform := BandwidthFromQuery("min_bitrate", "max_bitrate")
+	m := applyTransform(t, transform, "live_profile.mpd", "/live.mpd?max_bitrate=2000000")
+	require.EqualInt(t, 2, len(m.Periods[0].AdaptationSets[1].Representations))
+
+	m = applyTransform(t, transform, "live_profile.mpd", "/live.mpd?min_bitrate=2000000")
+	require.EqualInt(t, 1, len(m.Periods[0].AdaptationSets))
+	require.EqualInt(t, 2, len(m.Periods[0].AdaptationSets[0].Representations))
+
+	m = applyTransform(t, transform, "live_profile.mpd", "/live.mpd")
+	require.EqualInt(t, 3, len(m.Periods[0].AdaptationSets))
+
+	_, err := transform(httptest.NewRequest(http.MethodGet, "/live.mpd?max_bitrate=-1", nil), m)
+	require.EqualError(t, err, `Invalid query parameter max_bitrate: "-1"`)
+}
+
+func TestLanguagesFromQuery(t *testing.T) {
+	m := applyTransform(t, LanguagesFromQuery("lang"), "live_profile.mpd", "/live.mpd?lang=fr,de")
+	require.EqualInt(t, 1, len(m.Periods[0].AdaptationSets))
+	require.EqualString(t, "video/mp4", *m.Periods[0].AdaptationSets[0].MimeType)
+
+	m = applyTransform(t, LanguagesFromQuery("lang"), "live_profile.mpd", "/live.mpd?lang=")
+	require.EqualInt(t, 3, len(m.Periods[0].AdaptationSets))
+}
+
+func TestSetBaseURL(t *testing.T) {
+	m := applyTransform(t, SetBaseURL("https://cdn.example.com/content/"), "live_profile.mpd", "/live.mpd")
+	require.EqualStringSlice(t, []string{"https://cdn.example.com/content/"}, m.BaseURL)
+
+	m = applyTransform(t, SetBaseURL("https://cdn.example.com/content/"), "live_profile_multi_base_url.mpd", "/live.mpd")
+	require.EqualStringSlice(t, []string{"https://cdn.example.com/content/", "https://cdn.example.com/a/", "https://cdn.example.com/b/"}, m.BaseURL)
+}
+
+func TestRewriteBaseURLs(t *testing.T) {
+	m := applyTransform(t, RewriteBaseURLs(func(r *http.Request, baseURL string) string {
+		return strings.Replace(baseURL, "http://example.com/", "https://cdn.example.com/", 1)
+	}), "ondemand_profile.mpd", "/ondemand.mpd")
+	require.EqualStringSlice(t, []string{"https://cdn.example.com/content/sintel/subtitles/subtitles_en.vtt"}, m.Periods[0].AdaptationSets[2].Representations[0].BaseURL)
+	require.EqualStringSlice(t, []string{"800k/output-video-1.mp4"}, m.Periods[0].AdaptationSets[1].Representations[0].BaseURL)
+}
+
+func TestInjectToken(t *testing.T) {
+	m := applyTransform(t, InjectToken("token"), "live_profile.mpd", "/live.mpd?token=a%24b")
+	st := m.Periods[0].AdaptationSets[0].SegmentTemplate
+	require.EqualString(t, "$RepresentationID$/audio/en/init.mp4?token=a%24b", *st.Initialization)
+	require.EqualString(t, "$RepresentationID$/audio/en/seg-$Number$.m4f?token=a%24b", *st.Media)
+
+	m = applyTransform(t, InjectToken("token"), "ondemand_profile.mpd", "/ondemand.mpd?token=abc")
+	require.EqualStringSlice(t, []string{"800k/output-video-1.mp4?token=abc"}, m.Periods[0].AdaptationSets[1].Representations[0].BaseURL)
+
+	m = applyTransform(t, InjectToken("token"), "segment_list.mpd", "/list.mpd?token=abc")
+	sl := m.Periods[0].AdaptationSets[0].Representations[0].SegmentList
+	require.EqualString(t, "?token=abc", (*sl.SegmentURLs[0].Media)[len(*sl.SegmentURLs[0].Media)-10:])
+
+	m = applyTransform(t, InjectToken("token"), "live_profile.mpd", "/live.mpd")
+	require.EqualString(t, "$RepresentationID$/audio/en/init.mp4", *m.Periods[0].AdaptationSets[0].SegmentTemplate.Initialization)
+}
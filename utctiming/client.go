@@ -0,0 +1,173 @@
+// Package utctiming synchronizes clocks with the time sources of MPD UTCTiming elements, and
+// serves the HTTP time formats for origins to provide their own time source.
+package utctiming
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/zencoder/go-dash/v3/mpd"
+)
+
+// Known error variables
+var (
+	ErrNoUTCTiming       = errors.New("No UTCTiming element")
+	ErrUnsupportedScheme = errors.New("Unsupported UTCTiming scheme")
+	ErrInvalidTime       = errors.New("Invalid time")
+)
+
+// xs:dateTime layouts, the time zone is optional and defaults to UTC
+var dateTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999Z0700",
+	"2006-01-02T15:04:05.999999999",
+}
+
+// Client resolves the server time from UTCTiming elements.
+type Client struct {
+	HTTPClient *http.Client // http.DefaultClient when nil
+	// Dial opens the UDP connections to NTP servers, net.Dialer DialContext when nil
+	Dial  func(ctx context.Context, network, address string) (net.Conn, error)
+	Clock func() time.Time // Local clock, time.Now when nil
+}
+
+// Creates a new Client with the default HTTP client, dialer and local clock.
+func NewClient() *Client {
+	return &Client{}
+}
+
+// Now returns the server time, from the first UTCTiming that resolves.
+func (c *Client) Now(ctx context.Context, utcTimings []*mpd.DescriptorType) (time.Time, error) {
+	offset, err := c.Offset(ctx, utcTimings)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return c.now().Add(offset), nil
+}
+
+// Offset returns the server time minus the local clock. The UTCTiming elements are tried in
+// document order, and the white-space separated sources of each in order, until one resolves. The
+// error joins the failure of every source when none does.
+func (c *Client) Offset(ctx context.Context, utcTimings []*mpd.DescriptorType) (time.Duration, error) {
+	var errs []error
+	for _, utcTiming := range utcTimings {
+		if utcTiming == nil || utcTiming.SchemeIDURI == nil || *utcTiming.SchemeIDURI == "" {
+			continue
+		}
+		scheme := *utcTiming.SchemeIDURI
+		value := ""
+		if utcTiming.Value != nil {
+			value = *utcTiming.Value
+		}
+		sources := strings.Fields(value)
+		if scheme == mpd.UTC_TIMING_DIRECT_SCHEME_ID {
+			sources = []string{strings.TrimSpace(value)}
+		}
+		for _, source := range sources {
+			offset, err := c.offset(ctx, scheme, source)
+			if err == nil {
+				return offset, nil
+			}
+			errs = append(errs, fmt.Errorf("%s %s: %w", scheme, source, err))
+			if ctx.Err() != nil {
+				return 0, errors.Join(errs...)
+			}
+		}
+	}
+	if len(errs) == 0 {
+		return 0, ErrNoUTCTiming
+	}
+	return 0, errors.Join(errs...)
+}
+
+// offset resolves a single time source.
+func (c *Client) offset(ctx context.Context, scheme, source string) (time.Duration, error) {
+	switch scheme {
+	case mpd.UTC_TIMING_DIRECT_SCHEME_ID:
+		t, err := ParseTime(source)
+		if err != nil {
+			return 0, err
+		}
+		return t.Sub(c.now()), nil
+	case mpd.UTC_TIMING_HTTP_XSDATE_SCHEME_ID, mpd.UTC_TIMING_HTTP_ISO_SCHEME_ID:
+		return c.httpOffset(ctx, http.MethodGet, source, func(resp *http.Response) (time.Time, error) {
+			body, err := io.ReadAll(io.LimitReader(resp.Body, 1024))
+			if err != nil {
+				return time.Time{}, err
+			}
+			return ParseTime(string(body))
+		})
+	case mpd.UTC_TIMING_HTTP_HEAD_SCHEME_ID:
+		return c.httpOffset(ctx, http.MethodHead, source, func(resp *http.Response) (time.Time, error) {
+			t, err := http.ParseTime(resp.Header.Get("Date"))
+			if err != nil {
+				return time.Time{}, fmt.Errorf("%w: Date header %q", ErrInvalidTime, resp.Header.Get("Date"))
+			}
+			return t, nil
+		})
+	case mpd.UTC_TIMING_HTTP_NTP_SCHEME_ID:
+		return c.httpOffset(ctx, http.MethodGet, source, func(resp *http.Response) (time.Time, error) {
+			b := make([]byte, 8)
+			if _, err := io.ReadFull(resp.Body, b); err != nil {
+				return time.Time{}, fmt.Errorf("%w: NTP timestamp truncated", ErrInvalidTime)
+			}
+			return fromNTP(binary.BigEndian.Uint64(b)), nil
+		})
+	case mpd.UTC_TIMING_NTP_SCHEME_ID:
+		return c.ntpOffset(ctx, source)
+	}
+	return 0, ErrUnsupportedScheme
+}
+
+// httpOffset requests a time source, the server time being taken halfway through the round trip.
+func (c *Client) httpOffset(ctx context.Context, method, url string, parse func(resp *http.Response) (time.Time, error)) (time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return 0, err
+	}
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	sent := c.now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("%s", resp.Status)
+	}
+	server, err := parse(resp)
+	if err != nil {
+		return 0, err
+	}
+	received := c.now()
+	return server.Add(received.Sub(sent) / 2).Sub(received), nil
+}
+
+func (c *Client) now() time.Time {
+	if c.Clock != nil {
+		return c.Clock()
+	}
+	return time.Now()
+}
+
+// ParseTime parses an xs:dateTime or ISO 8601 time (i.e. 2024-01-01T00:00:00.000Z), in UTC when
+// it has no time zone.
+func ParseTime(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range dateTimeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%w: %q", ErrInvalidTime, s)
+}
@@ -0,0 +1,125 @@
+package utctiming
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/zencoder/go-dash/v3/helpers/require"
+	"github.com/zencoder/go-dash/v3/mpd"
+)
+
+var testNow = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func newTestClient() *Client {
+	return &Client{Clock: func() time.Time { return testNow }}
+}
+
+func newTestServer(t *testing.T) *httptest.Server {
+	s := httptest.NewServer(&Handler{Clock: func() time.Time { return testNow.Add(10 * time.Second) }})
+	t.Cleanup(s.Close)
+	return s
+}
+
+func utcTimings(pairs ...string) []*mpd.DescriptorType {
+	m := &mpd.MPD{}
+	for i := 0; i < len(pairs); i += 2 {
+		m.AddNewUTCTiming(pairs[i], pairs[i+1])
+	}
+	return m.UTCTiming
+}
+
+func TestOffsetHTTPSchemes(t *testing.T) {
+	s := newTestServer(t)
+	c := newTestClient()
+	for _, utcTiming := range (&Handler{}).UTCTimings(s.URL) {
+		offset, err := c.Offset(context.Background(), []*mpd.DescriptorType{utcTiming})
+		require.NoError(t, err, *utcTiming.SchemeIDURI)
+		require.EqualInt(t, int(10*time.Second), int(offset), *utcTiming.SchemeIDURI)
+	}
+}
+
+func TestOffsetDirect(t *testing.T) {
+	offset, err := newTestClient().Offset(context.Background(),
+		utcTimings(mpd.UTC_TIMING_DIRECT_SCHEME_ID, " 2024-01-01T00:00:05.5Z "))
+	require.NoError(t, err)
+	require.EqualInt(t, int(5500*time.Millisecond), int(offset))
+}
+
+func TestNow(t *testing.T) {
+	now, err := newTestClient().Now(context.Background(),
+		utcTimings(mpd.UTC_TIMING_DIRECT_SCHEME_ID, "2024-01-01T00:01:00Z"))
+	require.NoError(t, err)
+	require.EqualString(t, "2024-01-01T00:01:00Z", now.Format(time.RFC3339Nano))
+}
+
+func TestOffsetFallback(t *testing.T) {
+	s := newTestServer(t)
+	offset, err := newTestClient().Offset(context.Background(), utcTimings(
+		"urn:example:unknown", "x",
+		mpd.UTC_TIMING_HTTP_ISO_SCHEME_ID, s.URL+"/missing "+s.URL+PATH_ISO,
+	))
+	require.NoError(t, err)
+	require.EqualInt(t, int(10*time.Second), int(offset))
+}
+
+func TestOffsetErrors(t *testing.T) {
+	s := newTestServer(t)
+	c := newTestClient()
+
+	_, err := c.Offset(context.Background(), nil)
+	require.EqualErr(t, ErrNoUTCTiming, err)
+	_, err = c.Offset(context.Background(), []*mpd.DescriptorType{nil, {}})
+	require.EqualErr(t, ErrNoUTCTiming, err)
+
+	_, err = c.Offset(context.Background(), utcTimings(
+		"urn:example:unknown", "x",
+		mpd.UTC_TIMING_HTTP_XSDATE_SCHEME_ID, s.URL+"/missing",
+		mpd.UTC_TIMING_DIRECT_SCHEME_ID, "yesterday",
+	))
+	if !errors.Is(err, ErrUnsupportedScheme) || !errors.Is(err, ErrInvalidTime) {
+		t.Fatalf("Expected the failures of every source, got %v", err)
+	}
+}
+
+func TestOffsetCustomHTTPClient(t *testing.T) {
+	c := newTestClient()
+	c.HTTPClient = &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		w := httptest.NewRecorder()
+		(&Handler{Clock: func() time.Time { return testNow.Add(-time.Second) }}).ServeHTTP(w, r)
+		return w.Result(), nil
+	})}
+	offset, err := c.Offset(context.Background(),
+		utcTimings(mpd.UTC_TIMING_HTTP_HEAD_SCHEME_ID, "http://time.example.com"+PATH_HEAD))
+	require.NoError(t, err)
+	require.EqualInt(t, int(-time.Second), int(offset))
+}
+
+type roundTripFunc func(r *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func TestParseTime(t *testing.T) {
+	for s, expected := range map[string]string{
+		"2024-01-01T00:00:00Z":          "2024-01-01T00:00:00Z",
+		"2024-01-01T00:00:00.123Z":      "2024-01-01T00:00:00.123Z",
+		"2024-01-01T02:00:00+02:00":     "2024-01-01T00:00:00Z",
+		"2024-01-01T02:00:00.5+0200":    "2024-01-01T00:00:00.5Z",
+		"2024-01-01T00:00:00.25\n":      "2024-01-01T00:00:00.25Z",
+		"2024-01-01T00:00:00.000000001": "2024-01-01T00:00:00.000000001Z",
+	} {
+		got, err := ParseTime(s)
+		require.NoError(t, err, s)
+		require.EqualString(t, expected, got.UTC().Format(time.RFC3339Nano), s)
+	}
+
+	_, err := ParseTime("Mon, 01 Jan 2024 00:00:00 GMT")
+	if !errors.Is(err, ErrInvalidTime) {
+		t.Fatalf("Expected %s but got %v", ErrInvalidTime, err)
+	}
+}
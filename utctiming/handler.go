@@ -0,0 +1,86 @@
+package utctiming
+
+import (
+	"encoding/binary"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/zencoder/go-dash/v3/mpd"
+)
+
+// Constants for the served time formats
+const (
+	PATH_XSDATE     = "/xsdate"
+	PATH_ISO        = "/iso"
+	PATH_NTP        = "/ntp"
+	PATH_HEAD       = "/head"
+	dateTimeLayout  = "2006-01-02T15:04:05.000Z"
+	contentTypeText = "text/plain; charset=utf-8"
+)
+
+// Handler serves the time of its clock in the formats of the HTTP UTCTiming schemes: xs:dateTime
+// at PATH_XSDATE, ISO 8601 at PATH_ISO, a 64-bit NTP timestamp at PATH_NTP and the Date header of
+// every response, including PATH_HEAD. Paths match by suffix, so the Handler can be mounted under a
+// prefix.
+type Handler struct {
+	Clock func() time.Time // time.Now when nil
+}
+
+// Creates a new Handler serving the system time.
+func NewHandler() *Handler {
+	return &Handler{}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	now := time.Now()
+	if h.Clock != nil {
+		now = h.Clock()
+	}
+	now = now.UTC()
+
+	var contentType string
+	var body []byte
+	switch {
+	case strings.HasSuffix(r.URL.Path, PATH_XSDATE), strings.HasSuffix(r.URL.Path, PATH_ISO):
+		contentType = contentTypeText
+		body = []byte(now.Format(dateTimeLayout))
+	case strings.HasSuffix(r.URL.Path, PATH_NTP):
+		contentType = "application/octet-stream"
+		body = binary.BigEndian.AppendUint64(nil, toNTP(now))
+	case strings.HasSuffix(r.URL.Path, PATH_HEAD):
+		contentType = contentTypeText
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	header := w.Header()
+	header.Set("Date", now.Format(http.TimeFormat))
+	header.Set("Cache-Control", "no-store")
+	header.Set("Content-Type", contentType)
+	header.Set("Access-Control-Allow-Origin", "*")
+	header.Set("Access-Control-Expose-Headers", "Date")
+	w.WriteHeader(http.StatusOK)
+	if r.Method != http.MethodHead {
+		_, _ = w.Write(body)
+	}
+}
+
+// UTCTimings returns UTCTiming elements for the formats of the Handler, from the most to the least
+// precise.
+// baseURL - URL the Handler is mounted at (i.e. https://origin.example.com/time).
+func (h *Handler) UTCTimings(baseURL string) []*mpd.DescriptorType {
+	baseURL = strings.TrimSuffix(baseURL, "/")
+	m := &mpd.MPD{}
+	m.AddNewUTCTiming(mpd.UTC_TIMING_HTTP_XSDATE_SCHEME_ID, baseURL+PATH_XSDATE)
+	m.AddNewUTCTiming(mpd.UTC_TIMING_HTTP_ISO_SCHEME_ID, baseURL+PATH_ISO)
+	m.AddNewUTCTiming(mpd.UTC_TIMING_HTTP_NTP_SCHEME_ID, baseURL+PATH_NTP)
+	m.AddNewUTCTiming(mpd.UTC_TIMING_HTTP_HEAD_SCHEME_ID, baseURL+PATH_HEAD)
+	return m.UTCTiming
+}
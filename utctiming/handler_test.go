@@ -0,0 +1,60 @@
+package utctiming
+
+import (
+	"encoding/binary"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/zencoder/go-dash/v3/helpers/ptrs"
+	"github.com/zencoder/go-dash/v3/helpers/require"
+	"github.com/zencoder/go-dash/v3/mpd"
+)
+
+func serveTest(method, target string) *httptest.ResponseRecorder {
+	h := &Handler{Clock: func() time.Time { return testNow.Add(1500 * time.Millisecond) }}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(method, target, nil))
+	return w
+}
+
+func TestServeTimeFormats(t *testing.T) {
+	for _, path := range []string{PATH_XSDATE, PATH_ISO, "/time" + PATH_ISO} {
+		w := serveTest(http.MethodGet, path)
+		require.EqualInt(t, http.StatusOK, w.Code, path)
+		require.EqualString(t, "2024-01-01T00:00:01.500Z", w.Body.String(), path)
+		require.EqualString(t, "Mon, 01 Jan 2024 00:00:01 GMT", w.Header().Get("Date"), path)
+		require.EqualString(t, "no-store", w.Header().Get("Cache-Control"), path)
+		require.EqualString(t, "Date", w.Header().Get("Access-Control-Expose-Headers"), path)
+	}
+
+	w := serveTest(http.MethodGet, PATH_NTP)
+	require.EqualInt(t, http.StatusOK, w.Code)
+	require.EqualInt(t, 8, w.Body.Len())
+	require.EqualString(t, "2024-01-01T00:00:01.5Z",
+		fromNTP(binary.BigEndian.Uint64(w.Body.Bytes())).Format(time.RFC3339Nano))
+
+	w = serveTest(http.MethodHead, PATH_HEAD)
+	require.EqualInt(t, http.StatusOK, w.Code)
+	require.EqualString(t, "Mon, 01 Jan 2024 00:00:01 GMT", w.Header().Get("Date"))
+	require.EqualString(t, "", w.Body.String())
+}
+
+func TestServeErrors(t *testing.T) {
+	w := serveTest(http.MethodPost, PATH_ISO)
+	require.EqualInt(t, http.StatusMethodNotAllowed, w.Code)
+	require.EqualString(t, "GET, HEAD", w.Header().Get("Allow"))
+
+	w = serveTest(http.MethodGet, "/other")
+	require.EqualInt(t, http.StatusNotFound, w.Code)
+}
+
+func TestUTCTimings(t *testing.T) {
+	utcTimings := NewHandler().UTCTimings("https://origin.example.com/time/")
+	require.EqualInt(t, 4, len(utcTimings))
+	require.EqualStringPtr(t, ptrs.Strptr(mpd.UTC_TIMING_HTTP_XSDATE_SCHEME_ID), utcTimings[0].SchemeIDURI)
+	require.EqualStringPtr(t, ptrs.Strptr("https://origin.example.com/time/xsdate"), utcTimings[0].Value)
+	require.EqualStringPtr(t, ptrs.Strptr(mpd.UTC_TIMING_HTTP_HEAD_SCHEME_ID), utcTimings[3].SchemeIDURI)
+	require.EqualStringPtr(t, ptrs.Strptr("https://origin.example.com/time/head"), utcTimings[3].Value)
+}
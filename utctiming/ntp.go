@@ -0,0 +1,93 @@
+package utctiming
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+// Constants for the NTP time sources
+const (
+	NTP_PORT            = "123"
+	DEFAULT_NTP_TIMEOUT = 5 * time.Second
+	ntpPacketSize       = 48
+	ntpModeClient       = 3
+	ntpModeServer       = 4
+	ntpVersion          = 4
+	// Seconds from the NTP epoch (1900) to the Unix epoch
+	ntpEpochOffset = 2208988800
+)
+
+// ntpOffset queries an NTP server with SNTP (RFC 4330).
+// server - host, with an optional port (i.e. time.example.com or 192.0.2.1:123).
+func (c *Client) ntpOffset(ctx context.Context, server string) (time.Duration, error) {
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, NTP_PORT)
+	}
+	dial := c.Dial
+	if dial == nil {
+		dial = (&net.Dialer{}).DialContext
+	}
+	conn, err := dial(ctx, "udp", server)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(DEFAULT_NTP_TIMEOUT)
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		return 0, err
+	}
+
+	request := make([]byte, ntpPacketSize)
+	request[0] = ntpVersion<<3 | ntpModeClient
+	sent := c.now()
+	originate := toNTP(sent)
+	binary.BigEndian.PutUint64(request[40:48], originate)
+	if _, err := conn.Write(request); err != nil {
+		return 0, err
+	}
+	response := make([]byte, ntpPacketSize)
+	n, err := conn.Read(response)
+	if err != nil {
+		return 0, err
+	}
+	received := c.now()
+
+	switch {
+	case n < ntpPacketSize:
+		return 0, errors.New("NTP response truncated")
+	case response[0]&0x7 != ntpModeServer:
+		return 0, fmt.Errorf("NTP response mode %d", response[0]&0x7)
+	case response[1] == 0:
+		return 0, fmt.Errorf("NTP kiss-o'-death %q", response[12:16])
+	case binary.BigEndian.Uint64(response[24:32]) != originate:
+		return 0, errors.New("NTP response doesn't match the request")
+	}
+	receive := fromNTP(binary.BigEndian.Uint64(response[32:40]))
+	transmit := fromNTP(binary.BigEndian.Uint64(response[40:48]))
+	return (receive.Sub(sent) + transmit.Sub(received)) / 2, nil
+}
+
+// toNTP converts a time to a 64-bit NTP timestamp.
+func toNTP(t time.Time) uint64 {
+	seconds := uint64(t.Unix()+ntpEpochOffset) & 0xffffffff
+	fraction := uint64(t.Nanosecond()) << 32 / uint64(time.Second)
+	return seconds<<32 | fraction
+}
+
+// fromNTP converts a 64-bit NTP timestamp to a time. Timestamps with the most significant bit
+// clear are in era 1, from 2036, as in RFC 4330 section 3.
+func fromNTP(ntp uint64) time.Time {
+	seconds := int64(ntp >> 32)
+	if seconds&0x80000000 == 0 {
+		seconds += 1 << 32
+	}
+	nanoseconds := int64((ntp & 0xffffffff) * uint64(time.Second) >> 32)
+	return time.Unix(seconds-ntpEpochOffset, nanoseconds).UTC()
+}
@@ -0,0 +1,74 @@
+package utctiming
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/zencoder/go-dash/v3/helpers/require"
+	"github.com/zencoder/go-dash/v3/mpd"
+)
+
+// newTestNTPServer answers a single SNTP request, reply editing the response.
+func newTestNTPServer(t *testing.T, serverTime time.Time, reply func(response []byte)) string {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	go func() {
+		request := make([]byte, ntpPacketSize)
+		n, addr, err := conn.ReadFrom(request)
+		if err != nil || n < ntpPacketSize {
+			return
+		}
+		response := make([]byte, ntpPacketSize)
+		response[0] = ntpVersion<<3 | ntpModeServer
+		response[1] = 1
+		copy(response[24:32], request[40:48])
+		binary.BigEndian.PutUint64(response[32:40], toNTP(serverTime))
+		binary.BigEndian.PutUint64(response[40:48], toNTP(serverTime))
+		if reply != nil {
+			reply(response)
+		}
+		_, _ = conn.WriteTo(response, addr)
+	}()
+	return conn.LocalAddr().String()
+}
+
+func TestOffsetNTP(t *testing.T) {
+	server := newTestNTPServer(t, testNow.Add(-3*time.Second), nil)
+	offset, err := newTestClient().Offset(context.Background(),
+		utcTimings(mpd.UTC_TIMING_NTP_SCHEME_ID, server))
+	require.NoError(t, err)
+	require.EqualInt(t, int(-3*time.Second), int(offset))
+}
+
+func TestOffsetNTPErrors(t *testing.T) {
+	for name, reply := range map[string]func(response []byte){
+		"NTP response mode 3":                    func(response []byte) { response[0] = ntpVersion<<3 | ntpModeClient },
+		"NTP kiss-o'-death \"RATE\"":             func(response []byte) { response[1] = 0; copy(response[12:16], "RATE") },
+		"NTP response doesn't match the request": func(response []byte) { response[31]++ },
+	} {
+		server := newTestNTPServer(t, testNow, reply)
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		_, err := newTestClient().ntpOffset(ctx, server)
+		cancel()
+		require.EqualError(t, err, name)
+	}
+}
+
+func TestNTPTimestamps(t *testing.T) {
+	for _, s := range []string{
+		"1970-01-01T00:00:00Z",
+		"2024-01-01T00:00:00.5Z",
+		"2036-02-07T06:28:15Z",
+		"2036-02-07T06:28:16Z",
+		"2040-01-01T00:00:00.25Z",
+	} {
+		expected, err := time.Parse(time.RFC3339Nano, s)
+		require.NoError(t, err)
+		require.EqualString(t, s, fromNTP(toNTP(expected)).Format(time.RFC3339Nano), s)
+	}
+	require.EqualUInt64(t, 0xe93c7f0080000000, toNTP(time.Date(2024, 1, 1, 0, 0, 0, 5e8, time.UTC)))
+}